@@ -0,0 +1,515 @@
+package audit
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+// AuditDB records an audit entry for every successful change made through it.
+// It is created for a single command and stamps entries with its author and text
+type AuditDB struct {
+	database.DataProvider
+	author  string
+	command string
+	// set for providers passed to RunInTx callbacks
+	tx bool
+}
+
+// constructor function
+func NewAuditDb(prov database.DataProvider, author string, command string) *AuditDB {
+	return &AuditDB{DataProvider: prov, author: author, command: command}
+}
+
+// RunInTx records entries in the same transaction as changes, so rolled back changes leave no trace
+func (a *AuditDB) RunInTx(f func(tx database.DataProvider) error) error {
+	return a.DataProvider.RunInTx(func(tx database.DataProvider) error {
+		return f(&AuditDB{DataProvider: tx, author: a.author, command: a.command, tx: true})
+	})
+}
+
+func (a *AuditDB) AddGuild(g *database.Guild) (*database.Guild, error) {
+	rv, err := a.DataProvider.AddGuild(g)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.guild(rv, &database.AuditEntry{Action: "guild added", Target: rv.Name})
+}
+
+func (a *AuditDB) RenameGuild(g uuid.UUID, name string) (*database.Guild, error) {
+	before := a.guildName(g)
+	rv, err := a.DataProvider.RenameGuild(g, name)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.guild(rv, &database.AuditEntry{Action: "guild renamed", Target: rv.Name, Before: before, After: rv.Name})
+}
+
+func (a *AuditDB) MoveGuild(g uuid.UUID, parent uuid.UUID) (*database.Guild, error) {
+	var before string
+	if old, err := a.DataProvider.GetGuild(g); err == nil {
+		before = a.guildName(old.ParentId)
+	}
+
+	rv, err := a.DataProvider.MoveGuild(g, parent)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.guild(rv, &database.AuditEntry{Action: "guild moved", Target: rv.Name, Before: before, After: a.guildName(parent)})
+}
+
+func (a *AuditDB) RemoveGuild(g uuid.UUID) (*database.Guild, error) {
+	rv, err := a.DataProvider.RemoveGuild(g)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.guild(rv, &database.AuditEntry{Action: "guild removed", Target: rv.Name})
+}
+
+func (a *AuditDB) RemoveGuildD(d string) (*database.Guild, error) {
+	rv, err := a.DataProvider.RemoveGuildD(d)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.record(&database.AuditEntry{GuildId: d, Action: "guild removed", Target: rv.Name})
+}
+
+func (a *AuditDB) AddGuildStat(g uuid.UUID, s *database.Stat) (*database.Guild, error) {
+	var old *database.Stat
+	if gld, err := a.DataProvider.GetGuild(g); err == nil {
+		old = gld.Stats[s.ID]
+	}
+
+	rv, err := a.DataProvider.AddGuildStat(g, s)
+	if err != nil {
+		return rv, err
+	}
+
+	if old != nil {
+		for _, e := range statChanges(old, s) {
+			if err = a.guild(rv, e); err != nil {
+				return rv, err
+			}
+		}
+		return rv, nil
+	}
+	return rv, a.guild(rv, &database.AuditEntry{Action: "stat added", Stat: s.ID, After: database.TypeToString(s.Type)})
+}
+
+// statChanges describes every changed part of stat definition, one entry per part.
+// Stat added again unchanged is recorded as a change without differences
+func statChanges(old *database.Stat, s *database.Stat) []*database.AuditEntry {
+	rv := make([]*database.AuditEntry, 0, 1)
+	add := func(action string, before string, after string) {
+		rv = append(rv, &database.AuditEntry{Action: action, Stat: s.ID, Before: before, After: after})
+	}
+
+	if old.Type != s.Type {
+		add("stat type changed", database.TypeToString(old.Type), database.TypeToString(s.Type))
+	}
+	if old.Description != s.Description {
+		add("stat description set", old.Description, s.Description)
+	}
+	if strings.Join(old.Values, ", ") != strings.Join(s.Values, ", ") {
+		add("stat values set", strings.Join(old.Values, ", "), strings.Join(s.Values, ", "))
+	}
+	if old.Formula != s.Formula {
+		add("stat formula set", old.Formula, s.Formula)
+	}
+	if old.Write != s.Write && s.Write == database.WriteSelf {
+		add("stat unlocked", writePolicy(old.Write), writePolicy(s.Write))
+	} else if old.Write != s.Write {
+		add("stat locked", writePolicy(old.Write), writePolicy(s.Write))
+	}
+	if old.Review != s.Review {
+		add("stat review set", strconv.FormatBool(old.Review), strconv.FormatBool(s.Review))
+	}
+	if old.Category != s.Category {
+		add("stat category set", old.Category, s.Category)
+	}
+	if old.Order != s.Order {
+		add("stat order set", strconv.Itoa(old.Order), strconv.Itoa(s.Order))
+	}
+	if before, after := limits(old), limits(s); before != after || len(rv) == 0 {
+		add("stat changed", before, after)
+	}
+
+	return rv
+}
+
+// limits lists constraints of s except for write policy and review, they are recorded separately
+func limits(s *database.Stat) string {
+	tmp := *s
+	tmp.Write, tmp.Review = database.WriteSelf, false
+	return tmp.Constraints()
+}
+
+func writePolicy(w int) string {
+	switch w {
+	case database.WriteOfficers:
+		return "officers"
+	case database.WriteGuild:
+		return "guild"
+	}
+	return "self"
+}
+
+func (a *AuditDB) SetDefaultGuildStat(g uuid.UUID, sn string) (*database.Guild, error) {
+	var before string
+	if old, err := a.DataProvider.GetGuild(g); err == nil {
+		before = old.DefaultStat
+	}
+
+	rv, err := a.DataProvider.SetDefaultGuildStat(g, sn)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.guild(rv, &database.AuditEntry{Action: "default stat set", Stat: sn, Before: before, After: sn})
+}
+
+func (a *AuditDB) RemoveGuildStat(g uuid.UUID, n string) (*database.Guild, error) {
+	rv, err := a.DataProvider.RemoveGuildStat(g, n)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.guild(rv, &database.AuditEntry{Action: "stat removed", Stat: n})
+}
+
+func (a *AuditDB) RenameGuildStat(g uuid.UUID, old string, name string) (*database.Guild, error) {
+	rv, err := a.DataProvider.RenameGuildStat(g, old, name)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.guild(rv, &database.AuditEntry{Action: "stat renamed", Stat: name, Before: old, After: name})
+}
+
+func (a *AuditDB) ChangeGuildStatType(g uuid.UUID, s *database.Stat) (*database.Guild, error) {
+	var before string
+	if old, err := a.DataProvider.GetGuild(g); err == nil && old.Stats[s.ID] != nil {
+		before = database.TypeToString(old.Stats[s.ID].Type)
+	}
+
+	rv, err := a.DataProvider.ChangeGuildStatType(g, s)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.guild(rv, &database.AuditEntry{Action: "stat type changed", Stat: s.ID, Before: before, After: database.TypeToString(s.Type)})
+}
+
+func (a *AuditDB) RemoveAllGuildStats(g uuid.UUID) (*database.Guild, error) {
+	rv, err := a.DataProvider.RemoveAllGuildStats(g)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.guild(rv, &database.AuditEntry{Action: "stats reset"})
+}
+
+func (a *AuditDB) AddUser(d string, g *database.GuildPermission) (*database.User, error) {
+	rv, err := a.DataProvider.AddUser(d, g)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.record(&database.AuditEntry{GuildId: g.TopGuild, Action: "user added", UserId: d, After: a.guildName(g.GuildId)})
+}
+
+func (a *AuditDB) SetUserPermissions(u string, g *database.GuildPermission) (*database.User, error) {
+	before := a.userGuild(u, g.TopGuild)
+	rv, err := a.DataProvider.SetUserPermissions(u, g)
+	if err != nil {
+		return rv, err
+	}
+
+	e := &database.AuditEntry{GuildId: g.TopGuild, Action: "user permissions set", UserId: u, After: database.PermissionToString(g.Permissions)}
+	if before != nil {
+		e.Before = database.PermissionToString(before.Permissions)
+	}
+	return rv, a.record(e)
+}
+
+func (a *AuditDB) SetUserSubGuild(u string, g *database.GuildPermission) (*database.User, error) {
+	before := a.userGuild(u, g.TopGuild)
+	rv, err := a.DataProvider.SetUserSubGuild(u, g)
+	if err != nil {
+		return rv, err
+	}
+
+	e := &database.AuditEntry{GuildId: g.TopGuild, Action: "user assigned", UserId: u, After: a.guildName(g.GuildId)}
+	if before != nil {
+		e.Before = a.guildName(before.GuildId)
+	}
+	return rv, a.record(e)
+}
+
+func (a *AuditDB) RemoveUserD(d string, g string) (*database.User, error) {
+	rv, err := a.DataProvider.RemoveUserD(d, g)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.record(&database.AuditEntry{GuildId: g, Action: "user removed", UserId: d})
+}
+
+// EraseUserD is not recorded: users asking to be forgotten should not stay in the log
+
+func (a *AuditDB) AddCharacter(c *database.Character) (*database.Character, error) {
+	rv, err := a.DataProvider.AddCharacter(c)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.character(rv, &database.AuditEntry{Action: "character added"})
+}
+
+func (a *AuditDB) RenameCharacter(g string, u string, old string, name string) (*database.Character, error) {
+	rv, err := a.DataProvider.RenameCharacter(g, u, old, name)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.character(rv, &database.AuditEntry{Action: "character renamed", Before: old, After: rv.Name})
+}
+
+func (a *AuditDB) ChangeMainCharacter(g string, u string, name string) (*database.Character, error) {
+	var before string
+	if old, err := a.DataProvider.GetMainCharacter(g, u); err == nil {
+		before = old.Name
+	}
+
+	rv, err := a.DataProvider.ChangeMainCharacter(g, u, name)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.character(rv, &database.AuditEntry{Action: "main character changed", Before: before, After: rv.Name})
+}
+
+func (a *AuditDB) SetCharacterStat(g string, u string, name string, s string, v interface{}) (*database.Character, error) {
+	before := a.stat(g, u, name, s)
+	rv, err := a.DataProvider.SetCharacterStat(g, u, name, s, v)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.character(rv, &database.AuditEntry{Action: "stat set", Stat: s, Before: before, After: database.FormatStatValue(v)})
+}
+
+func (a *AuditDB) SetCharacterStatVersion(g string, u string, name string, stats map[string]*database.Stat, version int) (*database.Character, error) {
+	var before string
+	if old, err := a.DataProvider.GetCharacter(g, u, name); err == nil {
+		before = fmt.Sprint(old.StatVersion)
+	}
+
+	rv, err := a.DataProvider.SetCharacterStatVersion(g, u, name, stats, version)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.character(rv, &database.AuditEntry{Action: "stats version set", Before: before, After: fmt.Sprint(version)})
+}
+
+func (a *AuditDB) ChangeCharacterOwner(g string, old string, name string, u string) (*database.Character, error) {
+	rv, err := a.DataProvider.ChangeCharacterOwner(g, old, name, u)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.character(rv, &database.AuditEntry{Action: "character owner changed", Before: old, After: u})
+}
+
+func (a *AuditDB) RemoveCharacterStat(g string, u string, name string, s string) (*database.Character, error) {
+	before := a.stat(g, u, name, s)
+	rv, err := a.DataProvider.RemoveCharacterStat(g, u, name, s)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.character(rv, &database.AuditEntry{Action: "stat removed", Stat: s, Before: before})
+}
+
+func (a *AuditDB) RemoveCharacter(g string, u string, name string) (*database.Character, error) {
+	rv, err := a.DataProvider.RemoveCharacter(g, u, name)
+	// removing a missing character is not an error, but there is nothing to record
+	if err != nil || rv == nil {
+		return rv, err
+	}
+
+	return rv, a.character(rv, &database.AuditEntry{Action: "character removed"})
+}
+
+func (a *AuditDB) AddRole(r *database.Role) (*database.Role, error) {
+	rv, err := a.DataProvider.AddRole(r)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.record(&database.AuditEntry{GuildId: rv.GuildId, Action: "role added", Target: rv.Id, After: database.PermissionToString(rv.Permissions)})
+}
+
+func (a *AuditDB) SetRolePermissions(g string, r string, p int) (*database.Role, error) {
+	var before string
+	if old, err := a.DataProvider.GetRole(g, r); err == nil {
+		before = database.PermissionToString(old.Permissions)
+	}
+
+	rv, err := a.DataProvider.SetRolePermissions(g, r, p)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.record(&database.AuditEntry{GuildId: g, Action: "role permissions set", Target: r, Before: before, After: database.PermissionToString(p)})
+}
+
+func (a *AuditDB) RemoveRole(g string, r string) (*database.Role, error) {
+	rv, err := a.DataProvider.RemoveRole(g, r)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.record(&database.AuditEntry{GuildId: g, Action: "role removed", Target: r, Before: database.PermissionToString(rv.Permissions)})
+}
+
+func (a *AuditDB) AddMoney(m *database.Money) (*database.Money, error) {
+	rv, err := a.DataProvider.AddMoney(m)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.record(&database.AuditEntry{GuildId: rv.GuildId, Action: "payment registered", UserId: rv.UserId})
+}
+
+func (a *AuditDB) ChangeMoneyOwner(g string, u string) (*database.Money, error) {
+	var before string
+	if old, err := a.DataProvider.GetMoney(g); err == nil {
+		before = old.UserId
+	}
+
+	rv, err := a.DataProvider.ChangeMoneyOwner(g, u)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.record(&database.AuditEntry{GuildId: g, Action: "payment owner changed", UserId: u, Before: before, After: u})
+}
+
+func (a *AuditDB) SetMoneyValid(g string, t time.Time) (*database.Money, error) {
+	var before string
+	if old, err := a.DataProvider.GetMoney(g); err == nil {
+		before = old.ValidTo.Format(time.RFC3339)
+	}
+
+	rv, err := a.DataProvider.SetMoneyValid(g, t)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.record(&database.AuditEntry{GuildId: g, Action: "subscription extended", Before: before, After: t.Format(time.RFC3339)})
+}
+
+func (a *AuditDB) AddSubmission(sub *database.Submission) (*database.Submission, error) {
+	rv, err := a.DataProvider.AddSubmission(sub)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.submission(rv, &database.AuditEntry{Action: "stat submitted", After: database.FormatStatValue(rv.Value)})
+}
+
+func (a *AuditDB) RemoveSubmission(g string, id int) (*database.Submission, error) {
+	rv, err := a.DataProvider.RemoveSubmission(g, id)
+	if err != nil {
+		return rv, err
+	}
+
+	return rv, a.submission(rv, &database.AuditEntry{Action: "submission closed", Before: database.FormatStatValue(rv.Value)})
+}
+
+// record stores e on behalf of the command. In transactions a failure rolls the change back with it.
+// Outside of them the change is already applied, so a failure is logged and the change succeeds
+func (a *AuditDB) record(e *database.AuditEntry) error {
+	e.AuthorId = a.author
+	e.Command = a.command
+	e.Time = time.Now()
+
+	_, err := a.DataProvider.AddAuditEntry(e)
+	if err != nil && !a.tx {
+		fmt.Println("Could not record audit entry:", e.Action, ". Error:", err.Error())
+		return nil
+	}
+	return err
+}
+
+func (a *AuditDB) guild(g *database.Guild, e *database.AuditEntry) error {
+	e.GuildId = g.DiscordId
+	if e.GuildId == "" {
+		if top, err := a.DataProvider.GetGuild(g.TopLevelParentId); err == nil {
+			e.GuildId = top.DiscordId
+		}
+	}
+
+	return a.record(e)
+}
+
+func (a *AuditDB) character(c *database.Character, e *database.AuditEntry) error {
+	e.GuildId, e.UserId, e.Character = c.GuildId, c.UserId, c.Name
+	return a.record(e)
+}
+
+func (a *AuditDB) submission(sub *database.Submission, e *database.AuditEntry) error {
+	e.GuildId, e.UserId, e.Character, e.Stat = sub.GuildId, sub.UserId, sub.Character, sub.Stat
+	e.Target = fmt.Sprintf("#%v", sub.Id)
+	return a.record(e)
+}
+
+func (a *AuditDB) guildName(g uuid.UUID) string {
+	if rv, err := a.DataProvider.GetGuild(g); err == nil {
+		return rv.Name
+	}
+
+	return ""
+}
+
+// userGuild returns a copy, as providers may change returned permissions in place
+func (a *AuditDB) userGuild(u string, g string) *database.GuildPermission {
+	rv, err := a.DataProvider.GetUserD(u)
+	if err != nil {
+		return nil
+	}
+
+	if gp, ok := rv.Guilds[g]; ok {
+		tmp := *gp
+		return &tmp
+	}
+	return nil
+}
+
+func (a *AuditDB) stat(g string, u string, name string, s string) string {
+	c, err := a.DataProvider.GetCharacter(g, u, name)
+	if err != nil {
+		return ""
+	}
+
+	if v, ok := c.Body[s]; ok {
+		return database.FormatStatValue(v)
+	}
+	return ""
+}
+
+func init() {
+	var _ database.DataProvider = (*AuditDB)(nil)
+}
@@ -0,0 +1,89 @@
+package conformance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+func testAuditAdd(t *testing.T, n string, d database.DataProvider) {
+	e := &database.AuditEntry{
+		GuildId:   "gid1",
+		AuthorId:  "uid1",
+		Time:      time.Now(),
+		Command:   "!g s set lvl 10",
+		Action:    "stat set",
+		UserId:    "uid2",
+		Character: "char1",
+		Stat:      "lvl",
+		Before:    "9",
+		After:     "10",
+	}
+
+	r1, err := d.AddAuditEntry(e)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if r1 == e {
+		t.Fatalf("[%v] Duplicate of entry expected, received original", n)
+	}
+	if r1.Id == 0 || r1.Command != e.Command || r1.Before != e.Before || r1.After != e.After || !r1.Time.Equal(e.Time) {
+		t.Fatalf("[%v] Wrong entry returned. Actual: %v, expected: %v", n, r1, e)
+	}
+
+	r2, err := d.AddAuditEntry(e)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if r2.Id <= r1.Id {
+		t.Fatalf("[%v] Growing IDs expected. First: %v, second: %v", n, r1.Id, r2.Id)
+	}
+}
+
+func testAuditGet(t *testing.T, n string, d database.DataProvider) {
+	now := time.Now()
+	entries := []*database.AuditEntry{
+		{GuildId: "gid1", AuthorId: "uid1", Time: now.Add(-3 * time.Hour), Action: "character added", UserId: "uid1", Character: "char1"},
+		{GuildId: "gid1", AuthorId: "uid1", Time: now.Add(-2 * time.Hour), Action: "stat set", UserId: "uid1", Character: "char1", Stat: "lvl"},
+		{GuildId: "gid1", AuthorId: "uid2", Time: now.Add(-time.Hour), Action: "stat set", UserId: "uid3", Character: "char3", Stat: "lvl"},
+		{GuildId: "gid1", AuthorId: "uid2", Time: now, Action: "stat set", UserId: "uid3", Character: "char3", Stat: "power"},
+		{GuildId: "gid2", AuthorId: "uid1", Time: now, Action: "stat set", UserId: "uid1", Character: "char1", Stat: "lvl"},
+	}
+	for _, e := range entries {
+		if _, err := d.AddAuditEntry(e); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		filter   database.AuditFilter
+		expected []*database.AuditEntry
+	}{
+		{"all", database.AuditFilter{}, []*database.AuditEntry{entries[3], entries[2], entries[1], entries[0]}},
+		{"user", database.AuditFilter{UserId: "uid3"}, []*database.AuditEntry{entries[3], entries[2]}},
+		{"author", database.AuditFilter{UserId: "uid2"}, []*database.AuditEntry{entries[3], entries[2]}},
+		{"character", database.AuditFilter{Character: "char1"}, []*database.AuditEntry{entries[1], entries[0]}},
+		{"stat", database.AuditFilter{Stat: "lvl"}, []*database.AuditEntry{entries[2], entries[1]}},
+		{"range", database.AuditFilter{From: now.Add(-150 * time.Minute), To: now.Add(-time.Minute)}, []*database.AuditEntry{entries[2], entries[1]}},
+		{"page", database.AuditFilter{Offset: 1, Limit: 2}, []*database.AuditEntry{entries[2], entries[1]}},
+		{"last page", database.AuditFilter{Offset: 3, Limit: 2}, []*database.AuditEntry{entries[0]}},
+		{"no match", database.AuditFilter{Stat: "none"}, []*database.AuditEntry{}},
+	}
+
+	for _, tc := range tests {
+		rv, err := d.GetAuditEntries("gid1", &tc.filter)
+		if err != nil {
+			t.Fatalf("[%v/%v] No errors expected. Received: %v", n, tc.name, err)
+		}
+		if len(rv) != len(tc.expected) {
+			t.Fatalf("[%v/%v] Wrong number of entries. Actual: %v, expected: %v", n, tc.name, len(rv), len(tc.expected))
+		}
+		for i, e := range tc.expected {
+			if rv[i].Action != e.Action || rv[i].AuthorId != e.AuthorId || rv[i].Stat != e.Stat || !rv[i].Time.Equal(e.Time) {
+				t.Fatalf("[%v/%v] Wrong entry %v. Actual: %v, expected: %v", n, tc.name, i, rv[i], e)
+			}
+		}
+	}
+}
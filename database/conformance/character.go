@@ -0,0 +1,1522 @@
+package conformance
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+func testCharAdd(t *testing.T, n string, d database.DataProvider) {
+	gid, id := uuid.New().String(), uuid.New().String()
+
+	c := &database.Character{
+		GuildId: gid,
+		UserId:  id,
+		Name:    "test",
+	}
+	rc, err := d.AddCharacter(c)
+
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rc.Name != c.Name || rc.UserId != c.UserId {
+		t.Fatalf("[%v] Wrong character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+	if rc == c {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	c = &database.Character{
+		GuildId: gid,
+		UserId:  id,
+		Name:    "test2",
+	}
+	rc, err = d.AddCharacter(c)
+
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rc.Name != c.Name || rc.UserId != c.UserId {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+	if rc == c {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	c = &database.Character{
+		GuildId: gid,
+		UserId:  id,
+		Name:    "test2",
+	}
+	rc, err = d.AddCharacter(c)
+
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, rc)
+	}
+	if e := assertError(err, fmt.Sprintf("User already has character with name %v", c.Name), database.CharacterNameTaken, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	c = &database.Character{
+		GuildId: gid,
+		UserId:  uuid.New().String(),
+		Name:    "test2",
+	}
+	rc, err = d.AddCharacter(c)
+
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rc.Name != c.Name || rc.UserId != c.UserId {
+		t.Fatalf("[%v] Wrong third character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+	if rc == c {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+}
+
+func testCharGet(t *testing.T, n string, d database.DataProvider) {
+	g, u, name := uuid.New().String(), uuid.New().String(), "test"
+
+	rc, err := d.GetCharacter(g, u, name)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Got: %v", n, rc)
+	}
+	if e := assertError(err, "Character with name test was not found", database.CharacterNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	c := &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    name,
+	}
+	d.AddCharacter(c)
+
+	rc, err = d.GetCharacter(g, u, name)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if rc.Name != c.Name || rc.UserId != c.UserId {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+	if rc == c {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	rc, err = d.GetCharacter(g, u, "test2")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Got: %v", n, rc)
+	}
+	if e := assertError(err, "Character with name test2 was not found", database.CharacterNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	rc, err = d.GetCharacter(g, uuid.New().String(), name)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Got: %v", n, rc)
+	}
+	if e := assertError(err, "Character with name test was not found", database.CharacterNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	rc, err = d.GetCharacter(uuid.New().String(), u, name)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Got: %v", n, rc)
+	}
+	if e := assertError(err, "Character with name test was not found", database.CharacterNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+}
+
+func testCharGetMain(t *testing.T, n string, d database.DataProvider) {
+	g, u, name := uuid.New().String(), uuid.New().String(), "test"
+
+	rc, err := d.GetMainCharacter(g, u)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Got: %v", n, rc)
+	}
+	if e := assertError(err, "No Characters found", database.CharacterNotFound, n); e != "" {
+		t.Fatal(e)
+	}
+
+	c := &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    "test3",
+	}
+	d.AddCharacter(c)
+
+	rc, err = d.GetMainCharacter(g, u)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if rc.Name != c.Name || rc.UserId != c.UserId {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+	if rc == c {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	c = &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    name,
+		Main:    true,
+	}
+	d.AddCharacter(c)
+
+	rc, err = d.GetMainCharacter(g, u)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if rc.Name != c.Name || rc.UserId != c.UserId {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+	if rc == c {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	c2 := &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    name,
+	}
+	d.AddCharacter(c2)
+
+	rc, err = d.GetMainCharacter(g, u)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if rc.Name != c.Name || rc.UserId != c.UserId {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+}
+
+func testCharGetNameless(t *testing.T, n string, d database.DataProvider) {
+	g, u, name := uuid.New().String(), uuid.New().String(), "test"
+
+	rc, err := d.GetCharacter(g, u, "")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Got: %v", n, rc)
+	}
+	if e := assertError(err, "No Characters found", database.CharacterNotFound, n); e != "" {
+		t.Fatal(e)
+	}
+
+	c := &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    "test3",
+	}
+	d.AddCharacter(c)
+
+	rc, err = d.GetCharacter(g, u, "")
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if rc.Name != c.Name || rc.UserId != c.UserId {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+	if rc == c {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	c = &database.Character{
+		GuildId: g,
+		Name:    name,
+		UserId:  u,
+		Main:    true,
+	}
+	d.AddCharacter(c)
+
+	rc, err = d.GetCharacter(g, u, "")
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if rc.Name != c.Name || rc.UserId != c.UserId {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+
+	c2 := &database.Character{
+		GuildId: g,
+		Name:    name,
+		UserId:  u,
+	}
+	d.AddCharacter(c2)
+
+	rc, err = d.GetCharacter(g, u, "")
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if rc.Name != c.Name || rc.UserId != c.UserId {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+}
+
+func testCharGets(t *testing.T, n string, d database.DataProvider) {
+	g, u, name := uuid.New().String(), uuid.New().String(), "test"
+
+	rc, err := d.GetCharacters(g, u)
+	if err != nil {
+		t.Fatalf("[%v] No error expected getting empty list of characters. Got: %v", n, err)
+	}
+	if rc != nil && len(rc) > 0 {
+		t.Fatalf("[%v] Expected nil or empty array. Got: %v", n, rc)
+	}
+
+	c := &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    "test3",
+	}
+	d.AddCharacter(c)
+
+	rc, err = d.GetCharacters(g, u)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rc) != 1 {
+		t.Fatalf("[%v] Expected single character. Got: %v", n, rc)
+	}
+	if rc[0].Name != c.Name || rc[0].UserId != c.UserId {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+	if rc[0] == c {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	c2 := &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    name,
+		Main:    true,
+	}
+	d.AddCharacter(c2)
+
+	c3 := &database.Character{
+		GuildId: g,
+		UserId:  uuid.New().String(),
+		Name:    name,
+	}
+	d.AddCharacter(c3)
+
+	rc, err = d.GetCharacters(g, u)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rc) != 2 {
+		t.Fatalf("[%v] Expected two characters. Got: %v", n, rc)
+	}
+
+	fi, se := false, false
+	for _, r := range rc {
+		fi = fi || (r.Name == c.Name && r.UserId == c.UserId)
+		se = se || (r.Name == c2.Name && r.UserId == c2.UserId)
+	}
+	if !fi || !se {
+		t.Fatalf("[%v] Expected both characters to be present. Got: %v ", n, rc)
+	}
+
+	rc, err = d.GetCharacters(c3.GuildId, c3.UserId)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rc) != 1 {
+		t.Fatalf("[%v] Expected single character. Got: %v", n, rc)
+	}
+	if rc[0].Name != c3.Name || rc[0].UserId != c3.UserId {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c3)
+	}
+}
+
+func testCharGetsByName(t *testing.T, n string, d database.DataProvider) {
+	g, u, name := uuid.New().String(), uuid.New().String(), "test"
+
+	rc, err := d.GetCharactersByName(g, name)
+	if err != nil {
+		t.Fatalf("[%v] No error expected getting empty list of characters. Got: %v", n, err)
+	}
+	if rc != nil && len(rc) > 0 {
+		t.Fatalf("[%v] Expected nil or empty array. Got: %v", n, rc)
+	}
+
+	c := &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    name,
+	}
+	d.AddCharacter(c)
+
+	rc, err = d.GetCharactersByName(g, name)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rc) != 1 {
+		t.Fatalf("[%v] Expected single character. Got: %v", n, rc)
+	}
+	if rc[0].Name != c.Name || rc[0].UserId != c.UserId {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+	if rc[0] == c {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	c2 := &database.Character{
+		GuildId: g,
+		UserId:  uuid.New().String(),
+		Name:    name,
+		Main:    true,
+	}
+	d.AddCharacter(c2)
+
+	c3 := &database.Character{
+		GuildId: g,
+		UserId:  uuid.New().String(),
+		Name:    name,
+	}
+	d.AddCharacter(c3)
+
+	c4 := &database.Character{
+		GuildId: uuid.New().String(),
+		UserId:  uuid.New().String(),
+		Name:    name,
+	}
+	d.AddCharacter(c4)
+
+	rc, err = d.GetCharactersByName(g, name)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rc) != 3 {
+		t.Fatalf("[%v] Expected 3 characters. Got: %v", n, rc)
+	}
+
+	fi, se, th := false, false, false
+	for _, r := range rc {
+		fi = fi || (r.Name == c.Name && r.UserId == c.UserId)
+		se = se || (r.Name == c2.Name && r.UserId == c2.UserId)
+		th = th || (r.Name == c3.Name && r.UserId == c3.UserId)
+	}
+	if !fi || !se || !th {
+		t.Fatalf("[%v] Expected all 3 characters to be present. Got: %v ", n, rc)
+	}
+}
+
+func testCharGetsOutdated(t *testing.T, n string, d database.DataProvider) {
+	g, u, name := uuid.New().String(), uuid.New().String(), "test"
+
+	rc, err := d.GetCharactersOutdated(g, 0)
+	if err != nil {
+		t.Fatalf("[%v] No error expected getting empty list of characters. Got: %v", n, err)
+	}
+	if rc != nil && len(rc) > 0 {
+		t.Fatalf("[%v] Expected nil or empty array. Got: %v", n, rc)
+	}
+
+	c := &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    name,
+	}
+	d.AddCharacter(c)
+
+	rc, err = d.GetCharactersOutdated(g, 0)
+	if err != nil {
+		t.Fatalf("[%v] No error expected getting empty list of characters. Got: %v", n, err)
+	}
+	if rc != nil && len(rc) > 0 {
+		t.Fatalf("[%v] Expected nil or empty array. Got: %v", n, rc)
+	}
+
+	rc, err = d.GetCharactersOutdated(g, 1)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rc) != 1 {
+		t.Fatalf("[%v] Expected single character. Got: %v", n, rc)
+	}
+	if rc[0].Name != c.Name || rc[0].UserId != c.UserId {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+	if rc[0] == c {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	c2 := &database.Character{
+		GuildId:     g,
+		UserId:      uuid.New().String(),
+		Name:        name,
+		Main:        true,
+		StatVersion: 1,
+	}
+	d.AddCharacter(c2)
+
+	c3 := &database.Character{
+		GuildId:     g,
+		UserId:      uuid.New().String(),
+		Name:        name,
+		StatVersion: 2,
+	}
+	d.AddCharacter(c3)
+
+	c4 := &database.Character{
+		GuildId:     uuid.New().String(),
+		UserId:      uuid.New().String(),
+		Name:        name,
+		StatVersion: 3,
+	}
+	d.AddCharacter(c4)
+
+	rc, err = d.GetCharactersOutdated(g, 10)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rc) != 3 {
+		t.Fatalf("[%v] Expected 3 characters. Got: %v", n, rc)
+	}
+
+	fi, se, th := false, false, false
+	for _, r := range rc {
+		fi = fi || (r.Name == c.Name && r.UserId == c.UserId)
+		se = se || (r.Name == c2.Name && r.UserId == c2.UserId)
+		th = th || (r.Name == c3.Name && r.UserId == c3.UserId)
+	}
+	if !fi || !se || !th {
+		t.Fatalf("[%v] Expected all 3 characters to be present. Got: %v ", n, rc)
+	}
+
+	rc, err = d.GetCharactersOutdated(g, 2)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rc) != 2 {
+		t.Fatalf("[%v] Expected 2 characters. Got: %v", n, len(rc))
+	}
+
+	fi, se = false, false
+	for _, r := range rc {
+		fi = fi || (r.Name == c.Name && r.UserId == c.UserId)
+		se = se || (r.Name == c2.Name && r.UserId == c2.UserId)
+	}
+	if !fi || !se {
+		t.Fatalf("[%v] Expected all 3 characters to be present. Got: %v ", n, rc)
+	}
+
+	rc, err = d.GetCharactersOutdated(g, 1)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rc) != 1 {
+		t.Fatalf("[%v] Expected 1 characters. Got: %v", n, len(rc))
+	}
+	if rc[0].Name != c.Name || rc[0].UserId != c.UserId {
+		t.Fatalf("[%v] Wrong character returned. Got: %v. Extected: %v", n, rc[0], c)
+	}
+}
+
+func testCharGetsSorted(t *testing.T, n string, d database.DataProvider) {
+	g, u := uuid.New().String(), uuid.New().String()
+
+	rc, err := d.GetCharactersSorted(g, "s1", database.Number, false, 0)
+	if err != nil {
+		t.Fatalf("[%v] No error expected getting empty list of characters. Got: %v", n, err)
+	}
+	if rc != nil && len(rc) > 0 {
+		t.Fatalf("[%v] Expected nil or empty array. Got: %v", n, rc)
+	}
+
+	c := &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    "test1",
+		Body: map[string]interface{}{
+			"s1": 1,
+			"s2": "c",
+		},
+	}
+	d.AddCharacter(c)
+
+	tmpC := &database.Character{
+		GuildId: uuid.New().String(),
+		UserId:  u,
+		Name:    "test1",
+		Body: map[string]interface{}{
+			"s1": 1,
+			"s2": "c",
+		},
+	}
+	d.AddCharacter(tmpC)
+
+	rc, err = d.GetCharactersSorted(g, "s1", database.Number, false, 0)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rc) != 1 {
+		t.Fatalf("[%v] Expected single character. Got: %v", n, rc)
+	}
+	if rc[0].Name != c.Name || rc[0].UserId != c.UserId {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+	if rc[0] == c {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	c2 := &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    "test2",
+		Main:    true,
+		Body: map[string]interface{}{
+			"s1": 2,
+			"s2": "a",
+			"s3": "b",
+		},
+	}
+	d.AddCharacter(c2)
+
+	c3 := &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    "test3",
+		Body: map[string]interface{}{
+			"s1": 3,
+			"s2": "b",
+			"s4": 1,
+		},
+	}
+	d.AddCharacter(c3)
+
+	rc, err = d.GetCharactersSorted(g, "s1", database.Number, true, 0)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rc) != 3 {
+		t.Fatalf("[%v] Expected %v characters. Got: %v", n, 3, len(rc))
+	}
+	if rc[0].Name != c.Name || rc[1].Name != c2.Name || rc[2].Name != c3.Name {
+		t.Fatalf("[%v] Expected order is 1-2-3. Got: %v-%v-%v", n, rc[0].Name, rc[1].Name, rc[2].Name)
+	}
+	rc, err = d.GetCharactersSorted(g, "s1", database.Number, false, 0)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rc) != 3 {
+		t.Fatalf("[%v] Expected %v characters. Got: %v", n, 3, len(rc))
+	}
+	if rc[2].Name != c.Name || rc[1].Name != c2.Name || rc[0].Name != c3.Name {
+		t.Fatalf("[%v] Expected order is 3-2-1. Got: %v-%v-%v", n, rc[0].Name, rc[1].Name, rc[2].Name)
+	}
+	rc, err = d.GetCharactersSorted(g, "s2", database.Str, true, 0)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rc) != 3 {
+		t.Fatalf("[%v] Expected %v characters. Got: %v", n, 3, len(rc))
+	}
+	if rc[0].Name != c2.Name || rc[1].Name != c3.Name || rc[2].Name != c.Name {
+		t.Fatalf("[%v] Expected order is 2-3-1. Got: %v-%v-%v", n, rc[0].Name, rc[1].Name, rc[2].Name)
+	}
+	rc, err = d.GetCharactersSorted(g, "s2", database.Str, false, 0)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rc) != 3 {
+		t.Fatalf("[%v] Expected %v characters. Got: %v", n, 3, len(rc))
+	}
+	if rc[0].Name != c.Name || rc[1].Name != c3.Name || rc[2].Name != c2.Name {
+		t.Fatalf("[%v] Expected order is 1-3-2. Got: %v-%v-%v", n, rc[0].Name, rc[1].Name, rc[2].Name)
+	}
+	rc, err = d.GetCharactersSorted(g, "s2", database.Str, false, 2)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rc) != 2 {
+		t.Fatalf("[%v] Expected %v characters. Got: %v", n, 3, len(rc))
+	}
+	if rc[0].Name != c.Name || rc[1].Name != c3.Name {
+		t.Fatalf("[%v] Expected order is 1-3. Got: %v-%v-%v", n, rc[0].Name, rc[1].Name, rc[2].Name)
+	}
+
+	// And more
+
+	rc, err = d.GetCharactersSorted(g, "s3", database.Str, false, 0)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rc) != 3 {
+		t.Fatalf("[%v] Expected %v characters. Got: %v", n, 3, len(rc))
+	}
+	if rc[0].Name != c2.Name {
+		t.Fatalf("[%v] Expected order is 2-?-?. Got: %v-%v-%v", n, rc[0].Name, rc[1].Name, rc[2].Name)
+	}
+	rc, err = d.GetCharactersSorted(g, "s3", database.Str, true, 0)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rc) != 3 {
+		t.Fatalf("[%v] Expected %v characters. Got: %v", n, 3, len(rc))
+	}
+	if rc[0].Name != c2.Name {
+		t.Fatalf("[%v] Expected order is 2-?-?. Got: %v-%v-%v", n, rc[0].Name, rc[1].Name, rc[2].Name)
+	}
+
+	rc, err = d.GetCharactersSorted(g, "s4", database.Number, false, 0)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rc) != 3 {
+		t.Fatalf("[%v] Expected %v characters. Got: %v", n, 3, len(rc))
+	}
+	if rc[0].Name != c3.Name {
+		t.Fatalf("[%v] Expected order is 3-?-?. Got: %v-%v-%v", n, rc[0].Name, rc[1].Name, rc[2].Name)
+	}
+	rc, err = d.GetCharactersSorted(g, "s4", database.Number, true, 0)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rc) != 3 {
+		t.Fatalf("[%v] Expected %v characters. Got: %v", n, 3, len(rc))
+	}
+	if rc[0].Name != c3.Name {
+		t.Fatalf("[%v] Expected order is 3-?-?. Got: %v-%v-%v", n, rc[0].Name, rc[1].Name, rc[2].Name)
+	}
+}
+
+func testCharRename(t *testing.T, n string, d database.DataProvider) {
+	g, u, name := uuid.New().String(), uuid.New().String(), "test"
+
+	c := &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    name,
+	}
+	d.AddCharacter(c)
+
+	rc, err := d.RenameCharacter(g, u, name, "test2")
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if rc.Name != "test2" || rc.UserId != c.UserId {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+	if rc == c {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	rc, err = d.GetCharacter(g, u, "test2")
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if rc.Name != "test2" || rc.UserId != c.UserId {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+
+	rc, err = d.GetCharacter(g, u, name)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Got: %v", n, rc)
+	}
+	if e := assertError(err, "Character with name test was not found", database.CharacterNotFound, n); e != "" {
+		t.Fatal(e)
+	}
+
+	c = &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    name,
+	}
+	d.AddCharacter(c)
+
+	rc, err = d.RenameCharacter(g, u, name, "test2")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Got: %v", n, rc)
+	}
+	if e := assertError(err, "Character with that name already exists", database.CharacterNameTaken, n); e != "" {
+		t.Fatal(e)
+	}
+
+	rc, err = d.GetCharacter(g, u, "test2")
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if rc.Name != "test2" || rc.UserId != c.UserId {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+
+	rc, err = d.GetCharacter(g, u, name)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if rc.Name != name || rc.UserId != c.UserId {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+
+	c = &database.Character{
+		GuildId: g,
+		UserId:  uuid.New().String(),
+		Name:    name,
+	}
+	d.AddCharacter(c)
+
+	rc, err = d.RenameCharacter(c.GuildId, c.UserId, name, "test2")
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if rc.Name != "test2" || rc.UserId != c.UserId {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+}
+
+func testCharChangeOwner(t *testing.T, n string, d database.DataProvider) {
+	g, u, name, u2 := uuid.New().String(), uuid.New().String(), "test", uuid.New().String()
+
+	rc, err := d.ChangeCharacterOwner(g, u, name, u2)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Got: %v", n, rc)
+	}
+	if e := assertError(err, "Character with name test was not found", database.CharacterNotFound, n); e != "" {
+		t.Fatal(e)
+	}
+
+	c := &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    name,
+	}
+	d.AddCharacter(c)
+
+	rc, err = d.ChangeCharacterOwner(g, u, name, u2)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if rc.Name != name || rc.UserId != u2 {
+		t.Fatalf("[%v] Wrong character returned. Actual: %v, expected: %v", n, *rc, *c)
+	}
+	if rc == c {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	rcs, err := d.GetCharacters(g, u)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rcs) != 0 {
+		t.Fatalf("[%v] Wrong characters amount returned. Actual: %v, expected: %v", n, rcs, "empty")
+	}
+
+	rcs, err = d.GetCharacters(g, u2)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rcs) != 1 {
+		t.Fatalf("[%v] Wrong characters amount returned. Actual: %v, expected: %v", n, rcs, 1)
+	}
+
+	rc, err = d.GetCharacter(g, u, name)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Got: %v", n, rc)
+	}
+	if e := assertError(err, "Character with name test was not found", database.CharacterNotFound, n); e != "" {
+		t.Fatal(e)
+	}
+
+	c = &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    name,
+	}
+	d.AddCharacter(c)
+
+	rc, err = d.ChangeCharacterOwner(g, u, name, u2)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Got: %v", n, rc)
+	}
+	if e := assertError(err, "Target user already has character with name 'test'", database.UserHasCharacter, n); e != "" {
+		t.Fatal(e)
+	}
+
+	rc, err = d.GetCharacter(g, u2, name)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if rc.Name != name || rc.UserId != u2 {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+
+	rc, err = d.GetCharacter(g, u, name)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if rc.Name != name || rc.UserId != u {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+}
+
+func testCharChangeMain(t *testing.T, n string, d database.DataProvider) {
+	g, u, name := uuid.New().String(), uuid.New().String(), "test"
+
+	c := &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    "test2",
+	}
+	c, _ = d.AddCharacter(c)
+
+	c2 := &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    name,
+		Main:    true,
+	}
+	c2, _ = d.AddCharacter(c2)
+
+	rc, err := d.ChangeMainCharacter(g, u, "test2")
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+
+	rc, err = d.GetMainCharacter(g, u)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if rc.Name != c.Name || rc.UserId != c.UserId {
+		t.Fatalf("[%v] Wrong second character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+	if rc == c {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	rc, err = d.ChangeMainCharacter(g, u, "test3")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Got: %v", n, rc)
+	}
+	if e := assertError(err, "Character with name test3 was not found", database.CharacterNotFound, n); e != "" {
+		t.Fatal(e)
+	}
+}
+
+func testCharSetStat(t *testing.T, n string, d database.DataProvider) {
+	g, u, name := uuid.New().String(), uuid.New().String(), "test"
+
+	rc, err := d.SetCharacterStat(g, u, name, "a", "b")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Got: %v", n, rc)
+	}
+	if e := assertError(err, "Character with name test was not found", database.CharacterNotFound, n); e != "" {
+		t.Fatal(e)
+	}
+
+	c := &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    name,
+	}
+	d.AddCharacter(c)
+
+	current := make(map[string]interface{})
+
+	rc, err = d.GetCharacter(g, u, name)
+	if rc.Body != nil && !reflect.DeepEqual(rc.Body, current) {
+		t.Fatalf("[%v] Unexpected stats. Actual: %v. Expected: %v", n, rc.Body, current)
+	}
+
+	current["t1"] = "str"
+	rc, err = d.SetCharacterStat(g, u, name, "t1", "str")
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if !reflect.DeepEqual(rc.Body, current) {
+		t.Fatalf("[%v] Unexpected stats. Actual: %v. Expected: %v", n, rc.Body, current)
+	}
+	if rc == c {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	current["t2"] = 5
+	rc, err = d.SetCharacterStat(g, u, name, "t2", 5)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if !reflect.DeepEqual(rc.Body, current) {
+		t.Fatalf("[%v] Unexpected stats. Actual: %v. Expected: %v", n, rc.Body, current)
+	}
+
+	current["t1"] = 10
+	rc, err = d.SetCharacterStat(g, u, name, "t1", 10)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if !reflect.DeepEqual(rc.Body, current) {
+		t.Fatalf("[%v] Unexpected stats. Actual: %v. Expected: %v", n, rc.Body, current)
+	}
+
+	current["t2"] = "str2"
+	rc, err = d.SetCharacterStat(g, u, name, "t2", "str2")
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if !reflect.DeepEqual(rc.Body, current) {
+		t.Fatalf("[%v] Unexpected stats. Actual: %v. Expected: %v", n, rc.Body, current)
+	}
+
+	rc, err = d.GetCharacter(g, u, name)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if !reflect.DeepEqual(rc.Body, current) {
+		t.Fatalf("[%v] Unexpected stats. Actual: %v. Expected: %v", n, rc.Body, current)
+	}
+}
+
+func testCharSetStatVersion(t *testing.T, n string, d database.DataProvider) {
+	g, u, name := uuid.New().String(), uuid.New().String(), "test"
+	stats := make(map[string]*database.Stat)
+
+	rc, err := d.SetCharacterStatVersion(g, u, name, stats, 1)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Got: %v", n, rc)
+	}
+	if e := assertError(err, "Character with name test was not found", database.CharacterNotFound, n); e != "" {
+		t.Fatal(e)
+	}
+
+	c := &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    name,
+	}
+	d.AddCharacter(c)
+
+	stats["s1"] = &database.Stat{ID: "s1", Type: database.Number}
+	stats["s2"] = &database.Stat{ID: "s2", Type: database.Str}
+	stats["s3"] = &database.Stat{ID: "s3", Type: 256}
+	rc, err = d.SetCharacterStatVersion(g, u, name, stats, 1)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Got: %v", n, rc)
+	}
+	if e := assertError(err, "Stat type for s3 is not defined", database.UnknownStatType, n); e != "" {
+		t.Fatal(e)
+	}
+
+	delete(stats, "s3")
+
+	current := map[string]interface{}{
+		"s1": 0,
+		"s2": "",
+	}
+	rc, err = d.SetCharacterStatVersion(g, u, name, stats, 1)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if !reflect.DeepEqual(rc.Body, current) {
+		t.Fatalf("[%v] Unexpected stats. Actual: %v. Expected: %v", n, rc.Body, current)
+	}
+	if rc.StatVersion != 1 {
+		t.Fatalf("[%v] Unexpected stats version. Actual: %v. Expected: %v", n, rc.StatVersion, 1)
+	}
+	if rc == c {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	delete(stats, "s2")
+	rc, err = d.SetCharacterStatVersion(g, u, name, stats, 1)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if !reflect.DeepEqual(rc.Body, current) {
+		t.Fatalf("[%v] Unexpected stats. Actual: %v. Expected: %v", n, rc.Body, current)
+	}
+	if rc.StatVersion != 1 {
+		t.Fatalf("[%v] Unexpected stats version. Actual: %v. Expected: %v", n, rc.StatVersion, 1)
+	}
+	if rc == c {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	delete(current, "s2")
+	rc, err = d.SetCharacterStatVersion(g, u, name, stats, 2)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if !reflect.DeepEqual(rc.Body, current) {
+		t.Fatalf("[%v] Unexpected stats. Actual: %v. Expected: %v", n, rc.Body, current)
+	}
+	if rc.StatVersion != 2 {
+		t.Fatalf("[%v] Unexpected stats version. Actual: %v. Expected: %v", n, rc.StatVersion, 2)
+	}
+	if rc == c {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+}
+
+func testCharRemoveStat(t *testing.T, n string, d database.DataProvider) {
+	g, u, name := uuid.New().String(), uuid.New().String(), "test"
+
+	rc, err := d.RemoveCharacterStat(g, u, name, "a")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Got: %v", n, rc)
+	}
+	if e := assertError(err, "Character with name test was not found", database.CharacterNotFound, n); e != "" {
+		t.Fatal(e)
+	}
+
+	c := &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    name,
+	}
+	d.AddCharacter(c)
+
+	current := make(map[string]interface{})
+
+	rc, err = d.SetCharacterStat(g, u, name, "t1", "str")
+	rc, err = d.SetCharacterStat(g, u, name, "t2", 5)
+	current["t1"] = 10
+	rc, err = d.SetCharacterStat(g, u, name, "t1", 10)
+	current["t2"] = "str2"
+	rc, err = d.SetCharacterStat(g, u, name, "t2", "str2")
+
+	rc, err = d.RemoveCharacterStat(g, u, name, "a")
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if !reflect.DeepEqual(rc.Body, current) {
+		t.Fatalf("[%v] Unexpected stats. Actual: %v. Expected: %v", n, rc.Body, current)
+	}
+	if rc == c {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	delete(current, "t1")
+	rc, err = d.RemoveCharacterStat(g, u, name, "t1")
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if !reflect.DeepEqual(rc.Body, current) {
+		t.Fatalf("[%v] Unexpected stats. Actual: %v. Expected: %v", n, rc.Body, current)
+	}
+
+	rc, err = d.GetCharacter(g, u, name)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if !reflect.DeepEqual(rc.Body, current) {
+		t.Fatalf("[%v] Unexpected stats. Actual: %v. Expected: %v", n, rc.Body, current)
+	}
+
+	delete(current, "t2")
+	rc, err = d.RemoveCharacterStat(g, u, name, "t2")
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if !reflect.DeepEqual(rc.Body, current) {
+		t.Fatalf("[%v] Unexpected stats. Actual: %v. Expected: %v", n, rc.Body, current)
+	}
+
+	rc, err = d.RemoveCharacterStat(g, u, name, "t3")
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if !reflect.DeepEqual(rc.Body, current) {
+		t.Fatalf("[%v] Unexpected stats. Actual: %v. Expected: %v", n, rc.Body, current)
+	}
+
+	rc, err = d.GetCharacter(g, u, name)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if !reflect.DeepEqual(rc.Body, current) {
+		t.Fatalf("[%v] Unexpected stats. Actual: %v. Expected: %v", n, rc.Body, current)
+	}
+}
+
+func testCharRemove(t *testing.T, n string, d database.DataProvider) {
+	g, u, name, name2 := uuid.New().String(), uuid.New().String(), "test", "test2"
+
+	rc, err := d.RemoveCharacter(g, u, name)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected adding character. Received: %v", n, err)
+	}
+	if rc != nil {
+		t.Fatalf("[%v] No character expected. Actual: %v", n, rc)
+	}
+
+	c := &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    name,
+	}
+	rc, err = d.AddCharacter(c)
+
+	c2 := &database.Character{
+		GuildId: g,
+		UserId:  u,
+		Name:    name2,
+	}
+	rc, err = d.AddCharacter(c2)
+
+	rc, err = d.RemoveCharacter(g, u, name)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rc.Name != c.Name || rc.UserId != c.UserId {
+		t.Fatalf("[%v] Wrong character returned. Actual: %v, expected: %v", n, rc, c)
+	}
+	if rc == c {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	rcs, err := d.GetCharacters(g, u)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if len(rcs) != 1 {
+		t.Fatalf("[%v] Wrong character count. Actual: %v, expected: %v", n, rcs, 1)
+	}
+	if rcs[0].Name != c2.Name || rcs[0].UserId != c2.UserId {
+		t.Fatalf("[%v] Wrong character is kept. Actual: %v, expected: %v", n, rcs, *c2)
+	}
+
+	rc, err = d.RemoveCharacter(g, u, name2)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rc.Name != c2.Name || rc.UserId != c2.UserId {
+		t.Fatalf("[%v] Wrong character returned. Actual: %v, expected: %v", n, *rc, *c2)
+	}
+
+	rcs, err = d.GetCharacters(g, u)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rcs != nil && len(rcs) != 0 {
+		t.Fatalf("[%v] Wrong character count. Actual: %v, expected: %v", n, rcs, 0)
+	}
+}
+
+func testCharLookupsAfterChanges(t *testing.T, n string, d database.DataProvider) {
+	g, u1, u2 := uuid.New().String(), uuid.New().String(), uuid.New().String()
+	if _, err := d.AddCharacter(&database.Character{GuildId: g, UserId: u1, Name: "a", Main: true}); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if _, err := d.AddCharacter(&database.Character{GuildId: g, UserId: u1, Name: "b"}); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+
+	if _, err := d.RenameCharacter(g, u1, "", "c"); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rcs, _ := d.GetCharactersByName(g, "a"); len(rcs) != 0 {
+		t.Fatalf("[%v] Old name expected to be gone. Received: %v", n, rcs)
+	}
+	if rcs, _ := d.GetCharactersByName(g, "c"); len(rcs) != 1 || rcs[0].UserId != u1 {
+		t.Fatalf("[%v] Renamed character expected. Received: %v", n, rcs)
+	}
+
+	if _, err := d.ChangeCharacterOwner(g, u1, "b", u2); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rcs, _ := d.GetCharacters(g, u1); len(rcs) != 1 || rcs[0].Name != "c" {
+		t.Fatalf("[%v] Only one character expected for old owner. Received: %v", n, rcs)
+	}
+	if rc, err := d.GetMainCharacter(g, u2); err != nil || rc.Name != "b" {
+		t.Fatalf("[%v] Moved character expected for new owner. Received: %v, %v", n, rc, err)
+	}
+	if rcs, _ := d.GetCharactersByName(g, "b"); len(rcs) != 1 || rcs[0].UserId != u2 {
+		t.Fatalf("[%v] Moved character expected by name. Received: %v", n, rcs)
+	}
+
+	if _, err := d.RemoveCharacter(g, u1, ""); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rcs, _ := d.GetCharactersOutdated(g, 1); len(rcs) != 1 || rcs[0].UserId != u2 {
+		t.Fatalf("[%v] Only one character expected in guild. Received: %v", n, rcs)
+	}
+	if rcs, _ := d.GetCharactersSorted(g, "lvl", database.Number, true, 10); len(rcs) != 1 {
+		t.Fatalf("[%v] Only one character expected in guild. Received: %v", n, rcs)
+	}
+}
+
+func testCharStatTypes(t *testing.T, n string, d database.DataProvider) {
+	g := uuid.New().String()
+	stats := map[string]*database.Stat{
+		"mult":  {ID: "mult", Type: database.Float},
+		"alive": {ID: "alive", Type: database.Bool},
+		"class": {ID: "class", Type: database.Enum, Values: []string{"mage", "warrior"}},
+		"raid":  {ID: "raid", Type: database.Date},
+		"time":  {ID: "time", Type: database.Duration},
+	}
+
+	for _, c := range []string{"c1", "c2", "c3"} {
+		d.AddCharacter(&database.Character{GuildId: g, UserId: "u1", Name: c})
+	}
+
+	rc, err := d.SetCharacterStatVersion(g, "u1", "c1", stats, 1)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	expected := map[string]interface{}{
+		"mult":  0.0,
+		"alive": false,
+		"class": "mage",
+		"raid":  time.Time{},
+		"time":  time.Duration(0),
+	}
+	if len(rc.Body) != len(expected) {
+		t.Fatalf("[%v] Unexpected default stats. Actual: %v. Expected: %v", n, rc.Body, expected)
+	}
+	for s, v := range expected {
+		if ok, _ := stats[s].IsValid(rc.Body[s]); !ok || database.CompareStatValues(stats[s].Type, rc.Body[s], v) != 0 {
+			t.Fatalf("[%v] Unexpected default of %v. Actual: %v. Expected: %v", n, s, rc.Body[s], v)
+		}
+	}
+
+	day := func(s string) time.Time {
+		rv, _ := time.Parse(database.DateFormat, s)
+		return rv
+	}
+	values := map[string][]interface{}{
+		"mult":  {1.5, 0.25, 2.0},
+		"alive": {true, false, true},
+		"class": {"warrior", "mage", "warrior"},
+		"raid":  {day("2020-05-01"), day("2020-06-01"), day("2019-12-31")},
+		"time":  {90 * time.Minute, time.Hour, 2 * time.Hour},
+	}
+	for s, vs := range values {
+		for i, v := range vs {
+			name := fmt.Sprintf("c%v", i+1)
+			if _, err = d.SetCharacterStat(g, "u1", name, s, v); err != nil {
+				t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+			}
+		}
+	}
+
+	for s, vs := range values {
+		for i, v := range vs {
+			rc, _ = d.GetCharacter(g, "u1", fmt.Sprintf("c%v", i+1))
+			if ok, _ := stats[s].IsValid(rc.Body[s]); !ok || database.CompareStatValues(stats[s].Type, rc.Body[s], v) != 0 {
+				t.Fatalf("[%v] Wrong value of %v. Actual: %v, expected: %v", n, s, rc.Body[s], v)
+			}
+		}
+	}
+
+	sorted := map[string][]string{
+		"mult": {"c3", "c1", "c2"},
+		"raid": {"c2", "c1", "c3"},
+		"time": {"c3", "c1", "c2"},
+	}
+	for s, names := range sorted {
+		rcs, err := d.GetCharactersSorted(g, s, stats[s].Type, false, 0)
+		if err != nil {
+			t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+		}
+		actual := make([]string, 0, len(rcs))
+		for _, c := range rcs {
+			actual = append(actual, c.Name)
+		}
+		if !reflect.DeepEqual(actual, names) {
+			t.Fatalf("[%v] Wrong order by %v. Actual: %v, expected: %v", n, s, actual, names)
+		}
+	}
+
+	rcs, err := d.GetCharactersSorted(g, "class", database.Enum, true, 1)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rcs) != 1 || rcs[0].Name != "c2" {
+		t.Fatalf("[%v] Wrong order by class. Actual: %v", n, rcs)
+	}
+	rcs, _ = d.GetCharactersSorted(g, "alive", database.Bool, true, 1)
+	if len(rcs) != 1 || rcs[0].Name != "c2" {
+		t.Fatalf("[%v] Wrong order by alive. Actual: %v", n, rcs)
+	}
+
+	// values that are no longer allowed are reset to defaults
+	stats["class"] = &database.Stat{ID: "class", Type: database.Enum, Values: []string{"rogue", "mage"}}
+	rc, err = d.SetCharacterStatVersion(g, "u1", "c1", stats, 2)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if rc.Body["class"] != "rogue" {
+		t.Fatalf("[%v] Wrong class after version change. Actual: %v, expected: %v", n, rc.Body["class"], "rogue")
+	}
+}
+
+func testCharQuery(t *testing.T, n string, d database.DataProvider) {
+	top, _ := d.AddGuild(&database.Guild{Name: "test", DiscordId: "gid1"})
+	sub1, _ := d.AddGuild(&database.Guild{Name: "sub1", ParentId: top.GuildId})
+	sub2, _ := d.AddGuild(&database.Guild{Name: "sub2", ParentId: top.GuildId})
+	d.AddUser("u1", &database.GuildPermission{TopGuild: "gid1", GuildId: sub1.GuildId})
+	d.AddUser("u2", &database.GuildPermission{TopGuild: "gid1", GuildId: sub2.GuildId})
+
+	d.AddCharacter(&database.Character{GuildId: "gid1", UserId: "u1", Name: "a", Main: true, Body: map[string]interface{}{"power": 100, "class": "mage", "lvl": 50}})
+	d.AddCharacter(&database.Character{GuildId: "gid1", UserId: "u2", Name: "b", Main: true, Body: map[string]interface{}{"power": 100, "class": "warrior", "lvl": 60}})
+	d.AddCharacter(&database.Character{GuildId: "gid1", UserId: "u1", Name: "c", Body: map[string]interface{}{"power": 300, "class": "mage", "lvl": 10}})
+	d.AddCharacter(&database.Character{GuildId: "gid1", UserId: "u3", Name: "d", Main: true, Body: map[string]interface{}{"power": "strong", "class": "mage"}})
+	d.AddCharacter(&database.Character{GuildId: "gid1", UserId: "u2", Name: "e", Body: map[string]interface{}{"lvl": 70}})
+	d.AddCharacter(&database.Character{GuildId: "gid2", UserId: "u1", Name: "f", Main: true, Body: map[string]interface{}{"power": 1000}})
+
+	main := true
+	testData := []struct {
+		name     string
+		q        *database.CharacterQuery
+		expected string
+	}{
+		{name: "all", q: &database.CharacterQuery{}, expected: "a,b,c,d,e"},
+		{name: "ties by name", q: &database.CharacterQuery{Order: []*database.SortKey{{Stat: "power", Type: database.Number}}}, expected: "c,a,b,d,e"},
+		{name: "multiple keys", q: &database.CharacterQuery{Order: []*database.SortKey{
+			{Stat: "power", Type: database.Number},
+			{Stat: "lvl", Type: database.Number, Asc: false},
+		}}, expected: "c,b,a,e,d"},
+		{name: "ascending", q: &database.CharacterQuery{Order: []*database.SortKey{
+			{Stat: "class", Type: database.Enum, Asc: true},
+			{Stat: "lvl", Type: database.Number, Asc: true},
+		}}, expected: "c,a,d,b,e"},
+		{name: "filters", q: &database.CharacterQuery{Filters: []*database.StatFilter{
+			{Stat: "class", Type: database.Enum, Op: database.OpEq, Value: "mage"},
+			{Stat: "lvl", Type: database.Number, Op: database.OpGreaterEq, Value: 20},
+		}}, expected: "a"},
+		{name: "not equal", q: &database.CharacterQuery{Filters: []*database.StatFilter{{Stat: "class", Type: database.Str, Op: database.OpNotEq, Value: "mage"}}}, expected: "b"},
+		{name: "less", q: &database.CharacterQuery{Filters: []*database.StatFilter{{Stat: "lvl", Type: database.Number, Op: database.OpLess, Value: 60}}}, expected: "a,c"},
+		{name: "mains", q: &database.CharacterQuery{Main: &main, Filters: []*database.StatFilter{{Stat: "class", Type: database.Str, Op: database.OpEq, Value: "mage"}}}, expected: "a,d"},
+		{name: "owner guilds", q: &database.CharacterQuery{OwnerGuilds: []uuid.UUID{sub1.GuildId}}, expected: "a,c"},
+		{name: "several owner guilds", q: &database.CharacterQuery{OwnerGuilds: []uuid.UUID{sub1.GuildId, sub2.GuildId}, Main: &main}, expected: "a,b"},
+		{name: "no owner guilds", q: &database.CharacterQuery{OwnerGuilds: []uuid.UUID{}}, expected: ""},
+		{name: "limit", q: &database.CharacterQuery{Order: []*database.SortKey{{Stat: "lvl", Type: database.Number}}, OwnerGuilds: []uuid.UUID{sub2.GuildId}, Limit: 1}, expected: "e"},
+	}
+
+	for _, td := range testData {
+		rc, err := d.QueryCharacters("gid1", td.q)
+		if err != nil {
+			t.Fatalf("[%v] [%v] No errors expected. Received: %v", n, td.name, err)
+		}
+		names := make([]string, len(rc))
+		for i, c := range rc {
+			names[i] = c.Name
+		}
+		if strings.Join(names, ",") != td.expected {
+			t.Fatalf("[%v] [%v] Wrong characters. Actual: %v, expected: %v", n, td.name, names, td.expected)
+		}
+	}
+
+	rc, _ := d.QueryCharacters("gid1", &database.CharacterQuery{Filters: []*database.StatFilter{{Stat: "class", Type: database.Str, Op: database.OpEq, Value: "warrior"}}})
+	if len(rc) != 1 || rc[0].Body["power"] != 100 || rc[0].Body["lvl"] != 60 || !rc[0].Main {
+		t.Fatalf("[%v] Character expected with all stats. Received: %v", n, rc)
+	}
+
+	rce, err := d.QueryCharacters("gid1", &database.CharacterQuery{Order: []*database.SortKey{{Stat: "power", Type: database.Computed}}})
+	if e := assertError(err, "Stat type for power can't be queried", database.UnknownStatType, n); e != "" {
+		t.Fatalf("%v. Received: %v", e, rce)
+	}
+	rce, err = d.QueryCharacters("gid1", &database.CharacterQuery{Filters: []*database.StatFilter{{Stat: "power", Type: database.Number, Op: database.OpEq, Value: "100"}}})
+	if e := assertError(err, "Invalid filter of stat power", database.WrongUserInput, n); e != "" {
+		t.Fatalf("%v. Received: %v", e, rce)
+	}
+}
+
+func testCharGetsGuild(t *testing.T, n string, d database.DataProvider) {
+	g := uuid.New().String()
+
+	rcs, err := d.GetGuildCharacters(g)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	if len(rcs) != 0 {
+		t.Fatalf("[%v] No characters expected. Got: %v", n, rcs)
+	}
+
+	d.AddCharacter(&database.Character{GuildId: g, UserId: "u1", Name: "c1", Body: map[string]interface{}{"s1": 1}})
+	d.AddCharacter(&database.Character{GuildId: g, UserId: "u2", Name: "c2"})
+	d.AddCharacter(&database.Character{GuildId: uuid.New().String(), UserId: "u1", Name: "c3"})
+
+	rcs, err = d.GetGuildCharacters(g)
+	if err != nil {
+		t.Fatalf("[%v] Error not expected. Got: %v", n, err)
+	}
+	names := make([]string, 0, len(rcs))
+	for _, c := range rcs {
+		names = append(names, c.Name)
+		if c.Name == "c1" && c.Body["s1"] != 1 {
+			t.Fatalf("[%v] Character stats expected. Got: %v", n, c.Body)
+		}
+	}
+	sort.Strings(names)
+	if !reflect.DeepEqual(names, []string{"c1", "c2"}) {
+		t.Fatalf("[%v] Wrong characters. Actual: %v, expected: %v", n, names, []string{"c1", "c2"})
+	}
+}
+
+func testCharRank(t *testing.T, n string, d database.DataProvider) {
+	top, _ := d.AddGuild(&database.Guild{Name: "test", DiscordId: "gid1"})
+	sub1, _ := d.AddGuild(&database.Guild{Name: "sub1", ParentId: top.GuildId})
+	sub2, _ := d.AddGuild(&database.Guild{Name: "sub2", ParentId: top.GuildId})
+	d.AddUser("u1", &database.GuildPermission{TopGuild: "gid1", GuildId: sub1.GuildId})
+	d.AddUser("u2", &database.GuildPermission{TopGuild: "gid1", GuildId: sub2.GuildId})
+
+	d.AddCharacter(&database.Character{GuildId: "gid1", UserId: "u1", Name: "a", Main: true, Body: map[string]interface{}{"power": 100}})
+	d.AddCharacter(&database.Character{GuildId: "gid1", UserId: "u2", Name: "b", Main: true, Body: map[string]interface{}{"power": 100}})
+	d.AddCharacter(&database.Character{GuildId: "gid1", UserId: "u1", Name: "c", Body: map[string]interface{}{"power": 300}})
+	d.AddCharacter(&database.Character{GuildId: "gid1", UserId: "u3", Name: "d", Main: true, Body: map[string]interface{}{"power": "strong"}})
+	d.AddCharacter(&database.Character{GuildId: "gid1", UserId: "u2", Name: "e", Body: map[string]interface{}{}})
+	d.AddCharacter(&database.Character{GuildId: "gid2", UserId: "u1", Name: "f", Main: true, Body: map[string]interface{}{"power": 1000}})
+
+	power := []*database.SortKey{{Stat: "power", Type: database.Number}}
+	main := true
+	testData := []struct {
+		name     string
+		u        string
+		char     string
+		q        *database.CharacterQuery
+		expected string
+	}{
+		{name: "middle", u: "u1", char: "a", q: &database.CharacterQuery{Order: power}, expected: "a 2/5 c b"},
+		{name: "first", u: "u1", char: "c", q: &database.CharacterQuery{Order: power}, expected: "c 1/5 - a"},
+		{name: "last", u: "u2", char: "e", q: &database.CharacterQuery{Order: power}, expected: "e 5/5 d -"},
+		{name: "main character", u: "u3", q: &database.CharacterQuery{Order: power}, expected: "d 4/5 b e"},
+		{name: "ascending", u: "u1", char: "c", q: &database.CharacterQuery{Order: []*database.SortKey{{Stat: "power", Type: database.Number, Asc: true}}}, expected: "c 3/5 b d"},
+		{name: "mains", u: "u2", char: "b", q: &database.CharacterQuery{Order: power, Main: &main}, expected: "b 2/3 a d"},
+		{name: "owner guilds", u: "u2", char: "b", q: &database.CharacterQuery{Order: power, OwnerGuilds: []uuid.UUID{sub2.GuildId}, Limit: 1}, expected: "b 1/2 - e"},
+		{name: "only one", u: "u1", char: "c", q: &database.CharacterQuery{Order: power, Filters: []*database.StatFilter{{Stat: "power", Type: database.Number, Op: database.OpGreater, Value: 200}}}, expected: "c 1/1 - -"},
+	}
+
+	name := func(c *database.Character) string {
+		if c == nil {
+			return "-"
+		}
+		return c.Name
+	}
+	for _, td := range testData {
+		r, err := d.RankCharacter("gid1", td.u, td.char, td.q)
+		if err != nil {
+			t.Fatalf("[%v] [%v] No errors expected. Received: %v", n, td.name, err)
+		}
+		actual := fmt.Sprintf("%v %v/%v %v %v", name(r.Character), r.Rank, r.Total, name(r.Above), name(r.Below))
+		if actual != td.expected {
+			t.Fatalf("[%v] [%v] Wrong rank. Actual: %v, expected: %v", n, td.name, actual, td.expected)
+		}
+	}
+
+	r, err := d.RankCharacter("gid1", "u1", "c", &database.CharacterQuery{Order: power, Main: &main})
+	if e := assertError(err, "Character c is not ranked", database.CharacterNotFound, n); e != "" {
+		t.Fatalf("%v. Received: %v", e, r)
+	}
+	r, err = d.RankCharacter("gid1", "u1", "c", &database.CharacterQuery{Order: power, OwnerGuilds: []uuid.UUID{}})
+	if e := assertError(err, "Character c is not ranked", database.CharacterNotFound, n); e != "" {
+		t.Fatalf("%v. Received: %v", e, r)
+	}
+	r, err = d.RankCharacter("gid1", "u1", "zz", &database.CharacterQuery{Order: power})
+	if e := assertError(err, "Character with name zz was not found", database.CharacterNotFound, n); e != "" {
+		t.Fatalf("%v. Received: %v", e, r)
+	}
+	r, err = d.RankCharacter("gid1", "u1", "c", &database.CharacterQuery{Order: []*database.SortKey{{Stat: "power", Type: database.Computed}}})
+	if e := assertError(err, "Stat type for power can't be queried", database.UnknownStatType, n); e != "" {
+		t.Fatalf("%v. Received: %v", e, r)
+	}
+}
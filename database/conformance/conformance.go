@@ -0,0 +1,113 @@
+package conformance
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+var cases = []struct {
+	name string
+	f    func(t *testing.T, n string, d database.DataProvider)
+}{
+	{"GuildAdd", testGuildAdd},
+	{"GuildAddWithId", testGuildAddWithId},
+	{"GuildGet", testGuildGet},
+	{"GuildGetD", testGuildGetD},
+	{"GuildGetN", testGuildGetN},
+	{"GuildGetSub", testGuildGetSub},
+	{"GuildRename", testGuildRename},
+	{"GuildAddStat", testGuildAddStat},
+	{"GuildSetDefaultStat", testGuildSetDefaultStat},
+	{"GuildRemoveStat", testGuildRemoveStat},
+	{"GuildRemoveAllStats", testGuildRemoveAllStats},
+	{"GuildAddEnumStat", testGuildAddEnumStat},
+	{"GuildStatConstraints", testGuildStatConstraints},
+	{"GuildStatDisplay", testGuildStatDisplay},
+	{"GuildRenameStat", testGuildRenameStat},
+	{"GuildRenameSubStat", testGuildRenameSubStat},
+	{"GuildChangeStatType", testGuildChangeStatType},
+	{"GuildMove", testGuildMove},
+	{"GuildRemove", testGuildRemove},
+	{"GuildRemoveD", testGuildRemoveD},
+	{"UserAdd", testUserAdd},
+	{"UserGetD", testUserGetD},
+	{"UserGetInGuild", testUserGetInGuild},
+	{"UserSetPermissions", testUserSetPermissions},
+	{"UserSetSubguild", testUserSetSubguild},
+	{"UserRemove", testUserRemove},
+	{"UserErase", testUserErase},
+	{"CharAdd", testCharAdd},
+	{"CharGet", testCharGet},
+	{"CharGetMain", testCharGetMain},
+	{"CharGetNameless", testCharGetNameless},
+	{"CharGets", testCharGets},
+	{"CharGetsByName", testCharGetsByName},
+	{"CharGetsOutdated", testCharGetsOutdated},
+	{"CharGetsSorted", testCharGetsSorted},
+	{"CharQuery", testCharQuery},
+	{"CharRank", testCharRank},
+	{"CharGetsGuild", testCharGetsGuild},
+	{"CharRename", testCharRename},
+	{"CharChangeOwner", testCharChangeOwner},
+	{"CharChangeMain", testCharChangeMain},
+	{"CharSetStat", testCharSetStat},
+	{"CharSetStatVersion", testCharSetStatVersion},
+	{"CharStatTypes", testCharStatTypes},
+	{"CharRemoveStat", testCharRemoveStat},
+	{"CharRemove", testCharRemove},
+	{"CharLookupsAfterChanges", testCharLookupsAfterChanges},
+	{"StatHistoryAdd", testStatHistoryAdd},
+	{"StatHistoryGet", testStatHistoryGet},
+	{"StatHistoryGuild", testStatHistoryGuild},
+	{"RoleAdd", testRoleAdd},
+	{"RoleGet", testRoleGet},
+	{"RoleGetGuild", testRoleGetGuild},
+	{"RoleSetPermisisons", testRoleSetPermisisons},
+	{"RoleRemove", testRoleRemove},
+	{"MoneyAdd", testMoneyAdd},
+	{"MoneyGet", testMoneyGet},
+	{"MoneyChangeOwner", testMoneyChangeOwner},
+	{"MoneySetValid", testMoneySetValid},
+	{"AuditAdd", testAuditAdd},
+	{"AuditGet", testAuditGet},
+	{"TrashAdd", testTrashAdd},
+	{"TrashRemove", testTrashRemove},
+	{"TrashPurge", testTrashPurge},
+	{"SubmissionAdd", testSubmissionAdd},
+	{"SubmissionRemove", testSubmissionRemove},
+	{"TxCommit", testTxCommit},
+	{"TxRollback", testTxRollback},
+	{"TxRollbackStatRename", testTxRollbackStatRename},
+}
+
+// Run executes the DataProvider test suite against the backend named n.
+// newProvider is called for every test, so each of them starts with an empty database
+func Run(t *testing.T, n string, newProvider func() database.DataProvider) {
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			d := newProvider()
+			if cl, ok := d.(io.Closer); ok {
+				defer cl.Close()
+			}
+
+			c.f(t, n, d)
+		})
+	}
+}
+
+func assertError(oe error, message string, code database.ErrorCode, dbn string) string {
+	e := database.ErrToDbErr(oe)
+	if e == nil {
+		return fmt.Sprintf("Expected Database Error. Received: %v", oe)
+	}
+
+	if e.Code != code || e.Message != message {
+		return fmt.Sprintf("[%v] Wrong error message. Actual: [%v, %v], Expected: [%v, %v]", dbn, e.Code, e.Message, code, message)
+	}
+
+	return ""
+}
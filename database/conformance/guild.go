@@ -0,0 +1,1251 @@
+package conformance
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+func testGuildAdd(t *testing.T, n string, d database.DataProvider) {
+	g := &database.Guild{
+		Name:      "test",
+		DiscordId: "did1",
+	}
+
+	rc1, err := d.AddGuild(g)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rc1.Name != g.Name || rc1.DiscordId != g.DiscordId {
+		t.Fatalf("[%v] Wrong guild returned. Actual: %v, expected: %v", n, rc1, g)
+	}
+
+	g = &database.Guild{
+		Name:      "test2",
+		DiscordId: "did2",
+	}
+	rc2, err := d.AddGuild(g)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rc2.Name != g.Name || rc2.DiscordId != g.DiscordId {
+		t.Fatalf("[%v] Wrong guild returned. Actual: %v, expected: %v", n, rc2, g)
+	}
+	if rc2 == g {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	g = &database.Guild{
+		Name:     "sub1-test1",
+		ParentId: rc1.GuildId,
+	}
+	rc3, err := d.AddGuild(g)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rc3.Name != g.Name || rc3.ParentId != rc1.GuildId || rc3.TopLevelParentId != rc1.GuildId {
+		t.Fatalf("[%v] Wrong guild returned. Actual: %v, expected: %v", n, rc3, g)
+	}
+
+	g = &database.Guild{
+		Name:     "sub2-test1",
+		ParentId: rc3.GuildId,
+	}
+	rc4, err := d.AddGuild(g)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rc4.Name != g.Name || rc4.ParentId != rc3.GuildId || rc4.TopLevelParentId != rc1.GuildId {
+		t.Fatalf("[%v] Wrong guild returned. Actual: %v, expected: %v", n, rc4, g)
+	}
+
+	g = &database.Guild{
+		Name:     "sub2-test1",
+		ParentId: rc2.GuildId,
+	}
+	rc5, err := d.AddGuild(g)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rc5.Name != g.Name || rc5.ParentId != rc2.GuildId || rc5.TopLevelParentId != rc2.GuildId {
+		t.Fatalf("[%v] Wrong guild returned. Actual: %v, expected: %v", n, rc4, g)
+	}
+
+	g = &database.Guild{
+		Name:     "sub2-test1",
+		ParentId: rc4.GuildId,
+	}
+	rce, err := d.AddGuild(g)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, rce)
+	}
+	if e := assertError(err, "Sub-Guild name 'sub2-test1' is already taken", database.SubguildNameTaken, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	g = &database.Guild{
+		Name:     "sub1-test1",
+		ParentId: rc4.GuildId,
+	}
+	rce, err = d.AddGuild(g)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, rce)
+	}
+	if e := assertError(err, "Sub-Guild name 'sub1-test1' is already taken", database.SubguildNameTaken, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	g = &database.Guild{
+		Name:     "sub10-test10",
+		ParentId: uuid.New(),
+	}
+	rce, err = d.AddGuild(g)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, rce)
+	}
+	if e := assertError(err, "Invalid parent guild ID", database.InvalidGuildDefinition, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	g = &database.Guild{
+		Name:      "sub10-test10",
+		DiscordId: "did1",
+	}
+	rce, err = d.AddGuild(g)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, rce)
+	}
+	if e := assertError(err, "Guild 'did1' is already registered", database.GuildAlreadyRegistered, n); e != "" {
+		t.Fatalf(e)
+	}
+}
+
+func testGuildAddWithId(t *testing.T, n string, d database.DataProvider) {
+	id := uuid.New()
+	g := &database.Guild{
+		GuildId:   id,
+		Name:      "test",
+		DiscordId: "did_preset",
+	}
+
+	rc, err := d.AddGuild(g)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rc.GuildId != id || rc.TopLevelParentId != id {
+		t.Fatalf("[%v] Provided guild ID expected to be kept. Actual: %v, expected: %v", n, rc.GuildId, id)
+	}
+
+	g = &database.Guild{
+		GuildId:  id,
+		Name:     "sub",
+		ParentId: id,
+	}
+	rce, err := d.AddGuild(g)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, rce)
+	}
+	if e := assertError(err, "Guild ID is already taken", database.InvalidGuildDefinition, n); e != "" {
+		t.Fatalf(e)
+	}
+}
+
+func testGuildGet(t *testing.T, n string, d database.DataProvider) {
+	g := &database.Guild{
+		Name:      "test22",
+		DiscordId: "did122",
+	}
+	rc1, err := d.AddGuild(g)
+
+	g = &database.Guild{
+		Name:     "sub1-test1",
+		ParentId: rc1.GuildId,
+	}
+	rc2, err := d.AddGuild(g)
+
+	g = &database.Guild{
+		Name:     "sub2-test1",
+		ParentId: rc2.GuildId,
+	}
+	rc3, err := d.AddGuild(g)
+
+	g, err = d.GetGuild(rc3.GuildId)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rc3.Name != g.Name || g.ParentId != rc2.GuildId || g.TopLevelParentId != rc1.GuildId {
+		t.Fatalf("[%v] Wrong guild returned. Actual: %v, expected: %v", n, g, rc3)
+	}
+	if rc3 == g {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	names := make(map[string]database.Void)
+	names["sub1-test1"] = database.Member
+	names["sub2-test1"] = database.Member
+	g, err = d.GetGuild(rc1.GuildId)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rc1.Name != g.Name || rc1.DiscordId != g.DiscordId || !reflect.DeepEqual(g.ChildNames, names) {
+		t.Fatalf("[%v] Wrong guild returned. Actual: %v, expected: %v, with child names: %v", n, g, rc1, names)
+	}
+
+	rce, err := d.GetGuild(uuid.New())
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, rce)
+	}
+	if e := assertError(err, "Guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+}
+
+func testGuildGetD(t *testing.T, n string, d database.DataProvider) {
+	g := &database.Guild{
+		Name:      "test33",
+		DiscordId: "did133",
+	}
+	rc1, err := d.AddGuild(g)
+
+	g = &database.Guild{
+		Name:     "sub1-test1",
+		ParentId: rc1.GuildId,
+	}
+	rc2, err := d.AddGuild(g)
+
+	g = &database.Guild{
+		Name:     "sub2-test1",
+		ParentId: rc2.GuildId,
+	}
+	d.AddGuild(g)
+
+	names := make(map[string]database.Void)
+	names["sub1-test1"] = database.Member
+	names["sub2-test1"] = database.Member
+	g, err = d.GetGuildD("did133")
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rc1.Name != g.Name || rc1.DiscordId != g.DiscordId || !reflect.DeepEqual(g.ChildNames, names) {
+		t.Fatalf("[%v] Wrong guild returned. Actual: %v, expected: %v, with child names: %v", n, g, rc1, names)
+	}
+	if rc1 == g {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	rce, err := d.GetGuildD("did233")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, rce)
+	}
+	if e := assertError(err, "Guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+}
+
+func testGuildGetN(t *testing.T, n string, d database.DataProvider) {
+	g := &database.Guild{
+		Name:      "test_getn",
+		DiscordId: "did_getn",
+	}
+	rc1, err := d.AddGuild(g)
+
+	g = &database.Guild{
+		Name:     "sub1-test_getn",
+		ParentId: rc1.GuildId,
+	}
+	rc2, err := d.AddGuild(g)
+
+	g = &database.Guild{
+		Name:     "sub2-test_getn",
+		ParentId: rc2.GuildId,
+	}
+	rc3, err := d.AddGuild(g)
+
+	g, err = d.GetGuildN("did_getn_nonexistent", "sub2-test_getn")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Parent guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	g, err = d.GetGuildN("did_getn", "sub2-test_getn_nonexistent")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	g, err = d.GetGuildN("did_getn", "sub2-test_getn")
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rc3.Name != g.Name || rc3.GuildId != g.GuildId {
+		t.Fatalf("[%v] Wrong guild returned. Actual: %v, expected: %v.", n, g, rc3)
+	}
+	if rc3 == g {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	g, err = d.GetGuildN("did_getn", "sub1-test_getn")
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rc2.Name != g.Name || rc2.GuildId != g.GuildId {
+		t.Fatalf("[%v] Wrong guild returned. Actual: %v, expected: %v.", n, g, rc2)
+	}
+}
+
+func testGuildGetSub(t *testing.T, n string, d database.DataProvider) {
+	g := &database.Guild{
+		Name:      "test_getsub",
+		DiscordId: "did_getsub",
+	}
+	rc1, err := d.AddGuild(g)
+
+	g = &database.Guild{
+		Name:     "sub1-test_getsub",
+		ParentId: rc1.GuildId,
+	}
+	rc2, err := d.AddGuild(g)
+
+	g = &database.Guild{
+		Name:     "sub2-test_getsub",
+		ParentId: rc2.GuildId,
+	}
+	rc3, err := d.AddGuild(g)
+
+	gs, err := d.GetSubGuilds(uuid.New())
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, gs)
+	}
+	if e := assertError(err, "Guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	gs, err = d.GetSubGuilds(rc3.GuildId)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if len(gs) != 1 || rc3.Name != gs[rc3.GuildId].Name || rc3.GuildId != gs[rc3.GuildId].GuildId {
+		t.Fatalf("[%v] Wrong guilds returned. Actual: %v, expected: %v.", n, gs, rc3)
+	}
+	if rc3 == gs[rc3.GuildId] {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	gs, err = d.GetSubGuilds(rc2.GuildId)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if len(gs) != 2 || rc3.Name != gs[rc3.GuildId].Name || rc3.GuildId != gs[rc3.GuildId].GuildId ||
+		rc2.Name != gs[rc2.GuildId].Name || rc2.GuildId != gs[rc2.GuildId].GuildId {
+
+		t.Fatalf("[%v] Wrong guilds returned. Actual: %v, expected: %v elements.", n, gs, 2)
+	}
+
+	gs, err = d.GetSubGuilds(rc1.GuildId)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if len(gs) != 3 || rc3.Name != gs[rc3.GuildId].Name || rc3.GuildId != gs[rc3.GuildId].GuildId ||
+		rc2.Name != gs[rc2.GuildId].Name || rc2.GuildId != gs[rc2.GuildId].GuildId ||
+		rc1.Name != gs[rc1.GuildId].Name || rc1.GuildId != gs[rc1.GuildId].GuildId {
+
+		t.Fatalf("[%v] Wrong guilds returned. Actual: %v, expected: %v elements.", n, gs, 3)
+	}
+}
+
+func testGuildRename(t *testing.T, n string, d database.DataProvider) {
+	g := &database.Guild{
+		Name:      "test",
+		DiscordId: "did144",
+	}
+	rc1, err := d.AddGuild(g)
+
+	g = &database.Guild{
+		Name:     "sub1-test1",
+		ParentId: rc1.GuildId,
+	}
+	rc2, err := d.AddGuild(g)
+
+	g = &database.Guild{
+		Name:     "sub2-test1",
+		ParentId: rc2.GuildId,
+	}
+	d.AddGuild(g)
+
+	names := make(map[string]database.Void)
+	names["sub1-test2"] = database.Member
+	names["sub2-test1"] = database.Member
+
+	rc, err := d.RenameGuild(rc2.GuildId, "sub1-test2")
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rc.Name != "sub1-test2" || rc.GuildId != rc2.GuildId || rc.ParentId != rc1.GuildId {
+		t.Fatalf("[%v] Wrong guild returned. Actual: %v, expected: name: %v, parentId: %v", n, rc, "sub1-test2", rc1.GuildId)
+	}
+	if rc == rc2 {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	rce, err := d.RenameGuild(uuid.New(), "test3")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, rce)
+	}
+	if e := assertError(err, "Guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	rce, err = d.RenameGuild(rc2.GuildId, "sub2-test1")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, rce)
+	}
+	if e := assertError(err, "Sub-Guild name 'sub2-test1' is already taken", database.SubguildNameTaken, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	rc, err = d.GetGuild(rc1.GuildId)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rc.Name != "test" || rc.DiscordId != "did144" || !reflect.DeepEqual(rc.ChildNames, names) {
+		t.Fatalf("[%v] Wrong guild returned. Actual: %v, expected: %v, with child names: %v", n, g, rc1, names)
+	}
+}
+
+func testGuildAddStat(t *testing.T, n string, d database.DataProvider) {
+	g := &database.Guild{
+		Name:      "test",
+		DiscordId: "did155",
+	}
+	rc1, _ := d.AddGuild(g)
+
+	g = &database.Guild{
+		Name:     "sub1-test1",
+		ParentId: rc1.GuildId,
+	}
+	rc2, _ := d.AddGuild(g)
+
+	s1 := &database.Stat{
+		ID:          "s1",
+		Type:        database.Number,
+		Description: "desc1",
+	}
+	g, err := d.AddGuildStat(uuid.New(), s1)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	stats := map[string]*database.Stat{"s1": s1}
+
+	g, err = d.AddGuildStat(rc1.GuildId, s1)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if !reflect.DeepEqual(g.Stats, stats) {
+		t.Fatalf("[%v] Wrong stats. Actual: %v, expected: %v", n, g.Stats, stats)
+	}
+	if rc1 == g {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+	if g.StatVersion != 1 {
+		t.Fatalf("[%v] Wrong stats version. Actual: %v, expected: %v", n, g.StatVersion, 1)
+	}
+
+	g, err = d.AddGuildStat(rc1.GuildId, s1)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if !reflect.DeepEqual(g.Stats, stats) {
+		t.Fatalf("[%v] Wrong stats. Actual: %v, expected: %v", n, g.Stats, stats)
+	}
+
+	s1.Type = database.Str
+	g, err = d.AddGuildStat(rc1.GuildId, s1)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Stat with same name (s1) but different type (1) found", database.StatNameConflict, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	s1.Type = database.Number
+	s2 := &database.Stat{
+		ID:          "s2",
+		Type:        database.Str,
+		Description: "desc2",
+	}
+	stats["s2"] = s2
+	g, err = d.AddGuildStat(rc1.GuildId, s2)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if !reflect.DeepEqual(g.Stats, stats) {
+		t.Fatalf("[%v] Wrong stats. Actual: %v, expected: %v", n, g.Stats, stats)
+	}
+	if g.StatVersion != 2 {
+		t.Fatalf("[%v] Wrong stats version. Actual: %v, expected: %v", n, g.StatVersion, 2)
+	}
+
+	g, err = d.GetGuild(rc1.GuildId)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if !reflect.DeepEqual(g.Stats, stats) {
+		t.Fatalf("[%v] Wrong stats. Actual: %v, expected: %v", n, g.Stats, stats)
+	}
+
+	s3 := &database.Stat{
+		ID:          "s3",
+		Type:        database.Number,
+		Description: "desc3",
+	}
+	g, err = d.AddGuildStat(rc2.GuildId, s3)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if !reflect.DeepEqual(g.Stats, map[string]*database.Stat{"s3": s3}) || g.DefaultStat != "" {
+		t.Fatalf("[%v] Wrong sub-guild stats. Actual: %v, default: %v", n, g.Stats, g.DefaultStat)
+	}
+
+	g, err = d.GetGuild(rc1.GuildId)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if !reflect.DeepEqual(g.Stats, stats) {
+		t.Fatalf("[%v] Wrong stats. Actual: %v, expected: %v", n, g.Stats, stats)
+	}
+	if g.StatVersion != 3 {
+		t.Fatalf("[%v] Wrong stats version. Actual: %v, expected: %v", n, g.StatVersion, 3)
+	}
+}
+
+func testGuildSetDefaultStat(t *testing.T, n string, d database.DataProvider) {
+	g := &database.Guild{
+		Name:      "test",
+		DiscordId: uuid.New().String(),
+	}
+	rc1, _ := d.AddGuild(g)
+
+	g = &database.Guild{
+		Name:     "sub1-test1",
+		ParentId: rc1.GuildId,
+	}
+	rc2, _ := d.AddGuild(g)
+
+	g, err := d.SetDefaultGuildStat(rc2.GuildId, "s2")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Only top-level guild stats are supported right now", database.GuildLevelError, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	g, err = d.SetDefaultGuildStat(uuid.New(), "s2")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	g, err = d.SetDefaultGuildStat(rc1.GuildId, "s2")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Stat was not found", database.StatNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	s1 := &database.Stat{
+		ID:          "s1",
+		Type:        database.Str,
+		Description: "desc1",
+	}
+	d.AddGuildStat(rc1.GuildId, s1)
+	g, err = d.SetDefaultGuildStat(rc1.GuildId, "s2")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Stat was not found", database.StatNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	s2 := &database.Stat{
+		ID:          "s2",
+		Type:        database.Str,
+		Description: "desc2",
+	}
+	d.AddGuildStat(rc1.GuildId, s2)
+	g, err = d.SetDefaultGuildStat(rc1.GuildId, "s2")
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if g.DefaultStat != "s2" {
+		t.Fatalf("[%v] Wrong default stat. Actual: %v, expected: %v", n, g.DefaultStat, "s2")
+	}
+	if rc1 == g {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+	if g.StatVersion != 2 {
+		t.Fatalf("[%v] Wrong stats version. Actual: %v, expected: %v", n, g.StatVersion, 2)
+	}
+
+	g, err = d.SetDefaultGuildStat(rc1.GuildId, "s1")
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if g.DefaultStat != "s1" {
+		t.Fatalf("[%v] Wrong default stat. Actual: %v, expected: %v", n, g.DefaultStat, "s1")
+	}
+	if g.StatVersion != 2 {
+		t.Fatalf("[%v] Wrong stats version. Actual: %v, expected: %v", n, g.StatVersion, 2)
+	}
+
+	g, err = d.GetGuild(rc1.GuildId)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if g.DefaultStat != "s1" {
+		t.Fatalf("[%v] Wrong default stat. Actual: %v, expected: %v", n, g.DefaultStat, "s1")
+	}
+	if g.StatVersion != 2 {
+		t.Fatalf("[%v] Wrong stats version. Actual: %v, expected: %v", n, g.StatVersion, 2)
+	}
+}
+
+func testGuildRemoveStat(t *testing.T, n string, d database.DataProvider) {
+	g := &database.Guild{
+		Name:      "test",
+		DiscordId: uuid.New().String(),
+	}
+	rc1, _ := d.AddGuild(g)
+
+	g = &database.Guild{
+		Name:     "sub1-test1",
+		ParentId: rc1.GuildId,
+	}
+	rc2, _ := d.AddGuild(g)
+
+	g, err := d.RemoveGuildStat(rc2.GuildId, "s2")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Stat was not found", database.StatNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	g, err = d.RemoveGuildStat(uuid.New(), "s2")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	g, err = d.RemoveGuildStat(rc1.GuildId, "s2")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Stat was not found", database.StatNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	s1 := &database.Stat{
+		ID:          "s1",
+		Type:        database.Str,
+		Description: "desc1",
+	}
+	d.AddGuildStat(rc1.GuildId, s1)
+	g, err = d.RemoveGuildStat(rc1.GuildId, "s2")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Stat was not found", database.StatNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	s2 := &database.Stat{
+		ID:          "s2",
+		Type:        database.Str,
+		Description: "desc2",
+	}
+	stats := map[string]*database.Stat{"s1": s1}
+	d.AddGuildStat(rc1.GuildId, s2)
+	g, err = d.RemoveGuildStat(rc1.GuildId, "s2")
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if !reflect.DeepEqual(g.Stats, stats) {
+		t.Fatalf("[%v] Wrong stats. Actual: %v, expected: %v", n, g.Stats, stats)
+	}
+	if rc1 == g {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+	if g.StatVersion != 3 {
+		t.Fatalf("[%v] Wrong stats version. Actual: %v, expected: %v", n, g.StatVersion, 3)
+	}
+
+	delete(stats, "s1")
+	g, err = d.RemoveGuildStat(rc1.GuildId, "s1")
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if g.Stats == nil || !reflect.DeepEqual(g.Stats, stats) {
+		t.Fatalf("[%v] Wrong stats. Actual: %v, expected: %v", n, g.Stats, stats)
+	}
+	if g.StatVersion != 4 {
+		t.Fatalf("[%v] Wrong stats version. Actual: %v, expected: %v", n, g.StatVersion, 4)
+	}
+
+	g, err = d.GetGuild(rc1.GuildId)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if g.Stats == nil || !reflect.DeepEqual(g.Stats, stats) {
+		t.Fatalf("[%v] Wrong stats. Actual: %v, expected: %v", n, g.Stats, stats)
+	}
+	if g.StatVersion != 4 {
+		t.Fatalf("[%v] Wrong stats version. Actual: %v, expected: %v", n, g.StatVersion, 4)
+	}
+}
+
+func testGuildRemoveAllStats(t *testing.T, n string, d database.DataProvider) {
+	g := &database.Guild{
+		Name:      "test",
+		DiscordId: uuid.New().String(),
+	}
+	rc1, f := d.AddGuild(g)
+	_ = f
+	g = &database.Guild{
+		Name:     "sub1-test1",
+		ParentId: rc1.GuildId,
+	}
+	rc2, _ := d.AddGuild(g)
+
+	g, err := d.RemoveAllGuildStats(rc2.GuildId)
+	if err != nil {
+		t.Fatalf("[%v] Sub-guild stats remove failed: %v", n, err)
+	}
+	if len(g.Stats) != 0 {
+		t.Fatalf("[%v] Sub-guild stats were not removed: %v", n, g)
+	}
+
+	g, err = d.RemoveAllGuildStats(uuid.New())
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	s1 := &database.Stat{
+		ID:          "s1",
+		Type:        database.Str,
+		Description: "desc1",
+	}
+	d.AddGuildStat(rc1.GuildId, s1)
+
+	s2 := &database.Stat{
+		ID:          "s2",
+		Type:        database.Str,
+		Description: "desc2",
+	}
+	d.AddGuildStat(rc1.GuildId, s2)
+
+	g, err = d.RemoveAllGuildStats(rc1.GuildId)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if g.Stats != nil && len(g.Stats) > 0 {
+		t.Fatalf("[%v] Wrong stats. Actual: %v, expected: %v", n, g.Stats, "empty")
+	}
+
+	g, err = d.GetGuild(rc1.GuildId)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if g.Stats != nil && len(g.Stats) > 0 {
+		t.Fatalf("[%v] Wrong stats. Actual: %v, expected: %v", n, g.Stats, "empty")
+	}
+}
+
+func testGuildMove(t *testing.T, n string, d database.DataProvider) {
+	g := &database.Guild{
+		Name:      "test_move",
+		DiscordId: "did_move",
+	}
+	rc1, err := d.AddGuild(g)
+
+	g = &database.Guild{
+		Name:     "sub1-test_move",
+		ParentId: rc1.GuildId,
+	}
+	rc2, err := d.AddGuild(g)
+
+	g = &database.Guild{
+		Name:     "sub2-test_move",
+		ParentId: rc2.GuildId,
+	}
+	rc3, err := d.AddGuild(g)
+
+	g, err = d.MoveGuild(uuid.New(), rc1.GuildId)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	g, err = d.MoveGuild(rc3.GuildId, uuid.New())
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Parent guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	g, err = d.MoveGuild(rc3.GuildId, rc1.GuildId)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if g.GuildId != rc3.GuildId || g.ParentId != rc1.GuildId || g.TopLevelParentId != rc1.GuildId {
+		t.Fatalf("[%v] Wrong guild returned. Actual: %v, expected: %v, with parent: %v", n, g, rc3, rc1.GuildId)
+	}
+	if rc3 == g {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+}
+
+func testGuildRemove(t *testing.T, n string, d database.DataProvider) {
+	g := &database.Guild{
+		Name:      "test",
+		DiscordId: "did177",
+	}
+	rc1, err := d.AddGuild(g)
+
+	g = &database.Guild{
+		Name:     "sub1-test1",
+		ParentId: rc1.GuildId,
+	}
+	rc2, err := d.AddGuild(g)
+
+	g = &database.Guild{
+		Name:     "sub2-test1",
+		ParentId: rc2.GuildId,
+	}
+	rc3, err := d.AddGuild(g)
+
+	g, err = d.RemoveGuild(uuid.New())
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	g, err = d.RemoveGuild(rc3.GuildId)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if g.GuildId != rc3.GuildId || g.Name != rc3.Name {
+		t.Fatalf("[%v] Wrong guild returned. Actual: %v, expected: %v", n, g, rc3)
+	}
+
+	g = &database.Guild{
+		Name:     "sub2-test1",
+		ParentId: rc2.GuildId,
+	}
+	rc3, err = d.AddGuild(g)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+
+	g, err = d.RemoveGuild(rc2.GuildId)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if g.GuildId != rc2.GuildId || g.Name != rc2.Name {
+		t.Fatalf("[%v] Wrong guild returned. Actual: %v, expected: %v", n, g, rc2)
+	}
+	if rc2 == g {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	_, err = d.GetGuild(rc2.GuildId)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	_, err = d.GetGuild(rc3.GuildId)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	g, err = d.GetGuild(rc1.GuildId)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if g.GuildId != rc1.GuildId || g.Name != rc1.Name {
+		t.Fatalf("[%v] Wrong guild returned. Actual: %v, expected: %v", n, g, rc1)
+	}
+
+	g = &database.Guild{
+		Name:     "sub1-test1",
+		ParentId: rc1.GuildId,
+	}
+	rc2, err = d.AddGuild(g)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+
+	g = &database.Guild{
+		Name:     "sub2-test1",
+		ParentId: rc2.GuildId,
+	}
+	rc3, err = d.AddGuild(g)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+
+	g, err = d.RemoveGuild(rc1.GuildId)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if g.GuildId != rc1.GuildId || g.Name != rc1.Name {
+		t.Fatalf("[%v] Wrong guild returned. Actual: %v, expected: %v", n, g, rc1)
+	}
+
+	_, err = d.GetGuild(rc3.GuildId)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+}
+
+func testGuildRemoveD(t *testing.T, n string, d database.DataProvider) {
+	g := &database.Guild{
+		Name:      "test",
+		DiscordId: "did18",
+	}
+	rc1, err := d.AddGuild(g)
+
+	g = &database.Guild{
+		Name:     "sub1-test1",
+		ParentId: rc1.GuildId,
+	}
+	rc2, err := d.AddGuild(g)
+
+	g = &database.Guild{
+		Name:     "sub2-test1",
+		ParentId: rc2.GuildId,
+	}
+	rc3, err := d.AddGuild(g)
+
+	g, err = d.RemoveGuildD("unknown did")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	g, err = d.RemoveGuildD(rc2.Name)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	g, err = d.RemoveGuildD(rc1.DiscordId)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if g.GuildId != rc1.GuildId || g.Name != rc1.Name {
+		t.Fatalf("[%v] Wrong guild returned. Actual: %v, expected: %v", n, g, rc1)
+	}
+	if rc1 == g {
+		t.Fatalf("[%v] Duplicate of character expected, received original", n)
+	}
+
+	_, err = d.GetGuild(rc1.GuildId)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	_, err = d.GetGuild(rc2.GuildId)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	_, err = d.GetGuild(rc3.GuildId)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, g)
+	}
+	if e := assertError(err, "Guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+}
+
+func testGuildAddEnumStat(t *testing.T, n string, d database.DataProvider) {
+	rc, _ := d.AddGuild(&database.Guild{Name: "test1", DiscordId: "did1"})
+
+	s := &database.Stat{ID: "class", Type: database.Enum, Values: []string{"mage", "warrior"}}
+	g, err := d.AddGuildStat(rc.GuildId, s)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if !reflect.DeepEqual(g.Stats["class"], s) {
+		t.Fatalf("[%v] Wrong stat. Actual: %v, expected: %v", n, g.Stats["class"], s)
+	}
+
+	s.Values = []string{"mage", "warrior", "rogue"}
+	if _, err = d.AddGuildStat(rc.GuildId, s); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	g, _ = d.GetGuild(rc.GuildId)
+	if !reflect.DeepEqual(g.Stats["class"], s) {
+		t.Fatalf("[%v] Wrong stat. Actual: %v, expected: %v", n, g.Stats["class"], s)
+	}
+}
+
+func testGuildStatConstraints(t *testing.T, n string, d database.DataProvider) {
+	rc, _ := d.AddGuild(&database.Guild{Name: "test1", DiscordId: "did1"})
+
+	min, max := 1.0, 99.5
+	s := &database.Stat{ID: "power", Type: database.Float, Min: &min, Max: &max, Review: true}
+	if _, err := d.AddGuildStat(rc.GuildId, s); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	g, _ := d.GetGuild(rc.GuildId)
+	if !reflect.DeepEqual(g.Stats["power"], s) {
+		t.Fatalf("[%v] Wrong stat. Actual: %v, expected: %v", n, g.Stats["power"], s)
+	}
+
+	s = &database.Stat{ID: "nick", Type: database.Str, Pattern: "^[a-z]+$", MaxLength: 12, Required: true, Write: database.WriteOfficers}
+	if _, err := d.AddGuildStat(rc.GuildId, s); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	g, _ = d.GetGuild(rc.GuildId)
+	if !reflect.DeepEqual(g.Stats["nick"], s) {
+		t.Fatalf("[%v] Wrong stat. Actual: %v, expected: %v", n, g.Stats["nick"], s)
+	}
+
+	s = &database.Stat{ID: "nick", Type: database.Str, Description: "nickname"}
+	if _, err := d.AddGuildStat(rc.GuildId, s); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	g, _ = d.GetGuild(rc.GuildId)
+	if !reflect.DeepEqual(g.Stats["nick"], s) {
+		t.Fatalf("[%v] Constraints expected to be dropped. Actual: %v, expected: %v", n, g.Stats["nick"], s)
+	}
+
+	s = &database.Stat{ID: "double", Type: database.Computed, Formula: "power * 2"}
+	if _, err := d.AddGuildStat(rc.GuildId, s); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	g, _ = d.GetGuild(rc.GuildId)
+	if !reflect.DeepEqual(g.Stats["double"], s) {
+		t.Fatalf("[%v] Wrong stat. Actual: %v, expected: %v", n, g.Stats["double"], s)
+	}
+}
+
+func testGuildStatDisplay(t *testing.T, n string, d database.DataProvider) {
+	rc, _ := d.AddGuild(&database.Guild{Name: "test1", DiscordId: "did1"})
+
+	s := &database.Stat{ID: "lvl", Type: database.Number, Category: "progress", Order: 2}
+	if _, err := d.AddGuildStat(rc.GuildId, s); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	g, _ := d.GetGuild(rc.GuildId)
+	if !reflect.DeepEqual(g.Stats["lvl"], s) {
+		t.Fatalf("[%v] Wrong stat. Actual: %v, expected: %v", n, g.Stats["lvl"], s)
+	}
+
+	s = &database.Stat{ID: "lvl", Type: database.Number, Category: "combat", Order: -1}
+	if _, err := d.AddGuildStat(rc.GuildId, s); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	g, _ = d.GetGuild(rc.GuildId)
+	if !reflect.DeepEqual(g.Stats["lvl"], s) {
+		t.Fatalf("[%v] Category and order expected to change. Actual: %v, expected: %v", n, g.Stats["lvl"], s)
+	}
+
+	g, err := d.RenameGuildStat(rc.GuildId, "lvl", "level")
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if st := g.Stats["level"]; st == nil || st.Category != "combat" || st.Order != -1 {
+		t.Fatalf("[%v] Category and order expected to follow rename. Actual: %v", n, st)
+	}
+}
+
+func testGuildRenameStat(t *testing.T, n string, d database.DataProvider) {
+	rc, _ := d.AddGuild(&database.Guild{Name: "test1", DiscordId: "did1"})
+	d.AddGuildStat(rc.GuildId, &database.Stat{ID: "lvl", Type: database.Number, Description: "level"})
+	d.AddGuildStat(rc.GuildId, &database.Stat{ID: "cls", Type: database.Str})
+
+	d.AddCharacter(&database.Character{GuildId: "did1", UserId: "u1", Name: "c1", Body: map[string]interface{}{"lvl": 5, "power": 1}})
+	d.AddCharacter(&database.Character{GuildId: "did2", UserId: "u1", Name: "c1", Body: map[string]interface{}{"lvl": 7}})
+	d.AddStatHistory(&database.StatHistory{GuildId: "did1", UserId: "u1", Character: "c1", Stat: "lvl", Value: 5, Time: time.Now()})
+	d.AddSubmission(&database.Submission{GuildId: "did1", UserId: "u1", Character: "c1", Stat: "lvl", Value: 6})
+	d.AddSubmission(&database.Submission{GuildId: "did2", UserId: "u1", Character: "c1", Stat: "lvl", Value: 8})
+	trashed := &database.Character{GuildId: "did1", UserId: "u2", Name: "c2", Body: map[string]interface{}{"lvl": 3, "cls": "mage"}}
+	d.AddTrashItem(&database.TrashItem{GuildId: "did1", Type: database.TrashCharacter, Name: "c2", Characters: []*database.Character{trashed},
+		History: []*database.StatHistory{{GuildId: "did1", UserId: "u2", Character: "c2", Stat: "lvl", Value: 3}}})
+
+	g, err := d.RenameGuildStat(uuid.New(), "lvl", "power")
+	if e := assertError(err, "Guild was not found", database.GuildNotFound, n); e != "" {
+		t.Fatal(e)
+	}
+	g, err = d.RenameGuildStat(rc.GuildId, "level", "power")
+	if e := assertError(err, "Stat was not found", database.StatNotFound, n); e != "" {
+		t.Fatal(e)
+	}
+	g, err = d.RenameGuildStat(rc.GuildId, "lvl", "cls")
+	if e := assertError(err, "Stat with name cls already exists", database.StatNameConflict, n); e != "" {
+		t.Fatal(e)
+	}
+
+	g, err = d.RenameGuildStat(rc.GuildId, "lvl", "power")
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	expected := &database.Stat{ID: "power", Type: database.Number, Description: "level"}
+	if _, ok := g.Stats["lvl"]; ok || !reflect.DeepEqual(g.Stats["power"], expected) {
+		t.Fatalf("[%v] Wrong stats. Actual: %v", n, g.Stats)
+	}
+	if g.DefaultStat != "power" {
+		t.Fatalf("[%v] Wrong default stat. Actual: %v, expected: %v", n, g.DefaultStat, "power")
+	}
+	if g.StatVersion != 3 {
+		t.Fatalf("[%v] Wrong stats version. Actual: %v, expected: %v", n, g.StatVersion, 3)
+	}
+
+	c, _ := d.GetCharacter("did1", "u1", "c1")
+	if !reflect.DeepEqual(c.Body, map[string]interface{}{"power": 5}) {
+		t.Fatalf("[%v] Character values expected to be moved. Actual: %v", n, c.Body)
+	}
+	c, _ = d.GetCharacter("did2", "u1", "c1")
+	if !reflect.DeepEqual(c.Body, map[string]interface{}{"lvl": 7}) {
+		t.Fatalf("[%v] Characters of other guilds expected to stay the same. Actual: %v", n, c.Body)
+	}
+	if hs, _ := d.GetStatHistory("did1", "u1", "c1", "power"); len(hs) != 1 {
+		t.Fatalf("[%v] History expected to be moved. Actual: %v", n, hs)
+	}
+
+	if subs, _ := d.GetSubmissions("did1"); len(subs) != 1 || subs[0].Stat != "power" || subs[0].Value != 6 {
+		t.Fatalf("[%v] Pending submissions expected to be moved. Actual: %v", n, subs)
+	}
+	if subs, _ := d.GetSubmissions("did2"); len(subs) != 1 || subs[0].Stat != "lvl" {
+		t.Fatalf("[%v] Submissions of other guilds expected to stay the same. Actual: %v", n, subs)
+	}
+	items, _ := d.GetTrashItems("did1")
+	if len(items) != 1 || len(items[0].Characters) != 1 || len(items[0].History) != 1 {
+		t.Fatalf("[%v] Trash item expected to be kept. Actual: %v", n, items)
+	}
+	if body := items[0].Characters[0].Body; !reflect.DeepEqual(body, map[string]interface{}{"power": 3, "cls": "mage"}) {
+		t.Fatalf("[%v] Values of removed characters expected to be moved. Actual: %v", n, body)
+	}
+	if items[0].History[0].Stat != "power" {
+		t.Fatalf("[%v] History of removed characters expected to be moved. Actual: %v", n, items[0].History[0])
+	}
+	if trashed.Body["lvl"] != 3 {
+		t.Fatalf("[%v] Trashed character passed by caller expected to stay the same. Actual: %v", n, trashed.Body)
+	}
+}
+
+func testGuildRenameSubStat(t *testing.T, n string, d database.DataProvider) {
+	rc, _ := d.AddGuild(&database.Guild{Name: "test1", DiscordId: "did1"})
+	sub, _ := d.AddGuild(&database.Guild{Name: "sub", ParentId: rc.GuildId})
+	d.AddGuildStat(rc.GuildId, &database.Stat{ID: "lvl", Type: database.Number})
+	d.AddGuildStat(sub.GuildId, &database.Stat{ID: "rank", Type: database.Str})
+	d.AddCharacter(&database.Character{GuildId: "did1", UserId: "u1", Name: "c1", Body: map[string]interface{}{"lvl": 5, "rank": "private"}})
+	d.AddStatHistory(&database.StatHistory{GuildId: "did1", UserId: "u1", Character: "c1", Stat: "rank", Value: "private", Time: time.Now()})
+	d.AddSubmission(&database.Submission{GuildId: "did1", UserId: "u1", Character: "c1", Stat: "rank", Value: "major"})
+	top, _ := d.GetGuild(rc.GuildId)
+
+	g, err := d.RenameGuildStat(sub.GuildId, "rank", "title")
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if _, ok := g.Stats["rank"]; ok || g.Stats["title"] == nil || g.Stats["title"].ID != "title" {
+		t.Fatalf("[%v] Wrong stats. Actual: %v", n, g.Stats)
+	}
+	if rt, _ := d.GetGuild(rc.GuildId); rt.StatVersion <= top.StatVersion || rt.DefaultStat != "lvl" {
+		t.Fatalf("[%v] Top-level guild stat version expected to grow. Actual: %v, before: %v", n, rt, top)
+	}
+
+	c, _ := d.GetCharacter("did1", "u1", "c1")
+	if !reflect.DeepEqual(c.Body, map[string]interface{}{"lvl": 5, "title": "private"}) {
+		t.Fatalf("[%v] Character values expected to be moved. Actual: %v", n, c.Body)
+	}
+	if hs, _ := d.GetStatHistory("did1", "u1", "c1", "title"); len(hs) != 1 {
+		t.Fatalf("[%v] History expected to be moved. Actual: %v", n, hs)
+	}
+	if subs, _ := d.GetSubmissions("did1"); len(subs) != 1 || subs[0].Stat != "title" {
+		t.Fatalf("[%v] Pending submissions expected to be moved. Actual: %v", n, subs)
+	}
+}
+
+func testGuildChangeStatType(t *testing.T, n string, d database.DataProvider) {
+	rc, _ := d.AddGuild(&database.Guild{Name: "test1", DiscordId: "did1"})
+	d.AddGuildStat(rc.GuildId, &database.Stat{ID: "lvl", Type: database.Number, Description: "level"})
+
+	s := &database.Stat{ID: "other", Type: database.Float}
+	g, err := d.ChangeGuildStatType(rc.GuildId, s)
+	if e := assertError(err, "Stat was not found", database.StatNotFound, n); e != "" {
+		t.Fatal(e)
+	}
+
+	s = &database.Stat{ID: "lvl", Type: database.Enum, Description: "level", Values: []string{"low", "high"}}
+	g, err = d.ChangeGuildStatType(rc.GuildId, s)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if !reflect.DeepEqual(g.Stats["lvl"], s) {
+		t.Fatalf("[%v] Wrong stat. Actual: %v, expected: %v", n, g.Stats["lvl"], s)
+	}
+	if g.StatVersion != 2 || g.DefaultStat != "lvl" {
+		t.Fatalf("[%v] Wrong stats version or default stat. Actual: %v, %v", n, g.StatVersion, g.DefaultStat)
+	}
+
+	sub, _ := d.AddGuild(&database.Guild{Name: "sub", ParentId: rc.GuildId})
+	d.AddGuildStat(sub.GuildId, &database.Stat{ID: "rank", Type: database.Str})
+	top, _ := d.GetGuild(rc.GuildId)
+	s = &database.Stat{ID: "rank", Type: database.Number}
+	if g, err = d.ChangeGuildStatType(sub.GuildId, s); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if !reflect.DeepEqual(g.Stats["rank"], s) {
+		t.Fatalf("[%v] Wrong sub-guild stat. Actual: %v, expected: %v", n, g.Stats["rank"], s)
+	}
+	if rt, _ := d.GetGuild(rc.GuildId); rt.StatVersion <= top.StatVersion {
+		t.Fatalf("[%v] Top-level guild stat version expected to grow. Actual: %v, before: %v", n, rt.StatVersion, top.StatVersion)
+	}
+}
@@ -0,0 +1,138 @@
+package conformance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+func testStatHistoryAdd(t *testing.T, n string, d database.DataProvider) {
+	if _, err := d.AddCharacter(&database.Character{GuildId: "gid1", UserId: "uid1", Name: "char1"}); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+
+	now := time.Now()
+	h := &database.StatHistory{GuildId: "gid1", UserId: "uid1", Character: "char1", Stat: "lvl", Value: 10, Time: now}
+	rv, err := d.AddStatHistory(h)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rv == h || rv.Character != "char1" || rv.Value != 10 || !rv.Time.Equal(now) {
+		t.Fatalf("[%v] Wrong entry returned. Actual: %v, expected: %v", n, rv, h)
+	}
+
+	rce, err := d.AddStatHistory(&database.StatHistory{GuildId: "gid1", UserId: "uid1", Character: "char2", Stat: "lvl", Value: 10, Time: now})
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, rce)
+	}
+	if e := assertError(err, "Character with name char2 was not found", database.CharacterNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+}
+
+func testStatHistoryGet(t *testing.T, n string, d database.DataProvider) {
+	if _, err := d.AddCharacter(&database.Character{GuildId: "gid1", UserId: "uid1", Name: "char1"}); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if _, err := d.AddCharacter(&database.Character{GuildId: "gid1", UserId: "uid1", Name: "char2"}); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+
+	now := time.Now()
+	values := []interface{}{10, "mage", 15, 12}
+	stats := []string{"lvl", "class", "lvl", "lvl"}
+	for i, v := range values {
+		h := &database.StatHistory{GuildId: "gid1", UserId: "uid1", Character: "char1", Stat: stats[i], Value: v, Time: now.Add(time.Duration(i) * time.Minute)}
+		if _, err := d.AddStatHistory(h); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+	}
+	if _, err := d.AddStatHistory(&database.StatHistory{GuildId: "gid1", UserId: "uid1", Character: "char2", Stat: "lvl", Value: 1, Time: now}); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+
+	check := func(prefix string, u string, name string, expected []interface{}) {
+		hs, err := d.GetStatHistory("gid1", u, name, "lvl")
+		if err != nil {
+			t.Fatalf("[%v/%v] No errors expected. Received: %v", n, prefix, err)
+		}
+		if len(hs) != len(expected) {
+			t.Fatalf("[%v/%v] Wrong history length. Actual: %v, expected: %v", n, prefix, len(hs), len(expected))
+		}
+		for i, v := range expected {
+			if hs[i].Value != v || hs[i].UserId != u || hs[i].Stat != "lvl" {
+				t.Fatalf("[%v/%v] Wrong history entry %v: %v", n, prefix, i, hs[i])
+			}
+		}
+	}
+
+	check("initial", "uid1", "char1", []interface{}{10, 15, 12})
+
+	if _, err := d.RenameCharacter("gid1", "uid1", "char1", "char3"); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	check("renamed", "uid1", "char3", []interface{}{10, 15, 12})
+
+	if _, err := d.ChangeCharacterOwner("gid1", "uid1", "char3", "uid2"); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	check("owner changed", "uid2", "char3", []interface{}{10, 15, 12})
+	check("other character", "uid1", "char2", []interface{}{1})
+
+	if _, err := d.RemoveCharacter("gid1", "uid2", "char3"); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if _, err := d.AddCharacter(&database.Character{GuildId: "gid1", UserId: "uid2", Name: "char3"}); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	check("removed", "uid2", "char3", []interface{}{})
+
+	rce, err := d.GetStatHistory("gid1", "uid3", "char1", "lvl")
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, rce)
+	}
+}
+
+func testStatHistoryGuild(t *testing.T, n string, d database.DataProvider) {
+	for _, c := range []string{"char1", "char2", "char3"} {
+		if _, err := d.AddCharacter(&database.Character{GuildId: "gid1", UserId: "uid1", Name: c}); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+	}
+	if _, err := d.AddCharacter(&database.Character{GuildId: "gid2", UserId: "uid1", Name: "char1"}); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+
+	now := time.Now()
+	entries := []*database.StatHistory{
+		{GuildId: "gid1", UserId: "uid1", Character: "char1", Stat: "lvl", Value: 1, Time: now.Add(-10 * time.Hour)},
+		{GuildId: "gid1", UserId: "uid1", Character: "char1", Stat: "lvl", Value: 2, Time: now.Add(-9 * time.Hour)},
+		{GuildId: "gid1", UserId: "uid1", Character: "char2", Stat: "lvl", Value: 5, Time: now.Add(-8 * time.Hour)},
+		{GuildId: "gid1", UserId: "uid1", Character: "char1", Stat: "class", Value: "mage", Time: now.Add(-2 * time.Hour)},
+		{GuildId: "gid1", UserId: "uid1", Character: "char1", Stat: "lvl", Value: 7, Time: now.Add(-time.Hour)},
+		{GuildId: "gid1", UserId: "uid1", Character: "char3", Stat: "lvl", Value: 3, Time: now.Add(-30 * time.Minute)},
+		{GuildId: "gid2", UserId: "uid1", Character: "char1", Stat: "lvl", Value: 9, Time: now.Add(-time.Minute)},
+		{GuildId: "gid1", UserId: "uid1", Character: "char1", Stat: "lvl", Value: 8, Time: now},
+	}
+	for _, h := range entries {
+		if _, err := d.AddStatHistory(h); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+	}
+
+	hs, err := d.GetGuildStatHistory("gid1", "lvl", now.Add(-5*time.Hour))
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+
+	expected := []*database.StatHistory{entries[1], entries[2], entries[4], entries[5], entries[7]}
+	if len(hs) != len(expected) {
+		t.Fatalf("[%v] Wrong history length. Actual: %v, expected: %v", n, len(hs), len(expected))
+	}
+	for i, e := range expected {
+		if hs[i].Character != e.Character || hs[i].Value != e.Value || !hs[i].Time.Equal(e.Time) {
+			t.Fatalf("[%v] Wrong history entry %v. Actual: %v, expected: %v", n, i, hs[i], e)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package conformance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+func testSubmissionAdd(t *testing.T, n string, d database.DataProvider) {
+	sub := &database.Submission{GuildId: "gid1", UserId: "uid1", Character: "char1", Stat: "lvl", Value: 10, SubmittedBy: "uid1", Time: time.Now()}
+
+	r1, err := d.AddSubmission(sub)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if r1 == sub {
+		t.Fatalf("[%v] Duplicate of submission expected, received original", n)
+	}
+	if r1.Id == 0 || r1.Character != sub.Character || r1.Stat != sub.Stat || r1.Value != 10 || !r1.Time.Equal(sub.Time) {
+		t.Fatalf("[%v] Wrong submission returned. Actual: %v, expected: %v", n, r1, sub)
+	}
+
+	r2, err := d.AddSubmission(&database.Submission{GuildId: "gid1", UserId: "uid2", Character: "char2", Stat: "power", Value: 2.5, SubmittedBy: "uid2", Time: time.Now()})
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if r2.Id == r1.Id {
+		t.Fatalf("[%v] Unique id expected. Received: %v", n, r2.Id)
+	}
+
+	subs, err := d.GetSubmissions("gid1")
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if len(subs) != 2 || subs[0].Id != r1.Id || subs[1].Id != r2.Id {
+		t.Fatalf("[%v] Two submissions expected in order. Received: %v", n, subs)
+	}
+	if subs[0].UserId != "uid1" || subs[0].SubmittedBy != "uid1" || subs[0].Value != 10 || subs[1].Value != 2.5 {
+		t.Fatalf("[%v] Wrong submission content: %v, %v", n, subs[0], subs[1])
+	}
+
+	subs, err = d.GetSubmissions("gid2")
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if len(subs) != 0 {
+		t.Fatalf("[%v] No submissions expected. Received: %v", n, subs)
+	}
+}
+
+func testSubmissionRemove(t *testing.T, n string, d database.DataProvider) {
+	sub, err := d.AddSubmission(&database.Submission{GuildId: "gid1", UserId: "uid1", Character: "char1", Stat: "class", Value: "mage", Time: time.Now()})
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+
+	rce, err := d.RemoveSubmission("gid2", sub.Id)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, rce)
+	}
+	if e := assertError(err, "Submission was not found", database.SubmissionNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	rv, err := d.RemoveSubmission("gid1", sub.Id)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rv.Id != sub.Id || rv.Value != "mage" {
+		t.Fatalf("[%v] Wrong submission removed. Actual: %v, expected: %v", n, rv, sub)
+	}
+
+	if rce, err = d.RemoveSubmission("gid1", sub.Id); err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, rce)
+	}
+	if subs, _ := d.GetSubmissions("gid1"); len(subs) != 0 {
+		t.Fatalf("[%v] No submissions expected. Received: %v", n, subs)
+	}
+}
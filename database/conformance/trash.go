@@ -0,0 +1,117 @@
+package conformance
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+func testTrashAdd(t *testing.T, n string, d database.DataProvider) {
+	sub := uuid.New()
+	tr := &database.TrashItem{
+		GuildId:    "gid1",
+		Type:       database.TrashCharacter,
+		Name:       "char1",
+		RemovedBy:  "uid1",
+		Time:       time.Now(),
+		Characters: []*database.Character{{GuildId: "gid1", UserId: "uid2", Name: "char1", Body: map[string]interface{}{"lvl": 10, "class": "mage"}}},
+		Members:    map[string]uuid.UUID{"uid2": sub},
+		History:    []*database.StatHistory{{GuildId: "gid1", UserId: "uid2", Character: "char1", Stat: "lvl", Value: 10, Time: time.Unix(100, 0)}},
+	}
+
+	r1, err := d.AddTrashItem(tr)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if r1 == tr {
+		t.Fatalf("[%v] Duplicate of item expected, received original", n)
+	}
+	if r1.Id == 0 || r1.Name != tr.Name || r1.Type != tr.Type || !r1.Time.Equal(tr.Time) {
+		t.Fatalf("[%v] Wrong item returned. Actual: %v, expected: %v", n, r1, tr)
+	}
+
+	items, err := d.GetTrashItems("gid1")
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if len(items) != 1 {
+		t.Fatalf("[%v] One item expected. Received: %v", n, items)
+	}
+	c := items[0].Characters[0]
+	if c.Name != "char1" || c.Body["lvl"] != 10 || c.Body["class"] != "mage" || items[0].Members["uid2"] != sub {
+		t.Fatalf("[%v] Wrong item content: %v, %v", n, c, items[0].Members)
+	}
+	if h := items[0].History; len(h) != 1 || h[0].Value != 10 || !h[0].Time.Equal(time.Unix(100, 0)) {
+		t.Fatalf("[%v] Wrong item history: %v", n, h)
+	}
+
+	items, err = d.GetTrashItems("gid2")
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if len(items) != 0 {
+		t.Fatalf("[%v] No items expected. Received: %v", n, items)
+	}
+}
+
+func testTrashRemove(t *testing.T, n string, d database.DataProvider) {
+	tr, err := d.AddTrashItem(&database.TrashItem{GuildId: "gid1", Type: database.TrashUser, Name: "uid1", Time: time.Now()})
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+
+	rce, err := d.RemoveTrashItem("gid2", tr.Id)
+	if err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, rce)
+	}
+	if e := assertError(err, "Item was not found in the trash", database.TrashItemNotFound, n); e != "" {
+		t.Fatalf(e)
+	}
+
+	rv, err := d.RemoveTrashItem("gid1", tr.Id)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if rv.Id != tr.Id || rv.Name != "uid1" {
+		t.Fatalf("[%v] Wrong item removed. Actual: %v, expected: %v", n, rv, tr)
+	}
+
+	if rce, err = d.RemoveTrashItem("gid1", tr.Id); err == nil {
+		t.Fatalf("[%v] Error expected. Received: %v", n, rce)
+	}
+}
+
+func testTrashPurge(t *testing.T, n string, d database.DataProvider) {
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		if _, err := d.AddTrashItem(&database.TrashItem{GuildId: "gid1", Name: "old", Time: now.Add(-time.Duration(i+1) * time.Hour)}); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+	}
+	if _, err := d.AddTrashItem(&database.TrashItem{GuildId: "gid1", Name: "new", Time: now}); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if _, err := d.AddTrashItem(&database.TrashItem{GuildId: "gid2", Name: "other", Time: now.Add(-time.Hour)}); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+
+	cnt, err := d.PurgeTrash("gid1", now.Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if cnt != 3 {
+		t.Fatalf("[%v] Wrong number of purged items. Actual: %v, expected: %v", n, cnt, 3)
+	}
+
+	items, _ := d.GetTrashItems("gid1")
+	if len(items) != 1 || items[0].Name != "new" {
+		t.Fatalf("[%v] Only new item expected. Received: %v", n, items)
+	}
+	items, _ = d.GetTrashItems("gid2")
+	if len(items) != 1 {
+		t.Fatalf("[%v] Other guilds expected to be kept. Received: %v", n, items)
+	}
+}
@@ -0,0 +1,101 @@
+package conformance
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+func testTxCommit(t *testing.T, n string, d database.DataProvider) {
+	err := d.RunInTx(func(tx database.DataProvider) error {
+		if _, err := tx.AddRole(&database.Role{GuildId: "gid1", Id: "rid1", Permissions: 1}); err != nil {
+			return err
+		}
+
+		if rce, err := tx.AddRole(&database.Role{GuildId: "gid1", Id: "rid1", Permissions: 2}); err == nil {
+			t.Fatalf("[%v] Error expected. Received: %v", n, rce)
+		}
+
+		return tx.RunInTx(func(tx database.DataProvider) error {
+			_, err := tx.SetRolePermissions("gid1", "rid1", 3)
+			return err
+		})
+	})
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+
+	r, err := d.GetRole("gid1", "rid1")
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if r.Permissions != 3 {
+		t.Fatalf("[%v] Wrong role after commit. Actual: %v, expected: %v", n, r.Permissions, 3)
+	}
+}
+
+func testTxRollback(t *testing.T, n string, d database.DataProvider) {
+	if _, err := d.AddRole(&database.Role{GuildId: "gid1", Id: "rid1", Permissions: 1}); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+
+	expected := errors.New("failure")
+	err := d.RunInTx(func(tx database.DataProvider) error {
+		if _, err := tx.SetRolePermissions("gid1", "rid1", 2); err != nil {
+			return err
+		}
+		if _, err := tx.AddRole(&database.Role{GuildId: "gid1", Id: "rid2", Permissions: 1}); err != nil {
+			return err
+		}
+		if _, err := tx.AddCharacter(&database.Character{GuildId: "gid1", UserId: "uid1", Name: "ch"}); err != nil {
+			return err
+		}
+
+		return expected
+	})
+	if err != expected {
+		t.Fatalf("[%v] Original error expected. Received: %v", n, err)
+	}
+
+	r, err := d.GetRole("gid1", "rid1")
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if r.Permissions != 1 {
+		t.Fatalf("[%v] Change expected to be rolled back. Actual: %v, expected: %v", n, r.Permissions, 1)
+	}
+
+	if rce, err := d.GetRole("gid1", "rid2"); err == nil {
+		t.Fatalf("[%v] Role expected to be rolled back. Received: %v", n, rce)
+	}
+	if rcs, _ := d.GetCharacters("gid1", "uid1"); len(rcs) != 0 {
+		t.Fatalf("[%v] Character expected to be rolled back. Received: %v", n, rcs)
+	}
+}
+
+func testTxRollbackStatRename(t *testing.T, n string, d database.DataProvider) {
+	g, _ := d.AddGuild(&database.Guild{Name: "test1", DiscordId: "did1"})
+	d.AddGuildStat(g.GuildId, &database.Stat{ID: "lvl", Type: database.Number})
+	d.AddSubmission(&database.Submission{GuildId: "did1", UserId: "u1", Character: "c1", Stat: "lvl", Value: 6})
+	d.AddTrashItem(&database.TrashItem{GuildId: "did1", Type: database.TrashCharacter, Name: "c2",
+		Characters: []*database.Character{{GuildId: "did1", UserId: "u2", Name: "c2", Body: map[string]interface{}{"lvl": 3}}}})
+
+	expected := errors.New("failure")
+	err := d.RunInTx(func(tx database.DataProvider) error {
+		if _, err := tx.RenameGuildStat(g.GuildId, "lvl", "power"); err != nil {
+			return err
+		}
+		return expected
+	})
+	if err != expected {
+		t.Fatalf("[%v] Original error expected. Received: %v", n, err)
+	}
+
+	if subs, _ := d.GetSubmissions("did1"); len(subs) != 1 || subs[0].Stat != "lvl" {
+		t.Fatalf("[%v] Submissions expected to be rolled back. Received: %v", n, subs)
+	}
+	if items, _ := d.GetTrashItems("did1"); len(items) != 1 || items[0].Characters[0].Body["lvl"] != 3 {
+		t.Fatalf("[%v] Trash expected to be rolled back. Received: %v", n, items)
+	}
+}
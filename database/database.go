@@ -16,12 +16,47 @@ const (
 	_ = iota
 	Number
 	Str
+	Float
+	Bool
+	Enum
+	Date
+	Duration
+	// Computed stats are not stored on characters, their values are calculated from Formula
+	Computed
+)
+
+// who can set values of a stat
+const (
+	// character owner and anyone allowed to modify the owner
+	WriteSelf = iota
+	// officers with character permissions over owner's sub-guild
+	WriteOfficers
+	// users with guild-wide character permissions only
+	WriteGuild
 )
 
 type Stat struct {
 	ID          string
 	Type        int
 	Description string
+	// allowed values of Enum stats
+	Values []string
+	// expression over other stats of Computed stats
+	Formula string
+
+	// optional constraints checked when stat is set. nil Min/Max and zero MaxLength mean no limit
+	Min       *float64
+	Max       *float64
+	Pattern   string
+	MaxLength int
+	Required  bool
+	Write     int
+	// values set by members wait for officer approval
+	Review bool
+
+	// stats are grouped by Category and sorted by Order, then by name, when shown
+	Category string
+	Order    int
 }
 
 type Guild struct {
@@ -56,6 +91,136 @@ type Character struct {
 	StatVersion int
 }
 
+// operators of StatFilter
+const (
+	_ = iota
+	OpEq
+	OpNotEq
+	OpLess
+	OpLessEq
+	OpGreater
+	OpGreaterEq
+)
+
+// SortKey orders characters by value of stat. Characters without a value of type Type go last
+type SortKey struct {
+	Stat string
+	Type int
+	Asc  bool
+}
+
+// StatFilter keeps characters whose value of stat compares to Value with Op.
+// Value must have the go type of stat type Type, characters without such value don't match
+type StatFilter struct {
+	Stat  string
+	Type  int
+	Op    int
+	Value interface{}
+}
+
+func (f *StatFilter) Matches(c *Character) bool {
+	v, ok := c.Body[f.Stat]
+	if !ok {
+		return false
+	}
+	if ok, _ := IsStatType(f.Type, v); !ok {
+		return false
+	}
+
+	cmp := CompareStatValues(f.Type, v, f.Value)
+	switch f.Op {
+	case OpEq:
+		return cmp == 0
+	case OpNotEq:
+		return cmp != 0
+	case OpLess:
+		return cmp < 0
+	case OpLessEq:
+		return cmp <= 0
+	case OpGreater:
+		return cmp > 0
+	case OpGreaterEq:
+		return cmp >= 0
+	}
+	return false
+}
+
+// CharacterQuery selects characters of a guild. Empty fields are not checked, nil OwnerGuilds as well,
+// so owners have to be in one of listed sub-guilds only if the list is set. Characters are ordered by
+// keys of Order, then by name and owner. Limit 0 means no limit
+type CharacterQuery struct {
+	Order       []*SortKey
+	Filters     []*StatFilter
+	Main        *bool
+	OwnerGuilds []uuid.UUID
+	Limit       int
+}
+
+// Validate checks that stats of q can be queried. Values of computed stats are not stored
+func (q *CharacterQuery) Validate() error {
+	check := func(s string, t int) error {
+		if _, err := IsStatType(t, nil); err != nil || t == Computed {
+			return &Error{Code: UnknownStatType, Message: "Stat type for " + s + " can't be queried"}
+		}
+		return nil
+	}
+
+	for _, k := range q.Order {
+		if err := check(k.Stat, k.Type); err != nil {
+			return err
+		}
+	}
+	for _, f := range q.Filters {
+		if err := check(f.Stat, f.Type); err != nil {
+			return err
+		}
+		if ok, _ := IsStatType(f.Type, f.Value); !ok || f.Op < OpEq || f.Op > OpGreaterEq {
+			return &Error{Code: WrongUserInput, Message: "Invalid filter of stat " + f.Stat}
+		}
+	}
+
+	return nil
+}
+
+// CharacterRank is a position of a character in the order of a query. Rank of the first character is 1,
+// Above and Below are neighbours of the character if there are any
+type CharacterRank struct {
+	Character *Character
+	Rank      int
+	Total     int
+	Above     *Character
+	Below     *Character
+}
+
+// Less compares characters by keys of Order
+func (q *CharacterQuery) Less(a *Character, b *Character) bool {
+	for _, k := range q.Order {
+		av, aok := a.Body[k.Stat]
+		if aok {
+			aok, _ = IsStatType(k.Type, av)
+		}
+		bv, bok := b.Body[k.Stat]
+		if bok {
+			bok, _ = IsStatType(k.Type, bv)
+		}
+
+		if aok != bok {
+			return aok
+		}
+		if !aok {
+			continue
+		}
+		if c := CompareStatValues(k.Type, av, bv); c != 0 {
+			return (k.Asc && c < 0) || (!k.Asc && c > 0)
+		}
+	}
+
+	if a.Name != b.Name {
+		return a.Name < b.Name
+	}
+	return a.UserId < b.UserId
+}
+
 type Role struct {
 	GuildId     string
 	Id          string
@@ -69,6 +234,131 @@ type Money struct {
 	Price   int
 }
 
+// StatHistory is a value a character stat was set to at Time
+type StatHistory struct {
+	GuildId   string
+	UserId    string
+	Character string
+	Stat      string
+	Value     interface{}
+	Time      time.Time
+}
+
+// AuditEntry describes a single change made by a command. UserId is the user affected by the change
+type AuditEntry struct {
+	Id        int
+	GuildId   string
+	AuthorId  string
+	Time      time.Time
+	Command   string
+	Action    string
+	UserId    string
+	Character string
+	Stat      string
+	Target    string
+	Before    string
+	After     string
+}
+
+// AuditFilter selects audit entries. Empty fields are not checked, UserId matches both author and affected user.
+// Entries are returned newest first, Limit 0 means no limit
+type AuditFilter struct {
+	UserId    string
+	Character string
+	Stat      string
+	From      time.Time
+	To        time.Time
+	Offset    int
+	Limit     int
+}
+
+func (f *AuditFilter) Matches(e *AuditEntry) bool {
+	if f.UserId != "" && f.UserId != e.UserId && f.UserId != e.AuthorId {
+		return false
+	}
+	if f.Character != "" && f.Character != e.Character {
+		return false
+	}
+	if f.Stat != "" && f.Stat != e.Stat {
+		return false
+	}
+	if !f.From.IsZero() && e.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !e.Time.Before(f.To) {
+		return false
+	}
+
+	return true
+}
+
+const (
+	_ = iota
+	TrashCharacter
+	TrashGuild
+	TrashUser
+)
+
+// TrashItem keeps removed entities until they are restored or purged.
+// Guilds are ordered parents first, User keeps only the permission in the trash guild
+type TrashItem struct {
+	Id         int
+	GuildId    string
+	Type       int
+	Name       string
+	RemovedBy  string
+	Time       time.Time
+	Characters []*Character
+	Guilds     []*Guild
+	User       *User
+	// stat values of removed characters, oldest first
+	History []*StatHistory
+	// sub-guilds users were assigned to before removed sub-guilds were
+	Members map[string]uuid.UUID
+}
+
+// RenameStat returns a copy of the item with values and history of stat old moved to name.
+// Characters and history entries are copied, the item itself is not changed
+func (t *TrashItem) RenameStat(old string, name string) *TrashItem {
+	rv := *t
+	rv.Characters = make([]*Character, 0, len(t.Characters))
+	for _, c := range t.Characters {
+		tmp := *c
+		if v, ok := c.Body[old]; ok {
+			tmp.Body = make(map[string]interface{}, len(c.Body))
+			for k, v := range c.Body {
+				tmp.Body[k] = v
+			}
+			delete(tmp.Body, old)
+			tmp.Body[name] = v
+		}
+		rv.Characters = append(rv.Characters, &tmp)
+	}
+
+	rv.History = make([]*StatHistory, 0, len(t.History))
+	for _, h := range t.History {
+		tmp := *h
+		if tmp.Stat == old {
+			tmp.Stat = name
+		}
+		rv.History = append(rv.History, &tmp)
+	}
+
+	return &rv
+}
+
+// Submission is a stat value waiting for officer approval
+type Submission struct {
+	Id          int
+	GuildId     string
+	UserId      string
+	Character   string
+	Stat        string
+	Value       interface{}
+	SubmittedBy string
+	Time        time.Time
+}
+
 type DataProvider interface {
 	AddGuild(g *Guild) (*Guild, error)
 	GetGuild(g uuid.UUID) (*Guild, error)
@@ -83,6 +373,10 @@ type DataProvider interface {
 	AddGuildStat(g uuid.UUID, s *Stat) (*Guild, error)
 	SetDefaultGuildStat(g uuid.UUID, sn string) (*Guild, error)
 	RemoveGuildStat(g uuid.UUID, n string) (*Guild, error)
+	// RenameGuildStat renames the stat along with its values and history in all characters of the guild
+	RenameGuildStat(g uuid.UUID, old string, name string) (*Guild, error)
+	// ChangeGuildStatType replaces definition of existing stat. Character values are not converted
+	ChangeGuildStatType(g uuid.UUID, s *Stat) (*Guild, error)
 	RemoveAllGuildStats(g uuid.UUID) (*Guild, error)
 
 	AddUser(d string, g *GuildPermission) (*User, error)
@@ -96,7 +390,13 @@ type DataProvider interface {
 	AddCharacter(c *Character) (*Character, error)
 	GetCharacters(g string, u string) ([]*Character, error)
 	GetCharactersSorted(g string, s string, t int, asc bool, limit int) ([]*Character, error)
+	// QueryCharacters returns characters of guild g selected and ordered by q
+	QueryCharacters(g string, q *CharacterQuery) ([]*Character, error)
+	// RankCharacter finds position of character name of user u among characters selected and ordered by q.
+	// Main character is ranked if name is empty. Limit of q is ignored
+	RankCharacter(g string, u string, name string, q *CharacterQuery) (*CharacterRank, error)
 	GetCharactersOutdated(g string, v int) ([]*Character, error)
+	GetGuildCharacters(g string) ([]*Character, error)
 	GetCharactersByName(g string, n string) ([]*Character, error)
 	GetMainCharacter(g string, u string) (*Character, error)
 	GetCharacter(g string, u string, n string) (*Character, error)
@@ -108,6 +408,13 @@ type DataProvider interface {
 	RemoveCharacterStat(g string, u string, name string, s string) (*Character, error)
 	RemoveCharacter(g string, u string, name string) (*Character, error)
 
+	AddStatHistory(h *StatHistory) (*StatHistory, error)
+	// GetStatHistory returns values of the character stat, oldest first
+	GetStatHistory(g string, u string, name string, s string) ([]*StatHistory, error)
+	// GetGuildStatHistory returns values of the stat set since given time for all characters of the guild,
+	// along with the last value each character had before it. Values are ordered oldest first
+	GetGuildStatHistory(g string, s string, since time.Time) ([]*StatHistory, error)
+
 	AddRole(r *Role) (*Role, error)
 	GetRole(g string, r string) (*Role, error)
 	GetGuildRoles(g string) ([]*Role, error)
@@ -119,8 +426,24 @@ type DataProvider interface {
 	ChangeMoneyOwner(g string, u string) (*Money, error)
 	SetMoneyValid(g string, t time.Time) (*Money, error)
 
+	AddAuditEntry(e *AuditEntry) (*AuditEntry, error)
+	GetAuditEntries(g string, f *AuditFilter) ([]*AuditEntry, error)
+
+	AddTrashItem(t *TrashItem) (*TrashItem, error)
+	GetTrashItems(g string) ([]*TrashItem, error)
+	RemoveTrashItem(g string, id int) (*TrashItem, error)
+	// PurgeTrash removes items put to the trash before given time and returns their number
+	PurgeTrash(g string, before time.Time) (int, error)
+
+	AddSubmission(s *Submission) (*Submission, error)
+	GetSubmissions(g string) ([]*Submission, error)
+	RemoveSubmission(g string, id int) (*Submission, error)
+
 	Export() ([]byte, error)
 	Import(b []byte) error
+
+	// RunInTx calls f with a provider whose changes are rolled back if f returns an error
+	RunInTx(f func(tx DataProvider) error) error
 }
 
 type ErrorCode int
@@ -159,6 +482,8 @@ const (
 	MoneyAlreadyRegistered
 	MoneyNotFound
 	IOErrorDuringImport
+	TrashItemNotFound
+	SubmissionNotFound
 )
 
 const (
@@ -208,14 +533,29 @@ var permToString = map[int]string{
 }
 
 var stringToType = map[string]int{
-	"str": Str,
-	"num": Number,
-	"int": Number,
+	"str":      Str,
+	"num":      Number,
+	"int":      Number,
+	"float":    Float,
+	"real":     Float,
+	"bool":     Bool,
+	"enum":     Enum,
+	"date":     Date,
+	"duration": Duration,
+	"dur":      Duration,
+	"formula":  Computed,
+	"computed": Computed,
 }
 
 var typeToString = map[int]string{
-	Str:    "str",
-	Number: "int",
+	Str:      "str",
+	Number:   "int",
+	Float:    "float",
+	Bool:     "bool",
+	Enum:     "enum",
+	Date:     "date",
+	Duration: "duration",
+	Computed: "formula",
 }
 
 func StringToPermission(s string) (int, error) {
@@ -0,0 +1,221 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+type EventType int
+
+const (
+	_ = iota
+	GuildAdded
+	GuildRenamed
+	GuildMoved
+	GuildRemoved
+	GuildStatAdded
+	GuildDefaultStatSet
+	GuildStatRemoved
+	GuildStatsRemoved
+	GuildStatRenamed
+	GuildStatTypeChanged
+
+	UserAdded
+	UserPermissionsSet
+	UserSubGuildSet
+	UserRemoved
+	UserErased
+
+	CharacterAdded
+	CharacterRenamed
+	CharacterMainChanged
+	CharacterStatSet
+	CharacterStatVersionSet
+	CharacterOwnerChanged
+	CharacterStatRemoved
+	CharacterRemoved
+
+	RoleAdded
+	RolePermissionsSet
+	RoleRemoved
+
+	MoneyAdded
+	MoneyOwnerChanged
+	MoneyValidSet
+
+	Imported
+)
+
+var typeToString = map[EventType]string{
+	GuildAdded:           "guild added",
+	GuildRenamed:         "guild renamed",
+	GuildMoved:           "guild moved",
+	GuildRemoved:         "guild removed",
+	GuildStatAdded:       "stat added",
+	GuildDefaultStatSet:  "default stat set",
+	GuildStatRemoved:     "stat removed",
+	GuildStatsRemoved:    "stats reset",
+	GuildStatRenamed:     "stat renamed",
+	GuildStatTypeChanged: "stat type changed",
+
+	UserAdded:          "user added",
+	UserPermissionsSet: "user permissions set",
+	UserSubGuildSet:    "user assigned",
+	UserRemoved:        "user removed",
+	UserErased:         "user erased",
+
+	CharacterAdded:          "character added",
+	CharacterRenamed:        "character renamed",
+	CharacterMainChanged:    "main character changed",
+	CharacterStatSet:        "character stat set",
+	CharacterStatVersionSet: "character stats updated",
+	CharacterOwnerChanged:   "character owner changed",
+	CharacterStatRemoved:    "character stat removed",
+	CharacterRemoved:        "character removed",
+
+	RoleAdded:          "role added",
+	RolePermissionsSet: "role permissions set",
+	RoleRemoved:        "role removed",
+
+	MoneyAdded:        "payment registered",
+	MoneyOwnerChanged: "payment owner changed",
+	MoneyValidSet:     "subscription extended",
+
+	Imported: "database imported",
+}
+
+func (t EventType) String() string {
+	if rv, ok := typeToString[t]; ok {
+		return rv
+	}
+
+	return "unknown"
+}
+
+// Event describes a successful change. GuildId is the discord ID of the top level guild,
+// it is empty for changes not bound to a guild (e.g. import).
+// Only fields relevant for the change are set
+type Event struct {
+	Type    EventType
+	GuildId string
+	UserId  string
+	Time    time.Time
+
+	Guild     *database.Guild
+	User      *database.User
+	Character *database.Character
+	// character state before the change
+	Previous *database.Character
+	Role     *database.Role
+	Money    *database.Money
+	Stat     string
+	Value    interface{}
+}
+
+// Subscription delivers events through C in the order they happened.
+// Events are queued without limit, so slow readers never block data changes
+type Subscription struct {
+	C <-chan Event
+
+	c      chan Event
+	types  map[EventType]bool
+	queue  []Event
+	closed bool
+	done   chan bool
+	mux    sync.Mutex
+	cond   *sync.Cond
+}
+
+func newSubscription(buffer int, types []EventType) *Subscription {
+	s := &Subscription{
+		c:    make(chan Event, buffer),
+		done: make(chan bool),
+	}
+	s.C = s.c
+	s.cond = sync.NewCond(&s.mux)
+
+	if len(types) != 0 {
+		s.types = make(map[EventType]bool)
+		for _, t := range types {
+			s.types[t] = true
+		}
+	}
+
+	go s.run()
+	return s
+}
+
+func (s *Subscription) push(e Event) {
+	if s.types != nil && !s.types[e.Type] {
+		return
+	}
+
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.closed {
+		return
+	}
+
+	s.queue = append(s.queue, e)
+	s.cond.Signal()
+}
+
+func (s *Subscription) run() {
+	defer close(s.c)
+
+	for {
+		s.mux.Lock()
+		for len(s.queue) == 0 && !s.closed {
+			s.cond.Wait()
+		}
+		if s.closed {
+			s.mux.Unlock()
+			return
+		}
+
+		e := s.queue[0]
+		s.queue[0] = Event{}
+		s.queue = s.queue[1:]
+		s.mux.Unlock()
+
+		select {
+		case s.c <- e:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *Subscription) close() {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.closed {
+		return
+	}
+
+	s.closed = true
+	s.queue = nil
+	s.cond.Broadcast()
+	close(s.done)
+}
+
+// bus is shared between EventDB and its views passed to RunInTx callbacks
+type bus struct {
+	subs map[*Subscription]database.Void
+	// held while a change is applied and published, so events are ordered the same way as changes
+	mux    sync.Mutex
+	subMux sync.Mutex
+}
+
+func (b *bus) publish(evs ...Event) {
+	b.subMux.Lock()
+	defer b.subMux.Unlock()
+
+	for _, e := range evs {
+		for s := range b.subs {
+			s.push(e)
+		}
+	}
+}
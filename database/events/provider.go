@@ -0,0 +1,381 @@
+package events
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+// EventDB publishes an Event for every successful change made through it
+type EventDB struct {
+	database.DataProvider
+	*bus
+	// events of a running transaction, published on commit. nil outside of transactions
+	pending *[]Event
+}
+
+// constructor function
+func NewEventDb(prov database.DataProvider) *EventDB {
+	return &EventDB{
+		DataProvider: prov,
+		bus:          &bus{subs: make(map[*Subscription]database.Void)},
+	}
+}
+
+// Subscribe starts delivery of events of given types (all types if none are given).
+// buffer is the capacity of Subscription.C
+func (e *EventDB) Subscribe(buffer int, types ...EventType) *Subscription {
+	s := newSubscription(buffer, types)
+
+	e.subMux.Lock()
+	defer e.subMux.Unlock()
+	e.subs[s] = database.Member
+
+	return s
+}
+
+// Unsubscribe stops delivery and closes Subscription.C. Undelivered events are dropped
+func (e *EventDB) Unsubscribe(s *Subscription) {
+	e.subMux.Lock()
+	delete(e.subs, s)
+	e.subMux.Unlock()
+
+	s.close()
+}
+
+// RunInTx publishes events of f only if the transaction is committed
+func (e *EventDB) RunInTx(f func(tx database.DataProvider) error) error {
+	if e.pending != nil {
+		return f(e)
+	}
+
+	pending := make([]Event, 0, 10)
+	err := e.DataProvider.RunInTx(func(tx database.DataProvider) error {
+		return f(&EventDB{DataProvider: tx, bus: e.bus, pending: &pending})
+	})
+	if err != nil {
+		return err
+	}
+
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	e.publish(pending...)
+
+	return nil
+}
+
+func (e *EventDB) AddGuild(g *database.Guild) (*database.Guild, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.AddGuild(g)
+	return rv, e.guildEvent(GuildAdded, rv, err, "")
+}
+
+func (e *EventDB) RenameGuild(g uuid.UUID, name string) (*database.Guild, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.RenameGuild(g, name)
+	return rv, e.guildEvent(GuildRenamed, rv, err, "")
+}
+
+func (e *EventDB) MoveGuild(g uuid.UUID, parent uuid.UUID) (*database.Guild, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.MoveGuild(g, parent)
+	return rv, e.guildEvent(GuildMoved, rv, err, "")
+}
+
+func (e *EventDB) RemoveGuild(g uuid.UUID) (*database.Guild, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.RemoveGuild(g)
+	return rv, e.guildEvent(GuildRemoved, rv, err, "")
+}
+
+func (e *EventDB) RemoveGuildD(d string) (*database.Guild, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.RemoveGuildD(d)
+	return rv, e.guildEvent(GuildRemoved, rv, err, "")
+}
+
+func (e *EventDB) AddGuildStat(g uuid.UUID, s *database.Stat) (*database.Guild, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.AddGuildStat(g, s)
+	return rv, e.guildEvent(GuildStatAdded, rv, err, s.ID)
+}
+
+func (e *EventDB) SetDefaultGuildStat(g uuid.UUID, sn string) (*database.Guild, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.SetDefaultGuildStat(g, sn)
+	return rv, e.guildEvent(GuildDefaultStatSet, rv, err, sn)
+}
+
+func (e *EventDB) RemoveGuildStat(g uuid.UUID, n string) (*database.Guild, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.RemoveGuildStat(g, n)
+	return rv, e.guildEvent(GuildStatRemoved, rv, err, n)
+}
+
+func (e *EventDB) RemoveAllGuildStats(g uuid.UUID) (*database.Guild, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.RemoveAllGuildStats(g)
+	return rv, e.guildEvent(GuildStatsRemoved, rv, err, "")
+}
+
+func (e *EventDB) RenameGuildStat(g uuid.UUID, old string, name string) (*database.Guild, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.RenameGuildStat(g, old, name)
+	return rv, e.guildEvent(GuildStatRenamed, rv, err, name)
+}
+
+func (e *EventDB) ChangeGuildStatType(g uuid.UUID, s *database.Stat) (*database.Guild, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.ChangeGuildStatType(g, s)
+	return rv, e.guildEvent(GuildStatTypeChanged, rv, err, s.ID)
+}
+
+func (e *EventDB) AddUser(d string, g *database.GuildPermission) (*database.User, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.AddUser(d, g)
+	return rv, e.userEvent(UserAdded, rv, err, g.TopGuild)
+}
+
+func (e *EventDB) SetUserPermissions(u string, g *database.GuildPermission) (*database.User, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.SetUserPermissions(u, g)
+	return rv, e.userEvent(UserPermissionsSet, rv, err, g.TopGuild)
+}
+
+func (e *EventDB) SetUserSubGuild(u string, g *database.GuildPermission) (*database.User, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.SetUserSubGuild(u, g)
+	return rv, e.userEvent(UserSubGuildSet, rv, err, g.TopGuild)
+}
+
+func (e *EventDB) RemoveUserD(d string, g string) (*database.User, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.RemoveUserD(d, g)
+	return rv, e.userEvent(UserRemoved, rv, err, g)
+}
+
+func (e *EventDB) EraseUserD(d string) (*database.User, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.EraseUserD(d)
+	if err != nil {
+		return rv, err
+	}
+
+	for g := range rv.Guilds {
+		e.emit(Event{Type: UserErased, GuildId: g, UserId: rv.Id, User: rv})
+	}
+	return rv, nil
+}
+
+func (e *EventDB) AddCharacter(c *database.Character) (*database.Character, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.AddCharacter(c)
+	return rv, e.charEvent(CharacterAdded, nil, rv, err, "", nil)
+}
+
+func (e *EventDB) RenameCharacter(g string, u string, old string, name string) (*database.Character, error) {
+	defer e.lock()()
+	prev := e.character(g, u, old)
+	rv, err := e.DataProvider.RenameCharacter(g, u, old, name)
+	return rv, e.charEvent(CharacterRenamed, prev, rv, err, "", nil)
+}
+
+func (e *EventDB) ChangeMainCharacter(g string, u string, name string) (*database.Character, error) {
+	defer e.lock()()
+	prev := e.character(g, u, "")
+	rv, err := e.DataProvider.ChangeMainCharacter(g, u, name)
+	return rv, e.charEvent(CharacterMainChanged, prev, rv, err, "", nil)
+}
+
+func (e *EventDB) SetCharacterStat(g string, u string, name string, s string, v interface{}) (*database.Character, error) {
+	defer e.lock()()
+	prev := e.character(g, u, name)
+	rv, err := e.DataProvider.SetCharacterStat(g, u, name, s, v)
+	return rv, e.charEvent(CharacterStatSet, prev, rv, err, s, v)
+}
+
+func (e *EventDB) SetCharacterStatVersion(g string, u string, name string, stats map[string]*database.Stat, version int) (*database.Character, error) {
+	defer e.lock()()
+	prev := e.character(g, u, name)
+	rv, err := e.DataProvider.SetCharacterStatVersion(g, u, name, stats, version)
+	return rv, e.charEvent(CharacterStatVersionSet, prev, rv, err, "", version)
+}
+
+func (e *EventDB) ChangeCharacterOwner(g string, old string, name string, u string) (*database.Character, error) {
+	defer e.lock()()
+	prev := e.character(g, old, name)
+	rv, err := e.DataProvider.ChangeCharacterOwner(g, old, name, u)
+	return rv, e.charEvent(CharacterOwnerChanged, prev, rv, err, "", nil)
+}
+
+func (e *EventDB) RemoveCharacterStat(g string, u string, name string, s string) (*database.Character, error) {
+	defer e.lock()()
+	prev := e.character(g, u, name)
+	rv, err := e.DataProvider.RemoveCharacterStat(g, u, name, s)
+	return rv, e.charEvent(CharacterStatRemoved, prev, rv, err, s, nil)
+}
+
+func (e *EventDB) RemoveCharacter(g string, u string, name string) (*database.Character, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.RemoveCharacter(g, u, name)
+	return rv, e.charEvent(CharacterRemoved, copyCharacter(rv), nil, err, "", nil)
+}
+
+func (e *EventDB) AddRole(r *database.Role) (*database.Role, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.AddRole(r)
+	return rv, e.roleEvent(RoleAdded, rv, err)
+}
+
+func (e *EventDB) SetRolePermissions(g string, r string, p int) (*database.Role, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.SetRolePermissions(g, r, p)
+	return rv, e.roleEvent(RolePermissionsSet, rv, err)
+}
+
+func (e *EventDB) RemoveRole(g string, r string) (*database.Role, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.RemoveRole(g, r)
+	return rv, e.roleEvent(RoleRemoved, rv, err)
+}
+
+func (e *EventDB) AddMoney(m *database.Money) (*database.Money, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.AddMoney(m)
+	return rv, e.moneyEvent(MoneyAdded, rv, err)
+}
+
+func (e *EventDB) ChangeMoneyOwner(g string, u string) (*database.Money, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.ChangeMoneyOwner(g, u)
+	return rv, e.moneyEvent(MoneyOwnerChanged, rv, err)
+}
+
+func (e *EventDB) SetMoneyValid(g string, t time.Time) (*database.Money, error) {
+	defer e.lock()()
+	rv, err := e.DataProvider.SetMoneyValid(g, t)
+	return rv, e.moneyEvent(MoneyValidSet, rv, err)
+}
+
+func (e *EventDB) Import(b []byte) error {
+	defer e.lock()()
+	if err := e.DataProvider.Import(b); err != nil {
+		return err
+	}
+
+	e.emit(Event{Type: Imported})
+	return nil
+}
+
+// lock keeps changes and their events in the same order. Views passed to RunInTx
+// do not lock: their events are published on commit
+func (e *EventDB) lock() func() {
+	if e.pending != nil {
+		return func() {}
+	}
+
+	e.mux.Lock()
+	return e.mux.Unlock
+}
+
+func (e *EventDB) emit(ev Event) {
+	ev.Time = time.Now()
+	if e.pending != nil {
+		*e.pending = append(*e.pending, ev)
+		return
+	}
+
+	e.publish(ev)
+}
+
+func (e *EventDB) guildEvent(t EventType, g *database.Guild, err error, stat string) error {
+	if err != nil {
+		return err
+	}
+
+	d := g.DiscordId
+	if d == "" {
+		if top, err := e.DataProvider.GetGuild(g.TopLevelParentId); err == nil {
+			d = top.DiscordId
+		}
+	}
+
+	e.emit(Event{Type: t, GuildId: d, Guild: g, Stat: stat})
+	return nil
+}
+
+func (e *EventDB) userEvent(t EventType, u *database.User, err error, g string) error {
+	if err != nil {
+		return err
+	}
+
+	e.emit(Event{Type: t, GuildId: g, UserId: u.Id, User: u})
+	return nil
+}
+
+func (e *EventDB) charEvent(t EventType, prev *database.Character, c *database.Character, err error, stat string, v interface{}) error {
+	if err != nil {
+		return err
+	}
+
+	ev := Event{Type: t, Character: copyCharacter(c), Previous: prev, Stat: stat, Value: v}
+	if c != nil {
+		ev.GuildId, ev.UserId = c.GuildId, c.UserId
+	} else if prev != nil {
+		ev.GuildId, ev.UserId = prev.GuildId, prev.UserId
+	} else {
+		return nil
+	}
+
+	e.emit(ev)
+	return nil
+}
+
+// character returns a copy of the character state before a change
+func (e *EventDB) character(g string, u string, name string) *database.Character {
+	c, err := e.DataProvider.GetCharacter(g, u, name)
+	if err != nil {
+		return nil
+	}
+
+	return copyCharacter(c)
+}
+
+// providers may share stat maps between returned copies, so events keep their own
+func copyCharacter(c *database.Character) *database.Character {
+	if c == nil {
+		return nil
+	}
+
+	rv := *c
+	rv.Body = make(map[string]interface{}, len(c.Body))
+	for k, v := range c.Body {
+		rv.Body[k] = v
+	}
+
+	return &rv
+}
+
+func (e *EventDB) roleEvent(t EventType, r *database.Role, err error) error {
+	if err != nil {
+		return err
+	}
+
+	e.emit(Event{Type: t, GuildId: r.GuildId, Role: r})
+	return nil
+}
+
+func (e *EventDB) moneyEvent(t EventType, m *database.Money, err error) error {
+	if err != nil {
+		return err
+	}
+
+	e.emit(Event{Type: t, GuildId: m.GuildId, UserId: m.UserId, Money: m})
+	return nil
+}
+
+func init() {
+	var _ database.DataProvider = (*EventDB)(nil)
+}
@@ -0,0 +1,381 @@
+package journal
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+const frameHeaderLen = 8
+
+const (
+	beginOp    = "Begin"
+	commitOp   = "Commit"
+	rollbackOp = "Rollback"
+)
+
+type entry struct {
+	Seq uint64
+	// sequence number of the transaction start, 0 outside of transactions
+	Tx   uint64
+	Op   string
+	Args []interface{}
+	// position of the entry in the journal file, not stored
+	off int64
+}
+
+// JournalDB appends every mutating call to a log before passing it to the wrapped provider
+type JournalDB struct {
+	database.DataProvider
+	*journalFile
+	tx uint64
+}
+
+// shared between JournalDB and its views passed to RunInTx callbacks
+type journalFile struct {
+	f    *os.File
+	path string
+	size int64
+	seq  uint64
+	mux  sync.Mutex
+	// held by transactions from begin to end, so their entries are never interleaved with other writes
+	// and replaying them at commit keeps the original order. Writes outside of transactions and
+	// checkpoints wait for running transactions
+	txMux sync.Mutex
+}
+
+// constructor function. Broken tail of the journal (e.g. after a crash mid-write) is cut off
+func NewJournalDb(prov database.DataProvider, path string) (*JournalDB, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	j := &JournalDB{DataProvider: prov, journalFile: &journalFile{f: f, path: path}}
+	entries, size, err := j.read()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if err = j.truncate(size); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if len(entries) != 0 {
+		j.seq = entries[len(entries)-1].Seq
+	}
+
+	return j, nil
+}
+
+func (j *JournalDB) Close() error {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	return j.f.Close()
+}
+
+func (j *JournalDB) Checkpoint(save func(b []byte, seq uint64) error) error {
+	j.txMux.Lock()
+	defer j.txMux.Unlock()
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	b, err := j.DataProvider.Export()
+	if err != nil {
+		return err
+	}
+
+	return save(b, j.seq)
+}
+
+// Compact drops entries covered by a snapshot taken at seq. There are no running transactions at checkpoints,
+// so entries after seq never belong to a transaction started before it
+func (j *JournalDB) Compact(seq uint64) error {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	entries, size, err := j.read()
+	if err != nil {
+		return err
+	}
+
+	from := size
+	for i := len(entries) - 1; i >= 0 && entries[i].Seq > seq; i-- {
+		from = entries[i].off
+	}
+	if from == 0 {
+		return nil
+	}
+	if from == size {
+		return j.truncate(0)
+	}
+
+	// the rest of the journal is copied to a new file, so a crash leaves either the old or the new journal
+	tmp, err := os.OpenFile(j.path+".tmp", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = io.Copy(tmp, io.NewSectionReader(j.f, from, size-from)); err == nil {
+		err = tmp.Sync()
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), j.path)
+	}
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	// the renamed file is opened again in append mode
+	tmp.Close()
+	f, err := os.OpenFile(j.path, os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	j.f.Close()
+	j.f = f
+	j.size = size - from
+
+	return nil
+}
+
+func (j *JournalDB) Replay(b []byte, seq uint64) error {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	entries, _, err := j.read()
+	if err != nil {
+		return err
+	}
+
+	// entries the snapshot doesn't cover were compacted away. Replaying the rest would silently lose changes
+	if len(entries) != 0 && entries[0].Seq > seq+1 {
+		return &database.Error{Code: database.IOErrorDuringImport,
+			Message: fmt.Sprintf("Journal starts at entry %v, but snapshot covers entries up to %v only", entries[0].Seq, seq)}
+	}
+
+	if b != nil {
+		if err := j.DataProvider.Import(b); err != nil {
+			return err
+		}
+	}
+
+	// transactions are applied on commit. Unfinished ones are dropped
+	pending := make(map[uint64][]*entry)
+	for _, e := range entries {
+		if e.Seq <= seq {
+			continue
+		}
+
+		switch {
+		case e.Op == beginOp:
+			pending[e.Seq] = make([]*entry, 0, 10)
+		case e.Op == commitOp:
+			if err = replay(j.DataProvider, pending[e.Tx]); err != nil {
+				return err
+			}
+			delete(pending, e.Tx)
+		case e.Op == rollbackOp:
+			delete(pending, e.Tx)
+		case e.Tx != 0:
+			pending[e.Tx] = append(pending[e.Tx], e)
+		default:
+			if err = replay(j.DataProvider, []*entry{e}); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(entries) != 0 && entries[len(entries)-1].Seq > seq {
+		seq = entries[len(entries)-1].Seq
+	}
+	if seq > j.seq {
+		j.seq = seq
+	}
+
+	return nil
+}
+
+// RunInTx journals calls made by f as a transaction. Nested calls join the outer transaction.
+// Calls made by f must go through the provider passed to it, other writes wait for the transaction
+func (j *JournalDB) RunInTx(f func(tx database.DataProvider) error) error {
+	if j.tx != 0 {
+		return f(j)
+	}
+
+	j.txMux.Lock()
+	defer j.txMux.Unlock()
+
+	begin, err := j.mark(beginOp, 0)
+	if err != nil {
+		return err
+	}
+
+	err = j.DataProvider.RunInTx(func(tx database.DataProvider) error {
+		return f(&JournalDB{DataProvider: tx, journalFile: j.journalFile, tx: begin})
+	})
+
+	end := commitOp
+	if err != nil {
+		end = rollbackOp
+	}
+	if _, merr := j.mark(end, begin); merr != nil && err == nil {
+		return merr
+	}
+
+	return err
+}
+
+func (j *JournalDB) apply(op string, args ...interface{}) (interface{}, error) {
+	if j.tx == 0 {
+		j.txMux.Lock()
+		defer j.txMux.Unlock()
+	}
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	if _, err := j.write(&entry{Tx: j.tx, Op: op, Args: args}); err != nil {
+		return nil, err
+	}
+
+	return ops[op](j.DataProvider, args)
+}
+
+func (j *JournalDB) mark(op string, tx uint64) (uint64, error) {
+	j.mux.Lock()
+	defer j.mux.Unlock()
+
+	return j.write(&entry{Tx: tx, Op: op})
+}
+
+func (j *JournalDB) write(e *entry) (uint64, error) {
+	e.Seq = j.seq + 1
+	if err := j.append(e); err != nil {
+		return 0, &database.Error{Code: database.ExternalError, Message: "Could not write journal: " + err.Error()}
+	}
+	j.seq = e.Seq
+
+	return e.Seq, nil
+}
+
+func (j *JournalDB) append(e *entry) error {
+	var buf bytes.Buffer
+	buf.Write(make([]byte, frameHeaderLen))
+	if err := gob.NewEncoder(&buf).Encode(e); err != nil {
+		return err
+	}
+
+	b := buf.Bytes()
+	binary.BigEndian.PutUint32(b[0:4], uint32(len(b)-frameHeaderLen))
+	binary.BigEndian.PutUint32(b[4:8], crc32.ChecksumIEEE(b[frameHeaderLen:]))
+
+	if _, err := j.f.Write(b); err != nil {
+		j.truncate(j.size)
+		return err
+	}
+	if err := j.f.Sync(); err != nil {
+		j.truncate(j.size)
+		return err
+	}
+
+	j.size += int64(len(b))
+	return nil
+}
+
+// read returns entries from the journal and the size of its valid part
+func (j *JournalDB) read() ([]*entry, int64, error) {
+	info, err := j.f.Stat()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	r := io.NewSectionReader(j.f, 0, info.Size())
+	rv := make([]*entry, 0, 10)
+	var size int64
+	header := make([]byte, frameHeaderLen)
+	for {
+		if _, err = io.ReadFull(r, header); err != nil {
+			break
+		}
+
+		n := int64(binary.BigEndian.Uint32(header[0:4]))
+		if n > info.Size()-size-frameHeaderLen {
+			break
+		}
+
+		body := make([]byte, n)
+		if _, err = io.ReadFull(r, body); err != nil {
+			break
+		}
+		if crc32.ChecksumIEEE(body) != binary.BigEndian.Uint32(header[4:8]) {
+			break
+		}
+
+		var e entry
+		if err = gob.NewDecoder(bytes.NewReader(body)).Decode(&e); err != nil {
+			break
+		}
+
+		e.off = size
+		rv = append(rv, &e)
+		size += int64(frameHeaderLen + len(body))
+	}
+
+	return rv, size, nil
+}
+
+func replay(p database.DataProvider, entries []*entry) error {
+	for _, e := range entries {
+		op, ok := ops[e.Op]
+		if !ok {
+			return &database.Error{Code: database.IOErrorDuringImport, Message: "Unknown journal operation: " + e.Op}
+		}
+
+		// failed calls are journaled as well. They fail the same way on replay
+		op(p, e.Args)
+	}
+
+	return nil
+}
+
+func (j *JournalDB) truncate(size int64) error {
+	if err := j.f.Truncate(size); err != nil {
+		return err
+	}
+	j.size = size
+
+	return j.f.Sync()
+}
+
+func init() {
+	gob.Register(&database.Guild{})
+	gob.Register(&database.Stat{})
+	gob.Register(&database.GuildPermission{})
+	gob.Register(&database.Character{})
+	gob.Register(&database.StatHistory{})
+	gob.Register(&database.Role{})
+	gob.Register(&database.Money{})
+	gob.Register(&database.AuditEntry{})
+	gob.Register(&database.TrashItem{})
+	gob.Register(&database.Submission{})
+	gob.Register(map[string]*database.Stat{})
+	gob.Register(uuid.UUID{})
+	gob.Register(time.Time{})
+
+	var _ database.DataProvider = (*JournalDB)(nil)
+}
@@ -0,0 +1,376 @@
+package journal
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+var ops = map[string]func(p database.DataProvider, a []interface{}) (interface{}, error){
+	"AddGuild": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.AddGuild(a[0].(*database.Guild))
+	},
+	"RenameGuild": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.RenameGuild(a[0].(uuid.UUID), a[1].(string))
+	},
+	"MoveGuild": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.MoveGuild(a[0].(uuid.UUID), a[1].(uuid.UUID))
+	},
+	"RemoveGuild": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.RemoveGuild(a[0].(uuid.UUID))
+	},
+	"RemoveGuildD": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.RemoveGuildD(a[0].(string))
+	},
+	"AddGuildStat": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.AddGuildStat(a[0].(uuid.UUID), a[1].(*database.Stat))
+	},
+	"SetDefaultGuildStat": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.SetDefaultGuildStat(a[0].(uuid.UUID), a[1].(string))
+	},
+	"RemoveGuildStat": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.RemoveGuildStat(a[0].(uuid.UUID), a[1].(string))
+	},
+	"RemoveAllGuildStats": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.RemoveAllGuildStats(a[0].(uuid.UUID))
+	},
+	"RenameGuildStat": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.RenameGuildStat(a[0].(uuid.UUID), a[1].(string), a[2].(string))
+	},
+	"ChangeGuildStatType": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.ChangeGuildStatType(a[0].(uuid.UUID), a[1].(*database.Stat))
+	},
+
+	"AddUser": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.AddUser(a[0].(string), a[1].(*database.GuildPermission))
+	},
+	"SetUserPermissions": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.SetUserPermissions(a[0].(string), a[1].(*database.GuildPermission))
+	},
+	"SetUserSubGuild": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.SetUserSubGuild(a[0].(string), a[1].(*database.GuildPermission))
+	},
+	"RemoveUserD": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.RemoveUserD(a[0].(string), a[1].(string))
+	},
+	"EraseUserD": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.EraseUserD(a[0].(string))
+	},
+
+	"AddCharacter": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.AddCharacter(a[0].(*database.Character))
+	},
+	"RenameCharacter": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.RenameCharacter(a[0].(string), a[1].(string), a[2].(string), a[3].(string))
+	},
+	"ChangeMainCharacter": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.ChangeMainCharacter(a[0].(string), a[1].(string), a[2].(string))
+	},
+	"SetCharacterStat": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.SetCharacterStat(a[0].(string), a[1].(string), a[2].(string), a[3].(string), a[4])
+	},
+	"SetCharacterStatVersion": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.SetCharacterStatVersion(a[0].(string), a[1].(string), a[2].(string), a[3].(map[string]*database.Stat), a[4].(int))
+	},
+	"ChangeCharacterOwner": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.ChangeCharacterOwner(a[0].(string), a[1].(string), a[2].(string), a[3].(string))
+	},
+	"RemoveCharacterStat": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.RemoveCharacterStat(a[0].(string), a[1].(string), a[2].(string), a[3].(string))
+	},
+	"RemoveCharacter": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.RemoveCharacter(a[0].(string), a[1].(string), a[2].(string))
+	},
+	"AddStatHistory": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.AddStatHistory(a[0].(*database.StatHistory))
+	},
+
+	"AddRole": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.AddRole(a[0].(*database.Role))
+	},
+	"SetRolePermissions": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.SetRolePermissions(a[0].(string), a[1].(string), a[2].(int))
+	},
+	"RemoveRole": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.RemoveRole(a[0].(string), a[1].(string))
+	},
+
+	"AddMoney": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.AddMoney(a[0].(*database.Money))
+	},
+	"ChangeMoneyOwner": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.ChangeMoneyOwner(a[0].(string), a[1].(string))
+	},
+	"SetMoneyValid": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.SetMoneyValid(a[0].(string), a[1].(time.Time))
+	},
+
+	"AddAuditEntry": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.AddAuditEntry(a[0].(*database.AuditEntry))
+	},
+
+	"AddTrashItem": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.AddTrashItem(a[0].(*database.TrashItem))
+	},
+	"RemoveTrashItem": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.RemoveTrashItem(a[0].(string), a[1].(int))
+	},
+	"PurgeTrash": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.PurgeTrash(a[0].(string), a[1].(time.Time))
+	},
+
+	"AddSubmission": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.AddSubmission(a[0].(*database.Submission))
+	},
+	"RemoveSubmission": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return p.RemoveSubmission(a[0].(string), a[1].(int))
+	},
+
+	"Import": func(p database.DataProvider, a []interface{}) (interface{}, error) {
+		return nil, p.Import(a[0].([]byte))
+	},
+}
+
+func (j *JournalDB) AddGuild(g *database.Guild) (*database.Guild, error) {
+	// IDs are generated here, so replay produces the same guild
+	newG := *g
+	if newG.GuildId == uuid.Nil {
+		newG.GuildId = uuid.New()
+	}
+
+	rv, err := j.apply("AddGuild", &newG)
+	return guild(rv), err
+}
+
+func (j *JournalDB) RenameGuild(g uuid.UUID, name string) (*database.Guild, error) {
+	rv, err := j.apply("RenameGuild", g, name)
+	return guild(rv), err
+}
+
+func (j *JournalDB) MoveGuild(g uuid.UUID, parent uuid.UUID) (*database.Guild, error) {
+	rv, err := j.apply("MoveGuild", g, parent)
+	return guild(rv), err
+}
+
+func (j *JournalDB) RemoveGuild(g uuid.UUID) (*database.Guild, error) {
+	rv, err := j.apply("RemoveGuild", g)
+	return guild(rv), err
+}
+
+func (j *JournalDB) RemoveGuildD(d string) (*database.Guild, error) {
+	rv, err := j.apply("RemoveGuildD", d)
+	return guild(rv), err
+}
+
+func (j *JournalDB) AddGuildStat(g uuid.UUID, s *database.Stat) (*database.Guild, error) {
+	rv, err := j.apply("AddGuildStat", g, s)
+	return guild(rv), err
+}
+
+func (j *JournalDB) SetDefaultGuildStat(g uuid.UUID, sn string) (*database.Guild, error) {
+	rv, err := j.apply("SetDefaultGuildStat", g, sn)
+	return guild(rv), err
+}
+
+func (j *JournalDB) RemoveGuildStat(g uuid.UUID, n string) (*database.Guild, error) {
+	rv, err := j.apply("RemoveGuildStat", g, n)
+	return guild(rv), err
+}
+
+func (j *JournalDB) RemoveAllGuildStats(g uuid.UUID) (*database.Guild, error) {
+	rv, err := j.apply("RemoveAllGuildStats", g)
+	return guild(rv), err
+}
+
+func (j *JournalDB) RenameGuildStat(g uuid.UUID, old string, name string) (*database.Guild, error) {
+	rv, err := j.apply("RenameGuildStat", g, old, name)
+	return guild(rv), err
+}
+
+func (j *JournalDB) ChangeGuildStatType(g uuid.UUID, s *database.Stat) (*database.Guild, error) {
+	rv, err := j.apply("ChangeGuildStatType", g, s)
+	return guild(rv), err
+}
+
+func (j *JournalDB) AddUser(d string, g *database.GuildPermission) (*database.User, error) {
+	rv, err := j.apply("AddUser", d, g)
+	return user(rv), err
+}
+
+func (j *JournalDB) SetUserPermissions(u string, g *database.GuildPermission) (*database.User, error) {
+	rv, err := j.apply("SetUserPermissions", u, g)
+	return user(rv), err
+}
+
+func (j *JournalDB) SetUserSubGuild(u string, g *database.GuildPermission) (*database.User, error) {
+	rv, err := j.apply("SetUserSubGuild", u, g)
+	return user(rv), err
+}
+
+func (j *JournalDB) RemoveUserD(d string, g string) (*database.User, error) {
+	rv, err := j.apply("RemoveUserD", d, g)
+	return user(rv), err
+}
+
+func (j *JournalDB) EraseUserD(d string) (*database.User, error) {
+	rv, err := j.apply("EraseUserD", d)
+	return user(rv), err
+}
+
+func (j *JournalDB) AddCharacter(c *database.Character) (*database.Character, error) {
+	rv, err := j.apply("AddCharacter", c)
+	return character(rv), err
+}
+
+func (j *JournalDB) RenameCharacter(g string, u string, old string, name string) (*database.Character, error) {
+	rv, err := j.apply("RenameCharacter", g, u, old, name)
+	return character(rv), err
+}
+
+func (j *JournalDB) ChangeMainCharacter(g string, u string, name string) (*database.Character, error) {
+	rv, err := j.apply("ChangeMainCharacter", g, u, name)
+	return character(rv), err
+}
+
+func (j *JournalDB) SetCharacterStat(g string, u string, name string, s string, v interface{}) (*database.Character, error) {
+	rv, err := j.apply("SetCharacterStat", g, u, name, s, v)
+	return character(rv), err
+}
+
+func (j *JournalDB) SetCharacterStatVersion(g string, u string, name string, stats map[string]*database.Stat, version int) (*database.Character, error) {
+	rv, err := j.apply("SetCharacterStatVersion", g, u, name, stats, version)
+	return character(rv), err
+}
+
+func (j *JournalDB) ChangeCharacterOwner(g string, old string, name string, u string) (*database.Character, error) {
+	rv, err := j.apply("ChangeCharacterOwner", g, old, name, u)
+	return character(rv), err
+}
+
+func (j *JournalDB) RemoveCharacterStat(g string, u string, name string, s string) (*database.Character, error) {
+	rv, err := j.apply("RemoveCharacterStat", g, u, name, s)
+	return character(rv), err
+}
+
+func (j *JournalDB) RemoveCharacter(g string, u string, name string) (*database.Character, error) {
+	rv, err := j.apply("RemoveCharacter", g, u, name)
+	return character(rv), err
+}
+
+func (j *JournalDB) AddStatHistory(h *database.StatHistory) (*database.StatHistory, error) {
+	rv, err := j.apply("AddStatHistory", h)
+	return statHistory(rv), err
+}
+
+func (j *JournalDB) AddRole(r *database.Role) (*database.Role, error) {
+	rv, err := j.apply("AddRole", r)
+	return role(rv), err
+}
+
+func (j *JournalDB) SetRolePermissions(g string, r string, p int) (*database.Role, error) {
+	rv, err := j.apply("SetRolePermissions", g, r, p)
+	return role(rv), err
+}
+
+func (j *JournalDB) RemoveRole(g string, r string) (*database.Role, error) {
+	rv, err := j.apply("RemoveRole", g, r)
+	return role(rv), err
+}
+
+func (j *JournalDB) AddMoney(m *database.Money) (*database.Money, error) {
+	rv, err := j.apply("AddMoney", m)
+	return money(rv), err
+}
+
+func (j *JournalDB) ChangeMoneyOwner(g string, u string) (*database.Money, error) {
+	rv, err := j.apply("ChangeMoneyOwner", g, u)
+	return money(rv), err
+}
+
+func (j *JournalDB) SetMoneyValid(g string, t time.Time) (*database.Money, error) {
+	rv, err := j.apply("SetMoneyValid", g, t)
+	return money(rv), err
+}
+
+func (j *JournalDB) AddAuditEntry(e *database.AuditEntry) (*database.AuditEntry, error) {
+	rv, err := j.apply("AddAuditEntry", e)
+	return auditEntry(rv), err
+}
+
+func (j *JournalDB) AddTrashItem(t *database.TrashItem) (*database.TrashItem, error) {
+	rv, err := j.apply("AddTrashItem", t)
+	return trashItem(rv), err
+}
+
+func (j *JournalDB) RemoveTrashItem(g string, id int) (*database.TrashItem, error) {
+	rv, err := j.apply("RemoveTrashItem", g, id)
+	return trashItem(rv), err
+}
+
+func (j *JournalDB) PurgeTrash(g string, before time.Time) (int, error) {
+	rv, err := j.apply("PurgeTrash", g, before)
+	n, _ := rv.(int)
+	return n, err
+}
+
+func (j *JournalDB) AddSubmission(s *database.Submission) (*database.Submission, error) {
+	rv, err := j.apply("AddSubmission", s)
+	return submission(rv), err
+}
+
+func (j *JournalDB) RemoveSubmission(g string, id int) (*database.Submission, error) {
+	rv, err := j.apply("RemoveSubmission", g, id)
+	return submission(rv), err
+}
+
+func (j *JournalDB) Import(b []byte) error {
+	_, err := j.apply("Import", b)
+	return err
+}
+
+func guild(v interface{}) *database.Guild {
+	rv, _ := v.(*database.Guild)
+	return rv
+}
+
+func user(v interface{}) *database.User {
+	rv, _ := v.(*database.User)
+	return rv
+}
+
+func character(v interface{}) *database.Character {
+	rv, _ := v.(*database.Character)
+	return rv
+}
+
+func statHistory(v interface{}) *database.StatHistory {
+	rv, _ := v.(*database.StatHistory)
+	return rv
+}
+
+func role(v interface{}) *database.Role {
+	rv, _ := v.(*database.Role)
+	return rv
+}
+
+func money(v interface{}) *database.Money {
+	rv, _ := v.(*database.Money)
+	return rv
+}
+
+func auditEntry(v interface{}) *database.AuditEntry {
+	rv, _ := v.(*database.AuditEntry)
+	return rv
+}
+
+func trashItem(v interface{}) *database.TrashItem {
+	rv, _ := v.(*database.TrashItem)
+	return rv
+}
+
+func submission(v interface{}) *database.Submission {
+	rv, _ := v.(*database.Submission)
+	return rv
+}
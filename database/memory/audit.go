@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+type AuditMemoryDb struct {
+	Audit       map[string][]*database.AuditEntry
+	LastAuditId int
+	mux         sync.Mutex
+}
+
+func (adb *AuditMemoryDb) AddAuditEntry(e *database.AuditEntry) (*database.AuditEntry, error) {
+	adb.mux.Lock()
+	defer adb.mux.Unlock()
+
+	adb.LastAuditId += 1
+	newE := *e
+	newE.Id = adb.LastAuditId
+	adb.Audit[e.GuildId] = append(adb.Audit[e.GuildId], &newE)
+
+	tmp := newE
+	return &tmp, nil
+}
+
+func (adb *AuditMemoryDb) GetAuditEntries(g string, f *database.AuditFilter) ([]*database.AuditEntry, error) {
+	adb.mux.Lock()
+	defer adb.mux.Unlock()
+
+	entries := adb.Audit[g]
+	rv := make([]*database.AuditEntry, 0, 10)
+	skip := f.Offset
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !f.Matches(entries[i]) {
+			continue
+		}
+		if skip > 0 {
+			skip -= 1
+			continue
+		}
+
+		tmp := *entries[i]
+		rv = append(rv, &tmp)
+		if f.Limit > 0 && len(rv) == f.Limit {
+			break
+		}
+	}
+
+	return rv, nil
+}
@@ -10,7 +10,19 @@ import (
 
 type CharMemoryDb struct {
 	Chars map[string]*database.Character
-	mux   sync.Mutex
+	// stat values by character ID. Entries keep time and value only, the rest is taken from the character
+	History map[string][]*database.StatHistory
+	mux     sync.Mutex
+
+	// indexes are not exported, so they are rebuilt after import
+	byGuild map[string]map[string]*database.Character
+	byUser  map[charKey]map[string]*database.Character
+	byName  map[charKey]map[string]*database.Character
+}
+
+type charKey struct {
+	guild string
+	key   string
 }
 
 func (cdb *CharMemoryDb) AddCharacter(c *database.Character) (*database.Character, error) {
@@ -22,100 +34,87 @@ func (cdb *CharMemoryDb) AddCharacter(c *database.Character) (*database.Characte
 		return nil, &database.Error{Code: database.CharacterNameTaken, Message: fmt.Sprintf("User already has character with name %v", c.Name)}
 	}
 
-	newC := *c
-	c = &newC
+	c = copyCharacter(c)
 	cdb.Chars[id] = c
+	cdb.index(id, c)
 
-	tmp := *c
-	return &tmp, nil
+	return copyCharacter(c), nil
 }
 
 func (cdb *CharMemoryDb) GetCharacters(g string, u string) ([]*database.Character, error) {
-	rv := make([]*database.Character, 0, 10)
-	for _, v := range cdb.Chars {
-		if v.UserId == u && v.GuildId == g {
-			tmp := *v
-			rv = append(rv, &tmp)
-		}
+	cdb.mux.Lock()
+	defer cdb.mux.Unlock()
+
+	chars := cdb.byUser[charKey{guild: g, key: u}]
+	rv := make([]*database.Character, 0, len(chars))
+	for _, v := range chars {
+		rv = append(rv, copyCharacter(v))
 	}
 
 	return rv, nil
 }
 
 func (cdb *CharMemoryDb) GetCharactersSorted(g string, s string, t int, asc bool, limit int) ([]*database.Character, error) {
-	rv := make([]*database.Character, 0, 600)
-	for _, v := range cdb.Chars {
-		if v.GuildId == g {
-			tmp := *v
-			rv = append(rv, &tmp)
-		}
+	cdb.mux.Lock()
+	chars := cdb.byGuild[g]
+	rv := make([]*database.Character, 0, len(chars))
+	for _, v := range chars {
+		rv = append(rv, copyCharacter(v))
 	}
+	cdb.mux.Unlock()
 
-	var f func(i int, j int) bool
-	switch t {
-	case database.Number:
-		f = func(i int, j int) bool {
-			av, ok := rv[i].Body[s]
-			if !ok {
-				return false
-			}
-			bv, ok := rv[j].Body[s]
-			if !ok {
-				return true
-			}
-
-			ai, ok := av.(int)
-			if !ok {
-				return false
-			}
-
-			bi, ok := bv.(int)
-			if !ok {
-				return true
-			}
+	if _, err := database.IsStatType(t, nil); err != nil {
+		return nil, &database.Error{Code: database.UnknownStatType, Message: "Stat type for " + s + " is not defined"}
+	}
 
-			return (asc && ai < bi) || (!asc && ai > bi)
+	f := func(i int, j int) bool {
+		av, ok := rv[i].Body[s]
+		if !ok {
+			return false
+		}
+		bv, ok := rv[j].Body[s]
+		if !ok {
+			return true
 		}
-	case database.Str:
-		f = func(i int, j int) bool {
-			av, ok := rv[i].Body[s]
-			if !ok {
-				return false
-			}
-			bv, ok := rv[j].Body[s]
-			if !ok {
-				return true
-			}
-
-			ai, ok := av.(string)
-			if !ok {
-				return false
-			}
-
-			bi, ok := bv.(string)
-			if !ok {
-				return true
-			}
 
-			return (asc && ai < bi) || (!asc && ai > bi)
+		if ok, _ := database.IsStatType(t, av); !ok {
+			return false
 		}
-	default:
-		return nil, &database.Error{Code: database.UnknownStatType, Message: "Stat type for " + s + " is not defined"}
+		if ok, _ := database.IsStatType(t, bv); !ok {
+			return true
+		}
+
+		c := database.CompareStatValues(t, av, bv)
+		return (asc && c < 0) || (!asc && c > 0)
 	}
 
 	sort.Slice(rv, f)
-	if limit > 0 {
+	if limit > 0 && limit < len(rv) {
 		rv = rv[:limit]
 	}
 	return rv, nil
 }
 
+func (cdb *CharMemoryDb) GetGuildCharacters(g string) ([]*database.Character, error) {
+	cdb.mux.Lock()
+	defer cdb.mux.Unlock()
+
+	rv := make([]*database.Character, 0, len(cdb.byGuild[g]))
+	for _, c := range cdb.byGuild[g] {
+		rv = append(rv, copyCharacter(c))
+	}
+
+	return rv, nil
+}
+
 func (cdb *CharMemoryDb) GetCharactersOutdated(g string, v int) ([]*database.Character, error) {
-	rv := make([]*database.Character, 0, 600)
-	for _, c := range cdb.Chars {
-		if c.GuildId == g && c.StatVersion < v {
-			tmp := *c
-			rv = append(rv, &tmp)
+	cdb.mux.Lock()
+	defer cdb.mux.Unlock()
+
+	rv := make([]*database.Character, 0, 10)
+	for _, c := range cdb.byGuild[g] {
+		if c.StatVersion < v {
+			rv = append(rv, copyCharacter(c))
 		}
 	}
 
@@ -123,35 +122,40 @@ func (cdb *CharMemoryDb) GetCharactersOutdated(g string, v int) ([]*database.Cha
 }
 
 func (cdb *CharMemoryDb) GetCharactersByName(g string, n string) ([]*database.Character, error) {
-	rv := make([]*database.Character, 0, 600)
-	for _, c := range cdb.Chars {
-		if c.GuildId == g && c.Name == n {
-			tmp := *c
-			rv = append(rv, &tmp)
-		}
+	cdb.mux.Lock()
+	defer cdb.mux.Unlock()
+
+	chars := cdb.byName[charKey{guild: g, key: n}]
+	rv := make([]*database.Character, 0, len(chars))
+	for _, c := range chars {
+		rv = append(rv, copyCharacter(c))
 	}
 
 	return rv, nil
 }
 
 func (cdb *CharMemoryDb) GetMainCharacter(g string, u string) (*database.Character, error) {
+	cdb.mux.Lock()
+	defer cdb.mux.Unlock()
+
 	rv, err := cdb.getMainCharacter(g, u)
 	if err != nil {
 		return nil, err
 	}
 
-	tmp := *rv
-	return &tmp, nil
+	return copyCharacter(rv), nil
 }
 
 func (cdb *CharMemoryDb) GetCharacter(g string, u string, name string) (*database.Character, error) {
+	cdb.mux.Lock()
+	defer cdb.mux.Unlock()
+
 	rv, err := cdb.getCharacter(g, u, name)
 	if err != nil {
 		return nil, err
 	}
 
-	tmp := *rv
-	return &tmp, nil
+	return copyCharacter(rv), nil
 }
 
 func (cdb *CharMemoryDb) RenameCharacter(g string, u string, old string, name string) (*database.Character, error) {
@@ -168,16 +172,23 @@ func (cdb *CharMemoryDb) RenameCharacter(g string, u string, old string, name st
 		return nil, &database.Error{Code: database.CharacterNameTaken, Message: "Character with that name already exists"}
 	}
 
-	c.Name = name
-	idO, idN := getCharacterId(g, u, old), getCharacterId(g, u, name)
+	idO := getCharacterId(g, u, c.Name)
+	cdb.unindex(idO, c)
 	delete(cdb.Chars, idO)
+
+	c.Name = name
+	idN := getCharacterId(g, u, name)
 	cdb.Chars[idN] = c
+	cdb.index(idN, c)
+	cdb.moveHistory(idO, idN)
 
-	tmp := *c
-	return &tmp, nil
+	return copyCharacter(c), nil
 }
 
 func (cdb *CharMemoryDb) ChangeMainCharacter(g string, u string, name string) (*database.Character, error) {
+	cdb.mux.Lock()
+	defer cdb.mux.Unlock()
+
 	c, err := cdb.getCharacter(g, u, name)
 	if err != nil {
 		return nil, err
@@ -190,11 +201,13 @@ func (cdb *CharMemoryDb) ChangeMainCharacter(g string, u string, name string) (*
 
 	c.Main = true
 
-	tmp := *c
-	return &tmp, nil
+	return copyCharacter(c), nil
 }
 
 func (cdb *CharMemoryDb) SetCharacterStat(g string, u string, name string, s string, v interface{}) (*database.Character, error) {
+	cdb.mux.Lock()
+	defer cdb.mux.Unlock()
+
 	c, err := cdb.getCharacter(g, u, name)
 	if err != nil {
 		return nil, err
@@ -205,17 +218,19 @@ func (cdb *CharMemoryDb) SetCharacterStat(g string, u string, name string, s str
 	}
 	c.Body[s] = v
 
-	tmp := *c
-	return &tmp, nil
+	return copyCharacter(c), nil
 }
 
 func (cdb *CharMemoryDb) SetCharacterStatVersion(g string, u string, name string, stats map[string]*database.Stat, version int) (*database.Character, error) {
+	cdb.mux.Lock()
+	defer cdb.mux.Unlock()
+
 	c, err := cdb.getCharacter(g, u, name)
 	if err != nil {
 		return nil, err
 	}
 	if c.StatVersion >= version {
-		return c, nil
+		return copyCharacter(c), nil
 	}
 
 	if c.Body == nil {
@@ -229,13 +244,9 @@ func (cdb *CharMemoryDb) SetCharacterStatVersion(g string, u string, name string
 				continue
 			}
 
-			switch s.Type {
-			case database.Number:
-				_, ok = v.(int)
-			case database.Str:
-				_, ok = v.(string)
-			default:
-				return nil, &database.Error{Code: database.UnknownStatType, Message: fmt.Sprintf("Stat type for %v is not defined", s.ID)}
+			ok, err := s.IsValid(v)
+			if err != nil {
+				return nil, err
 			}
 			if !ok {
 				rm = append(rm, k)
@@ -250,20 +261,18 @@ func (cdb *CharMemoryDb) SetCharacterStatVersion(g string, u string, name string
 
 	for _, s := range stats {
 		if _, ok := c.Body[s.ID]; !ok {
-			switch s.Type {
-			case database.Number:
-				c.Body[s.ID] = 0
-			case database.Str:
-				c.Body[s.ID] = ""
-			default:
-				return nil, &database.Error{Code: database.UnknownStatType, Message: fmt.Sprintf("Stat type for %v is not defined", s.ID)}
+			v, err := s.DefaultValue()
+			if err != nil {
+				return nil, err
+			}
+			if v != nil {
+				c.Body[s.ID] = v
 			}
 		}
 	}
 	c.StatVersion = version
 
-	tmp := *c
-	return &tmp, nil
+	return copyCharacter(c), nil
 }
 
 func (cdb *CharMemoryDb) ChangeCharacterOwner(g string, old string, name string, u string) (*database.Character, error) {
@@ -280,29 +289,34 @@ func (cdb *CharMemoryDb) ChangeCharacterOwner(g string, old string, name string,
 		return nil, &database.Error{Code: database.UserHasCharacter, Message: fmt.Sprintf("Target user already has character with name '%v'", name)}
 	}
 
-	c.UserId = u
-	ido, idn := getCharacterId(g, old, name), getCharacterId(g, u, name)
+	ido := getCharacterId(g, old, c.Name)
+	cdb.unindex(ido, c)
 	delete(cdb.Chars, ido)
+
+	c.UserId = u
+	idn := getCharacterId(g, u, c.Name)
 	cdb.Chars[idn] = c
+	cdb.index(idn, c)
+	cdb.moveHistory(ido, idn)
 
-	tmp := *c
-	return &tmp, nil
+	return copyCharacter(c), nil
 }
 
 func (cdb *CharMemoryDb) RemoveCharacterStat(g string, u string, name string, s string) (*database.Character, error) {
+	cdb.mux.Lock()
+	defer cdb.mux.Unlock()
+
 	c, err := cdb.getCharacter(g, u, name)
 	if err != nil {
 		return nil, err
 	}
 
 	if c.Body == nil {
-		tmp := *c
-		return &tmp, nil
+		return copyCharacter(c), nil
 	}
 
 	delete(c.Body, s)
-	tmp := *c
-	return &tmp, nil
+	return copyCharacter(c), nil
 }
 
 func (cdb *CharMemoryDb) RemoveCharacter(g string, u string, name string) (*database.Character, error) {
@@ -314,10 +328,11 @@ func (cdb *CharMemoryDb) RemoveCharacter(g string, u string, name string) (*data
 		return nil, nil
 	}
 
-	id := getCharacterId(g, u, name)
+	id := getCharacterId(g, u, c.Name)
+	cdb.unindex(id, c)
 	delete(cdb.Chars, id)
-	tmp := *c
-	return &tmp, nil
+	delete(cdb.History, id)
+	return copyCharacter(c), nil
 }
 
 func getCharacterId(g string, u string, name string) string {
@@ -339,14 +354,12 @@ func (cdb *CharMemoryDb) getCharacter(g string, u string, name string) (*databas
 
 func (cdb *CharMemoryDb) getMainCharacter(g string, u string) (*database.Character, error) {
 	var rv *database.Character = nil
-	for _, v := range cdb.Chars {
-		if v.UserId == u && v.GuildId == g {
-			if v.Main {
-				return v, nil
-			}
-			if rv == nil {
-				rv = v
-			}
+	for _, v := range cdb.byUser[charKey{guild: g, key: u}] {
+		if v.Main {
+			return v, nil
+		}
+		if rv == nil {
+			rv = v
 		}
 	}
 
@@ -356,3 +369,71 @@ func (cdb *CharMemoryDb) getMainCharacter(g string, u string) (*database.Charact
 
 	return rv, nil
 }
+
+func (cdb *CharMemoryDb) index(id string, c *database.Character) {
+	if cdb.byGuild == nil {
+		cdb.byGuild = make(map[string]map[string]*database.Character)
+		cdb.byUser = make(map[charKey]map[string]*database.Character)
+		cdb.byName = make(map[charKey]map[string]*database.Character)
+	}
+
+	if cdb.byGuild[c.GuildId] == nil {
+		cdb.byGuild[c.GuildId] = make(map[string]*database.Character)
+	}
+	cdb.byGuild[c.GuildId][id] = c
+
+	uk := charKey{guild: c.GuildId, key: c.UserId}
+	if cdb.byUser[uk] == nil {
+		cdb.byUser[uk] = make(map[string]*database.Character)
+	}
+	cdb.byUser[uk][id] = c
+
+	nk := charKey{guild: c.GuildId, key: c.Name}
+	if cdb.byName[nk] == nil {
+		cdb.byName[nk] = make(map[string]*database.Character)
+	}
+	cdb.byName[nk][id] = c
+}
+
+func (cdb *CharMemoryDb) unindex(id string, c *database.Character) {
+	if chars := cdb.byGuild[c.GuildId]; chars != nil {
+		delete(chars, id)
+		if len(chars) == 0 {
+			delete(cdb.byGuild, c.GuildId)
+		}
+	}
+
+	uk := charKey{guild: c.GuildId, key: c.UserId}
+	if chars := cdb.byUser[uk]; chars != nil {
+		delete(chars, id)
+		if len(chars) == 0 {
+			delete(cdb.byUser, uk)
+		}
+	}
+
+	nk := charKey{guild: c.GuildId, key: c.Name}
+	if chars := cdb.byName[nk]; chars != nil {
+		delete(chars, id)
+		if len(chars) == 0 {
+			delete(cdb.byName, nk)
+		}
+	}
+}
+
+func (cdb *CharMemoryDb) reindex() {
+	cdb.byGuild, cdb.byUser, cdb.byName = nil, nil, nil
+	for id, c := range cdb.Chars {
+		cdb.index(id, c)
+	}
+}
+
+func copyCharacter(c *database.Character) *database.Character {
+	rv := *c
+	if c.Body != nil {
+		rv.Body = make(map[string]interface{}, len(c.Body))
+		for k, v := range c.Body {
+			rv.Body[k] = v
+		}
+	}
+	return &rv
+}
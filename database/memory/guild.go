@@ -18,6 +18,9 @@ type GuildMemoryDb struct {
 func (gdb *GuildMemoryDb) AddGuild(g *database.Guild) (*database.Guild, error) {
 	gdb.mux.Lock()
 	defer gdb.mux.Unlock()
+	if _, ok := gdb.Guilds[g.GuildId]; ok {
+		return nil, &database.Error{Code: database.InvalidGuildDefinition, Message: "Guild ID is already taken"}
+	}
 	if g.DiscordId != "" {
 		if _, ok := gdb.GuildsD[g.DiscordId]; ok {
 			return nil, &database.Error{Code: database.GuildAlreadyRegistered, Message: fmt.Sprintf("Guild '%v' is already registered", g.DiscordId)}
@@ -49,9 +52,10 @@ func (gdb *GuildMemoryDb) AddGuild(g *database.Guild) (*database.Guild, error) {
 		p.ChildNames[g.Name] = database.Member
 	}
 
-	newG := *g
-	g = &newG
-	g.GuildId = uuid.New()
+	g = copyGuild(g)
+	if g.GuildId == uuid.Nil {
+		g.GuildId = uuid.New()
+	}
 	if g.DiscordId != "" {
 		g.TopLevelParentId = g.GuildId
 		g.StatVersion = 0
@@ -59,14 +63,12 @@ func (gdb *GuildMemoryDb) AddGuild(g *database.Guild) (*database.Guild, error) {
 	}
 	gdb.Guilds[g.GuildId] = g
 
-	tmp := *g
-	return &tmp, nil
+	return copyGuild(g), nil
 }
 
 func (gdb *GuildMemoryDb) GetGuild(g uuid.UUID) (*database.Guild, error) {
 	if guild, ok := gdb.Guilds[g]; ok {
-		tmp := *guild
-		return &tmp, nil
+		return copyGuild(guild), nil
 	}
 
 	return nil, &database.Error{Code: database.GuildNotFound, Message: "Guild was not found"}
@@ -74,8 +76,7 @@ func (gdb *GuildMemoryDb) GetGuild(g uuid.UUID) (*database.Guild, error) {
 
 func (gdb *GuildMemoryDb) GetGuildD(d string) (*database.Guild, error) {
 	if guild, ok := gdb.GuildsD[d]; ok {
-		tmp := *guild
-		return &tmp, nil
+		return copyGuild(guild), nil
 	}
 
 	return nil, &database.Error{Code: database.GuildNotFound, Message: "Guild was not found"}
@@ -92,8 +93,7 @@ func (gdb *GuildMemoryDb) GetGuildN(p string, n string) (*database.Guild, error)
 
 	for _, g := range gdb.Guilds {
 		if g.Name == n && g.TopLevelParentId == parent.GuildId {
-			tmp := *g
-			return &tmp, nil
+			return copyGuild(g), nil
 		}
 	}
 
@@ -117,15 +117,14 @@ func (gdb *GuildMemoryDb) GetSubGuilds(g uuid.UUID) (map[uuid.UUID]*database.Gui
 	}
 
 	subGuilds := map[uuid.UUID]*database.Guild{
-		gld.GuildId: gld,
+		gld.GuildId: copyGuild(gld),
 	}
 	prevLen, length := -1, 0
 	for length > prevLen {
 		prevLen = length
 		for _, g := range allGuilds {
 			if _, ok := subGuilds[g.ParentId]; ok {
-				tmp := *g
-				subGuilds[g.GuildId] = &tmp
+				subGuilds[g.GuildId] = copyGuild(g)
 			}
 		}
 		length = len(subGuilds)
@@ -144,8 +143,7 @@ func (gdb *GuildMemoryDb) RenameGuild(g uuid.UUID, name string) (*database.Guild
 	}
 
 	if guild.Name == name {
-		tmp := *guild
-		return &tmp, nil
+		return copyGuild(guild), nil
 	}
 
 	p, ok := gdb.Guilds[guild.TopLevelParentId]
@@ -165,8 +163,7 @@ func (gdb *GuildMemoryDb) RenameGuild(g uuid.UUID, name string) (*database.Guild
 	guild.Name = name
 	p.ChildNames[name] = database.Member
 
-	tmp := *guild
-	return &tmp, nil
+	return copyGuild(guild), nil
 }
 
 func (gdb *GuildMemoryDb) MoveGuild(g uuid.UUID, p uuid.UUID) (*database.Guild, error) {
@@ -181,8 +178,7 @@ func (gdb *GuildMemoryDb) MoveGuild(g uuid.UUID, p uuid.UUID) (*database.Guild,
 
 	guild.ParentId = p
 
-	tmp := *guild
-	return &tmp, nil
+	return copyGuild(guild), nil
 }
 
 func (gdb *GuildMemoryDb) RemoveGuild(g uuid.UUID) (*database.Guild, error) {
@@ -203,8 +199,7 @@ func (gdb *GuildMemoryDb) RemoveGuild(g uuid.UUID) (*database.Guild, error) {
 		delete(parent.ChildNames, guild.Name)
 	}
 
-	tmp := *guild
-	return &tmp, nil
+	return copyGuild(guild), nil
 }
 
 func (gdb *GuildMemoryDb) RemoveGuildD(d string) (*database.Guild, error) {
@@ -218,8 +213,7 @@ func (gdb *GuildMemoryDb) RemoveGuildD(d string) (*database.Guild, error) {
 		return nil, err
 	}
 
-	tmp := *guild
-	return &tmp, nil
+	return copyGuild(guild), nil
 }
 
 func (gdb *GuildMemoryDb) AddGuildStat(g uuid.UUID, s *database.Stat) (*database.Guild, error) {
@@ -227,15 +221,13 @@ func (gdb *GuildMemoryDb) AddGuildStat(g uuid.UUID, s *database.Stat) (*database
 	if !ok {
 		return nil, &database.Error{Code: database.GuildNotFound, Message: "Guild was not found"}
 	}
-	if guild.DiscordId == "" {
-		return nil, &database.Error{Code: database.GuildLevelError, Message: "Only top-level guild stats are supported right now"}
-	}
 
 	if et, ok := guild.Stats[s.ID]; ok {
 		if et.Type == s.Type {
-			et.Description = s.Description
-			tmp := *guild
-			return &tmp, nil
+			tmpStat := *s
+			tmpStat.Values = append([]string(nil), s.Values...)
+			guild.Stats[s.ID] = &tmpStat
+			return copyGuild(guild), nil
 		} else {
 			return nil, &database.Error{Code: database.StatNameConflict, Message: fmt.Sprintf("Stat with same name (%v) but different type (%v) found", s.ID, et.Type)}
 		}
@@ -243,13 +235,15 @@ func (gdb *GuildMemoryDb) AddGuildStat(g uuid.UUID, s *database.Stat) (*database
 
 	if guild.Stats == nil {
 		guild.Stats = make(map[string]*database.Stat)
-		guild.DefaultStat = s.ID
+		if guild.DiscordId != "" {
+			guild.DefaultStat = s.ID
+		}
 	}
 	tmpStat := *s
+	tmpStat.Values = append([]string(nil), s.Values...)
 	guild.Stats[s.ID] = &tmpStat
-	guild.StatVersion += 1
-	tmp := *guild
-	return &tmp, nil
+	gdb.bumpStatVersion(guild)
+	return copyGuild(guild), nil
 }
 
 func (gdb *GuildMemoryDb) SetDefaultGuildStat(g uuid.UUID, sn string) (*database.Guild, error) {
@@ -266,11 +260,55 @@ func (gdb *GuildMemoryDb) SetDefaultGuildStat(g uuid.UUID, sn string) (*database
 	}
 
 	guild.DefaultStat = sn
-	tmp := *guild
-	return &tmp, nil
+	return copyGuild(guild), nil
 }
 
-func (gdb *GuildMemoryDb) RemoveGuildStat(g uuid.UUID, n string) (*database.Guild, error) {
+// RenameGuildStat changes characters, pending submissions and trashed characters as well, so it is defined on MemoryDB.
+// Characters belong to the top-level guild. Stat names are unique in the hierarchy, so values are moved
+// for all of its characters, the same way stat type changes convert them
+func (m *MemoryDB) RenameGuildStat(g uuid.UUID, old string, name string) (*database.Guild, error) {
+	m.CharMemoryDb.mux.Lock()
+	defer m.CharMemoryDb.mux.Unlock()
+	m.GuildMemoryDb.mux.Lock()
+	defer m.GuildMemoryDb.mux.Unlock()
+	m.SubmissionMemoryDb.mux.Lock()
+	defer m.SubmissionMemoryDb.mux.Unlock()
+	m.TrashMemoryDb.mux.Lock()
+	defer m.TrashMemoryDb.mux.Unlock()
+
+	guild, ok := m.Guilds[g]
+	if !ok {
+		return nil, &database.Error{Code: database.GuildNotFound, Message: "Guild was not found"}
+	}
+	top, ok := m.Guilds[guild.TopLevelParentId]
+	if !ok {
+		top = guild
+	}
+
+	st, ok := guild.Stats[old]
+	if !ok {
+		return nil, &database.Error{Code: database.StatNotFound, Message: "Stat was not found"}
+	}
+	if _, ok := guild.Stats[name]; ok {
+		return nil, &database.Error{Code: database.StatNameConflict, Message: fmt.Sprintf("Stat with name %v already exists", name)}
+	}
+
+	tmpStat := *st
+	tmpStat.ID = name
+	delete(guild.Stats, old)
+	guild.Stats[name] = &tmpStat
+	if guild.DefaultStat == old {
+		guild.DefaultStat = name
+	}
+	m.GuildMemoryDb.bumpStatVersion(guild)
+	m.CharMemoryDb.renameStat(top.DiscordId, old, name)
+	m.SubmissionMemoryDb.renameStat(top.DiscordId, old, name)
+	m.TrashMemoryDb.renameStat(top.DiscordId, old, name)
+
+	return copyGuild(guild), nil
+}
+
+func (gdb *GuildMemoryDb) ChangeGuildStatType(g uuid.UUID, s *database.Stat) (*database.Guild, error) {
 	gdb.mux.Lock()
 	defer gdb.mux.Unlock()
 
@@ -278,8 +316,34 @@ func (gdb *GuildMemoryDb) RemoveGuildStat(g uuid.UUID, n string) (*database.Guil
 	if !ok {
 		return nil, &database.Error{Code: database.GuildNotFound, Message: "Guild was not found"}
 	}
-	if guild.DiscordId == "" {
-		return nil, &database.Error{Code: database.GuildLevelError, Message: "Only top-level guild stats are supported right now"}
+	if _, ok := guild.Stats[s.ID]; !ok {
+		return nil, &database.Error{Code: database.StatNotFound, Message: "Stat was not found"}
+	}
+
+	tmpStat := *s
+	tmpStat.Values = append([]string(nil), s.Values...)
+	guild.Stats[s.ID] = &tmpStat
+	gdb.bumpStatVersion(guild)
+
+	return copyGuild(guild), nil
+}
+
+// bumpStatVersion makes characters update their stats. Characters check version of the top-level guild,
+// so it is increased for sub-guild stat changes as well
+func (gdb *GuildMemoryDb) bumpStatVersion(guild *database.Guild) {
+	guild.StatVersion += 1
+	if top, ok := gdb.Guilds[guild.TopLevelParentId]; ok && top != guild {
+		top.StatVersion += 1
+	}
+}
+
+func (gdb *GuildMemoryDb) RemoveGuildStat(g uuid.UUID, n string) (*database.Guild, error) {
+	gdb.mux.Lock()
+	defer gdb.mux.Unlock()
+
+	guild, ok := gdb.Guilds[g]
+	if !ok {
+		return nil, &database.Error{Code: database.GuildNotFound, Message: "Guild was not found"}
 	}
 
 	if _, ok := guild.Stats[n]; !ok {
@@ -298,9 +362,8 @@ func (gdb *GuildMemoryDb) RemoveGuildStat(g uuid.UUID, n string) (*database.Guil
 			}
 		}
 	}
-	guild.StatVersion += 1
-	tmp := *guild
-	return &tmp, nil
+	gdb.bumpStatVersion(guild)
+	return copyGuild(guild), nil
 }
 
 func (gdb *GuildMemoryDb) RemoveAllGuildStats(g uuid.UUID) (*database.Guild, error) {
@@ -311,14 +374,10 @@ func (gdb *GuildMemoryDb) RemoveAllGuildStats(g uuid.UUID) (*database.Guild, err
 	if !ok {
 		return nil, &database.Error{Code: database.GuildNotFound, Message: "Guild was not found"}
 	}
-	if guild.DiscordId == "" {
-		return nil, &database.Error{Code: database.GuildLevelError, Message: "Only top-level guild stats are supported right now"}
-	}
 
 	guild.Stats = nil
-	guild.StatVersion += 1
-	tmp := *guild
-	return &tmp, nil
+	gdb.bumpStatVersion(guild)
+	return copyGuild(guild), nil
 }
 
 func (gdb *GuildMemoryDb) removeGuildsByParent(g uuid.UUID) error {
@@ -342,3 +401,23 @@ func (gdb *GuildMemoryDb) removeGuildsByParent(g uuid.UUID) error {
 
 	return nil
 }
+
+// copies share no maps with the stored guild, so callers can change them freely
+func copyGuild(g *database.Guild) *database.Guild {
+	rv := *g
+	if g.Stats != nil {
+		rv.Stats = make(map[string]*database.Stat, len(g.Stats))
+		for k, s := range g.Stats {
+			tmp := *s
+			tmp.Values = append([]string(nil), s.Values...)
+			rv.Stats[k] = &tmp
+		}
+	}
+	if g.ChildNames != nil {
+		rv.ChildNames = make(map[string]database.Void, len(g.ChildNames))
+		for k, v := range g.ChildNames {
+			rv.ChildNames[k] = v
+		}
+	}
+	return &rv
+}
@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+func (cdb *CharMemoryDb) AddStatHistory(h *database.StatHistory) (*database.StatHistory, error) {
+	cdb.mux.Lock()
+	defer cdb.mux.Unlock()
+
+	c, err := cdb.getCharacter(h.GuildId, h.UserId, h.Character)
+	if err != nil {
+		return nil, err
+	}
+
+	id := getCharacterId(c.GuildId, c.UserId, c.Name)
+	cdb.History[id] = append(cdb.History[id], &database.StatHistory{Stat: h.Stat, Value: h.Value, Time: h.Time})
+
+	return historyEntry(c, cdb.History[id][len(cdb.History[id])-1]), nil
+}
+
+func (cdb *CharMemoryDb) GetStatHistory(g string, u string, name string, s string) ([]*database.StatHistory, error) {
+	cdb.mux.Lock()
+	defer cdb.mux.Unlock()
+
+	c, err := cdb.getCharacter(g, u, name)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]*database.StatHistory, 0, 10)
+	for _, h := range cdb.History[getCharacterId(c.GuildId, c.UserId, c.Name)] {
+		if h.Stat == s {
+			rv = append(rv, historyEntry(c, h))
+		}
+	}
+
+	return rv, nil
+}
+
+func (cdb *CharMemoryDb) GetGuildStatHistory(g string, s string, since time.Time) ([]*database.StatHistory, error) {
+	cdb.mux.Lock()
+	defer cdb.mux.Unlock()
+
+	rv := make([]*database.StatHistory, 0, 10)
+	for id, c := range cdb.byGuild[g] {
+		var before *database.StatHistory
+		for _, h := range cdb.History[id] {
+			if h.Stat != s {
+				continue
+			}
+
+			if h.Time.Before(since) {
+				before = h
+			} else {
+				if before != nil {
+					rv = append(rv, historyEntry(c, before))
+					before = nil
+				}
+				rv = append(rv, historyEntry(c, h))
+			}
+		}
+
+		if before != nil {
+			rv = append(rv, historyEntry(c, before))
+		}
+	}
+
+	sort.SliceStable(rv, func(i, j int) bool { return rv[i].Time.Before(rv[j].Time) })
+	return rv, nil
+}
+
+// renameStat moves values and history of the stat in all characters of the guild. Lock is held by caller
+func (cdb *CharMemoryDb) renameStat(g string, old string, name string) {
+	for id, c := range cdb.byGuild[g] {
+		if v, ok := c.Body[old]; ok {
+			delete(c.Body, old)
+			c.Body[name] = v
+		}
+
+		for _, h := range cdb.History[id] {
+			if h.Stat == old {
+				h.Stat = name
+			}
+		}
+	}
+}
+
+func (cdb *CharMemoryDb) moveHistory(from string, to string) {
+	if h, ok := cdb.History[from]; ok {
+		delete(cdb.History, from)
+		cdb.History[to] = h
+	}
+}
+
+func historyEntry(c *database.Character, h *database.StatHistory) *database.StatHistory {
+	return &database.StatHistory{
+		GuildId:   c.GuildId,
+		UserId:    c.UserId,
+		Character: c.Name,
+		Stat:      h.Stat,
+		Value:     h.Value,
+		Time:      h.Time,
+	}
+}
+
+func copyHistory(h []*database.StatHistory) []*database.StatHistory {
+	rv := make([]*database.StatHistory, 0, len(h))
+	for _, e := range h {
+		tmp := *e
+		rv = append(rv, &tmp)
+	}
+	return rv
+}
@@ -4,45 +4,125 @@ import (
 	"bytes"
 	"encoding/gob"
 	"io"
+	"sync"
 
 	"github.com/google/uuid"
 	"github.com/mebaranov/disguildie/database"
 )
 
 type MemoryDB struct {
+	AuditMemoryDb
 	CharMemoryDb
 	GuildMemoryDb
 	MoneyMemoryDb
 	RoleMemoryDb
+	SubmissionMemoryDb
+	TrashMemoryDb
 	UserMemoryDb
+
+	txMux sync.Mutex
 }
 
 // constructor function
 func NewMemoryDb() *MemoryDB {
 	m := MemoryDB{}
+	m.Audit = make(map[string][]*database.AuditEntry)
 	m.Chars = make(map[string]*database.Character)
+	m.History = make(map[string][]*database.StatHistory)
 	m.Guilds = make(map[uuid.UUID]*database.Guild)
 	m.GuildsD = make(map[string]*database.Guild)
 	m.Money = make(map[string]*database.Money)
 	m.Roles = make(map[string]*database.Role)
+	m.Submissions = make(map[string][]*database.Submission)
+	m.Trash = make(map[string][]*database.TrashItem)
 	m.UsersD = make(map[string]*database.User)
 	return &m
 }
 
 func (m *MemoryDB) Export() ([]byte, error) {
+	m.lock()
+	defer m.unlock()
+
 	var buf bytes.Buffer
 	encoder := gob.NewEncoder(io.Writer(&buf))
-	encoder.Encode(m)
+	if err := encoder.Encode(m); err != nil {
+		return nil, &database.Error{Code: database.ExternalError, Message: err.Error()}
+	}
 
 	return buf.Bytes(), nil
 }
 
 func (m *MemoryDB) Import(b []byte) error {
+	tmp := NewMemoryDb()
 	buf := bytes.NewBuffer(b)
 	decoder := gob.NewDecoder(io.Reader(buf))
-	err := decoder.Decode(m)
+	if err := decoder.Decode(tmp); err != nil {
+		return &database.Error{Code: database.IOErrorDuringImport, Message: err.Error()}
+	}
+
+	// gob does not keep pointer identity, so top level guilds have to be linked again
+	tmp.GuildsD = make(map[string]*database.Guild)
+	for _, g := range tmp.Guilds {
+		if g.DiscordId != "" {
+			tmp.GuildsD[g.DiscordId] = g
+		}
+	}
+
+	m.lock()
+	defer m.unlock()
+	m.Audit = tmp.Audit
+	m.LastAuditId = tmp.LastAuditId
+	m.Chars = tmp.Chars
+	m.History = tmp.History
+	m.Guilds = tmp.Guilds
+	m.GuildsD = tmp.GuildsD
+	m.Money = tmp.Money
+	m.Roles = tmp.Roles
+	m.Submissions = tmp.Submissions
+	m.LastSubmissionId = tmp.LastSubmissionId
+	m.Trash = tmp.Trash
+	m.LastTrashId = tmp.LastTrashId
+	m.UsersD = tmp.UsersD
+	m.CharMemoryDb.reindex()
+
+	return nil
+}
+
+// RunInTx serializes transactions with each other. If f fails, only entries changed by f are put back,
+// so changes made outside of transactions in the meantime are kept
+func (m *MemoryDB) RunInTx(f func(tx database.DataProvider) error) error {
+	m.txMux.Lock()
+	defer m.txMux.Unlock()
+
+	tx := &memoryTx{MemoryDB: m}
+	if err := f(tx); err != nil {
+		tx.rollback()
+		return err
+	}
+
+	return nil
+}
+
+func (m *MemoryDB) lock() {
+	m.AuditMemoryDb.mux.Lock()
+	m.CharMemoryDb.mux.Lock()
+	m.GuildMemoryDb.mux.Lock()
+	m.MoneyMemoryDb.mux.Lock()
+	m.RoleMemoryDb.mux.Lock()
+	m.SubmissionMemoryDb.mux.Lock()
+	m.TrashMemoryDb.mux.Lock()
+	m.UserMemoryDb.mux.Lock()
+}
 
-	return &database.Error{Code: database.IOErrorDuringImport, Message: err.Error()}
+func (m *MemoryDB) unlock() {
+	m.UserMemoryDb.mux.Unlock()
+	m.TrashMemoryDb.mux.Unlock()
+	m.SubmissionMemoryDb.mux.Unlock()
+	m.RoleMemoryDb.mux.Unlock()
+	m.MoneyMemoryDb.mux.Unlock()
+	m.GuildMemoryDb.mux.Unlock()
+	m.CharMemoryDb.mux.Unlock()
+	m.AuditMemoryDb.mux.Unlock()
 }
 
 func init() {
@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"fmt"
+
+	"sort"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+func (m *MemoryDB) QueryCharacters(g string, q *database.CharacterQuery) ([]*database.Character, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	chars, err := m.GetGuildCharacters(g)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]*database.Character, 0, len(chars))
+	for _, c := range chars {
+		ok, err := m.matches(g, q, c)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			rv = append(rv, c)
+		}
+	}
+
+	sort.Slice(rv, func(i int, j int) bool { return q.Less(rv[i], rv[j]) })
+	if q.Limit > 0 && q.Limit < len(rv) {
+		rv = rv[:q.Limit]
+	}
+	return rv, nil
+}
+
+func (m *MemoryDB) matches(g string, q *database.CharacterQuery, c *database.Character) (bool, error) {
+	if q.Main != nil && c.Main != *q.Main {
+		return false, nil
+	}
+	for _, f := range q.Filters {
+		if !f.Matches(c) {
+			return false, nil
+		}
+	}
+	if q.OwnerGuilds == nil {
+		return true, nil
+	}
+
+	u, err := m.GetUserD(c.UserId)
+	if err != nil {
+		if dbErr := database.ErrToDbErr(err); dbErr != nil && dbErr.Code == database.UserNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	p, ok := u.Guilds[g]
+	if !ok {
+		return false, nil
+	}
+	for _, og := range q.OwnerGuilds {
+		if og == p.GuildId {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *MemoryDB) RankCharacter(g string, u string, name string, q *database.CharacterQuery) (*database.CharacterRank, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	c, err := m.GetCharacter(g, u, name)
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := m.matches(g, q, c); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, &database.Error{Code: database.CharacterNotFound, Message: fmt.Sprintf("Character %v is not ranked", c.Name)}
+	}
+
+	chars, err := m.GetGuildCharacters(g)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := &database.CharacterRank{Character: c, Rank: 1}
+	for _, o := range chars {
+		if o.UserId == c.UserId && o.Name == c.Name {
+			rv.Total++
+			continue
+		}
+		if ok, err := m.matches(g, q, o); err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+
+		rv.Total++
+		if q.Less(o, c) {
+			rv.Rank++
+			if rv.Above == nil || q.Less(rv.Above, o) {
+				rv.Above = o
+			}
+		} else if rv.Below == nil || q.Less(o, rv.Below) {
+			rv.Below = o
+		}
+	}
+
+	return rv, nil
+}
@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"sync"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+type SubmissionMemoryDb struct {
+	Submissions      map[string][]*database.Submission
+	LastSubmissionId int
+	mux              sync.Mutex
+}
+
+func (sdb *SubmissionMemoryDb) AddSubmission(s *database.Submission) (*database.Submission, error) {
+	sdb.mux.Lock()
+	defer sdb.mux.Unlock()
+
+	sdb.LastSubmissionId += 1
+	newS := *s
+	newS.Id = sdb.LastSubmissionId
+	sdb.Submissions[s.GuildId] = append(sdb.Submissions[s.GuildId], &newS)
+
+	tmp := newS
+	return &tmp, nil
+}
+
+func (sdb *SubmissionMemoryDb) GetSubmissions(g string) ([]*database.Submission, error) {
+	sdb.mux.Lock()
+	defer sdb.mux.Unlock()
+
+	subs := sdb.Submissions[g]
+	rv := make([]*database.Submission, 0, len(subs))
+	for _, s := range subs {
+		tmp := *s
+		rv = append(rv, &tmp)
+	}
+
+	return rv, nil
+}
+
+func (sdb *SubmissionMemoryDb) RemoveSubmission(g string, id int) (*database.Submission, error) {
+	sdb.mux.Lock()
+	defer sdb.mux.Unlock()
+
+	subs := sdb.Submissions[g]
+	for i, s := range subs {
+		if s.Id == id {
+			sdb.Submissions[g] = append(subs[:i:i], subs[i+1:]...)
+			return s, nil
+		}
+	}
+
+	return nil, &database.Error{Code: database.SubmissionNotFound, Message: "Submission was not found"}
+}
+
+// renameStat moves pending values of stat old to name. Changed submissions are replaced, not updated in place
+func (sdb *SubmissionMemoryDb) renameStat(g string, old string, name string) {
+	for i, s := range sdb.Submissions[g] {
+		if s.Stat == old {
+			tmp := *s
+			tmp.Stat = name
+			sdb.Submissions[g][i] = &tmp
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+type TrashMemoryDb struct {
+	Trash       map[string][]*database.TrashItem
+	LastTrashId int
+	mux         sync.Mutex
+}
+
+func (tdb *TrashMemoryDb) AddTrashItem(t *database.TrashItem) (*database.TrashItem, error) {
+	tdb.mux.Lock()
+	defer tdb.mux.Unlock()
+
+	tdb.LastTrashId += 1
+	newT := *t
+	newT.Id = tdb.LastTrashId
+	tdb.Trash[t.GuildId] = append(tdb.Trash[t.GuildId], &newT)
+
+	tmp := newT
+	return &tmp, nil
+}
+
+func (tdb *TrashMemoryDb) GetTrashItems(g string) ([]*database.TrashItem, error) {
+	tdb.mux.Lock()
+	defer tdb.mux.Unlock()
+
+	items := tdb.Trash[g]
+	rv := make([]*database.TrashItem, 0, len(items))
+	for _, t := range items {
+		tmp := *t
+		rv = append(rv, &tmp)
+	}
+
+	return rv, nil
+}
+
+func (tdb *TrashMemoryDb) RemoveTrashItem(g string, id int) (*database.TrashItem, error) {
+	tdb.mux.Lock()
+	defer tdb.mux.Unlock()
+
+	items := tdb.Trash[g]
+	for i, t := range items {
+		if t.Id == id {
+			tdb.Trash[g] = append(items[:i:i], items[i+1:]...)
+			return t, nil
+		}
+	}
+
+	return nil, &database.Error{Code: database.TrashItemNotFound, Message: "Item was not found in the trash"}
+}
+
+func (tdb *TrashMemoryDb) PurgeTrash(g string, before time.Time) (int, error) {
+	tdb.mux.Lock()
+	defer tdb.mux.Unlock()
+
+	items := tdb.Trash[g]
+	rv := make([]*database.TrashItem, 0, len(items))
+	for _, t := range items {
+		if !t.Time.Before(before) {
+			rv = append(rv, t)
+		}
+	}
+
+	tdb.Trash[g] = rv
+	return len(items) - len(rv), nil
+}
+
+// renameStat moves values and history of stat old to name in removed characters. Items are replaced, not updated in place
+func (tdb *TrashMemoryDb) renameStat(g string, old string, name string) {
+	for i, t := range tdb.Trash[g] {
+		tdb.Trash[g][i] = t.RenameStat(old, name)
+	}
+}
@@ -0,0 +1,534 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/mebaranov/disguildie/database"
+)
+
+// memoryTx is passed to RunInTx callbacks, so nested calls join the outer transaction.
+// Changes made through it keep state of the entries they touch, which is put back in reverse order
+// if the transaction fails. Entries not touched by the transaction are never reverted
+type memoryTx struct {
+	*MemoryDB
+	undo []func()
+}
+
+func (t *memoryTx) RunInTx(f func(tx database.DataProvider) error) error {
+	return f(t)
+}
+
+func (t *memoryTx) rollback() {
+	t.lock()
+	defer t.unlock()
+
+	for i := len(t.undo) - 1; i >= 0; i-- {
+		t.undo[i]()
+	}
+	t.undo = nil
+}
+
+// save calls f to keep entries with all locks held
+func (t *memoryTx) save(f func()) {
+	t.lock()
+	defer t.unlock()
+	f()
+}
+
+func (t *memoryTx) keepGuild(id uuid.UUID) {
+	var saved *database.Guild
+	if g, ok := t.Guilds[id]; ok {
+		saved = copyGuild(g)
+	}
+
+	t.undo = append(t.undo, func() {
+		if g, ok := t.Guilds[id]; ok {
+			delete(t.Guilds, id)
+			if g.DiscordId != "" {
+				delete(t.GuildsD, g.DiscordId)
+			}
+		}
+		if saved != nil {
+			g := copyGuild(saved)
+			t.Guilds[id] = g
+			if g.DiscordId != "" {
+				t.GuildsD[g.DiscordId] = g
+			}
+		}
+	})
+}
+
+// keepGuildAndTop keeps the guild along with its top-level guild, which has names and stat version of sub-guilds
+func (t *memoryTx) keepGuildAndTop(id uuid.UUID) {
+	t.keepGuild(id)
+	if g, ok := t.Guilds[id]; ok && g.TopLevelParentId != id {
+		t.keepGuild(g.TopLevelParentId)
+	}
+}
+
+// keepGuildTree keeps the guild, all its sub-guilds and its top-level guild
+func (t *memoryTx) keepGuildTree(id uuid.UUID) {
+	g, ok := t.Guilds[id]
+	if !ok {
+		return
+	}
+
+	tree := map[uuid.UUID]database.Void{id: database.Member}
+	for prev := 0; len(tree) > prev; {
+		prev = len(tree)
+		for _, sg := range t.Guilds {
+			if _, ok := tree[sg.ParentId]; ok && sg.TopLevelParentId == g.TopLevelParentId {
+				tree[sg.GuildId] = database.Member
+			}
+		}
+	}
+
+	for sg := range tree {
+		t.keepGuild(sg)
+	}
+	if _, ok := tree[g.TopLevelParentId]; !ok {
+		t.keepGuild(g.TopLevelParentId)
+	}
+}
+
+func (t *memoryTx) keepUser(d string) {
+	var saved *database.User
+	if u, ok := t.UsersD[d]; ok {
+		saved = copyUser(u)
+	}
+
+	t.undo = append(t.undo, func() {
+		if saved == nil {
+			delete(t.UsersD, d)
+		} else {
+			t.UsersD[d] = copyUser(saved)
+		}
+	})
+}
+
+func (t *memoryTx) keepCharacter(id string) {
+	var saved *database.Character
+	if c, ok := t.Chars[id]; ok {
+		saved = copyCharacter(c)
+	}
+	history, ok := t.History[id]
+	if ok {
+		history = copyHistory(history)
+	}
+
+	t.undo = append(t.undo, func() {
+		if c, ok := t.Chars[id]; ok {
+			t.unindex(id, c)
+			delete(t.Chars, id)
+		}
+		if saved != nil {
+			c := copyCharacter(saved)
+			t.Chars[id] = c
+			t.index(id, c)
+		}
+
+		if ok {
+			t.History[id] = copyHistory(history)
+		} else {
+			delete(t.History, id)
+		}
+	})
+}
+
+// keepCharacterN keeps character by the name used in requests, so empty name means main character
+func (t *memoryTx) keepCharacterN(g string, u string, name string) {
+	if c, err := t.getCharacter(g, u, name); err == nil {
+		name = c.Name
+	}
+	t.keepCharacter(getCharacterId(g, u, name))
+}
+
+func (t *memoryTx) keepUserCharacters(g string, u string) {
+	for id := range t.byUser[charKey{guild: g, key: u}] {
+		t.keepCharacter(id)
+	}
+}
+
+func (t *memoryTx) keepGuildCharacters(g string) {
+	for id := range t.byGuild[g] {
+		t.keepCharacter(id)
+	}
+}
+
+// keepGuildSubmissions is for changes replacing submissions in place, the slice is put back as it was
+func (t *memoryTx) keepGuildSubmissions(g string) {
+	saved, ok := t.Submissions[g]
+	saved = append([]*database.Submission(nil), saved...)
+	t.undo = append(t.undo, func() {
+		if ok {
+			t.Submissions[g] = saved
+		} else {
+			delete(t.Submissions, g)
+		}
+	})
+}
+
+// keepGuildTrash is for changes replacing trash items in place, the slice is put back as it was
+func (t *memoryTx) keepGuildTrash(g string) {
+	saved, ok := t.Trash[g]
+	saved = append([]*database.TrashItem(nil), saved...)
+	t.undo = append(t.undo, func() {
+		if ok {
+			t.Trash[g] = saved
+		} else {
+			delete(t.Trash, g)
+		}
+	})
+}
+
+func (t *memoryTx) keepRole(id string) {
+	var saved *database.Role
+	if r, ok := t.Roles[id]; ok {
+		tmp := *r
+		saved = &tmp
+	}
+
+	t.undo = append(t.undo, func() {
+		if saved == nil {
+			delete(t.Roles, id)
+		} else {
+			tmp := *saved
+			t.Roles[id] = &tmp
+		}
+	})
+}
+
+func (t *memoryTx) keepMoney(g string) {
+	var saved *database.Money
+	if m, ok := t.Money[g]; ok {
+		tmp := *m
+		saved = &tmp
+	}
+
+	t.undo = append(t.undo, func() {
+		if saved == nil {
+			delete(t.Money, g)
+		} else {
+			tmp := *saved
+			t.Money[g] = &tmp
+		}
+	})
+}
+
+// IDs of added entries are given back only if nothing was added after them, so replay of the journal
+// without the rolled back transaction produces the same IDs
+func (t *memoryTx) dropAuditEntry(g string, id int) {
+	t.undo = append(t.undo, func() {
+		entries := t.Audit[g]
+		for i, e := range entries {
+			if e.Id == id {
+				t.Audit[g] = append(entries[:i:i], entries[i+1:]...)
+				break
+			}
+		}
+		if t.LastAuditId == id {
+			t.LastAuditId -= 1
+		}
+	})
+}
+
+func (t *memoryTx) dropSubmission(g string, id int) {
+	t.undo = append(t.undo, func() {
+		subs := t.Submissions[g]
+		for i, s := range subs {
+			if s.Id == id {
+				t.Submissions[g] = append(subs[:i:i], subs[i+1:]...)
+				break
+			}
+		}
+		if t.LastSubmissionId == id {
+			t.LastSubmissionId -= 1
+		}
+	})
+}
+
+// restoreSubmission puts removed submission back in order of IDs
+func (t *memoryTx) restoreSubmission(s *database.Submission) {
+	t.undo = append(t.undo, func() {
+		subs := t.Submissions[s.GuildId]
+		i := sort.Search(len(subs), func(i int) bool { return subs[i].Id >= s.Id })
+		if i < len(subs) && subs[i].Id == s.Id {
+			return
+		}
+		t.Submissions[s.GuildId] = append(subs[:i:i], append([]*database.Submission{s}, subs[i:]...)...)
+	})
+}
+
+func (t *memoryTx) dropTrashItem(g string, id int) {
+	t.undo = append(t.undo, func() {
+		items := t.Trash[g]
+		for i, it := range items {
+			if it.Id == id {
+				t.Trash[g] = append(items[:i:i], items[i+1:]...)
+				break
+			}
+		}
+		if t.LastTrashId == id {
+			t.LastTrashId -= 1
+		}
+	})
+}
+
+// restoreTrashItems puts removed items back in order of IDs
+func (t *memoryTx) restoreTrashItems(g string, removed []*database.TrashItem) {
+	t.undo = append(t.undo, func() {
+		for _, it := range removed {
+			items := t.Trash[g]
+			i := sort.Search(len(items), func(i int) bool { return items[i].Id >= it.Id })
+			if i < len(items) && items[i].Id == it.Id {
+				continue
+			}
+			t.Trash[g] = append(items[:i:i], append([]*database.TrashItem{it}, items[i:]...)...)
+		}
+	})
+}
+
+func (t *memoryTx) AddGuild(g *database.Guild) (*database.Guild, error) {
+	// ID is generated here, so it is known before the guild is added
+	if g.GuildId == uuid.Nil {
+		tmp := *g
+		tmp.GuildId = uuid.New()
+		g = &tmp
+	}
+
+	t.save(func() {
+		t.keepGuild(g.GuildId)
+		if g.DiscordId == "" {
+			t.keepGuildAndTop(g.ParentId)
+		}
+	})
+	return t.MemoryDB.AddGuild(g)
+}
+
+func (t *memoryTx) RenameGuild(g uuid.UUID, name string) (*database.Guild, error) {
+	t.save(func() { t.keepGuildAndTop(g) })
+	return t.MemoryDB.RenameGuild(g, name)
+}
+
+func (t *memoryTx) MoveGuild(g uuid.UUID, parent uuid.UUID) (*database.Guild, error) {
+	t.save(func() { t.keepGuild(g) })
+	return t.MemoryDB.MoveGuild(g, parent)
+}
+
+func (t *memoryTx) RemoveGuild(g uuid.UUID) (*database.Guild, error) {
+	t.save(func() { t.keepGuildTree(g) })
+	return t.MemoryDB.RemoveGuild(g)
+}
+
+func (t *memoryTx) RemoveGuildD(d string) (*database.Guild, error) {
+	t.save(func() {
+		if g, ok := t.GuildsD[d]; ok {
+			t.keepGuildTree(g.GuildId)
+		}
+	})
+	return t.MemoryDB.RemoveGuildD(d)
+}
+
+func (t *memoryTx) AddGuildStat(g uuid.UUID, s *database.Stat) (*database.Guild, error) {
+	t.save(func() { t.keepGuildAndTop(g) })
+	return t.MemoryDB.AddGuildStat(g, s)
+}
+
+func (t *memoryTx) SetDefaultGuildStat(g uuid.UUID, sn string) (*database.Guild, error) {
+	t.save(func() { t.keepGuild(g) })
+	return t.MemoryDB.SetDefaultGuildStat(g, sn)
+}
+
+func (t *memoryTx) RemoveGuildStat(g uuid.UUID, n string) (*database.Guild, error) {
+	t.save(func() { t.keepGuildAndTop(g) })
+	return t.MemoryDB.RemoveGuildStat(g, n)
+}
+
+func (t *memoryTx) RenameGuildStat(g uuid.UUID, old string, name string) (*database.Guild, error) {
+	t.save(func() {
+		t.keepGuildAndTop(g)
+		if guild, ok := t.Guilds[g]; ok {
+			if top, ok := t.Guilds[guild.TopLevelParentId]; ok {
+				guild = top
+			}
+			t.keepGuildCharacters(guild.DiscordId)
+			t.keepGuildSubmissions(guild.DiscordId)
+			t.keepGuildTrash(guild.DiscordId)
+		}
+	})
+	return t.MemoryDB.RenameGuildStat(g, old, name)
+}
+
+func (t *memoryTx) ChangeGuildStatType(g uuid.UUID, s *database.Stat) (*database.Guild, error) {
+	t.save(func() { t.keepGuildAndTop(g) })
+	return t.MemoryDB.ChangeGuildStatType(g, s)
+}
+
+func (t *memoryTx) RemoveAllGuildStats(g uuid.UUID) (*database.Guild, error) {
+	t.save(func() { t.keepGuildAndTop(g) })
+	return t.MemoryDB.RemoveAllGuildStats(g)
+}
+
+func (t *memoryTx) AddUser(d string, g *database.GuildPermission) (*database.User, error) {
+	t.save(func() { t.keepUser(d) })
+	return t.MemoryDB.AddUser(d, g)
+}
+
+func (t *memoryTx) SetUserPermissions(u string, g *database.GuildPermission) (*database.User, error) {
+	t.save(func() { t.keepUser(u) })
+	return t.MemoryDB.SetUserPermissions(u, g)
+}
+
+func (t *memoryTx) SetUserSubGuild(u string, g *database.GuildPermission) (*database.User, error) {
+	t.save(func() { t.keepUser(u) })
+	return t.MemoryDB.SetUserSubGuild(u, g)
+}
+
+func (t *memoryTx) RemoveUserD(d string, g string) (*database.User, error) {
+	t.save(func() { t.keepUser(d) })
+	return t.MemoryDB.RemoveUserD(d, g)
+}
+
+func (t *memoryTx) EraseUserD(d string) (*database.User, error) {
+	t.save(func() { t.keepUser(d) })
+	return t.MemoryDB.EraseUserD(d)
+}
+
+func (t *memoryTx) AddCharacter(c *database.Character) (*database.Character, error) {
+	t.save(func() { t.keepCharacter(getCharacterId(c.GuildId, c.UserId, c.Name)) })
+	return t.MemoryDB.AddCharacter(c)
+}
+
+func (t *memoryTx) RenameCharacter(g string, u string, old string, name string) (*database.Character, error) {
+	t.save(func() {
+		t.keepCharacterN(g, u, old)
+		t.keepCharacter(getCharacterId(g, u, name))
+	})
+	return t.MemoryDB.RenameCharacter(g, u, old, name)
+}
+
+func (t *memoryTx) ChangeMainCharacter(g string, u string, name string) (*database.Character, error) {
+	t.save(func() { t.keepUserCharacters(g, u) })
+	return t.MemoryDB.ChangeMainCharacter(g, u, name)
+}
+
+func (t *memoryTx) SetCharacterStat(g string, u string, name string, s string, v interface{}) (*database.Character, error) {
+	t.save(func() { t.keepCharacterN(g, u, name) })
+	return t.MemoryDB.SetCharacterStat(g, u, name, s, v)
+}
+
+func (t *memoryTx) SetCharacterStatVersion(g string, u string, name string, stats map[string]*database.Stat, version int) (*database.Character, error) {
+	t.save(func() { t.keepCharacterN(g, u, name) })
+	return t.MemoryDB.SetCharacterStatVersion(g, u, name, stats, version)
+}
+
+func (t *memoryTx) ChangeCharacterOwner(g string, old string, name string, u string) (*database.Character, error) {
+	t.save(func() {
+		if c, err := t.getCharacter(g, old, name); err == nil {
+			name = c.Name
+		}
+		t.keepCharacter(getCharacterId(g, old, name))
+		t.keepCharacter(getCharacterId(g, u, name))
+	})
+	return t.MemoryDB.ChangeCharacterOwner(g, old, name, u)
+}
+
+func (t *memoryTx) RemoveCharacterStat(g string, u string, name string, s string) (*database.Character, error) {
+	t.save(func() { t.keepCharacterN(g, u, name) })
+	return t.MemoryDB.RemoveCharacterStat(g, u, name, s)
+}
+
+func (t *memoryTx) RemoveCharacter(g string, u string, name string) (*database.Character, error) {
+	t.save(func() { t.keepCharacterN(g, u, name) })
+	return t.MemoryDB.RemoveCharacter(g, u, name)
+}
+
+func (t *memoryTx) AddStatHistory(h *database.StatHistory) (*database.StatHistory, error) {
+	t.save(func() { t.keepCharacterN(h.GuildId, h.UserId, h.Character) })
+	return t.MemoryDB.AddStatHistory(h)
+}
+
+func (t *memoryTx) AddRole(r *database.Role) (*database.Role, error) {
+	t.save(func() { t.keepRole(getRoleId(r.GuildId, r.Id)) })
+	return t.MemoryDB.AddRole(r)
+}
+
+func (t *memoryTx) SetRolePermissions(g string, r string, p int) (*database.Role, error) {
+	t.save(func() { t.keepRole(getRoleId(g, r)) })
+	return t.MemoryDB.SetRolePermissions(g, r, p)
+}
+
+func (t *memoryTx) RemoveRole(g string, r string) (*database.Role, error) {
+	t.save(func() { t.keepRole(getRoleId(g, r)) })
+	return t.MemoryDB.RemoveRole(g, r)
+}
+
+func (t *memoryTx) AddMoney(m *database.Money) (*database.Money, error) {
+	t.save(func() { t.keepMoney(m.GuildId) })
+	return t.MemoryDB.AddMoney(m)
+}
+
+func (t *memoryTx) ChangeMoneyOwner(g string, u string) (*database.Money, error) {
+	t.save(func() { t.keepMoney(g) })
+	return t.MemoryDB.ChangeMoneyOwner(g, u)
+}
+
+func (t *memoryTx) SetMoneyValid(g string, tm time.Time) (*database.Money, error) {
+	t.save(func() { t.keepMoney(g) })
+	return t.MemoryDB.SetMoneyValid(g, tm)
+}
+
+func (t *memoryTx) AddAuditEntry(e *database.AuditEntry) (*database.AuditEntry, error) {
+	rv, err := t.MemoryDB.AddAuditEntry(e)
+	if err == nil {
+		t.save(func() { t.dropAuditEntry(rv.GuildId, rv.Id) })
+	}
+	return rv, err
+}
+
+func (t *memoryTx) AddTrashItem(it *database.TrashItem) (*database.TrashItem, error) {
+	rv, err := t.MemoryDB.AddTrashItem(it)
+	if err == nil {
+		t.save(func() { t.dropTrashItem(rv.GuildId, rv.Id) })
+	}
+	return rv, err
+}
+
+func (t *memoryTx) RemoveTrashItem(g string, id int) (*database.TrashItem, error) {
+	rv, err := t.MemoryDB.RemoveTrashItem(g, id)
+	if err == nil {
+		t.save(func() { t.restoreTrashItems(g, []*database.TrashItem{rv}) })
+	}
+	return rv, err
+}
+
+func (t *memoryTx) PurgeTrash(g string, before time.Time) (int, error) {
+	t.save(func() {
+		removed := make([]*database.TrashItem, 0, len(t.Trash[g]))
+		for _, it := range t.Trash[g] {
+			if it.Time.Before(before) {
+				removed = append(removed, it)
+			}
+		}
+		t.restoreTrashItems(g, removed)
+	})
+	return t.MemoryDB.PurgeTrash(g, before)
+}
+
+func (t *memoryTx) AddSubmission(s *database.Submission) (*database.Submission, error) {
+	rv, err := t.MemoryDB.AddSubmission(s)
+	if err == nil {
+		t.save(func() { t.dropSubmission(rv.GuildId, rv.Id) })
+	}
+	return rv, err
+}
+
+func (t *memoryTx) RemoveSubmission(g string, id int) (*database.Submission, error) {
+	rv, err := t.MemoryDB.RemoveSubmission(g, id)
+	if err == nil {
+		t.save(func() { t.restoreSubmission(rv) })
+	}
+	return rv, err
+}
@@ -18,8 +18,7 @@ func (udb *UserMemoryDb) AddUser(d string, gp *database.GuildPermission) (*datab
 	if user, ok := udb.UsersD[d]; ok {
 		if _, ok = user.Guilds[gp.TopGuild]; !ok {
 			user.Guilds[gp.TopGuild] = gp
-			tmp := *user
-			return &tmp, nil
+			return copyUser(user), nil
 		}
 
 		return nil, &database.Error{Code: database.UserAlreadyInGuild, Message: "The user is already registered in the guild"}
@@ -32,8 +31,7 @@ func (udb *UserMemoryDb) AddUser(d string, gp *database.GuildPermission) (*datab
 	}
 	udb.UsersD[d] = u
 
-	tmp := *u
-	return &tmp, nil
+	return copyUser(u), nil
 }
 
 func (udb *UserMemoryDb) GetUserD(d string) (*database.User, error) {
@@ -42,8 +40,7 @@ func (udb *UserMemoryDb) GetUserD(d string) (*database.User, error) {
 		return nil, err
 	}
 
-	tmp := *rv
-	return &tmp, nil
+	return copyUser(rv), nil
 }
 
 func (udb *UserMemoryDb) GetUsersInGuild(d string) ([]*database.User, error) {
@@ -53,8 +50,7 @@ func (udb *UserMemoryDb) GetUsersInGuild(d string) ([]*database.User, error) {
 	rv := make([]*database.User, 0, 100)
 	for _, u := range udb.UsersD {
 		if _, ok := u.Guilds[d]; ok {
-			tmp := *u
-			rv = append(rv, &tmp)
+			rv = append(rv, copyUser(u))
 		}
 	}
 
@@ -73,8 +69,7 @@ func (udb *UserMemoryDb) SetUserPermissions(u string, gp *database.GuildPermissi
 	}
 
 	curGp.Permissions = gp.Permissions
-	tmp := *user
-	return &tmp, nil
+	return copyUser(user), nil
 }
 
 func (udb *UserMemoryDb) SetUserSubGuild(u string, gp *database.GuildPermission) (*database.User, error) {
@@ -89,8 +84,7 @@ func (udb *UserMemoryDb) SetUserSubGuild(u string, gp *database.GuildPermission)
 	}
 
 	curGp.GuildId = gp.GuildId
-	tmp := *user
-	return &tmp, nil
+	return copyUser(user), nil
 }
 
 func (udb *UserMemoryDb) RemoveUserD(u string, g string) (*database.User, error) {
@@ -105,8 +99,7 @@ func (udb *UserMemoryDb) RemoveUserD(u string, g string) (*database.User, error)
 
 	delete(user.Guilds, g)
 
-	tmp := *user
-	return &tmp, nil
+	return copyUser(user), nil
 }
 
 func (udb *UserMemoryDb) EraseUserD(u string) (*database.User, error) {
@@ -119,8 +112,7 @@ func (udb *UserMemoryDb) EraseUserD(u string) (*database.User, error) {
 	}
 
 	delete(udb.UsersD, u)
-	tmp := *user
-	return &tmp, nil
+	return copyUser(user), nil
 }
 
 func (udb *UserMemoryDb) getUserD(d string) (*database.User, error) {
@@ -130,3 +122,13 @@ func (udb *UserMemoryDb) getUserD(d string) (*database.User, error) {
 
 	return nil, &database.Error{Code: database.UserNotFound, Message: "User was not found"}
 }
+
+func copyUser(u *database.User) *database.User {
+	rv := *u
+	rv.Guilds = make(map[string]*database.GuildPermission, len(u.Guilds))
+	for k, gp := range u.Guilds {
+		tmp := *gp
+		rv.Guilds[k] = &tmp
+	}
+	return &rv
+}
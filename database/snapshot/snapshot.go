@@ -0,0 +1,319 @@
+package snapshot
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+const (
+	prefix    = "snapshot-"
+	suffix    = ".snap"
+	tmpPrefix = ".snapshot-"
+	magic     = "DGSNAP02"
+	headerLen = len(magic) + sha256.Size + 16
+)
+
+// Journaled is implemented by providers which log changes made after the last snapshot
+type Journaled interface {
+	// Checkpoint passes exported database and the last journal position it covers to save
+	Checkpoint(save func(b []byte, seq uint64) error) error
+	// Compact drops journal entries up to seq
+	Compact(seq uint64) error
+	// Replay imports the snapshot (if any) and applies journal entries after seq on top of it
+	Replay(b []byte, seq uint64) error
+}
+
+// Snapshotter periodically stores Export() of the provider to a directory and restores it back at startup
+type Snapshotter struct {
+	prov     database.DataProvider
+	dir      string
+	keep     int
+	interval time.Duration
+	stop     chan bool
+	done     chan bool
+	mux      sync.Mutex
+}
+
+// constructor function. keep is the number of snapshots stored on disk
+func NewSnapshotter(prov database.DataProvider, dir string, keep int, interval time.Duration) *Snapshotter {
+	if keep < 1 {
+		keep = 1
+	}
+
+	return &Snapshotter{
+		prov:     prov,
+		dir:      dir,
+		keep:     keep,
+		interval: interval,
+	}
+}
+
+// Restore imports the newest valid snapshot. Corrupted snapshots are skipped in favor of older ones.
+// Journaled providers replay their journal on top of it. Returns empty string when there is no snapshot
+func (s *Snapshotter) Restore() (string, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	files, err := s.list()
+	if err != nil {
+		return "", err
+	}
+
+	var failed []string
+	for i := len(files) - 1; i >= 0; i-- {
+		b, seq, err := read(files[i])
+		if err == nil {
+			err = s.load(b, seq)
+		}
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%v: %v", filepath.Base(files[i]), err.Error()))
+			continue
+		}
+
+		return files[i], nil
+	}
+
+	if len(failed) != 0 {
+		return "", &database.Error{Code: database.IOErrorDuringImport, Message: "No valid snapshot found. " + strings.Join(failed, "; ")}
+	}
+
+	return "", s.load(nil, 0)
+}
+
+// Save writes a new snapshot and removes the ones exceeding the limit
+func (s *Snapshotter) Save() (string, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return "", err
+	}
+
+	name := filepath.Join(s.dir, fmt.Sprintf("%v%020d%v", prefix, time.Now().UnixNano(), suffix))
+	save := func(b []byte, seq uint64) error {
+		return write(s.dir, name, b, seq)
+	}
+
+	var err error
+	if j, ok := s.prov.(Journaled); ok {
+		err = j.Checkpoint(save)
+	} else {
+		var b []byte
+		if b, err = s.prov.Export(); err == nil {
+			err = save(b, 0)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if err = s.rotate(); err != nil {
+		return "", err
+	}
+
+	// journal is kept since the oldest snapshot, so changes are not lost if newer ones turn out to be corrupted
+	if j, ok := s.prov.(Journaled); ok {
+		if seq, ok, err := s.oldest(); err != nil {
+			return "", err
+		} else if ok {
+			return name, j.Compact(seq)
+		}
+	}
+
+	return name, nil
+}
+
+// Start runs periodic snapshots in background until Close is called
+func (s *Snapshotter) Start() {
+	s.stop = make(chan bool)
+	s.done = make(chan bool)
+
+	go func() {
+		defer close(s.done)
+		t := time.NewTicker(s.interval)
+		defer t.Stop()
+
+		for {
+			select {
+			case <-t.C:
+				if _, err := s.Save(); err != nil {
+					fmt.Println("Could not save snapshot:", err.Error())
+				}
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Close stops periodic snapshots and stores the final one
+func (s *Snapshotter) Close() error {
+	if s.stop != nil {
+		close(s.stop)
+		<-s.done
+		s.stop = nil
+	}
+
+	_, err := s.Save()
+	return err
+}
+
+func (s *Snapshotter) load(b []byte, seq uint64) error {
+	if j, ok := s.prov.(Journaled); ok {
+		return j.Replay(b, seq)
+	}
+	if b == nil {
+		return nil
+	}
+
+	return s.prov.Import(b)
+}
+
+func (s *Snapshotter) list() ([]string, error) {
+	entries, err := ioutil.ReadDir(s.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	rv := make([]string, 0, len(entries))
+	for _, e := range entries {
+		n := e.Name()
+		if !e.IsDir() && strings.HasPrefix(n, prefix) && strings.HasSuffix(n, suffix) {
+			rv = append(rv, filepath.Join(s.dir, n))
+		}
+	}
+	sort.Strings(rv)
+
+	return rv, nil
+}
+
+func (s *Snapshotter) rotate() error {
+	files, err := s.list()
+	if err != nil {
+		return err
+	}
+
+	for len(files) > s.keep {
+		if err = os.Remove(files[0]); err != nil {
+			return err
+		}
+		files = files[1:]
+	}
+
+	return nil
+}
+
+// oldest returns the journal position of the oldest snapshot with a readable header
+func (s *Snapshotter) oldest() (uint64, bool, error) {
+	files, err := s.list()
+	if err != nil {
+		return 0, false, err
+	}
+
+	for _, file := range files {
+		if seq, err := readSeq(file); err == nil {
+			return seq, true, nil
+		}
+	}
+
+	return 0, false, nil
+}
+
+func write(dir string, name string, b []byte, seq uint64) error {
+	sum := sha256.Sum256(b)
+	var size [16]byte
+	binary.BigEndian.PutUint64(size[:8], seq)
+	binary.BigEndian.PutUint64(size[8:], uint64(len(b)))
+
+	f, err := ioutil.TempFile(dir, tmpPrefix)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	for _, part := range [][]byte{[]byte(magic), sum[:], size[:], b} {
+		if _, err = f.Write(part); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	if err = f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+
+	if err = os.Rename(f.Name(), name); err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+func read(name string) ([]byte, uint64, error) {
+	b, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if len(b) < headerLen || string(b[:len(magic)]) != magic {
+		return nil, 0, &database.Error{Code: database.IOErrorDuringImport, Message: "Invalid snapshot header"}
+	}
+
+	sum := b[len(magic) : len(magic)+sha256.Size]
+	seq := binary.BigEndian.Uint64(b[len(magic)+sha256.Size : headerLen-8])
+	size := binary.BigEndian.Uint64(b[headerLen-8 : headerLen])
+	body := b[headerLen:]
+	if uint64(len(body)) != size {
+		return nil, 0, &database.Error{Code: database.IOErrorDuringImport, Message: "Snapshot is truncated"}
+	}
+
+	if actual := sha256.Sum256(body); !bytes.Equal(sum, actual[:]) {
+		return nil, 0, &database.Error{Code: database.IOErrorDuringImport, Message: "Snapshot checksum mismatch"}
+	}
+
+	return body, seq, nil
+}
+
+func readSeq(name string) (uint64, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	b := make([]byte, headerLen)
+	if _, err = io.ReadFull(f, b); err != nil || string(b[:len(magic)]) != magic {
+		return 0, &database.Error{Code: database.IOErrorDuringImport, Message: "Invalid snapshot header"}
+	}
+
+	return binary.BigEndian.Uint64(b[len(magic)+sha256.Size : headerLen-8]), nil
+}
+
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
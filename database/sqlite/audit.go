@@ -0,0 +1,106 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+func (s *SqliteDB) AddAuditEntry(e *database.AuditEntry) (*database.AuditEntry, error) {
+	var rv *database.AuditEntry
+	err := s.inTx(func(tx *sql.Tx) error {
+		newE := *e
+		newE.Id = 0
+		id, err := insertAuditEntry(tx, &newE)
+		if err != nil {
+			return err
+		}
+
+		newE.Id = int(id)
+		rv = &newE
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func (s *SqliteDB) GetAuditEntries(g string, f *database.AuditFilter) ([]*database.AuditEntry, error) {
+	where, args := "WHERE guild_id = ?", []interface{}{g}
+	if f.UserId != "" {
+		where += " AND (user_id = ? OR author_id = ?)"
+		args = append(args, f.UserId, f.UserId)
+	}
+	if f.Character != "" {
+		where += " AND character = ?"
+		args = append(args, f.Character)
+	}
+	if f.Stat != "" {
+		where += " AND stat = ?"
+		args = append(args, f.Stat)
+	}
+	if !f.From.IsZero() {
+		where += " AND time >= ?"
+		args = append(args, f.From.UnixNano())
+	}
+	if !f.To.IsZero() {
+		where += " AND time < ?"
+		args = append(args, f.To.UnixNano())
+	}
+
+	limit := f.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	where += " ORDER BY id DESC LIMIT ? OFFSET ?"
+	args = append(args, limit, f.Offset)
+
+	rv, err := queryAudit(s.q(), where, args...)
+	if err != nil {
+		return nil, dbErr(err)
+	}
+
+	return rv, nil
+}
+
+func queryAudit(q querier, where string, args ...interface{}) ([]*database.AuditEntry, error) {
+	rows, err := q.Query("SELECT id, guild_id, author_id, time, command, action, user_id, character, stat, target, before_value, after_value FROM audit "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rv := make([]*database.AuditEntry, 0, 10)
+	for rows.Next() {
+		var (
+			e database.AuditEntry
+			t int64
+		)
+		if err = rows.Scan(&e.Id, &e.GuildId, &e.AuthorId, &t, &e.Command, &e.Action, &e.UserId, &e.Character, &e.Stat, &e.Target, &e.Before, &e.After); err != nil {
+			return nil, err
+		}
+		e.Time = time.Unix(0, t)
+		rv = append(rv, &e)
+	}
+
+	return rv, rows.Err()
+}
+
+// insertAuditEntry keeps e.Id if it is set (e.g. on import)
+func insertAuditEntry(q querier, e *database.AuditEntry) (int64, error) {
+	var id interface{}
+	if e.Id != 0 {
+		id = e.Id
+	}
+
+	res, err := q.Exec("INSERT INTO audit(id, guild_id, author_id, time, command, action, user_id, character, stat, target, before_value, after_value) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		id, e.GuildId, e.AuthorId, e.Time.UnixNano(), e.Command, e.Action, e.UserId, e.Character, e.Stat, e.Target, e.Before, e.After)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.LastInsertId()
+}
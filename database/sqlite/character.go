@@ -0,0 +1,628 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+const charColumns = "c.id, c.guild_id, c.user_id, c.name, c.main, c.stat_version, s.stat, s.type, s.int_value, s.real_value, s.str_value"
+
+func (s *SqliteDB) AddCharacter(c *database.Character) (*database.Character, error) {
+	var rv *database.Character
+	err := s.inTx(func(tx *sql.Tx) error {
+		if _, err := getCharacterExact(tx, c.GuildId, c.UserId, c.Name); err == nil {
+			return &database.Error{Code: database.CharacterNameTaken, Message: fmt.Sprintf("User already has character with name %v", c.Name)}
+		} else if !isNotFound(err) {
+			return err
+		}
+
+		if err := insertCharacter(tx, c); err != nil {
+			return err
+		}
+
+		tmp, err := getCharacterExact(tx, c.GuildId, c.UserId, c.Name)
+		if err != nil {
+			return err
+		}
+
+		rv = tmp.Character
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func (s *SqliteDB) GetCharacters(g string, u string) ([]*database.Character, error) {
+	return queryCharacters(s.q(), "WHERE guild_id = ? AND user_id = ?", "", g, u)
+}
+
+func (s *SqliteDB) GetCharactersSorted(g string, st string, t int, asc bool, limit int) ([]*database.Character, error) {
+	col, t, ok := statColumn(t)
+	if !ok {
+		return nil, &database.Error{Code: database.UnknownStatType, Message: "Stat type for " + st + " is not defined"}
+	}
+
+	dir := "DESC"
+	if asc {
+		dir = "ASC"
+	}
+	if limit <= 0 {
+		limit = -1
+	}
+
+	inner := fmt.Sprintf(`SELECT characters.*, k.%[1]v AS sort_key FROM characters
+		LEFT JOIN character_stats AS k ON k.character_id = characters.id AND k.stat = ? AND k.type = ?
+		WHERE guild_id = ? ORDER BY sort_key IS NULL, sort_key %[2]v, characters.id LIMIT ?`, col, dir)
+	query := fmt.Sprintf(`SELECT %[1]v FROM (%[2]v) AS c
+		LEFT JOIN character_stats AS s ON s.character_id = c.id
+		ORDER BY c.sort_key IS NULL, c.sort_key %[3]v, c.id`, charColumns, inner, dir)
+
+	return scanCharacters(s.q(), query, st, t, g, limit)
+}
+
+var queryOps = map[int]string{
+	database.OpEq:        "=",
+	database.OpNotEq:     "<>",
+	database.OpLess:      "<",
+	database.OpLessEq:    "<=",
+	database.OpGreater:   ">",
+	database.OpGreaterEq: ">=",
+}
+
+// QueryCharacters joins a row of character_stats per sort key and filter, so all of them are checked by SQLite
+func (s *SqliteDB) QueryCharacters(g string, q *database.CharacterQuery) ([]*database.Character, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	if q.OwnerGuilds != nil && len(q.OwnerGuilds) == 0 {
+		return []*database.Character{}, nil
+	}
+
+	from, order, args, err := characterQuery(g, q)
+	if err != nil {
+		return nil, err
+	}
+
+	cols, outer := "characters.*", ""
+	for i, k := range q.Order {
+		col, _, _ := statColumn(k.Type)
+		cols += fmt.Sprintf(", k%[1]v.%[2]v AS sort_key%[1]v", i, col)
+		outer += fmt.Sprintf("c.sort_key%[1]v IS NULL, c.sort_key%[1]v %[2]v, ", i, sortDirection(k))
+	}
+
+	limit := q.Limit
+	if limit <= 0 {
+		limit = -1
+	}
+	args = append(args, limit)
+
+	query := fmt.Sprintf(`SELECT %[1]v FROM (SELECT %[2]v FROM %[3]v ORDER BY %[4]v LIMIT ?) AS c
+		LEFT JOIN character_stats AS s ON s.character_id = c.id
+		ORDER BY %[5]vc.name, c.user_id, c.id`, charColumns, cols, from, order, outer)
+
+	rv, err := scanCharacters(s.q(), query, args...)
+	if err != nil {
+		return nil, dbErr(err)
+	}
+	return rv, nil
+}
+
+// RankCharacter numbers selected characters with a window function and reads the character with its neighbours only
+func (s *SqliteDB) RankCharacter(g string, u string, name string, q *database.CharacterQuery) (*database.CharacterRank, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+
+	c, err := getCharacter(s.q(), g, u, name)
+	if err != nil {
+		return nil, err
+	}
+	notRanked := &database.Error{Code: database.CharacterNotFound, Message: fmt.Sprintf("Character %v is not ranked", c.Name)}
+	if q.OwnerGuilds != nil && len(q.OwnerGuilds) == 0 {
+		return nil, notRanked
+	}
+
+	from, order, args, err := characterQuery(g, q)
+	if err != nil {
+		return nil, err
+	}
+	args = append(args, c.UserId, c.Name)
+
+	query := fmt.Sprintf(`WITH ranked AS (SELECT characters.user_id, characters.name, ROW_NUMBER() OVER (ORDER BY %[1]v) AS pos, COUNT(*) OVER () AS total FROM %[2]v)
+		SELECT r.user_id, r.name, r.pos, r.total FROM ranked AS r JOIN ranked AS t ON r.pos BETWEEN t.pos - 1 AND t.pos + 1
+		WHERE t.user_id = ? AND t.name = ? ORDER BY r.pos`, order, from)
+
+	rows, err := s.q().Query(query, args...)
+	if err != nil {
+		return nil, dbErr(err)
+	}
+	type position struct {
+		user, name string
+		pos, total int
+	}
+	found := make([]*position, 0, 3)
+	for rows.Next() {
+		p := &position{}
+		if err := rows.Scan(&p.user, &p.name, &p.pos, &p.total); err != nil {
+			rows.Close()
+			return nil, dbErr(err)
+		}
+		found = append(found, p)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, dbErr(err)
+	}
+
+	rv := &database.CharacterRank{}
+	for _, p := range found {
+		pc, err := getCharacterExact(s.q(), g, p.user, p.name)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case p.user == c.UserId && p.name == c.Name:
+			rv.Character, rv.Rank, rv.Total = pc.Character, p.pos, p.total
+		case rv.Character == nil:
+			rv.Above = pc.Character
+		default:
+			rv.Below = pc.Character
+		}
+	}
+	if rv.Character == nil {
+		return nil, notRanked
+	}
+	return rv, nil
+}
+
+// characterQuery builds FROM clause selecting characters of guild g by q and ORDER BY clause of q.
+// Sort key of q.Order[i] is joined as k<i>
+func characterQuery(g string, q *database.CharacterQuery) (from string, order string, args []interface{}, err error) {
+	joins, where := "", "WHERE characters.guild_id = ?"
+	args, whereArgs := make([]interface{}, 0, 10), []interface{}{g}
+	for i, k := range q.Order {
+		col, t, _ := statColumn(k.Type)
+		joins += fmt.Sprintf(" LEFT JOIN character_stats AS k%[1]v ON k%[1]v.character_id = characters.id AND k%[1]v.stat = ? AND k%[1]v.type = ?", i)
+		args = append(args, k.Stat, t)
+		order += fmt.Sprintf("k%[1]v.%[2]v IS NULL, k%[1]v.%[2]v %[3]v, ", i, col, sortDirection(k))
+	}
+	order += "characters.name, characters.user_id"
+
+	for i, f := range q.Filters {
+		col, t, _ := statColumn(f.Type)
+		_, intVal, realVal, strVal, err := encodeStat(f.Stat, f.Value)
+		if err != nil {
+			return "", "", nil, err
+		}
+		val := map[string]interface{}{"int_value": intVal, "real_value": realVal, "str_value": strVal}[col]
+
+		joins += fmt.Sprintf(" JOIN character_stats AS f%[1]v ON f%[1]v.character_id = characters.id AND f%[1]v.stat = ? AND f%[1]v.type = ? AND f%[1]v.%[2]v %[3]v ?",
+			i, col, queryOps[f.Op])
+		args = append(args, f.Stat, t, val)
+	}
+	if q.Main != nil {
+		where += " AND characters.main = ?"
+		whereArgs = append(whereArgs, *q.Main)
+	}
+	if q.OwnerGuilds != nil {
+		joins += " JOIN user_guilds AS ug ON ug.user_id = characters.user_id AND ug.top_guild = characters.guild_id"
+		where += " AND ug.guild_id IN (?" + strings.Repeat(", ?", len(q.OwnerGuilds)-1) + ")"
+		for _, og := range q.OwnerGuilds {
+			whereArgs = append(whereArgs, og.String())
+		}
+	}
+
+	return "characters" + joins + " " + where, order, append(args, whereArgs...), nil
+}
+
+func sortDirection(k *database.SortKey) string {
+	if k.Asc {
+		return "ASC"
+	}
+	return "DESC"
+}
+
+func (s *SqliteDB) GetGuildCharacters(g string) ([]*database.Character, error) {
+	return queryCharacters(s.q(), "WHERE guild_id = ?", "", g)
+}
+
+func (s *SqliteDB) GetCharactersOutdated(g string, v int) ([]*database.Character, error) {
+	return queryCharacters(s.q(), "WHERE guild_id = ? AND stat_version < ?", "", g, v)
+}
+
+func (s *SqliteDB) GetCharactersByName(g string, n string) ([]*database.Character, error) {
+	return queryCharacters(s.q(), "WHERE guild_id = ? AND name = ?", "", g, n)
+}
+
+func (s *SqliteDB) GetMainCharacter(g string, u string) (*database.Character, error) {
+	return getMainCharacter(s.q(), g, u)
+}
+
+func (s *SqliteDB) GetCharacter(g string, u string, name string) (*database.Character, error) {
+	c, err := getCharacter(s.q(), g, u, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Character, nil
+}
+
+func (s *SqliteDB) RenameCharacter(g string, u string, old string, name string) (*database.Character, error) {
+	return s.updateCharacter(g, u, old, func(tx *sql.Tx, c *character) error {
+		if _, err := getCharacter(tx, g, u, name); err == nil {
+			return &database.Error{Code: database.CharacterNameTaken, Message: "Character with that name already exists"}
+		} else if !isNotFound(err) {
+			return err
+		}
+
+		_, err := tx.Exec("UPDATE characters SET name = ? WHERE id = ?", name, c.id)
+		return err
+	})
+}
+
+func (s *SqliteDB) ChangeMainCharacter(g string, u string, name string) (*database.Character, error) {
+	return s.updateCharacter(g, u, name, func(tx *sql.Tx, c *character) error {
+		if _, err := tx.Exec("UPDATE characters SET main = 0 WHERE guild_id = ? AND user_id = ?", g, u); err != nil {
+			return err
+		}
+
+		_, err := tx.Exec("UPDATE characters SET main = 1 WHERE id = ?", c.id)
+		return err
+	})
+}
+
+func (s *SqliteDB) SetCharacterStat(g string, u string, name string, st string, v interface{}) (*database.Character, error) {
+	return s.updateCharacter(g, u, name, func(tx *sql.Tx, c *character) error {
+		return setCharacterStat(tx, c.id, st, v)
+	})
+}
+
+func (s *SqliteDB) SetCharacterStatVersion(g string, u string, name string, stats map[string]*database.Stat, version int) (*database.Character, error) {
+	return s.updateCharacter(g, u, name, func(tx *sql.Tx, c *character) error {
+		if c.StatVersion >= version {
+			return nil
+		}
+
+		for k, v := range c.Body {
+			st, ok := stats[k]
+			if ok {
+				var err error
+				if ok, err = st.IsValid(v); err != nil {
+					return err
+				}
+			}
+
+			if !ok {
+				if _, err := tx.Exec("DELETE FROM character_stats WHERE character_id = ? AND stat = ?", c.id, k); err != nil {
+					return err
+				}
+				delete(c.Body, k)
+			}
+		}
+
+		for _, st := range stats {
+			if _, ok := c.Body[st.ID]; ok {
+				continue
+			}
+
+			v, err := st.DefaultValue()
+			if err != nil {
+				return err
+			}
+			if v == nil {
+				continue
+			}
+			if err := setCharacterStat(tx, c.id, st.ID, v); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.Exec("UPDATE characters SET stat_version = ? WHERE id = ?", version, c.id)
+		return err
+	})
+}
+
+func (s *SqliteDB) ChangeCharacterOwner(g string, old string, name string, u string) (*database.Character, error) {
+	return s.updateCharacter(g, old, name, func(tx *sql.Tx, c *character) error {
+		if _, err := getCharacter(tx, g, u, c.Name); err == nil {
+			return &database.Error{Code: database.UserHasCharacter, Message: fmt.Sprintf("Target user already has character with name '%v'", name)}
+		} else if !isNotFound(err) {
+			return err
+		}
+
+		_, err := tx.Exec("UPDATE characters SET user_id = ? WHERE id = ?", u, c.id)
+		return err
+	})
+}
+
+func (s *SqliteDB) RemoveCharacterStat(g string, u string, name string, st string) (*database.Character, error) {
+	return s.updateCharacter(g, u, name, func(tx *sql.Tx, c *character) error {
+		_, err := tx.Exec("DELETE FROM character_stats WHERE character_id = ? AND stat = ?", c.id, st)
+		return err
+	})
+}
+
+func (s *SqliteDB) RemoveCharacter(g string, u string, name string) (*database.Character, error) {
+	var rv *database.Character
+	err := s.inTx(func(tx *sql.Tx) error {
+		c, err := getCharacter(tx, g, u, name)
+		if err != nil {
+			if isNotFound(err) {
+				return nil
+			}
+			return err
+		}
+
+		if _, err = tx.Exec("DELETE FROM characters WHERE id = ?", c.id); err != nil {
+			return err
+		}
+
+		rv = c.Character
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+// character carries the row id along with the data so updates don't need
+// to look it up again
+type character struct {
+	*database.Character
+	id int64
+}
+
+// updateCharacter runs f over the character and returns the result re-read from the database
+func (s *SqliteDB) updateCharacter(g string, u string, name string, f func(*sql.Tx, *character) error) (*database.Character, error) {
+	var rv *database.Character
+	err := s.inTx(func(tx *sql.Tx) error {
+		c, err := getCharacter(tx, g, u, name)
+		if err != nil {
+			return err
+		}
+
+		if err = f(tx, c); err != nil {
+			return err
+		}
+
+		cs, err := queryCharacters(tx, "WHERE id = ?", "", c.id)
+		if err != nil {
+			return err
+		}
+		if len(cs) == 0 {
+			return &database.Error{Code: database.InvalidDatabaseState, Message: "Character disappeared during update"}
+		}
+
+		rv = cs[0]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func getCharacter(q querier, g string, u string, name string) (*character, error) {
+	if name == "" {
+		return getMainCharacterRow(q, g, u)
+	}
+
+	return getCharacterExact(q, g, u, name)
+}
+
+func getCharacterExact(q querier, g string, u string, name string) (*character, error) {
+	cs, err := queryCharacterRows(q, "WHERE guild_id = ? AND user_id = ? AND name = ?", "", g, u, name)
+	if err != nil {
+		return nil, err
+	}
+	if len(cs) == 0 {
+		return nil, &database.Error{Code: database.CharacterNotFound, Message: fmt.Sprintf("Character with name %v was not found", name)}
+	}
+
+	return cs[0], nil
+}
+
+func getMainCharacter(q querier, g string, u string) (*database.Character, error) {
+	c, err := getMainCharacterRow(q, g, u)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Character, nil
+}
+
+func getMainCharacterRow(q querier, g string, u string) (*character, error) {
+	cs, err := queryCharacterRows(q, "WHERE guild_id = ? AND user_id = ?", "ORDER BY main DESC, id LIMIT 1", g, u)
+	if err != nil {
+		return nil, err
+	}
+	if len(cs) == 0 {
+		return nil, &database.Error{Code: database.CharacterNotFound, Message: fmt.Sprintf("No Characters found")}
+	}
+
+	return cs[0], nil
+}
+
+func queryCharacters(q querier, where string, order string, args ...interface{}) ([]*database.Character, error) {
+	cs, err := queryCharacterRows(q, where, order, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]*database.Character, len(cs))
+	for i, c := range cs {
+		rv[i] = c.Character
+	}
+
+	return rv, nil
+}
+
+func queryCharacterRows(q querier, where string, order string, args ...interface{}) ([]*character, error) {
+	if order == "" {
+		order = "ORDER BY id"
+	}
+
+	inner := "SELECT * FROM characters " + where + " " + order
+	return scanCharacterRows(q, "SELECT "+charColumns+" FROM ("+inner+") AS c LEFT JOIN character_stats AS s ON s.character_id = c.id ORDER BY c.main DESC, c.id", args...)
+}
+
+func scanCharacters(q querier, query string, args ...interface{}) ([]*database.Character, error) {
+	cs, err := scanCharacterRows(q, query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]*database.Character, len(cs))
+	for i, c := range cs {
+		rv[i] = c.Character
+	}
+
+	return rv, nil
+}
+
+// scanCharacterRows expects charColumns with all stats of a character
+// coming in consecutive rows
+func scanCharacterRows(q querier, query string, args ...interface{}) ([]*character, error) {
+	rows, err := q.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rv := make([]*character, 0, 10)
+	var cur *character
+	for rows.Next() {
+		var (
+			id       int64
+			c        database.Character
+			stat     sql.NullString
+			statType sql.NullInt64
+			intVal   sql.NullInt64
+			realVal  sql.NullFloat64
+			strVal   sql.NullString
+		)
+		if err = rows.Scan(&id, &c.GuildId, &c.UserId, &c.Name, &c.Main, &c.StatVersion, &stat, &statType, &intVal, &realVal, &strVal); err != nil {
+			return nil, err
+		}
+
+		if cur == nil || cur.id != id {
+			c.Body = make(map[string]interface{})
+			cur = &character{Character: &c, id: id}
+			rv = append(rv, cur)
+		}
+
+		if !stat.Valid {
+			continue
+		}
+
+		if v, ok := decodeStat(statType.Int64, intVal, realVal, strVal); ok {
+			cur.Body[stat.String] = v
+		}
+	}
+
+	return rv, rows.Err()
+}
+
+func insertCharacter(q querier, c *database.Character) error {
+	res, err := q.Exec("INSERT INTO characters(guild_id, user_id, name, main, stat_version) VALUES (?, ?, ?, ?, ?)",
+		c.GuildId, c.UserId, c.Name, c.Main, c.StatVersion)
+	if err != nil {
+		return err
+	}
+
+	id, err := res.LastInsertId()
+	if err != nil {
+		return err
+	}
+
+	for k, v := range c.Body {
+		if err = setCharacterStat(q, id, k, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func setCharacterStat(q querier, id int64, st string, v interface{}) error {
+	t, intVal, realVal, strVal, err := encodeStat(st, v)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec(`INSERT INTO character_stats(character_id, stat, type, int_value, real_value, str_value) VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(character_id, stat) DO UPDATE SET type = excluded.type, int_value = excluded.int_value,
+		real_value = excluded.real_value, str_value = excluded.str_value`,
+		id, st, t, intVal, realVal, strVal)
+	return err
+}
+
+// encodeStat splits a value into type and column values of stat tables. Enum values are stored as strings
+func encodeStat(st string, v interface{}) (t int, intVal interface{}, realVal interface{}, strVal interface{}, err error) {
+	switch val := v.(type) {
+	case int:
+		t, intVal = database.Number, val
+	case string:
+		t, strVal = database.Str, val
+	case float64:
+		t, realVal = database.Float, val
+	case bool:
+		t, intVal = database.Bool, 0
+		if val {
+			intVal = 1
+		}
+	case time.Time:
+		t, intVal = database.Date, val.Unix()
+	case time.Duration:
+		t, intVal = database.Duration, int64(val)
+	default:
+		err = &database.Error{Code: database.UnknownStatType, Message: fmt.Sprintf("Value type for %v is not supported", st)}
+	}
+
+	return
+}
+
+func decodeStat(t int64, intVal sql.NullInt64, realVal sql.NullFloat64, strVal sql.NullString) (interface{}, bool) {
+	switch t {
+	case database.Number:
+		return int(intVal.Int64), true
+	case database.Str:
+		return strVal.String, true
+	case database.Float:
+		return realVal.Float64, true
+	case database.Bool:
+		return intVal.Int64 != 0, true
+	case database.Date:
+		return time.Unix(intVal.Int64, 0).UTC(), true
+	case database.Duration:
+		return time.Duration(intVal.Int64), true
+	}
+
+	return nil, false
+}
+
+// statColumn returns the column and stored type used for sorting by stat type t
+func statColumn(t int) (string, int, bool) {
+	switch t {
+	case database.Number, database.Bool, database.Date, database.Duration:
+		return "int_value", t, true
+	case database.Float:
+		return "real_value", t, true
+	case database.Str:
+		return "str_value", t, true
+	case database.Enum:
+		return "str_value", database.Str, true
+	}
+
+	return "", 0, false
+}
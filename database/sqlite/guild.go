@@ -0,0 +1,574 @@
+package sqlite
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+const guildColumns = "guild_id, parent_id, top_level_parent_id, discord_id, name, default_stat, stat_version"
+
+func (s *SqliteDB) AddGuild(g *database.Guild) (*database.Guild, error) {
+	var rv *database.Guild
+	err := s.inTx(func(tx *sql.Tx) error {
+		newG := *g
+		newG.Stats = nil
+		newG.ChildNames = nil
+		if newG.GuildId == uuid.Nil {
+			newG.GuildId = uuid.New()
+		} else if _, err := getGuild(tx, newG.GuildId); err == nil {
+			return &database.Error{Code: database.InvalidGuildDefinition, Message: "Guild ID is already taken"}
+		} else if !isNotFound(err) {
+			return err
+		}
+
+		if g.DiscordId != "" {
+			if _, err := getGuildD(tx, g.DiscordId); err == nil {
+				return &database.Error{Code: database.GuildAlreadyRegistered, Message: fmt.Sprintf("Guild '%v' is already registered", g.DiscordId)}
+			} else if !isNotFound(err) {
+				return err
+			}
+
+			newG.ParentId = uuid.Nil
+			newG.TopLevelParentId = newG.GuildId
+			newG.StatVersion = 0
+		} else {
+			p, err := getGuild(tx, g.ParentId)
+			if err != nil {
+				if isNotFound(err) {
+					return &database.Error{Code: database.InvalidGuildDefinition, Message: "Invalid parent guild ID"}
+				}
+				return err
+			}
+
+			if p.DiscordId != "" {
+				newG.TopLevelParentId = p.GuildId
+			} else {
+				newG.TopLevelParentId = p.TopLevelParentId
+			}
+
+			taken, err := childNameTaken(tx, newG.TopLevelParentId, g.Name)
+			if err != nil {
+				return err
+			}
+			if taken {
+				return &database.Error{Code: database.SubguildNameTaken, Message: fmt.Sprintf("Sub-Guild name '%v' is already taken", g.Name)}
+			}
+		}
+
+		if err := insertGuild(tx, &newG); err != nil {
+			return err
+		}
+
+		var err error
+		rv, err = getGuild(tx, newG.GuildId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func (s *SqliteDB) GetGuild(g uuid.UUID) (*database.Guild, error) {
+	return getGuild(s.q(), g)
+}
+
+func (s *SqliteDB) GetGuildD(d string) (*database.Guild, error) {
+	return getGuildD(s.q(), d)
+}
+
+func (s *SqliteDB) GetGuildN(p string, n string) (*database.Guild, error) {
+	parent, err := getGuildD(s.q(), p)
+	if err != nil {
+		if isNotFound(err) {
+			return nil, &database.Error{Code: database.GuildNotFound, Message: "Parent guild was not found"}
+		}
+		return nil, err
+	}
+
+	return getSingleGuild(s.q(), "WHERE top_level_parent_id = ? AND name = ?", parent.GuildId.String(), n)
+}
+
+func (s *SqliteDB) GetSubGuilds(g uuid.UUID) (map[uuid.UUID]*database.Guild, error) {
+	if _, err := getGuild(s.q(), g); err != nil {
+		return nil, err
+	}
+
+	gs, err := queryGuilds(s.q(), `WHERE guild_id IN (
+		WITH RECURSIVE sub(id) AS (
+			SELECT ?
+			UNION
+			SELECT guilds.guild_id FROM guilds JOIN sub ON guilds.parent_id = sub.id
+		)
+		SELECT id FROM sub)`, g.String())
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make(map[uuid.UUID]*database.Guild, len(gs))
+	for _, sg := range gs {
+		rv[sg.GuildId] = sg
+	}
+
+	return rv, nil
+}
+
+func (s *SqliteDB) RenameGuild(g uuid.UUID, name string) (*database.Guild, error) {
+	var rv *database.Guild
+	err := s.inTx(func(tx *sql.Tx) error {
+		guild, err := getGuild(tx, g)
+		if err != nil {
+			return err
+		}
+
+		if guild.Name == name {
+			rv = guild
+			return nil
+		}
+
+		taken, err := childNameTaken(tx, guild.TopLevelParentId, name)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return &database.Error{Code: database.SubguildNameTaken, Message: fmt.Sprintf("Sub-Guild name '%v' is already taken", name)}
+		}
+
+		if _, err = tx.Exec("UPDATE guilds SET name = ? WHERE guild_id = ?", name, g.String()); err != nil {
+			return err
+		}
+
+		rv, err = getGuild(tx, g)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func (s *SqliteDB) MoveGuild(g uuid.UUID, p uuid.UUID) (*database.Guild, error) {
+	var rv *database.Guild
+	err := s.inTx(func(tx *sql.Tx) error {
+		if _, err := getGuild(tx, g); err != nil {
+			return err
+		}
+
+		if _, err := getGuild(tx, p); err != nil {
+			if isNotFound(err) {
+				return &database.Error{Code: database.GuildNotFound, Message: "Parent guild was not found"}
+			}
+			return err
+		}
+
+		if _, err := tx.Exec("UPDATE guilds SET parent_id = ? WHERE guild_id = ?", p.String(), g.String()); err != nil {
+			return err
+		}
+
+		var err error
+		rv, err = getGuild(tx, g)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func (s *SqliteDB) RemoveGuild(g uuid.UUID) (*database.Guild, error) {
+	var rv *database.Guild
+	err := s.inTx(func(tx *sql.Tx) error {
+		var err error
+		rv, err = getGuild(tx, g)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM guilds WHERE guild_id = ?", g.String())
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func (s *SqliteDB) RemoveGuildD(d string) (*database.Guild, error) {
+	guild, err := getGuildD(s.q(), d)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.RemoveGuild(guild.GuildId)
+}
+
+func (s *SqliteDB) AddGuildStat(g uuid.UUID, st *database.Stat) (*database.Guild, error) {
+	return s.updateGuildStats(g, func(tx *sql.Tx, guild *database.Guild) error {
+		if et, ok := guild.Stats[st.ID]; ok {
+			if et.Type != st.Type {
+				return &database.Error{Code: database.StatNameConflict, Message: fmt.Sprintf("Stat with same name (%v) but different type (%v) found", st.ID, et.Type)}
+			}
+
+			_, err := tx.Exec(`UPDATE guild_stats SET description = ?, enum_values = ?, min_value = ?, max_value = ?, pattern = ?, max_length = ?, required = ?,
+				write_policy = ?, review = ?, formula = ?, category = ?, display_order = ? WHERE guild_id = ? AND stat_id = ?`,
+				st.Description, strings.Join(st.Values, "\n"), st.Min, st.Max, st.Pattern, st.MaxLength, st.Required, st.Write, st.Review, st.Formula,
+				st.Category, st.Order, g.String(), st.ID)
+			return err
+		}
+
+		if err := insertGuildStat(tx, g, st); err != nil {
+			return err
+		}
+
+		def := guild.DefaultStat
+		if len(guild.Stats) == 0 && guild.DiscordId != "" {
+			def = st.ID
+		}
+
+		if _, err := tx.Exec("UPDATE guilds SET stat_version = stat_version + 1, default_stat = ? WHERE guild_id = ?", def, g.String()); err != nil {
+			return err
+		}
+		return bumpTopStatVersion(tx, guild)
+	})
+}
+
+func (s *SqliteDB) SetDefaultGuildStat(g uuid.UUID, sn string) (*database.Guild, error) {
+	return s.updateGuildStats(g, func(tx *sql.Tx, guild *database.Guild) error {
+		if err := topLevelOnly(guild); err != nil {
+			return err
+		}
+		if _, ok := guild.Stats[sn]; !ok {
+			return &database.Error{Code: database.StatNotFound, Message: "Stat was not found"}
+		}
+
+		_, err := tx.Exec("UPDATE guilds SET default_stat = ? WHERE guild_id = ?", sn, g.String())
+		return err
+	})
+}
+
+func (s *SqliteDB) RemoveGuildStat(g uuid.UUID, n string) (*database.Guild, error) {
+	return s.updateGuildStats(g, func(tx *sql.Tx, guild *database.Guild) error {
+		if _, ok := guild.Stats[n]; !ok {
+			return &database.Error{Code: database.StatNotFound, Message: "Stat was not found"}
+		}
+
+		if _, err := tx.Exec("DELETE FROM guild_stats WHERE guild_id = ? AND stat_id = ?", g.String(), n); err != nil {
+			return err
+		}
+
+		def := guild.DefaultStat
+		if n == def {
+			def = ""
+			err := tx.QueryRow("SELECT stat_id FROM guild_stats WHERE guild_id = ? ORDER BY stat_id LIMIT 1", g.String()).Scan(&def)
+			if err != nil && err != sql.ErrNoRows {
+				return err
+			}
+		}
+
+		if _, err := tx.Exec("UPDATE guilds SET stat_version = stat_version + 1, default_stat = ? WHERE guild_id = ?", def, g.String()); err != nil {
+			return err
+		}
+		return bumpTopStatVersion(tx, guild)
+	})
+}
+
+// RenameGuildStat moves values of all characters of the top-level guild, stat names are unique in the hierarchy
+func (s *SqliteDB) RenameGuildStat(g uuid.UUID, old string, name string) (*database.Guild, error) {
+	return s.updateGuildStats(g, func(tx *sql.Tx, guild *database.Guild) error {
+		if _, ok := guild.Stats[old]; !ok {
+			return &database.Error{Code: database.StatNotFound, Message: "Stat was not found"}
+		}
+		if _, ok := guild.Stats[name]; ok {
+			return &database.Error{Code: database.StatNameConflict, Message: fmt.Sprintf("Stat with name %v already exists", name)}
+		}
+
+		if _, err := tx.Exec("UPDATE guild_stats SET stat_id = ? WHERE guild_id = ? AND stat_id = ?", name, g.String(), old); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(`UPDATE guilds SET stat_version = stat_version + 1,
+			default_stat = CASE WHEN default_stat = ? THEN ? ELSE default_stat END WHERE guild_id = ?`, old, name, g.String()); err != nil {
+			return err
+		}
+		if err := bumpTopStatVersion(tx, guild); err != nil {
+			return err
+		}
+
+		top := guild
+		if guild.DiscordId == "" {
+			var err error
+			if top, err = getGuild(tx, guild.TopLevelParentId); err != nil {
+				return err
+			}
+		}
+
+		// values left from a removed stat with the new name are overwritten
+		chars := "SELECT id FROM characters WHERE guild_id = ?"
+		if _, err := tx.Exec("DELETE FROM character_stats WHERE stat = ? AND character_id IN ("+chars+")", name, top.DiscordId); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE character_stats SET stat = ? WHERE stat = ? AND character_id IN ("+chars+")", name, old, top.DiscordId); err != nil {
+			return err
+		}
+		if _, err := tx.Exec("UPDATE stat_history SET stat = ? WHERE stat = ? AND character_id IN ("+chars+")", name, old, top.DiscordId); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("UPDATE submissions SET stat = ? WHERE guild_id = ? AND stat = ?", name, top.DiscordId, old); err != nil {
+			return err
+		}
+		return renameTrashedStat(tx, top.DiscordId, old, name)
+	})
+}
+
+func (s *SqliteDB) ChangeGuildStatType(g uuid.UUID, st *database.Stat) (*database.Guild, error) {
+	return s.updateGuildStats(g, func(tx *sql.Tx, guild *database.Guild) error {
+		if _, ok := guild.Stats[st.ID]; !ok {
+			return &database.Error{Code: database.StatNotFound, Message: "Stat was not found"}
+		}
+
+		if _, err := tx.Exec("DELETE FROM guild_stats WHERE guild_id = ? AND stat_id = ?", g.String(), st.ID); err != nil {
+			return err
+		}
+		if err := insertGuildStat(tx, g, st); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("UPDATE guilds SET stat_version = stat_version + 1 WHERE guild_id = ?", g.String()); err != nil {
+			return err
+		}
+		return bumpTopStatVersion(tx, guild)
+	})
+}
+
+func (s *SqliteDB) RemoveAllGuildStats(g uuid.UUID) (*database.Guild, error) {
+	return s.updateGuildStats(g, func(tx *sql.Tx, guild *database.Guild) error {
+		if _, err := tx.Exec("DELETE FROM guild_stats WHERE guild_id = ?", g.String()); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("UPDATE guilds SET stat_version = stat_version + 1, default_stat = '' WHERE guild_id = ?", g.String()); err != nil {
+			return err
+		}
+		return bumpTopStatVersion(tx, guild)
+	})
+}
+
+func (s *SqliteDB) updateGuildStats(g uuid.UUID, f func(tx *sql.Tx, guild *database.Guild) error) (*database.Guild, error) {
+	var rv *database.Guild
+	err := s.inTx(func(tx *sql.Tx) error {
+		guild, err := getGuild(tx, g)
+		if err != nil {
+			return err
+		}
+
+		if err = f(tx, guild); err != nil {
+			return err
+		}
+
+		rv, err = getGuild(tx, g)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func topLevelOnly(guild *database.Guild) error {
+	if guild.DiscordId == "" {
+		return &database.Error{Code: database.GuildLevelError, Message: "Only top-level guild stats are supported right now"}
+	}
+
+	return nil
+}
+
+// bumpTopStatVersion makes characters update their stats. Characters check version of the top-level guild,
+// so it is increased for sub-guild stat changes as well
+func bumpTopStatVersion(q querier, guild *database.Guild) error {
+	if guild.DiscordId != "" {
+		return nil
+	}
+
+	_, err := q.Exec("UPDATE guilds SET stat_version = stat_version + 1 WHERE guild_id = ?", guild.TopLevelParentId.String())
+	return err
+}
+
+func getGuild(q querier, g uuid.UUID) (*database.Guild, error) {
+	return getSingleGuild(q, "WHERE guild_id = ?", g.String())
+}
+
+func getGuildD(q querier, d string) (*database.Guild, error) {
+	return getSingleGuild(q, "WHERE discord_id = ?", d)
+}
+
+func getSingleGuild(q querier, where string, args ...interface{}) (*database.Guild, error) {
+	gs, err := queryGuilds(q, where+" LIMIT 1", args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(gs) == 0 {
+		return nil, &database.Error{Code: database.GuildNotFound, Message: "Guild was not found"}
+	}
+
+	return gs[0], nil
+}
+
+func queryGuilds(q querier, where string, args ...interface{}) ([]*database.Guild, error) {
+	rows, err := q.Query("SELECT "+guildColumns+" FROM guilds "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rv := make([]*database.Guild, 0, 10)
+	for rows.Next() {
+		var (
+			g                 database.Guild
+			id, top           string
+			parent, discordId sql.NullString
+		)
+		if err = rows.Scan(&id, &parent, &top, &discordId, &g.Name, &g.DefaultStat, &g.StatVersion); err != nil {
+			return nil, err
+		}
+
+		if g.GuildId, err = uuid.Parse(id); err != nil {
+			return nil, err
+		}
+		if g.TopLevelParentId, err = uuid.Parse(top); err != nil {
+			return nil, err
+		}
+		if parent.Valid {
+			if g.ParentId, err = uuid.Parse(parent.String); err != nil {
+				return nil, err
+			}
+		}
+		g.DiscordId = discordId.String
+		rv = append(rv, &g)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, g := range rv {
+		if err = loadGuildDetails(q, g); err != nil {
+			return nil, err
+		}
+	}
+
+	return rv, nil
+}
+
+func loadGuildDetails(q querier, g *database.Guild) error {
+	rows, err := q.Query(`SELECT stat_id, type, description, enum_values, min_value, max_value, pattern, max_length, required, write_policy,
+		review, formula, category, display_order FROM guild_stats WHERE guild_id = ?`, g.GuildId.String())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	g.Stats = make(map[string]*database.Stat)
+	for rows.Next() {
+		var (
+			st       database.Stat
+			values   string
+			min, max sql.NullFloat64
+		)
+		if err = rows.Scan(&st.ID, &st.Type, &st.Description, &values, &min, &max, &st.Pattern, &st.MaxLength, &st.Required, &st.Write,
+			&st.Review, &st.Formula, &st.Category, &st.Order); err != nil {
+			return err
+		}
+		if min.Valid {
+			st.Min = &min.Float64
+		}
+		if max.Valid {
+			st.Max = &max.Float64
+		}
+		if values != "" {
+			st.Values = strings.Split(values, "\n")
+		}
+		g.Stats[st.ID] = &st
+	}
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	if g.DiscordId == "" {
+		return nil
+	}
+
+	rows, err = q.Query("SELECT name FROM guilds WHERE top_level_parent_id = ? AND parent_id IS NOT NULL", g.GuildId.String())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var n string
+		if err = rows.Scan(&n); err != nil {
+			return err
+		}
+		if g.ChildNames == nil {
+			g.ChildNames = make(map[string]database.Void)
+		}
+		g.ChildNames[n] = database.Member
+	}
+
+	return rows.Err()
+}
+
+func childNameTaken(q querier, top uuid.UUID, name string) (bool, error) {
+	var count int
+	err := q.QueryRow("SELECT COUNT(*) FROM guilds WHERE top_level_parent_id = ? AND parent_id IS NOT NULL AND name = ?", top.String(), name).Scan(&count)
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func insertGuild(q querier, g *database.Guild) error {
+	var parent, discordId interface{}
+	if g.ParentId != uuid.Nil {
+		parent = g.ParentId.String()
+	}
+	if g.DiscordId != "" {
+		discordId = g.DiscordId
+	}
+
+	_, err := q.Exec("INSERT INTO guilds("+guildColumns+") VALUES (?, ?, ?, ?, ?, ?, ?)",
+		g.GuildId.String(), parent, g.TopLevelParentId.String(), discordId, g.Name, g.DefaultStat, g.StatVersion)
+	return err
+}
+
+func insertGuildStat(q querier, g uuid.UUID, st *database.Stat) error {
+	_, err := q.Exec(`INSERT INTO guild_stats(guild_id, stat_id, type, description, enum_values, min_value, max_value, pattern, max_length, required, write_policy,
+		review, formula, category, display_order) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		g.String(), st.ID, st.Type, st.Description, strings.Join(st.Values, "\n"), st.Min, st.Max, st.Pattern, st.MaxLength, st.Required, st.Write,
+		st.Review, st.Formula, st.Category, st.Order)
+	return err
+}
+
+func isNotFound(err error) bool {
+	dbErr := database.ErrToDbErr(err)
+	if dbErr == nil {
+		return false
+	}
+
+	switch dbErr.Code {
+	case database.GuildNotFound, database.UserNotFound, database.CharacterNotFound, database.RoleNotFound, database.MoneyNotFound:
+		return true
+	}
+	return false
+}
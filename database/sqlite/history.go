@@ -0,0 +1,98 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+func (s *SqliteDB) AddStatHistory(h *database.StatHistory) (*database.StatHistory, error) {
+	var rv *database.StatHistory
+	err := s.inTx(func(tx *sql.Tx) error {
+		c, err := getCharacter(tx, h.GuildId, h.UserId, h.Character)
+		if err != nil {
+			return err
+		}
+
+		if err = insertStatHistory(tx, c.id, h); err != nil {
+			return err
+		}
+
+		rv = &database.StatHistory{GuildId: c.GuildId, UserId: c.UserId, Character: c.Name, Stat: h.Stat, Value: h.Value, Time: h.Time}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func (s *SqliteDB) GetStatHistory(g string, u string, name string, st string) ([]*database.StatHistory, error) {
+	c, err := getCharacter(s.q(), g, u, name)
+	if err != nil {
+		return nil, err
+	}
+
+	rv, err := queryStatHistory(s.q(), "WHERE h.character_id = ? AND h.stat = ? ORDER BY h.id", c.id, st)
+	if err != nil {
+		return nil, dbErr(err)
+	}
+
+	return rv, nil
+}
+
+func (s *SqliteDB) GetGuildStatHistory(g string, st string, since time.Time) ([]*database.StatHistory, error) {
+	t := since.UnixNano()
+	rv, err := queryStatHistory(s.q(), `WHERE c.guild_id = ? AND h.stat = ? AND (h.time >= ? OR h.id IN (
+			SELECT MAX(p.id) FROM stat_history p JOIN characters pc ON pc.id = p.character_id
+			WHERE pc.guild_id = ? AND p.stat = ? AND p.time < ? GROUP BY p.character_id))
+		ORDER BY h.time, h.id`, g, st, t, g, st, t)
+	if err != nil {
+		return nil, dbErr(err)
+	}
+
+	return rv, nil
+}
+
+func queryStatHistory(q querier, where string, args ...interface{}) ([]*database.StatHistory, error) {
+	rows, err := q.Query(`SELECT c.guild_id, c.user_id, c.name, h.stat, h.type, h.int_value, h.real_value, h.str_value, h.time
+		FROM stat_history h JOIN characters c ON c.id = h.character_id `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rv := make([]*database.StatHistory, 0, 10)
+	for rows.Next() {
+		var (
+			h       database.StatHistory
+			t       int64
+			intVal  sql.NullInt64
+			realVal sql.NullFloat64
+			strVal  sql.NullString
+			tm      int64
+		)
+		if err = rows.Scan(&h.GuildId, &h.UserId, &h.Character, &h.Stat, &t, &intVal, &realVal, &strVal, &tm); err != nil {
+			return nil, err
+		}
+
+		h.Value, _ = decodeStat(t, intVal, realVal, strVal)
+		h.Time = time.Unix(0, tm)
+		rv = append(rv, &h)
+	}
+
+	return rv, rows.Err()
+}
+
+func insertStatHistory(q querier, id int64, h *database.StatHistory) error {
+	t, intVal, realVal, strVal, err := encodeStat(h.Stat, h.Value)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.Exec("INSERT INTO stat_history(character_id, stat, type, int_value, real_value, str_value, time) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		id, h.Stat, t, intVal, realVal, strVal, h.Time.UnixNano())
+	return err
+}
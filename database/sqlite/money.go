@@ -0,0 +1,151 @@
+package sqlite
+
+import (
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+func (s *SqliteDB) AddMoney(m *database.Money) (*database.Money, error) {
+	var rv *database.Money
+	err := s.inTx(func(tx *sql.Tx) error {
+		if _, err := getMoney(tx, m.GuildId); err == nil {
+			return &database.Error{Code: database.MoneyAlreadyRegistered, Message: "Payment stuff for the guild is already registered"}
+		} else if !isNotFound(err) {
+			return err
+		}
+
+		if err := insertMoney(tx, m); err != nil {
+			return err
+		}
+
+		var err error
+		rv, err = getMoney(tx, m.GuildId)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.keepValidTo(rv.GuildId, m.ValidTo)
+	s.restoreValidTo([]*database.Money{rv})
+	return rv, nil
+}
+
+func (s *SqliteDB) GetMoney(g string) (*database.Money, error) {
+	rv, err := getMoney(s.q(), g)
+	if err != nil {
+		return nil, err
+	}
+
+	s.restoreValidTo([]*database.Money{rv})
+	return rv, nil
+}
+
+func (s *SqliteDB) ChangeMoneyOwner(g string, u string) (*database.Money, error) {
+	return s.updateMoney(g, "UPDATE money SET user_id = ? WHERE guild_id = ?", u)
+}
+
+func (s *SqliteDB) SetMoneyValid(g string, t time.Time) (*database.Money, error) {
+	rv, err := s.updateMoney(g, "UPDATE money SET valid_to = ? WHERE guild_id = ?", t.UnixNano())
+	if err != nil {
+		return nil, err
+	}
+
+	s.keepValidTo(g, t)
+	s.restoreValidTo([]*database.Money{rv})
+	return rv, nil
+}
+
+func (s *SqliteDB) updateMoney(g string, q string, v interface{}) (*database.Money, error) {
+	var rv *database.Money
+	err := s.inTx(func(tx *sql.Tx) error {
+		if _, err := getMoney(tx, g); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(q, v, g); err != nil {
+			return err
+		}
+
+		var err error
+		rv, err = getMoney(tx, g)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.restoreValidTo([]*database.Money{rv})
+	return rv, nil
+}
+
+// validTimes keeps money validity times as they were passed by callers
+type validTimes struct {
+	times map[string]time.Time
+	mux   sync.Mutex
+}
+
+// keepValidTo remembers validity time of guild money. Monotonic clock reading of the time doesn't survive storage
+func (s *SqliteDB) keepValidTo(g string, t time.Time) {
+	s.validTo.mux.Lock()
+	defer s.validTo.mux.Unlock()
+
+	s.validTo.times[g] = t
+}
+
+// restoreValidTo replaces validity times read from storage with the ones passed by callers, if they are the same
+// instants. This way values compare equal to the passed ones in the same process, as with MemoryDB
+func (s *SqliteDB) restoreValidTo(ms []*database.Money) {
+	s.validTo.mux.Lock()
+	defer s.validTo.mux.Unlock()
+
+	for _, m := range ms {
+		if t, ok := s.validTo.times[m.GuildId]; ok && t.Equal(m.ValidTo) {
+			m.ValidTo = t
+		}
+	}
+}
+
+func getMoney(q querier, g string) (*database.Money, error) {
+	ms, err := queryMoney(q, "WHERE guild_id = ?", g)
+	if err != nil {
+		return nil, err
+	}
+	if len(ms) == 0 {
+		return nil, &database.Error{Code: database.MoneyNotFound, Message: "Payment stuff for the guild is not found"}
+	}
+
+	return ms[0], nil
+}
+
+func queryMoney(q querier, where string, args ...interface{}) ([]*database.Money, error) {
+	rows, err := q.Query("SELECT guild_id, user_id, valid_to, price FROM money "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rv := make([]*database.Money, 0, 1)
+	for rows.Next() {
+		var (
+			m       database.Money
+			validTo int64
+		)
+		if err = rows.Scan(&m.GuildId, &m.UserId, &validTo, &m.Price); err != nil {
+			return nil, err
+		}
+		m.ValidTo = time.Unix(0, validTo)
+		rv = append(rv, &m)
+	}
+
+	return rv, rows.Err()
+}
+
+func insertMoney(q querier, m *database.Money) error {
+	_, err := q.Exec("INSERT INTO money(guild_id, user_id, valid_to, price) VALUES (?, ?, ?, ?)",
+		m.GuildId, m.UserId, m.ValidTo.UnixNano(), m.Price)
+	return err
+}
@@ -0,0 +1,115 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+func (s *SqliteDB) AddRole(r *database.Role) (*database.Role, error) {
+	var rv *database.Role
+	err := s.inTx(func(tx *sql.Tx) error {
+		if _, err := getRole(tx, r.GuildId, r.Id); err == nil {
+			return &database.Error{Code: database.RoleAlreadyExists, Message: "Role with this ID already exists in this guild"}
+		} else if !isNotFound(err) {
+			return err
+		}
+
+		if err := insertRole(tx, r); err != nil {
+			return err
+		}
+
+		var err error
+		rv, err = getRole(tx, r.GuildId, r.Id)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func (s *SqliteDB) GetRole(g string, r string) (*database.Role, error) {
+	return getRole(s.q(), g, r)
+}
+
+func (s *SqliteDB) GetGuildRoles(g string) ([]*database.Role, error) {
+	return queryRoles(s.q(), "WHERE guild_id = ?", g)
+}
+
+func (s *SqliteDB) SetRolePermissions(g string, r string, p int) (*database.Role, error) {
+	var rv *database.Role
+	err := s.inTx(func(tx *sql.Tx) error {
+		if _, err := getRole(tx, g, r); err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec("UPDATE roles SET permissions = ? WHERE guild_id = ? AND role_id = ?", p, g, r); err != nil {
+			return err
+		}
+
+		var err error
+		rv, err = getRole(tx, g, r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func (s *SqliteDB) RemoveRole(g string, r string) (*database.Role, error) {
+	var rv *database.Role
+	err := s.inTx(func(tx *sql.Tx) error {
+		var err error
+		if rv, err = getRole(tx, g, r); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM roles WHERE guild_id = ? AND role_id = ?", g, r)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func getRole(q querier, g string, r string) (*database.Role, error) {
+	rs, err := queryRoles(q, "WHERE guild_id = ? AND role_id = ?", g, r)
+	if err != nil {
+		return nil, err
+	}
+	if len(rs) == 0 {
+		return nil, &database.Error{Code: database.RoleNotFound, Message: "Role was not found"}
+	}
+
+	return rs[0], nil
+}
+
+func queryRoles(q querier, where string, args ...interface{}) ([]*database.Role, error) {
+	rows, err := q.Query("SELECT guild_id, role_id, permissions FROM roles "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rv := make([]*database.Role, 0, 10)
+	for rows.Next() {
+		var r database.Role
+		if err = rows.Scan(&r.GuildId, &r.Id, &r.Permissions); err != nil {
+			return nil, err
+		}
+		rv = append(rv, &r)
+	}
+
+	return rv, rows.Err()
+}
+
+func insertRole(q querier, r *database.Role) error {
+	_, err := q.Exec("INSERT INTO roles(guild_id, role_id, permissions) VALUES (?, ?, ?)", r.GuildId, r.Id, r.Permissions)
+	return err
+}
@@ -0,0 +1,518 @@
+package sqlite
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+const schema = `
+CREATE TABLE IF NOT EXISTS guilds (
+	guild_id            TEXT PRIMARY KEY,
+	parent_id           TEXT REFERENCES guilds(guild_id) ON DELETE CASCADE,
+	top_level_parent_id TEXT NOT NULL REFERENCES guilds(guild_id) ON DELETE CASCADE,
+	discord_id          TEXT UNIQUE,
+	name                TEXT NOT NULL,
+	default_stat        TEXT NOT NULL DEFAULT '',
+	stat_version        INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS guilds_parent ON guilds(parent_id);
+CREATE UNIQUE INDEX IF NOT EXISTS guilds_child_name ON guilds(top_level_parent_id, name) WHERE parent_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS guild_stats (
+	guild_id    TEXT NOT NULL REFERENCES guilds(guild_id) ON DELETE CASCADE,
+	stat_id     TEXT NOT NULL,
+	type        INTEGER NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	enum_values TEXT NOT NULL DEFAULT '',
+	min_value   REAL,
+	max_value   REAL,
+	pattern     TEXT NOT NULL DEFAULT '',
+	max_length  INTEGER NOT NULL DEFAULT 0,
+	required    INTEGER NOT NULL DEFAULT 0,
+	write_policy INTEGER NOT NULL DEFAULT 0,
+	review      INTEGER NOT NULL DEFAULT 0,
+	formula     TEXT NOT NULL DEFAULT '',
+	category    TEXT NOT NULL DEFAULT '',
+	display_order INTEGER NOT NULL DEFAULT 0,
+	PRIMARY KEY (guild_id, stat_id)
+);
+
+CREATE TABLE IF NOT EXISTS users (
+	user_id TEXT PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS user_guilds (
+	user_id     TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	top_guild   TEXT NOT NULL,
+	guild_id    TEXT NOT NULL,
+	permissions INTEGER NOT NULL,
+	PRIMARY KEY (user_id, top_guild)
+);
+CREATE INDEX IF NOT EXISTS user_guilds_top ON user_guilds(top_guild);
+
+CREATE TABLE IF NOT EXISTS characters (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	guild_id     TEXT NOT NULL,
+	user_id      TEXT NOT NULL,
+	name         TEXT NOT NULL,
+	main         INTEGER NOT NULL DEFAULT 0,
+	stat_version INTEGER NOT NULL DEFAULT 0,
+	UNIQUE (guild_id, user_id, name)
+);
+CREATE INDEX IF NOT EXISTS characters_name ON characters(guild_id, name);
+CREATE INDEX IF NOT EXISTS characters_version ON characters(guild_id, stat_version);
+
+CREATE TABLE IF NOT EXISTS character_stats (
+	character_id INTEGER NOT NULL REFERENCES characters(id) ON DELETE CASCADE,
+	stat         TEXT NOT NULL,
+	type         INTEGER NOT NULL,
+	int_value    INTEGER,
+	real_value   REAL,
+	str_value    TEXT,
+	PRIMARY KEY (character_id, stat)
+);
+CREATE INDEX IF NOT EXISTS character_stats_int ON character_stats(stat, type, int_value);
+CREATE INDEX IF NOT EXISTS character_stats_real ON character_stats(stat, type, real_value);
+CREATE INDEX IF NOT EXISTS character_stats_str ON character_stats(stat, type, str_value);
+
+CREATE TABLE IF NOT EXISTS stat_history (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	character_id INTEGER NOT NULL REFERENCES characters(id) ON DELETE CASCADE,
+	stat         TEXT NOT NULL,
+	type         INTEGER NOT NULL,
+	int_value    INTEGER,
+	real_value   REAL,
+	str_value    TEXT,
+	time         INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS stat_history_character ON stat_history(character_id, stat);
+
+CREATE TABLE IF NOT EXISTS roles (
+	guild_id    TEXT NOT NULL,
+	role_id     TEXT NOT NULL,
+	permissions INTEGER NOT NULL,
+	PRIMARY KEY (guild_id, role_id)
+);
+
+CREATE TABLE IF NOT EXISTS money (
+	guild_id TEXT PRIMARY KEY,
+	user_id  TEXT NOT NULL,
+	valid_to INTEGER NOT NULL,
+	price    INTEGER NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS audit (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	guild_id     TEXT NOT NULL,
+	author_id    TEXT NOT NULL,
+	time         INTEGER NOT NULL,
+	command      TEXT NOT NULL,
+	action       TEXT NOT NULL,
+	user_id      TEXT NOT NULL,
+	character    TEXT NOT NULL,
+	stat         TEXT NOT NULL,
+	target       TEXT NOT NULL,
+	before_value TEXT NOT NULL,
+	after_value  TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS audit_guild ON audit(guild_id, id);
+
+CREATE TABLE IF NOT EXISTS trash (
+	id         INTEGER PRIMARY KEY AUTOINCREMENT,
+	guild_id   TEXT NOT NULL,
+	type       INTEGER NOT NULL,
+	name       TEXT NOT NULL,
+	removed_by TEXT NOT NULL,
+	time       INTEGER NOT NULL,
+	body       BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS trash_guild ON trash(guild_id, time);
+
+CREATE TABLE IF NOT EXISTS submissions (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	guild_id     TEXT NOT NULL,
+	user_id      TEXT NOT NULL,
+	character    TEXT NOT NULL,
+	stat         TEXT NOT NULL,
+	type         INTEGER NOT NULL,
+	int_value    INTEGER,
+	real_value   REAL,
+	str_value    TEXT,
+	submitted_by TEXT NOT NULL,
+	time         INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS submissions_guild ON submissions(guild_id, id);
+`
+
+// migrations bring databases created by older versions up to date. Version of a database is the number of
+// applied migrations, it is kept in user_version. Applied migrations must never change, new ones are appended.
+// Tables missing after migrations are created from the schema
+var migrations = []func(tx *sql.Tx) error{
+	addColumns([]column{
+		{"guild_stats", "enum_values", "TEXT NOT NULL DEFAULT ''"},
+		{"character_stats", "real_value", "REAL"},
+		{"stat_history", "real_value", "REAL"},
+	}),
+	addColumns([]column{
+		{"guild_stats", "min_value", "REAL"},
+		{"guild_stats", "max_value", "REAL"},
+		{"guild_stats", "pattern", "TEXT NOT NULL DEFAULT ''"},
+		{"guild_stats", "max_length", "INTEGER NOT NULL DEFAULT 0"},
+		{"guild_stats", "required", "INTEGER NOT NULL DEFAULT 0"},
+	}),
+	addColumns([]column{
+		{"guild_stats", "formula", "TEXT NOT NULL DEFAULT ''"},
+	}),
+	addColumns([]column{
+		{"guild_stats", "category", "TEXT NOT NULL DEFAULT ''"},
+		{"guild_stats", "display_order", "INTEGER NOT NULL DEFAULT 0"},
+	}),
+	addColumns([]column{
+		{"guild_stats", "write_policy", "INTEGER NOT NULL DEFAULT 0"},
+	}),
+	addColumns([]column{
+		{"guild_stats", "review", "INTEGER NOT NULL DEFAULT 0"},
+	}),
+}
+
+type column struct {
+	table      string
+	name       string
+	definition string
+}
+
+// addColumns adds columns to existing tables that don't have them yet
+func addColumns(cols []column) func(tx *sql.Tx) error {
+	return func(tx *sql.Tx) error {
+		for _, c := range cols {
+			ok, err := hasColumn(tx, c.table, c.name)
+			if err != nil {
+				return err
+			}
+			if ok {
+				continue
+			}
+
+			if _, err = tx.Exec(fmt.Sprintf("ALTER TABLE %v ADD COLUMN %v %v", c.table, c.name, c.definition)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// hasColumn is true for missing tables as well, they are created with all columns
+func hasColumn(tx *sql.Tx, table string, column string) (bool, error) {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%v)", table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	found, exists := false, false
+	for rows.Next() {
+		var (
+			cid     int
+			name    string
+			typ     string
+			notNull int
+			def     interface{}
+			pk      int
+		)
+		if err = rows.Scan(&cid, &name, &typ, &notNull, &def, &pk); err != nil {
+			return false, err
+		}
+		exists = true
+		found = found || name == column
+	}
+
+	return found || !exists, rows.Err()
+}
+
+func migrate(db *sql.DB) error {
+	var v int
+	if err := db.QueryRow("PRAGMA user_version").Scan(&v); err != nil {
+		return err
+	}
+	if v > len(migrations) {
+		return &database.Error{Code: database.InvalidDatabaseState, Message: fmt.Sprintf("Database version %v is newer than supported version %v", v, len(migrations))}
+	}
+
+	for ; v < len(migrations); v++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+
+		if err = migrations[v](tx); err == nil {
+			_, err = tx.Exec(fmt.Sprintf("PRAGMA user_version = %v", v+1))
+		}
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+
+	_, err := db.Exec(schema)
+	return err
+}
+
+type SqliteDB struct {
+	db *sql.DB
+	// set for providers passed to RunInTx callbacks
+	tx *sql.Tx
+	// money validity as callers passed it, see keepValidTo
+	validTo *validTimes
+}
+
+// constructor function. Use ":memory:" as a path for a throw-away database
+func NewSqliteDb(path string) (*SqliteDB, error) {
+	db, err := sql.Open("sqlite3", "file:"+path+"?_foreign_keys=on&_busy_timeout=5000")
+	if err != nil {
+		return nil, dbErr(err)
+	}
+
+	// SQLite allows a single writer anyway, and in-memory databases
+	// exist per connection
+	db.SetMaxOpenConns(1)
+
+	if err = migrate(db); err != nil {
+		db.Close()
+		return nil, dbErr(err)
+	}
+
+	return &SqliteDB{db: db, validTo: &validTimes{times: make(map[string]time.Time)}}, nil
+}
+
+func (s *SqliteDB) Close() error {
+	return s.db.Close()
+}
+
+type dump struct {
+	Guilds      []*database.Guild
+	Users       []*database.User
+	Chars       []*database.Character
+	Roles       []*database.Role
+	Money       []*database.Money
+	Audit       []*database.AuditEntry
+	Trash       []*database.TrashItem
+	History     []*database.StatHistory
+	Submissions []*database.Submission
+}
+
+func (s *SqliteDB) Export() ([]byte, error) {
+	var d dump
+	err := s.inTx(func(tx *sql.Tx) error {
+		var err error
+		if d.Guilds, err = queryGuilds(tx, ""); err != nil {
+			return err
+		}
+		if d.Users, err = queryUsers(tx, ""); err != nil {
+			return err
+		}
+		if d.Chars, err = queryCharacters(tx, "", ""); err != nil {
+			return err
+		}
+		if d.Roles, err = queryRoles(tx, ""); err != nil {
+			return err
+		}
+		if d.Money, err = queryMoney(tx, ""); err != nil {
+			return err
+		}
+		if d.Audit, err = queryAudit(tx, "ORDER BY id"); err != nil {
+			return err
+		}
+		if d.Trash, err = queryTrash(tx, "ORDER BY id"); err != nil {
+			return err
+		}
+		if d.History, err = queryStatHistory(tx, "ORDER BY h.id"); err != nil {
+			return err
+		}
+		d.Submissions, err = querySubmissions(tx, "ORDER BY id")
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.restoreValidTo(d.Money)
+
+	var buf bytes.Buffer
+	encoder := gob.NewEncoder(io.Writer(&buf))
+	if err = encoder.Encode(&d); err != nil {
+		return nil, &database.Error{Code: database.IOErrorDuringImport, Message: err.Error()}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (s *SqliteDB) Import(b []byte) error {
+	var d dump
+	decoder := gob.NewDecoder(io.Reader(bytes.NewBuffer(b)))
+	if err := decoder.Decode(&d); err != nil {
+		return &database.Error{Code: database.IOErrorDuringImport, Message: err.Error()}
+	}
+
+	return s.inTx(func(tx *sql.Tx) error {
+		// Sub-guilds may come before their parents
+		if _, err := tx.Exec("PRAGMA defer_foreign_keys = ON"); err != nil {
+			return err
+		}
+
+		for _, t := range []string{"stat_history", "character_stats", "characters", "guild_stats", "guilds", "user_guilds", "users", "roles", "money", "audit", "trash", "submissions"} {
+			if _, err := tx.Exec("DELETE FROM " + t); err != nil {
+				return err
+			}
+		}
+
+		for _, g := range d.Guilds {
+			if err := insertGuild(tx, g); err != nil {
+				return err
+			}
+			for _, st := range g.Stats {
+				if err := insertGuildStat(tx, g.GuildId, st); err != nil {
+					return err
+				}
+			}
+		}
+		for _, u := range d.Users {
+			if _, err := tx.Exec("INSERT INTO users(user_id) VALUES (?)", u.Id); err != nil {
+				return err
+			}
+			for _, gp := range u.Guilds {
+				if err := insertUserGuild(tx, u.Id, gp); err != nil {
+					return err
+				}
+			}
+		}
+		for _, c := range d.Chars {
+			if err := insertCharacter(tx, c); err != nil {
+				return err
+			}
+		}
+		for _, r := range d.Roles {
+			if err := insertRole(tx, r); err != nil {
+				return err
+			}
+		}
+		for _, m := range d.Money {
+			if err := insertMoney(tx, m); err != nil {
+				return err
+			}
+			s.keepValidTo(m.GuildId, m.ValidTo)
+		}
+		for _, e := range d.Audit {
+			if _, err := insertAuditEntry(tx, e); err != nil {
+				return err
+			}
+		}
+		for _, t := range d.Trash {
+			if _, err := insertTrashItem(tx, t); err != nil {
+				return err
+			}
+		}
+		for _, h := range d.History {
+			c, err := getCharacterExact(tx, h.GuildId, h.UserId, h.Character)
+			if err != nil {
+				return err
+			}
+			if err = insertStatHistory(tx, c.id, h); err != nil {
+				return err
+			}
+		}
+		for _, sub := range d.Submissions {
+			if _, err := insertSubmission(tx, sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+type querier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// RunInTx calls f with a provider bound to a single transaction. Nested calls join the outer transaction
+func (s *SqliteDB) RunInTx(f func(tx database.DataProvider) error) error {
+	if s.tx != nil {
+		return f(s)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return dbErr(err)
+	}
+
+	if err = f(&SqliteDB{db: s.db, tx: tx, validTo: s.validTo}); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return dbErr(tx.Commit())
+}
+
+func (s *SqliteDB) q() querier {
+	if s.tx != nil {
+		return s.tx
+	}
+
+	return s.db
+}
+
+// inTx runs f atomically. Inside RunInTx a savepoint is used, so a failed call does not abort the whole transaction
+func (s *SqliteDB) inTx(f func(tx *sql.Tx) error) error {
+	if s.tx != nil {
+		if _, err := s.tx.Exec("SAVEPOINT op"); err != nil {
+			return dbErr(err)
+		}
+
+		if err := f(s.tx); err != nil {
+			s.tx.Exec("ROLLBACK TO op")
+			s.tx.Exec("RELEASE op")
+			return dbErr(err)
+		}
+
+		_, err := s.tx.Exec("RELEASE op")
+		return dbErr(err)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return dbErr(err)
+	}
+
+	if err = f(tx); err != nil {
+		tx.Rollback()
+		return dbErr(err)
+	}
+
+	return dbErr(tx.Commit())
+}
+
+func dbErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(*database.Error); ok {
+		return err
+	}
+
+	return &database.Error{Code: database.ExternalError, Message: err.Error()}
+}
+
+func init() {
+	var _ database.DataProvider = (*SqliteDB)(nil)
+}
@@ -0,0 +1,115 @@
+package sqlite
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+func (s *SqliteDB) AddSubmission(sub *database.Submission) (*database.Submission, error) {
+	var rv *database.Submission
+	err := s.inTx(func(tx *sql.Tx) error {
+		newS := *sub
+		newS.Id = 0
+		id, err := insertSubmission(tx, &newS)
+		if err != nil {
+			return err
+		}
+
+		newS.Id = int(id)
+		rv = &newS
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func (s *SqliteDB) GetSubmissions(g string) ([]*database.Submission, error) {
+	rv, err := querySubmissions(s.q(), "WHERE guild_id = ? ORDER BY id", g)
+	if err != nil {
+		return nil, dbErr(err)
+	}
+
+	return rv, nil
+}
+
+func (s *SqliteDB) RemoveSubmission(g string, id int) (*database.Submission, error) {
+	var rv *database.Submission
+	err := s.inTx(func(tx *sql.Tx) error {
+		subs, err := querySubmissions(tx, "WHERE guild_id = ? AND id = ?", g, id)
+		if err != nil {
+			return err
+		}
+		if len(subs) == 0 {
+			return &database.Error{Code: database.SubmissionNotFound, Message: "Submission was not found"}
+		}
+
+		if _, err = tx.Exec("DELETE FROM submissions WHERE id = ?", id); err != nil {
+			return err
+		}
+
+		rv = subs[0]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func querySubmissions(q querier, where string, args ...interface{}) ([]*database.Submission, error) {
+	rows, err := q.Query(`SELECT id, guild_id, user_id, character, stat, type, int_value, real_value, str_value, submitted_by, time
+		FROM submissions `+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rv := make([]*database.Submission, 0, 10)
+	for rows.Next() {
+		var (
+			sub     database.Submission
+			t       int64
+			intVal  sql.NullInt64
+			realVal sql.NullFloat64
+			strVal  sql.NullString
+			tm      int64
+		)
+		if err = rows.Scan(&sub.Id, &sub.GuildId, &sub.UserId, &sub.Character, &sub.Stat, &t, &intVal, &realVal, &strVal, &sub.SubmittedBy, &tm); err != nil {
+			return nil, err
+		}
+
+		sub.Value, _ = decodeStat(t, intVal, realVal, strVal)
+		sub.Time = time.Unix(0, tm)
+		rv = append(rv, &sub)
+	}
+
+	return rv, rows.Err()
+}
+
+// insertSubmission keeps sub.Id if it is set (e.g. on import)
+func insertSubmission(q querier, sub *database.Submission) (int64, error) {
+	t, intVal, realVal, strVal, err := encodeStat(sub.Stat, sub.Value)
+	if err != nil {
+		return 0, err
+	}
+
+	var id interface{}
+	if sub.Id != 0 {
+		id = sub.Id
+	}
+
+	res, err := q.Exec(`INSERT INTO submissions(id, guild_id, user_id, character, stat, type, int_value, real_value, str_value, submitted_by, time)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		id, sub.GuildId, sub.UserId, sub.Character, sub.Stat, t, intVal, realVal, strVal, sub.SubmittedBy, sub.Time.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+
+	return res.LastInsertId()
+}
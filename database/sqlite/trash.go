@@ -0,0 +1,172 @@
+package sqlite
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/gob"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+// removed entities are stored as a single blob, they are never queried by content
+type trashBody struct {
+	Characters []*database.Character
+	Guilds     []*database.Guild
+	User       *database.User
+	Members    map[string]uuid.UUID
+	History    []*database.StatHistory
+}
+
+func (s *SqliteDB) AddTrashItem(t *database.TrashItem) (*database.TrashItem, error) {
+	var rv *database.TrashItem
+	err := s.inTx(func(tx *sql.Tx) error {
+		newT := *t
+		newT.Id = 0
+		id, err := insertTrashItem(tx, &newT)
+		if err != nil {
+			return err
+		}
+
+		newT.Id = int(id)
+		rv = &newT
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func (s *SqliteDB) GetTrashItems(g string) ([]*database.TrashItem, error) {
+	rv, err := queryTrash(s.q(), "WHERE guild_id = ? ORDER BY id", g)
+	if err != nil {
+		return nil, dbErr(err)
+	}
+
+	return rv, nil
+}
+
+func (s *SqliteDB) RemoveTrashItem(g string, id int) (*database.TrashItem, error) {
+	var rv *database.TrashItem
+	err := s.inTx(func(tx *sql.Tx) error {
+		ts, err := queryTrash(tx, "WHERE guild_id = ? AND id = ?", g, id)
+		if err != nil {
+			return err
+		}
+		if len(ts) == 0 {
+			return &database.Error{Code: database.TrashItemNotFound, Message: "Item was not found in the trash"}
+		}
+
+		if _, err = tx.Exec("DELETE FROM trash WHERE id = ?", id); err != nil {
+			return err
+		}
+
+		rv = ts[0]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func (s *SqliteDB) PurgeTrash(g string, before time.Time) (int, error) {
+	res, err := s.q().Exec("DELETE FROM trash WHERE guild_id = ? AND time < ?", g, before.UnixNano())
+	if err != nil {
+		return 0, dbErr(err)
+	}
+
+	n, err := res.RowsAffected()
+	return int(n), dbErr(err)
+}
+
+func queryTrash(q querier, where string, args ...interface{}) ([]*database.TrashItem, error) {
+	rows, err := q.Query("SELECT id, guild_id, type, name, removed_by, time, body FROM trash "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rv := make([]*database.TrashItem, 0, 10)
+	for rows.Next() {
+		var (
+			t    database.TrashItem
+			tm   int64
+			blob []byte
+			body trashBody
+		)
+		if err = rows.Scan(&t.Id, &t.GuildId, &t.Type, &t.Name, &t.RemovedBy, &tm, &blob); err != nil {
+			return nil, err
+		}
+		if err = gob.NewDecoder(io.Reader(bytes.NewBuffer(blob))).Decode(&body); err != nil {
+			return nil, err
+		}
+
+		t.Time = time.Unix(0, tm)
+		t.Characters, t.Guilds, t.User, t.Members, t.History = body.Characters, body.Guilds, body.User, body.Members, body.History
+		rv = append(rv, &t)
+	}
+
+	return rv, rows.Err()
+}
+
+// insertTrashItem keeps t.Id if it is set (e.g. on import)
+func insertTrashItem(q querier, t *database.TrashItem) (int64, error) {
+	body, err := encodeTrashBody(t)
+	if err != nil {
+		return 0, err
+	}
+
+	var id interface{}
+	if t.Id != 0 {
+		id = t.Id
+	}
+
+	res, err := q.Exec("INSERT INTO trash(id, guild_id, type, name, removed_by, time, body) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		id, t.GuildId, t.Type, t.Name, t.RemovedBy, t.Time.UnixNano(), body)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.LastInsertId()
+}
+
+// renameTrashedStat moves values and history of stat old to name in removed characters of guild g
+func renameTrashedStat(q querier, g string, old string, name string) error {
+	ts, err := queryTrash(q, "WHERE guild_id = ?", g)
+	if err != nil {
+		return err
+	}
+
+	for _, t := range ts {
+		if len(t.Characters) == 0 && len(t.History) == 0 {
+			continue
+		}
+
+		body, err := encodeTrashBody(t.RenameStat(old, name))
+		if err != nil {
+			return err
+		}
+		if _, err = q.Exec("UPDATE trash SET body = ? WHERE id = ?", body, t.Id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func encodeTrashBody(t *database.TrashItem) ([]byte, error) {
+	var buf bytes.Buffer
+	body := trashBody{Characters: t.Characters, Guilds: t.Guilds, User: t.User, Members: t.Members, History: t.History}
+	if err := gob.NewEncoder(io.Writer(&buf)).Encode(&body); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
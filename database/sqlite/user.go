@@ -0,0 +1,162 @@
+package sqlite
+
+import (
+	"database/sql"
+
+	"github.com/google/uuid"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+func (s *SqliteDB) AddUser(d string, gp *database.GuildPermission) (*database.User, error) {
+	var rv *database.User
+	err := s.inTx(func(tx *sql.Tx) error {
+		user, err := getUserD(tx, d)
+		if err != nil {
+			if !isNotFound(err) {
+				return err
+			}
+			if _, err = tx.Exec("INSERT INTO users(user_id) VALUES (?)", d); err != nil {
+				return err
+			}
+		} else if _, ok := user.Guilds[gp.TopGuild]; ok {
+			return &database.Error{Code: database.UserAlreadyInGuild, Message: "The user is already registered in the guild"}
+		}
+
+		if err = insertUserGuild(tx, d, gp); err != nil {
+			return err
+		}
+
+		rv, err = getUserD(tx, d)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func (s *SqliteDB) GetUserD(d string) (*database.User, error) {
+	return getUserD(s.q(), d)
+}
+
+func (s *SqliteDB) GetUsersInGuild(d string) ([]*database.User, error) {
+	return queryUsers(s.q(), "WHERE user_id IN (SELECT user_id FROM user_guilds WHERE top_guild = ?)", d)
+}
+
+func (s *SqliteDB) SetUserPermissions(u string, gp *database.GuildPermission) (*database.User, error) {
+	return s.updateUserGuild(u, gp, "UPDATE user_guilds SET permissions = ? WHERE user_id = ? AND top_guild = ?", gp.Permissions)
+}
+
+func (s *SqliteDB) SetUserSubGuild(u string, gp *database.GuildPermission) (*database.User, error) {
+	return s.updateUserGuild(u, gp, "UPDATE user_guilds SET guild_id = ? WHERE user_id = ? AND top_guild = ?", gp.GuildId.String())
+}
+
+func (s *SqliteDB) RemoveUserD(u string, g string) (*database.User, error) {
+	return s.updateUserGuild(u, &database.GuildPermission{TopGuild: g}, "DELETE FROM user_guilds WHERE user_id = ? AND top_guild = ?")
+}
+
+func (s *SqliteDB) EraseUserD(u string) (*database.User, error) {
+	var rv *database.User
+	err := s.inTx(func(tx *sql.Tx) error {
+		var err error
+		rv, err = getUserD(tx, u)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec("DELETE FROM users WHERE user_id = ?", u)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func (s *SqliteDB) updateUserGuild(u string, gp *database.GuildPermission, q string, args ...interface{}) (*database.User, error) {
+	var rv *database.User
+	err := s.inTx(func(tx *sql.Tx) error {
+		user, err := getUserD(tx, u)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := user.Guilds[gp.TopGuild]; !ok {
+			return &database.Error{Code: database.UserNotInGuild, Message: "User is not registered in the guild"}
+		}
+
+		if _, err = tx.Exec(q, append(args, u, gp.TopGuild)...); err != nil {
+			return err
+		}
+
+		rv, err = getUserD(tx, u)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+func getUserD(q querier, d string) (*database.User, error) {
+	us, err := queryUsers(q, "WHERE user_id = ?", d)
+	if err != nil {
+		return nil, err
+	}
+	if len(us) == 0 {
+		return nil, &database.Error{Code: database.UserNotFound, Message: "User was not found"}
+	}
+
+	return us[0], nil
+}
+
+func queryUsers(q querier, where string, args ...interface{}) ([]*database.User, error) {
+	rows, err := q.Query(`SELECT users.user_id, user_guilds.top_guild, user_guilds.guild_id, user_guilds.permissions
+		FROM (SELECT user_id FROM users `+where+`) AS users
+		LEFT JOIN user_guilds ON user_guilds.user_id = users.user_id
+		ORDER BY users.user_id`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rv := make([]*database.User, 0, 10)
+	var cur *database.User
+	for rows.Next() {
+		var (
+			id                string
+			topGuild, guildId sql.NullString
+			perm              sql.NullInt64
+		)
+		if err = rows.Scan(&id, &topGuild, &guildId, &perm); err != nil {
+			return nil, err
+		}
+
+		if cur == nil || cur.Id != id {
+			cur = &database.User{Id: id, Guilds: make(map[string]*database.GuildPermission)}
+			rv = append(rv, cur)
+		}
+
+		if !topGuild.Valid {
+			continue
+		}
+
+		gp := &database.GuildPermission{TopGuild: topGuild.String, Permissions: int(perm.Int64)}
+		if gp.GuildId, err = uuid.Parse(guildId.String); err != nil {
+			return nil, err
+		}
+		cur.Guilds[gp.TopGuild] = gp
+	}
+
+	return rv, rows.Err()
+}
+
+func insertUserGuild(q querier, u string, gp *database.GuildPermission) error {
+	_, err := q.Exec("INSERT INTO user_guilds(user_id, top_guild, guild_id, permissions) VALUES (?, ?, ?, ?)",
+		u, gp.TopGuild, gp.GuildId.String(), gp.Permissions)
+	return err
+}
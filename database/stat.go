@@ -0,0 +1,329 @@
+package database
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const DateFormat = "2006-01-02"
+
+// DefaultValue is a value new characters get for the stat. Computed stats have no stored value, so nil is returned
+func (s *Stat) DefaultValue() (interface{}, error) {
+	switch s.Type {
+	case Computed:
+		return nil, nil
+	case Number:
+		return 0, nil
+	case Str:
+		return "", nil
+	case Float:
+		return 0.0, nil
+	case Bool:
+		return false, nil
+	case Enum:
+		if len(s.Values) == 0 {
+			return "", nil
+		}
+		return s.Values[0], nil
+	case Date:
+		return time.Time{}, nil
+	case Duration:
+		return time.Duration(0), nil
+	}
+
+	return nil, &Error{Code: UnknownStatType, Message: fmt.Sprintf("Stat type for %v is not defined", s.ID)}
+}
+
+// IsValid checks that the value can be stored in the stat
+func (s *Stat) IsValid(v interface{}) (bool, error) {
+	ok, err := IsStatType(s.Type, v)
+	if err != nil {
+		return false, &Error{Code: UnknownStatType, Message: fmt.Sprintf("Stat type for %v is not defined", s.ID)}
+	}
+
+	if ok && s.Type == Enum {
+		ok = s.HasValue(v.(string))
+	}
+	return ok, nil
+}
+
+// IsStatType checks that the value has a go type used for stat type t
+func IsStatType(t int, v interface{}) (bool, error) {
+	ok := false
+	switch t {
+	case Number:
+		_, ok = v.(int)
+	case Str, Enum:
+		_, ok = v.(string)
+	case Float:
+		_, ok = v.(float64)
+	case Bool:
+		_, ok = v.(bool)
+	case Date:
+		_, ok = v.(time.Time)
+	case Duration:
+		_, ok = v.(time.Duration)
+	case Computed:
+	default:
+		return false, &Error{Code: UnknownStatType, Message: fmt.Sprintf("Stat type %v is not defined", t)}
+	}
+
+	return ok, nil
+}
+
+func (s *Stat) HasValue(v string) bool {
+	for _, a := range s.Values {
+		if a == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseValue converts user input into a stat value
+func (s *Stat) ParseValue(v string) (interface{}, error) {
+	switch s.Type {
+	case Computed:
+		return nil, errors.New(fmt.Sprintf("Stat %v is calculated by formula %v and can't be set", s.ID, s.Formula))
+	case Number:
+		rv, err := strconv.Atoi(v)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Expected numeric value. Got %v", v))
+		}
+		return rv, nil
+	case Str:
+		return v, nil
+	case Float:
+		rv, err := strconv.ParseFloat(v, 64)
+		// NaN and infinities can't be compared or summed up
+		if err != nil || math.IsNaN(rv) || math.IsInf(rv, 0) {
+			return nil, errors.New(fmt.Sprintf("Expected decimal value. Got %v", v))
+		}
+		return rv, nil
+	case Bool:
+		switch strings.ToLower(v) {
+		case "yes", "y", "true", "t", "1", "on":
+			return true, nil
+		case "no", "n", "false", "f", "0", "off":
+			return false, nil
+		}
+		return nil, errors.New(fmt.Sprintf("Expected yes or no. Got %v", v))
+	case Enum:
+		for _, a := range s.Values {
+			if strings.EqualFold(a, v) {
+				return a, nil
+			}
+		}
+		return nil, errors.New(fmt.Sprintf("Expected one of %v. Got %v", strings.Join(s.Values, ", "), v))
+	case Date:
+		rv, err := time.Parse(DateFormat, v)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Expected date in YYYY-MM-DD format. Got %v", v))
+		}
+		return rv, nil
+	case Duration:
+		rv, err := time.ParseDuration(v)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Expected duration like 1h30m. Got %v", v))
+		}
+		return rv, nil
+	}
+
+	return nil, &Error{Code: UnknownStatType, Message: fmt.Sprintf("Stat type for %v is not defined", s.ID)}
+}
+
+// ConvertValue converts value of another stat type into a value of this stat. Numbers and decimals convert
+// into each other (decimals must be whole), yes/no converts to 1/0 and back, anything converts to text
+// and text is parsed as the new type
+func (s *Stat) ConvertValue(v interface{}) (interface{}, error) {
+	if ok, err := s.IsValid(v); err != nil || ok {
+		return v, err
+	}
+
+	var num float64
+	isNum := true
+	switch val := v.(type) {
+	case int:
+		num = float64(val)
+	case float64:
+		num = val
+	case bool:
+		if val {
+			num = 1
+		}
+	default:
+		isNum = false
+	}
+
+	switch s.Type {
+	case Number:
+		if isNum && num == math.Trunc(num) {
+			return int(num), nil
+		}
+	case Float:
+		if isNum {
+			return num, nil
+		}
+	case Bool:
+		if isNum && (num == 0 || num == 1) {
+			return num == 1, nil
+		}
+	case Str:
+		if t, ok := v.(time.Time); ok && t.IsZero() {
+			return "", nil
+		}
+		return FormatStatValue(v), nil
+	}
+
+	if str, ok := v.(string); ok {
+		if strings.TrimSpace(str) == "" {
+			return s.DefaultValue()
+		}
+		return s.ParseValue(str)
+	}
+
+	return nil, errors.New(fmt.Sprintf("Value %v can't be converted to %v", FormatStatValue(v), TypeToString(s.Type)))
+}
+
+// Validate checks value against stat constraints. Errors are meant to be shown to users
+func (s *Stat) Validate(v interface{}) error {
+	var num float64
+	isNum := true
+	switch val := v.(type) {
+	case int:
+		num = float64(val)
+	case float64:
+		num = val
+	default:
+		isNum = false
+	}
+
+	if isNum && s.Min != nil && num < *s.Min {
+		return errors.New(fmt.Sprintf("Stat %v can't be less than %v", s.ID, FormatStatValue(*s.Min)))
+	}
+	if isNum && s.Max != nil && num > *s.Max {
+		return errors.New(fmt.Sprintf("Stat %v can't be greater than %v", s.ID, FormatStatValue(*s.Max)))
+	}
+
+	str, ok := v.(string)
+	if !ok {
+		return nil
+	}
+
+	if s.Required && strings.TrimSpace(str) == "" {
+		return errors.New(fmt.Sprintf("Stat %v is required and can't be empty", s.ID))
+	}
+	if s.MaxLength > 0 && len([]rune(str)) > s.MaxLength {
+		return errors.New(fmt.Sprintf("Stat %v can't be longer than %v characters", s.ID, s.MaxLength))
+	}
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Stat %v has invalid pattern %v", s.ID, s.Pattern))
+		}
+		if !re.MatchString(str) {
+			return errors.New(fmt.Sprintf("Value %v doesn't match pattern %v of stat %v", str, s.Pattern, s.ID))
+		}
+	}
+
+	return nil
+}
+
+// Constraints describes stat constraints, or returns "" if there are none
+func (s *Stat) Constraints() string {
+	rv := make([]string, 0, 5)
+	if s.Min != nil {
+		rv = append(rv, "min "+FormatStatValue(*s.Min))
+	}
+	if s.Max != nil {
+		rv = append(rv, "max "+FormatStatValue(*s.Max))
+	}
+	if s.MaxLength > 0 {
+		rv = append(rv, fmt.Sprintf("max length %v", s.MaxLength))
+	}
+	if s.Pattern != "" {
+		rv = append(rv, "pattern "+s.Pattern)
+	}
+	if s.Required {
+		rv = append(rv, "required")
+	}
+	switch s.Write {
+	case WriteOfficers:
+		rv = append(rv, "set by officers")
+	case WriteGuild:
+		rv = append(rv, "set by guild officers")
+	}
+	if s.Review {
+		rv = append(rv, "needs approval")
+	}
+
+	return strings.Join(rv, ", ")
+}
+
+// FormatStatValue renders a stat value for chat
+func FormatStatValue(v interface{}) string {
+	switch val := v.(type) {
+	case bool:
+		if val {
+			return "yes"
+		}
+		return "no"
+	case time.Time:
+		if val.IsZero() {
+			return "never"
+		}
+		return val.UTC().Format(DateFormat)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	}
+
+	return fmt.Sprint(v)
+}
+
+// CompareStatValues returns -1, 0 or 1. Both values must have the go type of stat type t
+func CompareStatValues(t int, a interface{}, b interface{}) int {
+	cmp := func(l bool, g bool) int {
+		if l {
+			return -1
+		} else if g {
+			return 1
+		}
+		return 0
+	}
+
+	switch t {
+	case Number:
+		av, bv := a.(int), b.(int)
+		return cmp(av < bv, av > bv)
+	case Str, Enum:
+		av, bv := a.(string), b.(string)
+		return cmp(av < bv, av > bv)
+	case Float:
+		av, bv := a.(float64), b.(float64)
+		return cmp(av < bv, av > bv)
+	case Bool:
+		av, bv := a.(bool), b.(bool)
+		return cmp(!av && bv, av && !bv)
+	case Date:
+		av, bv := a.(time.Time), b.(time.Time)
+		return cmp(av.Before(bv), av.After(bv))
+	case Duration:
+		av, bv := a.(time.Duration), b.(time.Duration)
+		return cmp(av < bv, av > bv)
+	}
+
+	return 0
+}
+
+func init() {
+	// stat values are stored in interface{} fields
+	gob.Register(time.Time{})
+	gob.Register(time.Duration(0))
+}
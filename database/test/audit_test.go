@@ -0,0 +1,176 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/database/audit"
+)
+
+func TestAuditRecords(t *testing.T) {
+	for n, f := range providers {
+		d := f()
+		g, err := d.AddGuild(&database.Guild{DiscordId: "au_d", Name: "main"})
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		sub, err := d.AddGuild(&database.Guild{Name: "sub", ParentId: g.GuildId})
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if _, err = d.AddUser("u", &database.GuildPermission{TopGuild: "au_d", GuildId: g.GuildId}); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if _, err = d.AddCharacter(&database.Character{GuildId: "au_d", UserId: "u", Name: "ch"}); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if _, err = d.SetCharacterStat("au_d", "u", "ch", "power", 10); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+
+		a := audit.NewAuditDb(d, "officer", "!g s set power 20")
+		if _, err = a.SetCharacterStat("au_d", "u", "ch", "power", 20); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		a = audit.NewAuditDb(d, "officer", "!g a u assign sub")
+		if _, err = a.SetUserSubGuild("u", &database.GuildPermission{TopGuild: "au_d", GuildId: sub.GuildId}); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if rce, err := a.SetCharacterStat("au_d", "u", "none", "power", 1); err == nil {
+			t.Fatalf("[%v] Error expected. Received: %v", n, rce)
+		}
+		err = a.RunInTx(func(tx database.DataProvider) error {
+			if _, err := tx.SetCharacterStat("au_d", "u", "ch", "power", 30); err != nil {
+				return err
+			}
+			return errors.New("failure")
+		})
+		if err == nil {
+			t.Fatalf("[%v] Error expected", n)
+		}
+
+		es, err := d.GetAuditEntries("au_d", &database.AuditFilter{})
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if len(es) != 2 {
+			t.Fatalf("[%v] Only successful changes expected to be recorded. Received: %v", n, len(es))
+		}
+
+		e := es[0]
+		if e.Action != "user assigned" || e.UserId != "u" || e.AuthorId != "officer" || e.Before != "main" || e.After != "sub" || e.Command != "!g a u assign sub" {
+			t.Fatalf("[%v] Wrong sub-guild entry: %v", n, e)
+		}
+		e = es[1]
+		if e.Action != "stat set" || e.Character != "ch" || e.Stat != "power" || e.Before != "10" || e.After != "20" || e.Time.IsZero() {
+			t.Fatalf("[%v] Wrong stat entry: %v", n, e)
+		}
+	}
+}
+
+func TestAuditStatChanges(t *testing.T) {
+	for n, f := range providers {
+		d := f()
+		g, err := d.AddGuild(&database.Guild{DiscordId: "au_s", Name: "main"})
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		a := audit.NewAuditDb(d, "officer", "!g a s")
+		if _, err = a.AddGuildStat(g.GuildId, &database.Stat{ID: "power", Type: database.Number}); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+
+		max := 100.0
+		testCases := []struct {
+			name   string
+			stat   database.Stat
+			action string
+			before string
+			after  string
+		}{
+			{name: "lock", stat: database.Stat{Write: database.WriteGuild}, action: "stat locked", before: "self", after: "guild"},
+			{name: "relock", stat: database.Stat{Write: database.WriteOfficers}, action: "stat locked", before: "guild", after: "officers"},
+			{name: "unlock", stat: database.Stat{}, action: "stat unlocked", before: "officers", after: "self"},
+			{name: "review", stat: database.Stat{Review: true}, action: "stat review set", before: "false", after: "true"},
+			{name: "category", stat: database.Stat{Review: true, Category: "combat"}, action: "stat category set", before: "", after: "combat"},
+			{name: "order", stat: database.Stat{Review: true, Category: "combat", Order: 2}, action: "stat order set", before: "0", after: "2"},
+			{name: "constraint", stat: database.Stat{Review: true, Category: "combat", Order: 2, Max: &max}, action: "stat changed", before: "", after: "max 100"},
+		}
+		for _, tc := range testCases {
+			s := tc.stat
+			s.ID, s.Type = "power", database.Number
+			if _, err = a.AddGuildStat(g.GuildId, &s); err != nil {
+				t.Fatalf("[%v, %v] No errors expected. Received: %v", n, tc.name, err)
+			}
+
+			es, err := d.GetAuditEntries("au_s", &database.AuditFilter{})
+			if err != nil {
+				t.Fatalf("[%v, %v] No errors expected. Received: %v", n, tc.name, err)
+			}
+			e := es[0]
+			if e.Action != tc.action || e.Stat != "power" || e.Before != tc.before || e.After != tc.after {
+				t.Errorf("[%v, %v] Wrong stat entry: %v", n, tc.name, e)
+			}
+		}
+
+		// every changed field is recorded
+		s := &database.Stat{ID: "power", Type: database.Number, Description: "attack", Review: true, Category: "melee", Order: 2, Max: &max, Required: true}
+		if _, err = a.AddGuildStat(g.GuildId, s); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		es, err := d.GetAuditEntries("au_s", &database.AuditFilter{})
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		actions := make(map[string]string)
+		for _, e := range es[:3] {
+			actions[e.Action] = e.Before + " -> " + e.After
+		}
+		if len(actions) != 3 || actions["stat description set"] != " -> attack" || actions["stat category set"] != "combat -> melee" ||
+			actions["stat changed"] != "max 100 -> max 100, required" {
+			t.Errorf("[%v] Wrong stat entries: %v", n, actions)
+		}
+	}
+}
+
+// failingAudit rejects every audit entry
+type failingAudit struct {
+	database.DataProvider
+}
+
+func (f *failingAudit) AddAuditEntry(e *database.AuditEntry) (*database.AuditEntry, error) {
+	return nil, errors.New("audit failure")
+}
+
+func (f *failingAudit) RunInTx(fn func(tx database.DataProvider) error) error {
+	return f.DataProvider.RunInTx(func(tx database.DataProvider) error {
+		return fn(&failingAudit{DataProvider: tx})
+	})
+}
+
+func TestAuditFailure(t *testing.T) {
+	for n, f := range providers {
+		d := f()
+		a := audit.NewAuditDb(&failingAudit{DataProvider: d}, "officer", "!g a role")
+
+		if _, err := a.AddRole(&database.Role{GuildId: "au_f", Id: "applied", Permissions: 1}); err != nil {
+			t.Fatalf("[%v] Applied change expected to succeed. Received: %v", n, err)
+		}
+		err := a.RunInTx(func(tx database.DataProvider) error {
+			_, err := tx.AddRole(&database.Role{GuildId: "au_f", Id: "rolled back", Permissions: 1})
+			return err
+		})
+		if err == nil {
+			t.Fatalf("[%v] Error expected for a transaction", n)
+		}
+
+		rs, err := d.GetGuildRoles("au_f")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if len(rs) != 1 || rs[0].Id != "applied" {
+			t.Fatalf("[%v] Only change made outside of the transaction expected. Received: %v", n, rs)
+		}
+	}
+}
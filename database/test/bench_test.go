@@ -0,0 +1,131 @@
+package database_test
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/database/memory"
+)
+
+const (
+	benchUsers = 50
+	benchChars = 2
+)
+
+// characters in benchmarked databases, every guild has benchUsers users with benchChars characters each
+var (
+	benchSizes = []int{1000, 10000, 100000}
+	benchDbs   = make(map[int]*memory.MemoryDB)
+)
+
+func benchProvider(n int) *memory.MemoryDB {
+	if d, ok := benchDbs[n]; ok {
+		return d
+	}
+
+	d := memory.NewMemoryDb()
+	for g := 0; g < n/(benchUsers*benchChars); g++ {
+		gid := fmt.Sprintf("g%v", g)
+		for u := 0; u < benchUsers; u++ {
+			uid := fmt.Sprintf("u%v", u)
+			for c := 0; c < benchChars; c++ {
+				name := fmt.Sprintf("%v_%v", uid, c)
+				if _, err := d.AddCharacter(&database.Character{GuildId: gid, UserId: uid, Name: name, Main: c == 0}); err != nil {
+					panic(err)
+				}
+				if _, err := d.SetCharacterStat(gid, uid, name, "lvl", u*benchChars+c); err != nil {
+					panic(err)
+				}
+			}
+		}
+	}
+	benchDbs[n] = d
+	return d
+}
+
+// runCharBench compares an indexed lookup with a scan over all characters of the database,
+// the way lookups worked before the indexes
+func runCharBench(b *testing.B, index func(d *memory.MemoryDB, g string) error, scan func(d *memory.MemoryDB, g string)) {
+	for _, n := range benchSizes {
+		d := benchProvider(n)
+		guilds := n / (benchUsers * benchChars)
+
+		b.Run(fmt.Sprintf("index/%v", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if err := index(d, fmt.Sprintf("g%v", i%guilds)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run(fmt.Sprintf("scan/%v", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				scan(d, fmt.Sprintf("g%v", i%guilds))
+			}
+		})
+	}
+}
+
+func scanCharacters(d *memory.MemoryDB, f func(c *database.Character) bool) []*database.Character {
+	rv := make([]*database.Character, 0, 10)
+	for _, c := range d.Chars {
+		if f(c) {
+			tmp := *c
+			tmp.Body = make(map[string]interface{}, len(c.Body))
+			for k, v := range c.Body {
+				tmp.Body[k] = v
+			}
+			rv = append(rv, &tmp)
+		}
+	}
+	return rv
+}
+
+func BenchmarkCharGets(b *testing.B) {
+	runCharBench(b, func(d *memory.MemoryDB, g string) error {
+		_, err := d.GetCharacters(g, "u1")
+		return err
+	}, func(d *memory.MemoryDB, g string) {
+		scanCharacters(d, func(c *database.Character) bool { return c.GuildId == g && c.UserId == "u1" })
+	})
+}
+
+func BenchmarkCharGetMain(b *testing.B) {
+	runCharBench(b, func(d *memory.MemoryDB, g string) error {
+		_, err := d.GetMainCharacter(g, "u1")
+		return err
+	}, func(d *memory.MemoryDB, g string) {
+		scanCharacters(d, func(c *database.Character) bool { return c.GuildId == g && c.UserId == "u1" && c.Main })
+	})
+}
+
+func BenchmarkCharGetsByName(b *testing.B) {
+	runCharBench(b, func(d *memory.MemoryDB, g string) error {
+		_, err := d.GetCharactersByName(g, "u1_1")
+		return err
+	}, func(d *memory.MemoryDB, g string) {
+		scanCharacters(d, func(c *database.Character) bool { return c.GuildId == g && c.Name == "u1_1" })
+	})
+}
+
+func BenchmarkCharGetsSorted(b *testing.B) {
+	runCharBench(b, func(d *memory.MemoryDB, g string) error {
+		_, err := d.GetCharactersSorted(g, "lvl", database.Number, false, 10)
+		return err
+	}, func(d *memory.MemoryDB, g string) {
+		rv := scanCharacters(d, func(c *database.Character) bool { return c.GuildId == g })
+		sort.Slice(rv, func(i int, j int) bool {
+			return database.CompareStatValues(database.Number, rv[i].Body["lvl"], rv[j].Body["lvl"]) > 0
+		})
+	})
+}
+
+func BenchmarkCharGetsOutdated(b *testing.B) {
+	runCharBench(b, func(d *memory.MemoryDB, g string) error {
+		_, err := d.GetCharactersOutdated(g, 1)
+		return err
+	}, func(d *memory.MemoryDB, g string) {
+		scanCharacters(d, func(c *database.Character) bool { return c.GuildId == g && c.StatVersion < 1 })
+	})
+}
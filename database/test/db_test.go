@@ -2,24 +2,69 @@ package database_test
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
 
 	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/database/audit"
+	"github.com/mebaranov/disguildie/database/conformance"
+	"github.com/mebaranov/disguildie/database/events"
+	"github.com/mebaranov/disguildie/database/journal"
 	"github.com/mebaranov/disguildie/database/memory"
+	"github.com/mebaranov/disguildie/database/sqlite"
 )
 
-var testable map[string]database.DataProvider = map[string]database.DataProvider{
-	"memory": memory.NewMemoryDb(),
+var providers map[string]func() database.DataProvider = map[string]func() database.DataProvider{
+	"memory": func() database.DataProvider { return memory.NewMemoryDb() },
+	"sqlite": newSqliteDb,
 }
 
-func assertError(oe error, message string, code database.ErrorCode, dbn string) string {
-	e := database.ErrToDbErr(oe)
-	if e == nil {
-		return fmt.Sprintf("Expected Database Error. Received: %v", oe)
+func newSqliteDb() database.DataProvider {
+	db, err := sqlite.NewSqliteDb(":memory:")
+	if err != nil {
+		panic(err)
 	}
 
-	if e.Code != code || e.Message != message {
-		return fmt.Sprintf("[%v] Wrong error message. Actual: [%v, %v], Expected: [%v, %v]", dbn, e.Code, e.Message, code, message)
+	return db
+}
+
+func TestMemory(t *testing.T) {
+	conformance.Run(t, "memory", providers["memory"])
+}
+
+func TestSqlite(t *testing.T) {
+	conformance.Run(t, "sqlite", providers["sqlite"])
+}
+
+func TestJournal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatalf("No errors expected. Received: %v", err)
 	}
+	defer os.RemoveAll(dir)
+
+	i := 0
+	conformance.Run(t, "journal", func() database.DataProvider {
+		i++
+		j, err := journal.NewJournalDb(memory.NewMemoryDb(), filepath.Join(dir, fmt.Sprintf("journal%v.log", i)))
+		if err != nil {
+			t.Fatalf("No errors expected. Received: %v", err)
+		}
+
+		return j
+	})
+}
+
+func TestEvents(t *testing.T) {
+	conformance.Run(t, "events", func() database.DataProvider {
+		return events.NewEventDb(memory.NewMemoryDb())
+	})
+}
 
-	return ""
+func TestAudit(t *testing.T) {
+	conformance.Run(t, "audit", func() database.DataProvider {
+		return audit.NewAuditDb(memory.NewMemoryDb(), "author", "command")
+	})
 }
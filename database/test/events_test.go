@@ -0,0 +1,130 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/database/events"
+)
+
+func nextEvent(t *testing.T, n string, s *events.Subscription) events.Event {
+	select {
+	case e, ok := <-s.C:
+		if !ok {
+			t.Fatalf("[%v] Subscription is closed unexpectedly", n)
+		}
+		return e
+	case <-time.After(time.Second):
+		t.Fatalf("[%v] Event expected", n)
+	}
+
+	return events.Event{}
+}
+
+func TestEventsOrder(t *testing.T) {
+	for n, f := range providers {
+		d := events.NewEventDb(f())
+		all := d.Subscribe(0)
+		stats := d.Subscribe(1, events.CharacterStatSet)
+
+		g, err := d.AddGuild(&database.Guild{DiscordId: "ev_d", Name: "main"})
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if _, err = d.AddGuild(&database.Guild{Name: "sub", ParentId: g.GuildId}); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if _, err = d.AddCharacter(&database.Character{GuildId: "ev_d", UserId: "u", Name: "ch"}); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		for i := 1; i <= 50; i++ {
+			if _, err = d.SetCharacterStat("ev_d", "u", "ch", "lvl", i); err != nil {
+				t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+			}
+		}
+		if _, err = d.RemoveCharacter("ev_d", "u", "ch"); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if rce, err := d.RemoveRole("ev_d", uuid.New().String()); err == nil {
+			t.Fatalf("[%v] Error expected. Received: %v", n, rce)
+		}
+
+		e := nextEvent(t, n, all)
+		if e.Type != events.GuildAdded || e.GuildId != "ev_d" {
+			t.Fatalf("[%v] Wrong event. Actual: %v, expected: %v", n, e, events.GuildAdded)
+		}
+		e = nextEvent(t, n, all)
+		if e.Type != events.GuildAdded || e.GuildId != "ev_d" || e.Guild.Name != "sub" {
+			t.Fatalf("[%v] Sub-guild event expected to be bound to top level guild: %v", n, e)
+		}
+		e = nextEvent(t, n, all)
+		if e.Type != events.CharacterAdded || e.Character.Name != "ch" || e.UserId != "u" {
+			t.Fatalf("[%v] Wrong event. Actual: %v, expected: %v", n, e, events.CharacterAdded)
+		}
+
+		for i := 1; i <= 50; i++ {
+			for _, s := range []*events.Subscription{all, stats} {
+				e = nextEvent(t, n, s)
+				if e.Type != events.CharacterStatSet || e.Stat != "lvl" || e.Value != i || e.Character.Body["lvl"] != i {
+					t.Fatalf("[%v] Stat events expected in order. Actual: %v, expected value: %v", n, e, i)
+				}
+				if i > 1 && e.Previous.Body["lvl"] != i-1 {
+					t.Fatalf("[%v] Wrong previous character state: %v", n, e.Previous)
+				}
+			}
+		}
+
+		e = nextEvent(t, n, all)
+		if e.Type != events.CharacterRemoved || e.Character != nil || e.Previous.Name != "ch" {
+			t.Fatalf("[%v] Wrong event. Actual: %v, expected: %v", n, e, events.CharacterRemoved)
+		}
+
+		d.Unsubscribe(all)
+		d.Unsubscribe(stats)
+		if _, ok := <-all.C; ok {
+			t.Fatalf("[%v] No events expected after failed change", n)
+		}
+	}
+}
+
+func TestEventsTransaction(t *testing.T) {
+	for n, f := range providers {
+		d := events.NewEventDb(f())
+		s := d.Subscribe(10)
+
+		err := d.RunInTx(func(tx database.DataProvider) error {
+			if _, err := tx.AddRole(&database.Role{GuildId: "ev_d", Id: "rolled back"}); err != nil {
+				return err
+			}
+			return errors.New("failure")
+		})
+		if err == nil {
+			t.Fatalf("[%v] Error expected", n)
+		}
+
+		err = d.RunInTx(func(tx database.DataProvider) error {
+			if _, err := tx.AddRole(&database.Role{GuildId: "ev_d", Id: "committed"}); err != nil {
+				return err
+			}
+			_, err := tx.SetRolePermissions("ev_d", "committed", 1)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+
+		e := nextEvent(t, n, s)
+		if e.Type != events.RoleAdded || e.Role.Id != "committed" {
+			t.Fatalf("[%v] Only committed events expected. Received: %v", n, e)
+		}
+		e = nextEvent(t, n, s)
+		if e.Type != events.RolePermissionsSet || e.Role.Permissions != 1 {
+			t.Fatalf("[%v] Wrong event. Actual: %v, expected: %v", n, e, events.RolePermissionsSet)
+		}
+		d.Unsubscribe(s)
+	}
+}
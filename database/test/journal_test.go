@@ -0,0 +1,415 @@
+package database_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/database/journal"
+	"github.com/mebaranov/disguildie/database/snapshot"
+)
+
+func openJournal(t *testing.T, n string, d database.DataProvider, path string) *journal.JournalDB {
+	j, err := journal.NewJournalDb(d, path)
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+
+	return j
+}
+
+func TestJournalReplay(t *testing.T) {
+	for n, f := range providers {
+		dir, err := ioutil.TempDir("", "journal")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "journal.log")
+
+		j := openJournal(t, n, f(), path)
+		g := fillSnapshotDb(t, n, j, "journal")
+		sub, err := j.AddGuild(&database.Guild{Name: "sub", ParentId: g.GuildId})
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if _, err = j.AddRole(&database.Role{GuildId: "snap_d", Id: "r1", Permissions: 1}); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if _, err = j.SetRolePermissions("snap_d", "r1", 2); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		valid := time.Now().Add(time.Hour)
+		if _, err = j.AddMoney(&database.Money{GuildId: "snap_d", UserId: "snap_u", Price: 10, ValidTo: time.Now()}); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if _, err = j.SetMoneyValid("snap_d", valid); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if rce, err := j.AddCharacter(&database.Character{GuildId: "snap_d", UserId: "snap_u", Name: "ch"}); err == nil {
+			t.Fatalf("[%v] Error expected. Received: %v", n, rce)
+		}
+		if err = j.Close(); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+
+		d := f()
+		j = openJournal(t, n, d, path)
+		if err = j.Replay(nil, 0); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+
+		rs, err := d.GetGuild(sub.GuildId)
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if rs.Name != "sub" || rs.ParentId != g.GuildId {
+			t.Fatalf("[%v] Wrong sub-guild replayed. Actual: %v, expected: %v", n, rs, sub)
+		}
+
+		c, err := d.GetMainCharacter("snap_d", "snap_u")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if c.Name != "ch" || c.Body["lvl"] != 42 {
+			t.Fatalf("[%v] Wrong character replayed: %v", n, c)
+		}
+
+		r, err := d.GetRole("snap_d", "r1")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if r.Permissions != 2 {
+			t.Fatalf("[%v] Wrong role replayed: %v", n, r)
+		}
+
+		m, err := d.GetMoney("snap_d")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if !m.ValidTo.Equal(valid) {
+			t.Fatalf("[%v] Wrong money replayed. Actual: %v, expected: %v", n, m.ValidTo, valid)
+		}
+		j.Close()
+	}
+}
+
+func TestJournalCompaction(t *testing.T) {
+	for n, f := range providers {
+		dir, err := ioutil.TempDir("", "journal")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "journal.log")
+
+		j := openJournal(t, n, f(), path)
+		g := fillSnapshotDb(t, n, j, "before")
+		if _, err = snapshot.NewSnapshotter(j, dir, 2, time.Hour).Save(); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if info.Size() != 0 {
+			t.Fatalf("[%v] Journal expected to be compacted. Size: %v", n, info.Size())
+		}
+
+		if _, err = j.RenameGuild(g.GuildId, "after"); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if _, err = j.SetCharacterStat("snap_d", "snap_u", "ch", "lvl", 43); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		j.Close()
+
+		d := f()
+		j = openJournal(t, n, d, path)
+		if _, err = snapshot.NewSnapshotter(j, dir, 2, time.Hour).Restore(); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+
+		rg, err := d.GetGuildD("snap_d")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if rg.Name != "after" {
+			t.Fatalf("[%v] Journal expected to be replayed over snapshot: %v", n, rg)
+		}
+
+		c, err := d.GetMainCharacter("snap_d", "snap_u")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if c.Body["lvl"] != 43 {
+			t.Fatalf("[%v] Wrong character restored: %v", n, c)
+		}
+		j.Close()
+	}
+}
+
+func TestJournalBrokenTail(t *testing.T) {
+	for n, f := range providers {
+		dir, err := ioutil.TempDir("", "journal")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "journal.log")
+
+		j := openJournal(t, n, f(), path)
+		if _, err = j.AddRole(&database.Role{GuildId: "g", Id: "r1", Permissions: 1}); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		j.Close()
+
+		fl, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0600)
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		fl.Write([]byte{0, 0, 1, 0, 1, 2, 3})
+		fl.Close()
+
+		j = openJournal(t, n, f(), path)
+		if _, err = j.AddRole(&database.Role{GuildId: "g", Id: "r2", Permissions: 2}); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		j.Close()
+
+		d := f()
+		j = openJournal(t, n, d, path)
+		if err = j.Replay(nil, 0); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		j.Close()
+
+		rs, err := d.GetGuildRoles("g")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if len(rs) != 2 {
+			t.Fatalf("[%v] Both roles expected to be replayed. Received: %v", n, rs)
+		}
+	}
+}
+
+func TestJournalTransactions(t *testing.T) {
+	for n, f := range providers {
+		dir, err := ioutil.TempDir("", "journal")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "journal.log")
+
+		j := openJournal(t, n, f(), path)
+		err = j.RunInTx(func(tx database.DataProvider) error {
+			_, err := tx.AddRole(&database.Role{GuildId: "g", Id: "committed", Permissions: 1})
+			return err
+		})
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+
+		err = j.RunInTx(func(tx database.DataProvider) error {
+			if _, err := tx.AddRole(&database.Role{GuildId: "g", Id: "rolled back", Permissions: 1}); err != nil {
+				return err
+			}
+			return errors.New("failure")
+		})
+		if err == nil {
+			t.Fatalf("[%v] Error expected", n)
+		}
+		j.Close()
+
+		d := f()
+		j = openJournal(t, n, d, path)
+		if err = j.Replay(nil, 0); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		j.Close()
+
+		rs, err := d.GetGuildRoles("g")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if len(rs) != 1 || rs[0].Id != "committed" {
+			t.Fatalf("[%v] Only committed role expected. Received: %v", n, rs)
+		}
+	}
+}
+
+// writes made outside of a transaction while it runs are replayed in the same order as they were applied
+func TestJournalConcurrentTransaction(t *testing.T) {
+	for n, f := range providers {
+		dir, err := ioutil.TempDir("", "journal")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "journal.log")
+
+		d := f()
+		j := openJournal(t, n, d, path)
+		done := make(chan error)
+		err = j.RunInTx(func(tx database.DataProvider) error {
+			if _, err := tx.AddSubmission(&database.Submission{GuildId: "g", UserId: "u", Stat: "lvl", Value: 1}); err != nil {
+				return err
+			}
+			go func() {
+				_, err := j.AddSubmission(&database.Submission{GuildId: "g", UserId: "u", Stat: "lvl", Value: 2})
+				done <- err
+			}()
+			time.Sleep(20 * time.Millisecond)
+
+			_, err := tx.AddSubmission(&database.Submission{GuildId: "g", UserId: "u", Stat: "lvl", Value: 3})
+			return err
+		})
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if err = <-done; err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		j.Close()
+
+		expected, err := d.GetSubmissions("g")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+
+		d = f()
+		j = openJournal(t, n, d, path)
+		if err = j.Replay(nil, 0); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		j.Close()
+
+		ss, err := d.GetSubmissions("g")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if len(ss) != len(expected) {
+			t.Fatalf("[%v] Submissions expected to be replayed. Actual: %v, expected: %v", n, ss, expected)
+		}
+		for i := range ss {
+			if ss[i].Id != expected[i].Id || ss[i].Value != expected[i].Value {
+				t.Fatalf("[%v] Submissions expected to be replayed in order. Actual: %v, expected: %v", n, ss[i], expected[i])
+			}
+		}
+	}
+}
+
+func TestJournalKeptForOlderSnapshots(t *testing.T) {
+	for n, f := range providers {
+		dir, err := ioutil.TempDir("", "journal")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "journal.log")
+
+		j := openJournal(t, n, f(), path)
+		g := fillSnapshotDb(t, n, j, "old")
+		s := snapshot.NewSnapshotter(j, dir, 2, time.Hour)
+		if _, err = s.Save(); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if _, err = j.RenameGuild(g.GuildId, "middle"); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		newest, err := s.Save()
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if _, err = j.SetCharacterStat("snap_d", "snap_u", "ch", "lvl", 43); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		j.Close()
+
+		if err = ioutil.WriteFile(newest, []byte("garbage"), 0600); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+
+		d := f()
+		j = openJournal(t, n, d, path)
+		if _, err = snapshot.NewSnapshotter(j, dir, 2, time.Hour).Restore(); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		j.Close()
+
+		rg, err := d.GetGuildD("snap_d")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if rg.Name != "middle" {
+			t.Fatalf("[%v] Changes made between snapshots expected to be replayed: %v", n, rg)
+		}
+		c, err := d.GetMainCharacter("snap_d", "snap_u")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if c.Body["lvl"] != 43 {
+			t.Fatalf("[%v] Changes made after the newest snapshot expected to be replayed: %v", n, c)
+		}
+	}
+}
+
+func TestJournalGap(t *testing.T) {
+	for n, f := range providers {
+		dir, err := ioutil.TempDir("", "journal")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, "journal.log")
+
+		j := openJournal(t, n, f(), path)
+		g := fillSnapshotDb(t, n, j, "old")
+		s := snapshot.NewSnapshotter(j, dir, 1, time.Hour)
+		old, err := s.Save()
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		b, err := ioutil.ReadFile(old)
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+
+		// the older snapshot is rotated away and the journal is compacted up to the newest one
+		if _, err = j.RenameGuild(g.GuildId, "middle"); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		newest, err := s.Save()
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if _, err = j.RenameGuild(g.GuildId, "new"); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		j.Close()
+
+		if err = ioutil.WriteFile(old, b, 0600); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if err = ioutil.WriteFile(newest, []byte("garbage"), 0600); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+
+		j = openJournal(t, n, f(), path)
+		file, err := snapshot.NewSnapshotter(j, dir, 1, time.Hour).Restore()
+		j.Close()
+		if err == nil {
+			t.Fatalf("[%v] Error expected. Restored: %v", n, file)
+		}
+		if e := database.ErrToDbErr(err); e == nil || e.Code != database.IOErrorDuringImport {
+			t.Fatalf("[%v] Import error expected. Received: %v", n, err)
+		}
+	}
+}
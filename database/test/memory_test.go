@@ -0,0 +1,91 @@
+package database_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/database/memory"
+)
+
+func TestMemoryRollbackKeepsOtherChanges(t *testing.T) {
+	d := memory.NewMemoryDb()
+	if _, err := d.AddRole(&database.Role{GuildId: "gid1", Id: "rid1", Permissions: 1}); err != nil {
+		t.Fatalf("No errors expected. Received: %v", err)
+	}
+	if _, err := d.AddCharacter(&database.Character{GuildId: "gid1", UserId: "uid1", Name: "ch", Body: map[string]interface{}{"lvl": 1}}); err != nil {
+		t.Fatalf("No errors expected. Received: %v", err)
+	}
+	if _, err := d.AddTrashItem(&database.TrashItem{GuildId: "gid1", Name: "old", Time: time.Unix(100, 0)}); err != nil {
+		t.Fatalf("No errors expected. Received: %v", err)
+	}
+
+	expected := errors.New("failure")
+	err := d.RunInTx(func(tx database.DataProvider) error {
+		if _, err := tx.SetCharacterStat("gid1", "uid1", "", "lvl", 2); err != nil {
+			return err
+		}
+		if _, err := tx.AddStatHistory(&database.StatHistory{GuildId: "gid1", UserId: "uid1", Character: "ch", Stat: "lvl", Value: 2}); err != nil {
+			return err
+		}
+		if _, err := tx.RenameCharacter("gid1", "uid1", "ch", "ch2"); err != nil {
+			return err
+		}
+		if _, err := tx.PurgeTrash("gid1", time.Unix(200, 0)); err != nil {
+			return err
+		}
+		if _, err := tx.AddSubmission(&database.Submission{GuildId: "gid1", UserId: "uid1", Character: "ch2"}); err != nil {
+			return err
+		}
+
+		// changes made outside of the transaction are not reverted
+		if _, err := d.SetRolePermissions("gid1", "rid1", 2); err != nil {
+			return err
+		}
+		if _, err := d.AddRole(&database.Role{GuildId: "gid1", Id: "rid2", Permissions: 3}); err != nil {
+			return err
+		}
+
+		return expected
+	})
+	if err != expected {
+		t.Fatalf("Original error expected. Received: %v", err)
+	}
+
+	if r, err := d.GetRole("gid1", "rid1"); err != nil || r.Permissions != 2 {
+		t.Fatalf("Change made outside of transaction expected to be kept. Received: %v, %v", r, err)
+	}
+	if r, err := d.GetRole("gid1", "rid2"); err != nil || r.Permissions != 3 {
+		t.Fatalf("Role added outside of transaction expected to be kept. Received: %v, %v", r, err)
+	}
+
+	c, err := d.GetCharacter("gid1", "uid1", "ch")
+	if err != nil {
+		t.Fatalf("No errors expected. Received: %v", err)
+	}
+	if c.Body["lvl"] != 1 {
+		t.Fatalf("Stat expected to be rolled back. Received: %v", c)
+	}
+	if rcs, _ := d.GetCharactersByName("gid1", "ch2"); len(rcs) != 0 {
+		t.Fatalf("Rename expected to be rolled back. Received: %v", rcs)
+	}
+	if h, _ := d.GetStatHistory("gid1", "uid1", "ch", "lvl"); len(h) != 0 {
+		t.Fatalf("History expected to be rolled back. Received: %v", h)
+	}
+	if items, _ := d.GetTrashItems("gid1"); len(items) != 1 || items[0].Name != "old" {
+		t.Fatalf("Purged items expected to be restored. Received: %v", items)
+	}
+	if subs, _ := d.GetSubmissions("gid1"); len(subs) != 0 {
+		t.Fatalf("Submission expected to be rolled back. Received: %v", subs)
+	}
+
+	// IDs of rolled back entries are given out again, as they would be after replay of the journal
+	s, err := d.AddSubmission(&database.Submission{GuildId: "gid1", UserId: "uid1", Character: "ch"})
+	if err != nil {
+		t.Fatalf("No errors expected. Received: %v", err)
+	}
+	if s.Id != 1 {
+		t.Fatalf("Submission ID expected to be reused. Received: %v", s.Id)
+	}
+}
@@ -0,0 +1,253 @@
+package database_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/database/snapshot"
+)
+
+func fillSnapshotDb(t *testing.T, n string, d database.DataProvider, name string) *database.Guild {
+	g, err := d.AddGuild(&database.Guild{DiscordId: "snap_d", Name: name})
+	if err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if g, err = d.AddGuildStat(g.GuildId, &database.Stat{ID: "lvl", Type: database.Number, Description: "level"}); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if _, err = d.AddCharacter(&database.Character{GuildId: "snap_d", UserId: "snap_u", Name: "ch", Main: true}); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+	if _, err = d.SetCharacterStat("snap_d", "snap_u", "ch", "lvl", 42); err != nil {
+		t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+	}
+
+	return g
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	for n, f := range providers {
+		dir, err := ioutil.TempDir("", "snap")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		defer os.RemoveAll(dir)
+
+		d := f()
+		g := fillSnapshotDb(t, n, d, "guild")
+		if _, err = snapshot.NewSnapshotter(d, dir, 3, time.Hour).Save(); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+
+		d = f()
+		file, err := snapshot.NewSnapshotter(d, dir, 3, time.Hour).Restore()
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if file == "" {
+			t.Fatalf("[%v] Snapshot file expected to be restored", n)
+		}
+
+		rg, err := d.GetGuildD("snap_d")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if rg.GuildId != g.GuildId || rg.Name != "guild" || rg.DefaultStat != "lvl" {
+			t.Fatalf("[%v] Wrong guild restored. Actual: %v, expected: %v", n, rg, g)
+		}
+
+		if _, err = d.AddGuildStat(g.GuildId, &database.Stat{ID: "cls", Type: database.Str}); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if rg, err = d.GetGuildD("snap_d"); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if _, ok := rg.Stats["cls"]; !ok {
+			t.Fatalf("[%v] Stat added by ID is expected to be visible by discord ID: %v", n, rg.Stats)
+		}
+
+		c, err := d.GetMainCharacter("snap_d", "snap_u")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if c.Name != "ch" || c.Body["lvl"] != 42 {
+			t.Fatalf("[%v] Wrong character restored: %v", n, c)
+		}
+	}
+}
+
+// run with -race to catch character writes racing with export
+func TestSnapshotConcurrentWrites(t *testing.T) {
+	for n, f := range providers {
+		dir, err := ioutil.TempDir("", "snap")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		defer os.RemoveAll(dir)
+
+		d := f()
+		fillSnapshotDb(t, n, d, "guild")
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 100; i++ {
+				d.SetCharacterStat("snap_d", "snap_u", "ch", "lvl", i)
+				d.ChangeMainCharacter("snap_d", "snap_u", "ch")
+				d.RemoveCharacterStat("snap_d", "snap_u", "ch", "lvl")
+				d.GetCharacter("snap_d", "snap_u", "ch")
+			}
+		}()
+
+		s := snapshot.NewSnapshotter(d, dir, 3, time.Hour)
+		for i := 0; i < 10; i++ {
+			if _, err = s.Save(); err != nil {
+				t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+			}
+		}
+		<-done
+	}
+}
+
+func TestSnapshotRestoreEmpty(t *testing.T) {
+	for n, f := range providers {
+		dir, err := ioutil.TempDir("", "snap")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		defer os.RemoveAll(dir)
+
+		file, err := snapshot.NewSnapshotter(f(), filepath.Join(dir, "missing"), 3, time.Hour).Restore()
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if file != "" {
+			t.Fatalf("[%v] Nothing expected to be restored. Received: %v", n, file)
+		}
+	}
+}
+
+func TestSnapshotFallback(t *testing.T) {
+	for n, f := range providers {
+		dir, err := ioutil.TempDir("", "snap")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		defer os.RemoveAll(dir)
+
+		d := f()
+		g := fillSnapshotDb(t, n, d, "old")
+		s := snapshot.NewSnapshotter(d, dir, 3, time.Hour)
+		old, err := s.Save()
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+
+		if _, err = d.RenameGuild(g.GuildId, "new"); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		newest, err := s.Save()
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+
+		b, err := ioutil.ReadFile(newest)
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		b[len(b)-1] ^= 0xff
+		if err = ioutil.WriteFile(newest, b, 0600); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+
+		d = f()
+		file, err := snapshot.NewSnapshotter(d, dir, 3, time.Hour).Restore()
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if file != old {
+			t.Fatalf("[%v] Older snapshot expected to be restored. Actual: %v, expected: %v", n, file, old)
+		}
+
+		rg, err := d.GetGuildD("snap_d")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if rg.Name != "old" {
+			t.Fatalf("[%v] Wrong guild restored: %v", n, rg)
+		}
+
+		if err = ioutil.WriteFile(old, []byte("garbage"), 0600); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		file, err = snapshot.NewSnapshotter(f(), dir, 3, time.Hour).Restore()
+		if err == nil {
+			t.Fatalf("[%v] Error expected. Restored: %v", n, file)
+		}
+		if e := database.ErrToDbErr(err); e == nil || e.Code != database.IOErrorDuringImport {
+			t.Fatalf("[%v] Import error expected. Received: %v", n, err)
+		}
+	}
+}
+
+func TestSnapshotRotation(t *testing.T) {
+	for n, f := range providers {
+		dir, err := ioutil.TempDir("", "snap")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		defer os.RemoveAll(dir)
+
+		s := snapshot.NewSnapshotter(f(), dir, 2, time.Hour)
+		saved := make([]string, 0, 4)
+		for i := 0; i < 4; i++ {
+			file, err := s.Save()
+			if err != nil {
+				t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+			}
+			saved = append(saved, file)
+		}
+
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if len(files) != 2 {
+			t.Fatalf("[%v] Wrong number of snapshots kept. Actual: %v, expected: 2", n, len(files))
+		}
+		for _, file := range saved[2:] {
+			if _, err = os.Stat(file); err != nil {
+				t.Fatalf("[%v] Newest snapshots expected to be kept. Received: %v", n, err)
+			}
+		}
+	}
+}
+
+func TestSnapshotClose(t *testing.T) {
+	for n, f := range providers {
+		dir, err := ioutil.TempDir("", "snap")
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		defer os.RemoveAll(dir)
+
+		d := f()
+		s := snapshot.NewSnapshotter(d, dir, 2, time.Hour)
+		s.Start()
+		fillSnapshotDb(t, n, d, "closed")
+		if err = s.Close(); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+
+		d = f()
+		if _, err = snapshot.NewSnapshotter(d, dir, 2, time.Hour).Restore(); err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", n, err)
+		}
+		if _, err = d.GetGuildD("snap_d"); err != nil {
+			t.Fatalf("[%v] Snapshot on close expected. Received: %v", n, err)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package database_test
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/database/sqlite"
+)
+
+// schema of the first release, before versioning
+const firstSchema = `
+CREATE TABLE IF NOT EXISTS guilds (
+	guild_id            TEXT PRIMARY KEY,
+	parent_id           TEXT REFERENCES guilds(guild_id) ON DELETE CASCADE,
+	top_level_parent_id TEXT NOT NULL REFERENCES guilds(guild_id) ON DELETE CASCADE,
+	discord_id          TEXT UNIQUE,
+	name                TEXT NOT NULL,
+	default_stat        TEXT NOT NULL DEFAULT '',
+	stat_version        INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX IF NOT EXISTS guilds_parent ON guilds(parent_id);
+CREATE UNIQUE INDEX IF NOT EXISTS guilds_child_name ON guilds(top_level_parent_id, name) WHERE parent_id IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS guild_stats (
+	guild_id    TEXT NOT NULL REFERENCES guilds(guild_id) ON DELETE CASCADE,
+	stat_id     TEXT NOT NULL,
+	type        INTEGER NOT NULL,
+	description TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (guild_id, stat_id)
+);
+
+CREATE TABLE IF NOT EXISTS users (
+	user_id TEXT PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS user_guilds (
+	user_id     TEXT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+	top_guild   TEXT NOT NULL,
+	guild_id    TEXT NOT NULL,
+	permissions INTEGER NOT NULL,
+	PRIMARY KEY (user_id, top_guild)
+);
+CREATE INDEX IF NOT EXISTS user_guilds_top ON user_guilds(top_guild);
+
+CREATE TABLE IF NOT EXISTS characters (
+	id           INTEGER PRIMARY KEY AUTOINCREMENT,
+	guild_id     TEXT NOT NULL,
+	user_id      TEXT NOT NULL,
+	name         TEXT NOT NULL,
+	main         INTEGER NOT NULL DEFAULT 0,
+	stat_version INTEGER NOT NULL DEFAULT 0,
+	UNIQUE (guild_id, user_id, name)
+);
+CREATE INDEX IF NOT EXISTS characters_name ON characters(guild_id, name);
+CREATE INDEX IF NOT EXISTS characters_version ON characters(guild_id, stat_version);
+
+CREATE TABLE IF NOT EXISTS character_stats (
+	character_id INTEGER NOT NULL REFERENCES characters(id) ON DELETE CASCADE,
+	stat         TEXT NOT NULL,
+	type         INTEGER NOT NULL,
+	int_value    INTEGER,
+	str_value    TEXT,
+	PRIMARY KEY (character_id, stat)
+);
+CREATE INDEX IF NOT EXISTS character_stats_int ON character_stats(stat, type, int_value);
+CREATE INDEX IF NOT EXISTS character_stats_str ON character_stats(stat, type, str_value);
+
+CREATE TABLE IF NOT EXISTS roles (
+	guild_id    TEXT NOT NULL,
+	role_id     TEXT NOT NULL,
+	permissions INTEGER NOT NULL,
+	PRIMARY KEY (guild_id, role_id)
+);
+
+CREATE TABLE IF NOT EXISTS money (
+	guild_id TEXT PRIMARY KEY,
+	user_id  TEXT NOT NULL,
+	valid_to INTEGER NOT NULL,
+	price    INTEGER NOT NULL
+);
+`
+
+func TestSqliteMigration(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlite")
+	if err != nil {
+		t.Fatalf("No errors expected. Received: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "old.db")
+
+	db, err := sql.Open("sqlite3", "file:"+path)
+	if err != nil {
+		t.Fatalf("No errors expected. Received: %v", err)
+	}
+	gid := uuid.New()
+	_, err = db.Exec(firstSchema+`
+INSERT INTO guilds(guild_id, top_level_parent_id, discord_id, name, default_stat) VALUES (?, ?, 'd', 'old', 'lvl');
+INSERT INTO guild_stats(guild_id, stat_id, type) VALUES (?, 'lvl', ?);
+INSERT INTO characters(guild_id, user_id, name, main) VALUES ('d', 'u', 'ch', 1);
+INSERT INTO character_stats(character_id, stat, type, int_value) VALUES (1, 'lvl', ?, 42);`,
+		gid.String(), gid.String(), gid.String(), database.Number, database.Number)
+	db.Close()
+	if err != nil {
+		t.Fatalf("No errors expected. Received: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		s, err := sqlite.NewSqliteDb(path)
+		if err != nil {
+			t.Fatalf("[%v] No errors expected. Received: %v", i, err)
+		}
+
+		c, err := s.GetMainCharacter("d", "u")
+		if err != nil || c.Body["lvl"] != 42 {
+			s.Close()
+			t.Fatalf("[%v] Old data expected to be kept. Received: %v, %v", i, c, err)
+		}
+
+		max := 100.0
+		if _, err = s.AddGuildStat(gid, &database.Stat{ID: "dps", Type: database.Float, Max: &max, Category: "combat"}); err != nil {
+			s.Close()
+			t.Fatalf("[%v] No errors expected. Received: %v", i, err)
+		}
+		if _, err = s.SetCharacterStat("d", "u", "ch", "dps", 12.5); err != nil {
+			s.Close()
+			t.Fatalf("[%v] No errors expected. Received: %v", i, err)
+		}
+
+		g, _ := s.GetGuildD("d")
+		c, _ = s.GetMainCharacter("d", "u")
+		s.Close()
+		if st := g.Stats["dps"]; st == nil || st.Max == nil || *st.Max != max || st.Category != "combat" {
+			t.Fatalf("[%v] New stat columns expected to work. Received: %v", i, st)
+		}
+		if c.Body["dps"] != 12.5 {
+			t.Fatalf("[%v] Decimal values expected to be stored. Received: %v", i, c)
+		}
+	}
+
+	db, err = sql.Open("sqlite3", "file:"+path)
+	if err != nil {
+		t.Fatalf("No errors expected. Received: %v", err)
+	}
+	defer db.Close()
+	if _, err = db.Exec("PRAGMA user_version = 1000"); err != nil {
+		t.Fatalf("No errors expected. Received: %v", err)
+	}
+	if s, err := sqlite.NewSqliteDb(path); err == nil {
+		s.Close()
+		t.Fatalf("Error expected for a database of newer version")
+	}
+}
+
+func TestSqliteMoney(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sqlite")
+	if err != nil {
+		t.Fatalf("No errors expected. Received: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "money.db")
+
+	s, err := sqlite.NewSqliteDb(path)
+	if err != nil {
+		t.Fatalf("No errors expected. Received: %v", err)
+	}
+	m := &database.Money{GuildId: "g", UserId: "u", Price: 10, ValidTo: time.Now()}
+	arg := *m
+	if _, err = s.AddMoney(m); err != nil {
+		s.Close()
+		t.Fatalf("No errors expected. Received: %v", err)
+	}
+	if *m != arg {
+		s.Close()
+		t.Fatalf("Argument expected to stay unchanged. Received: %v", m)
+	}
+	target := time.Now().Add(time.Hour)
+	if _, err = s.SetMoneyValid("g", target); err != nil {
+		s.Close()
+		t.Fatalf("No errors expected. Received: %v", err)
+	}
+	s.Close()
+
+	// monotonic clock reading is lost with the process, the instant is kept
+	s, err = sqlite.NewSqliteDb(path)
+	if err != nil {
+		t.Fatalf("No errors expected. Received: %v", err)
+	}
+	defer s.Close()
+	rc, err := s.GetMoney("g")
+	if err != nil {
+		t.Fatalf("No errors expected. Received: %v", err)
+	}
+	if rc.UserId != "u" || rc.Price != 10 || !rc.ValidTo.Equal(target) {
+		t.Fatalf("Wrong money returned after reopening. Actual: %v, expected valid to: %v", rc, target)
+	}
+}
@@ -0,0 +1,493 @@
+package formula
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Formula is a parsed arithmetic expression over named variables. Supported syntax:
+// numbers, variables, + - * / %, comparisons (< <= > >= == !=), && || !, parentheses
+// and functions min, max, abs, round, floor, ceil and if(cond, then, else).
+// Comparisons and logical operators return 1 for true and 0 for false
+type Formula struct {
+	src  string
+	root node
+	refs map[string]bool
+}
+
+// Vars resolves variable values during evaluation
+type Vars func(name string) (float64, error)
+
+type node interface {
+	eval(v Vars) (float64, error)
+}
+
+func Parse(s string) (*Formula, error) {
+	p := &parser{src: s, refs: make(map[string]bool)}
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+
+	root, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.kind != tokEnd {
+		return nil, p.errorf("unexpected %v", p.tok.text)
+	}
+
+	return &Formula{src: s, root: root, refs: p.refs}, nil
+}
+
+func (f *Formula) String() string {
+	return f.src
+}
+
+// Refs returns sorted names of variables used by the formula
+func (f *Formula) Refs() []string {
+	rv := make([]string, 0, len(f.refs))
+	for r := range f.refs {
+		rv = append(rv, r)
+	}
+
+	sort.Strings(rv)
+	return rv
+}
+
+func (f *Formula) Eval(v Vars) (float64, error) {
+	rv, err := f.root.eval(v)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(rv) || math.IsInf(rv, 0) {
+		return 0, errors.New("Result is not a number")
+	}
+
+	return rv, nil
+}
+
+// Rename returns formula source with variable old replaced by name
+func Rename(src string, old string, name string) (string, error) {
+	p := &parser{src: src}
+	if err := p.next(); err != nil {
+		return "", err
+	}
+
+	var rv strings.Builder
+	last := 0
+	for p.tok.kind != tokEnd {
+		tok := p.tok
+		if err := p.next(); err != nil {
+			return "", err
+		}
+
+		if tok.kind == tokIdent && tok.text == old && !p.is("(") {
+			rv.WriteString(src[last:tok.start])
+			rv.WriteString(name)
+			last = tok.start + len(tok.text)
+		}
+	}
+	rv.WriteString(src[last:])
+
+	return rv.String(), nil
+}
+
+const (
+	tokEnd = iota
+	tokNum
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	kind  int
+	text  string
+	num   float64
+	start int
+}
+
+type parser struct {
+	src  string
+	pos  int
+	tok  token
+	refs map[string]bool
+}
+
+func (p *parser) errorf(f string, args ...interface{}) error {
+	return errors.New(fmt.Sprintf("Invalid formula at position %v: ", utf8.RuneCountInString(p.src[:p.pos])) + fmt.Sprintf(f, args...))
+}
+
+var twoCharOps = []string{"<=", ">=", "==", "!=", "&&", "||"}
+
+// peek decodes the rune at current position. Stat names are not limited to ASCII
+func (p *parser) peek() (rune, int) {
+	return utf8.DecodeRuneInString(p.src[p.pos:])
+}
+
+func (p *parser) next() error {
+	for p.pos < len(p.src) {
+		c, n := p.peek()
+		if !unicode.IsSpace(c) {
+			break
+		}
+		p.pos += n
+	}
+	if p.pos >= len(p.src) {
+		p.tok = token{kind: tokEnd, text: "end of formula"}
+		return nil
+	}
+
+	start := p.pos
+	defer func() { p.tok.start = start }()
+	c, _ := p.peek()
+	switch {
+	case unicode.IsDigit(c) || c == '.':
+		for p.pos < len(p.src) {
+			c, n := p.peek()
+			if !unicode.IsDigit(c) && c != '.' {
+				break
+			}
+			p.pos += n
+		}
+		n, err := strconv.ParseFloat(p.src[start:p.pos], 64)
+		if err != nil {
+			return p.errorf("bad number %v", p.src[start:p.pos])
+		}
+		p.tok = token{kind: tokNum, text: p.src[start:p.pos], num: n}
+	case unicode.IsLetter(c) || c == '_':
+		for p.pos < len(p.src) {
+			c, n := p.peek()
+			if !unicode.IsLetter(c) && !unicode.IsDigit(c) && c != '_' {
+				break
+			}
+			p.pos += n
+		}
+		p.tok = token{kind: tokIdent, text: p.src[start:p.pos]}
+	default:
+		for _, op := range twoCharOps {
+			if strings.HasPrefix(p.src[p.pos:], op) {
+				p.pos += 2
+				p.tok = token{kind: tokOp, text: op}
+				return nil
+			}
+		}
+		if !strings.ContainsRune("+-*/%<>!(),", c) {
+			return p.errorf("unexpected symbol %c", c)
+		}
+		p.pos++
+		p.tok = token{kind: tokOp, text: string(c)}
+	}
+
+	return nil
+}
+
+func (p *parser) is(op string) bool {
+	return p.tok.kind == tokOp && p.tok.text == op
+}
+
+// binary parses left-associative operators of one precedence level
+func (p *parser) binary(ops []string, operand func() (node, error)) (node, error) {
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		op := ""
+		for _, o := range ops {
+			if p.is(o) {
+				op = o
+			}
+		}
+		if op == "" {
+			return left, nil
+		}
+
+		if err = p.next(); err != nil {
+			return nil, err
+		}
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+func (p *parser) expr() (node, error) {
+	return p.binary([]string{"||"}, p.and)
+}
+
+func (p *parser) and() (node, error) {
+	return p.binary([]string{"&&"}, p.comparison)
+}
+
+func (p *parser) comparison() (node, error) {
+	return p.binary([]string{"<", "<=", ">", ">=", "==", "!="}, p.sum)
+}
+
+func (p *parser) sum() (node, error) {
+	return p.binary([]string{"+", "-"}, p.product)
+}
+
+func (p *parser) product() (node, error) {
+	return p.binary([]string{"*", "/", "%"}, p.unary)
+}
+
+func (p *parser) unary() (node, error) {
+	if p.is("-") || p.is("!") {
+		op := p.tok.text
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		arg, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &unaryNode{op: op, arg: arg}, nil
+	}
+
+	return p.primary()
+}
+
+func (p *parser) primary() (node, error) {
+	tok := p.tok
+	switch {
+	case tok.kind == tokNum:
+		return numNode(tok.num), p.next()
+	case tok.kind == tokIdent:
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		if !p.is("(") {
+			p.refs[tok.text] = true
+			return varNode(tok.text), nil
+		}
+		return p.call(tok.text)
+	case p.is("("):
+		if err := p.next(); err != nil {
+			return nil, err
+		}
+		rv, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.is(")") {
+			return nil, p.errorf("expected )")
+		}
+		return rv, p.next()
+	}
+
+	return nil, p.errorf("unexpected %v", tok.text)
+}
+
+func (p *parser) call(name string) (node, error) {
+	f, ok := functions[strings.ToLower(name)]
+	if !ok {
+		return nil, p.errorf("unknown function %v", name)
+	}
+
+	args := make([]node, 0, 3)
+	if err := p.next(); err != nil {
+		return nil, err
+	}
+	for !p.is(")") {
+		if len(args) > 0 {
+			if !p.is(",") {
+				return nil, p.errorf("expected , or )")
+			}
+			if err := p.next(); err != nil {
+				return nil, err
+			}
+		}
+
+		arg, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+
+	if len(args) < f.min || (f.max > 0 && len(args) > f.max) {
+		return nil, p.errorf("wrong number of arguments for %v", name)
+	}
+	return &callNode{name: name, f: f, args: args}, p.next()
+}
+
+type numNode float64
+
+func (n numNode) eval(v Vars) (float64, error) {
+	return float64(n), nil
+}
+
+type varNode string
+
+func (n varNode) eval(v Vars) (float64, error) {
+	return v(string(n))
+}
+
+type unaryNode struct {
+	op  string
+	arg node
+}
+
+func (n *unaryNode) eval(v Vars) (float64, error) {
+	a, err := n.arg.eval(v)
+	if err != nil {
+		return 0, err
+	}
+
+	if n.op == "-" {
+		return -a, nil
+	}
+	return boolNum(a == 0), nil
+}
+
+type binaryNode struct {
+	op          string
+	left, right node
+}
+
+func (n *binaryNode) eval(v Vars) (float64, error) {
+	l, err := n.left.eval(v)
+	if err != nil {
+		return 0, err
+	}
+
+	// short-circuit, so guards like "games > 0 && wins / games > 0.5" work
+	switch {
+	case n.op == "&&" && l == 0:
+		return 0, nil
+	case n.op == "||" && l != 0:
+		return 1, nil
+	}
+
+	r, err := n.right.eval(v)
+	if err != nil {
+		return 0, err
+	}
+
+	switch n.op {
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		if r == 0 {
+			return 0, errors.New("Division by zero")
+		}
+		return l / r, nil
+	case "%":
+		if r == 0 {
+			return 0, errors.New("Division by zero")
+		}
+		return math.Mod(l, r), nil
+	case "<":
+		return boolNum(l < r), nil
+	case "<=":
+		return boolNum(l <= r), nil
+	case ">":
+		return boolNum(l > r), nil
+	case ">=":
+		return boolNum(l >= r), nil
+	case "==":
+		return boolNum(l == r), nil
+	case "!=":
+		return boolNum(l != r), nil
+	}
+
+	// && and || with the left side not deciding the result
+	return boolNum(r != 0), nil
+}
+
+type function struct {
+	min, max int
+	f        func(args []node, v Vars) (float64, error)
+}
+
+type callNode struct {
+	name string
+	f    *function
+	args []node
+}
+
+func (n *callNode) eval(v Vars) (float64, error) {
+	return n.f.f(n.args, v)
+}
+
+func evalAll(args []node, v Vars) ([]float64, error) {
+	rv := make([]float64, 0, len(args))
+	for _, a := range args {
+		val, err := a.eval(v)
+		if err != nil {
+			return nil, err
+		}
+		rv = append(rv, val)
+	}
+
+	return rv, nil
+}
+
+func unaryFunction(f func(float64) float64) *function {
+	return &function{min: 1, max: 1, f: func(args []node, v Vars) (float64, error) {
+		a, err := args[0].eval(v)
+		if err != nil {
+			return 0, err
+		}
+		return f(a), nil
+	}}
+}
+
+var functions = map[string]*function{
+	"min": {min: 1, f: func(args []node, v Vars) (float64, error) {
+		vals, err := evalAll(args, v)
+		if err != nil {
+			return 0, err
+		}
+		rv := vals[0]
+		for _, a := range vals[1:] {
+			rv = math.Min(rv, a)
+		}
+		return rv, nil
+	}},
+	"max": {min: 1, f: func(args []node, v Vars) (float64, error) {
+		vals, err := evalAll(args, v)
+		if err != nil {
+			return 0, err
+		}
+		rv := vals[0]
+		for _, a := range vals[1:] {
+			rv = math.Max(rv, a)
+		}
+		return rv, nil
+	}},
+	"if": {min: 3, max: 3, f: func(args []node, v Vars) (float64, error) {
+		c, err := args[0].eval(v)
+		if err != nil {
+			return 0, err
+		}
+		if c != 0 {
+			return args[1].eval(v)
+		}
+		return args[2].eval(v)
+	}},
+	"abs":   unaryFunction(math.Abs),
+	"round": unaryFunction(math.Round),
+	"floor": unaryFunction(math.Floor),
+	"ceil":  unaryFunction(math.Ceil),
+}
+
+func boolNum(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
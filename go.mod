@@ -6,6 +6,7 @@ require (
 	github.com/bwmarrin/discordgo v0.22.0
 	github.com/google/go-cmp v0.5.2
 	github.com/google/uuid v1.1.2
+	github.com/mattn/go-sqlite3 v1.14.5
 	golang.org/x/crypto v0.0.0-20201016220609-9e8e0b390897 // indirect
 	golang.org/x/sys v0.0.0-20201029020603-3518587229cd // indirect
 )
@@ -4,10 +4,18 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
+	"syscall"
 	"time"
 
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/database/events"
+	"github.com/mebaranov/disguildie/database/journal"
 	"github.com/mebaranov/disguildie/database/memory"
+	"github.com/mebaranov/disguildie/database/snapshot"
+	"github.com/mebaranov/disguildie/database/sqlite"
 	"github.com/mebaranov/disguildie/processor"
 
 	"github.com/bwmarrin/discordgo"
@@ -32,6 +40,11 @@ func main() {
 		price     int
 		duration  int
 		link      string
+		dbType    string
+		dbPath    string
+		snapDir   string
+		snapEvery int
+		snapKeep  int
 	)
 
 	flag.StringVar(&token, "t", "", "Bot Token")
@@ -40,6 +53,11 @@ func main() {
 	flag.IntVar(&price, "p", -1, "Price (Cents)")
 	flag.IntVar(&duration, "d", -1, "Free trial duration (days)")
 	flag.StringVar(&link, "l", "", "Payment link template")
+	flag.StringVar(&dbType, "db", "", "Database type (memory or sqlite)")
+	flag.StringVar(&dbPath, "dbp", "", "Database file path (sqlite only)")
+	flag.StringVar(&snapDir, "sd", "", "Snapshot and journal directory (memory only)")
+	flag.IntVar(&snapEvery, "si", -1, "Snapshot interval (minutes)")
+	flag.IntVar(&snapKeep, "sk", -1, "Number of snapshots to keep")
 	flag.Parse()
 
 	if token == "" {
@@ -54,6 +72,37 @@ func main() {
 	if link == "" {
 		link = os.Getenv("BOT_PAYMENT_LINK")
 	}
+	if dbType == "" {
+		dbType = os.Getenv("BOT_DATABASE")
+	}
+	if dbPath == "" {
+		dbPath = os.Getenv("BOT_DATABASE_PATH")
+	}
+	if snapDir == "" {
+		snapDir = os.Getenv("BOT_SNAPSHOT_DIR")
+	}
+	if snapEvery == -1 {
+		tmp := os.Getenv("BOT_SNAPSHOT_INTERVAL")
+		var err error
+		if tmp != "" {
+			if snapEvery, err = strconv.Atoi(tmp); err != nil {
+				fmt.Println("Could not parse snapshot interval:", tmp, ". Error:", err.Error())
+			}
+		} else {
+			snapEvery = 5
+		}
+	}
+	if snapKeep == -1 {
+		tmp := os.Getenv("BOT_SNAPSHOT_KEEP")
+		var err error
+		if tmp != "" {
+			if snapKeep, err = strconv.Atoi(tmp); err != nil {
+				fmt.Println("Could not parse snapshot count:", tmp, ". Error:", err.Error())
+			}
+		} else {
+			snapKeep = 5
+		}
+	}
 	if price == -1 {
 		tmp := os.Getenv("BOT_PRICE")
 		var err error
@@ -82,7 +131,64 @@ func main() {
 		return
 	}
 
-	dataProvider := memory.NewMemoryDb()
+	var dataProvider database.DataProvider
+	switch dbType {
+	case "", "memory":
+		dataProvider = memory.NewMemoryDb()
+		if snapDir == "" {
+			break
+		}
+		if snapEvery <= 0 {
+			fmt.Println("Snapshot interval should be positive. Got:", snapEvery)
+			return
+		}
+
+		if err := os.MkdirAll(snapDir, 0700); err != nil {
+			fmt.Println("Could not create snapshot directory:", snapDir, ". Error:", err.Error())
+			return
+		}
+		jdb, err := journal.NewJournalDb(dataProvider, filepath.Join(snapDir, "journal.log"))
+		if err != nil {
+			fmt.Println("Could not open journal in:", snapDir, ". Error:", err.Error())
+			return
+		}
+		defer jdb.Close()
+		dataProvider = jdb
+
+		snap := snapshot.NewSnapshotter(dataProvider, snapDir, snapKeep, time.Duration(snapEvery)*time.Minute)
+		restored, err := snap.Restore()
+		if err != nil {
+			fmt.Println("Could not restore database from:", snapDir, ". Error:", err.Error())
+			return
+		}
+		if restored != "" {
+			fmt.Println("Database restored from:", restored)
+		}
+
+		snap.Start()
+		defer func() {
+			if err := snap.Close(); err != nil {
+				fmt.Println("Could not save snapshot on exit. Error:", err.Error())
+			}
+		}()
+	case "sqlite":
+		if dbPath == "" {
+			dbPath = "disguildie.db"
+		}
+		sdb, err := sqlite.NewSqliteDb(dbPath)
+		if err != nil {
+			fmt.Println("Could not open database:", dbPath, ". Error:", err.Error())
+			return
+		}
+		defer sdb.Close()
+		dataProvider = sdb
+	default:
+		fmt.Println("Unknown database type:", dbType, ". Use \"memory\" or \"sqlite\"")
+		return
+	}
+
+	dataProvider = events.NewEventDb(dataProvider)
+
 	intent := discordgo.IntentsNone
 	for _, i := range intents {
 		intent |= i
@@ -103,8 +209,9 @@ func main() {
 
 	defer p.Close()
 
-	runningChannel := make(chan bool)
+	runningChannel := make(chan os.Signal, 1)
 	// go cmd.Start(runningChannel)
+	signal.Notify(runningChannel, os.Interrupt, syscall.SIGTERM)
 	<-runningChannel
 
 	fmt.Println("Exitting. Hoping to do it gracefully.")
@@ -3,6 +3,8 @@ package message
 import (
 	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/bwmarrin/discordgo"
 	"github.com/google/uuid"
@@ -165,7 +167,7 @@ func (dgm *DiscordGoMessage) CurSegment() string {
 func (dgm *DiscordGoMessage) PeekSegment() string {
 	var rv string
 	tmp := dgm.curMsg
-	for rv == "" && dgm.curMsg != "" {
+	for rv == "" && tmp != "" {
 		rv, tmp = utility.NextCommand(&tmp)
 	}
 
@@ -185,6 +187,54 @@ func (dgm *DiscordGoMessage) SendMessage(s string, strs ...interface{}) {
 	go utility.SendMonitored(dgm.session, &dgm.orig.ChannelID, &msg)
 }
 
+// SendPaged sends long text as a single message with reactions to flip pages.
+// Only the author can flip pages, controls are removed after a timeout
+func (dgm *DiscordGoMessage) SendPaged(s string) {
+	p := NewPager(s)
+	if p.Pages() < 2 {
+		dgm.SendMessage("%v", s)
+		return
+	}
+
+	go dgm.sendPaged(p)
+}
+
+func (dgm *DiscordGoMessage) sendPaged(p *Pager) {
+	ch := dgm.orig.ChannelID
+	sent, err := dgm.session.ChannelMessageSend(ch, p.String())
+	if err != nil {
+		return
+	}
+
+	for _, c := range PageControls {
+		dgm.session.MessageReactionAdd(ch, sent.ID, c)
+	}
+
+	var lock sync.Mutex
+	remove := dgm.session.AddHandler(func(s *discordgo.Session, r *discordgo.MessageReactionAdd) {
+		if r.MessageID != sent.ID || r.UserID == s.State.User.ID {
+			return
+		}
+
+		// reaction is removed so the same control can be used again
+		s.MessageReactionRemove(ch, sent.ID, r.Emoji.APIName(), r.UserID)
+		if r.UserID != dgm.orig.Author.ID {
+			return
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		if p.Turn(r.Emoji.Name) {
+			s.ChannelMessageEdit(ch, sent.ID, p.String())
+		}
+	})
+
+	time.AfterFunc(pageTimeout, func() {
+		remove()
+		dgm.session.MessageReactionsRemoveAll(ch, sent.ID)
+	})
+}
+
 func (dgm *DiscordGoMessage) UserRoles(id string) ([]string, error) {
 	m, err := dgm.session.GuildMember(dgm.orig.GuildID, id)
 	if err != nil {
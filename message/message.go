@@ -26,6 +26,7 @@ type Message interface {
 	MoreSegments() bool
 
 	SendMessage(string, ...interface{})
+	SendPaged(string)
 
 	CheckGuildModificationPermissions(uuid.UUID) (bool, error)
 	CheckUserModificationPermissions(uid string) (bool, error)
@@ -0,0 +1,115 @@
+package message
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const pageLines = 20
+const pageChars = 1900
+const pageTimeout = 5 * time.Minute
+
+// room left on every page for page number
+const pageFooter = len("\nPage 9999/9999")
+
+// PageControls are reactions used to flip pages: first, previous, next and last page
+var PageControls = []string{"\u23ee\ufe0f", "\u2b05\ufe0f", "\u27a1\ufe0f", "\u23ed\ufe0f"}
+
+// Pager splits long command output into pages. The first line is a header repeated on every page
+type Pager struct {
+	header string
+	pages  [][]string
+	cur    int
+}
+
+// NewPager keeps every page within pageChars along with its header and page number.
+// Longer lines are split, the header takes at most half of a page and the rest of it goes to the lines
+func NewPager(text string) *Pager {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	head := splitLine(lines[0], pageChars/2)
+	p := &Pager{header: head[0], pages: [][]string{{}}}
+
+	room := pageChars - len(p.header) - pageFooter
+	l := 0
+	for _, line := range append(head[1:], lines[1:]...) {
+		for _, part := range splitLine(line, room-1) {
+			last := len(p.pages) - 1
+			if n := len(p.pages[last]); n > 0 && (n >= pageLines || l+1+len(part) > room) {
+				p.pages = append(p.pages, []string{})
+				last++
+				l = 0
+			}
+
+			p.pages[last] = append(p.pages[last], part)
+			l += 1 + len(part)
+		}
+	}
+
+	return p
+}
+
+// splitLine cuts line into parts of at most n bytes without breaking characters
+func splitLine(line string, n int) []string {
+	rv := make([]string, 0, 1)
+	for len(line) > n {
+		i := n
+		for i > 0 && !utf8.RuneStart(line[i]) {
+			i--
+		}
+		rv = append(rv, line[:i])
+		line = line[i:]
+	}
+
+	return append(rv, line)
+}
+
+func (p *Pager) Pages() int {
+	return len(p.pages)
+}
+
+func (p *Pager) Page() int {
+	return p.cur
+}
+
+// Turn moves to another page by control reaction. Returns false if the page didn't change
+func (p *Pager) Turn(control string) bool {
+	prev := p.cur
+	control = strings.TrimSuffix(control, "\ufe0f")
+	for i, c := range PageControls {
+		if control != strings.TrimSuffix(c, "\ufe0f") {
+			continue
+		}
+
+		switch i {
+		case 0:
+			p.cur = 0
+		case 1:
+			if p.cur > 0 {
+				p.cur--
+			}
+		case 2:
+			if p.cur < len(p.pages)-1 {
+				p.cur++
+			}
+		case 3:
+			p.cur = len(p.pages) - 1
+		}
+	}
+
+	return p.cur != prev
+}
+
+// String renders current page. Page number is added only if there are several pages
+func (p *Pager) String() string {
+	rv := p.header
+	for _, line := range p.pages[p.cur] {
+		rv += "\n" + line
+	}
+
+	if len(p.pages) > 1 {
+		rv += fmt.Sprintf("\nPage %v/%v", p.cur+1, len(p.pages))
+	}
+	return rv
+}
@@ -0,0 +1,117 @@
+package message_tests
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/mebaranov/disguildie/message"
+)
+
+func pagerText(header string, lines int, length int) string {
+	rv := header
+	for i := 0; i < lines; i++ {
+		line := fmt.Sprintf("%v", i)
+		rv += "\n" + line + strings.Repeat(".", length-len(line))
+	}
+	return rv + "\n"
+}
+
+func TestPagerSplit(t *testing.T) {
+	testCases := []struct {
+		name  string
+		text  string
+		pages []int
+	}{
+		{name: "header only", text: "Header", pages: []int{0}},
+		{name: "single page", text: pagerText("Header", 20, 10), pages: []int{20}},
+		{name: "by lines", text: pagerText("Header", 45, 10), pages: []int{20, 20, 5}},
+		{name: "by characters", text: pagerText("H", 7, 600), pages: []int{3, 3, 1}},
+		{name: "long lines", text: pagerText("H", 2, 2000), pages: []int{1, 1, 1, 1}},
+		{name: "almost full lines", text: pagerText("H", 3, 1500), pages: []int{1, 1, 1}},
+		{name: "long header", text: pagerText(strings.Repeat("H", 1500), 3, 600), pages: []int{1, 1, 1, 1}},
+		{name: "long non-ASCII line", text: "H\n" + strings.Repeat("ж", 1500), pages: []int{1, 1}},
+	}
+
+	for _, tc := range testCases {
+		p := message.NewPager(tc.text)
+		if p.Pages() != len(tc.pages) {
+			t.Errorf("[%v] Wrong number of pages. Expected: %v, got: %v", tc.name, len(tc.pages), p.Pages())
+			continue
+		}
+
+		header := strings.SplitN(tc.text, "\n", 2)[0]
+		if len(header) > 950 {
+			header = header[:950]
+		}
+		text := header
+		for i, n := range tc.pages {
+			if i > 0 && !p.Turn(message.PageControls[2]) {
+				t.Fatalf("[%v] Page %v expected to be turned", tc.name, i+1)
+			}
+
+			// Discord rejects longer messages
+			if l := len(p.String()); l > 1900 || !utf8.ValidString(p.String()) {
+				t.Errorf("[%v] Page %v is too long or broken: %v characters", tc.name, i+1, l)
+			}
+			lines := strings.Split(p.String(), "\n")
+			if lines[0] != header {
+				t.Errorf("[%v] Header expected on page %v. Got: %v", tc.name, i+1, lines[0])
+			}
+			if len(tc.pages) > 1 {
+				if lines[len(lines)-1] != fmt.Sprintf("Page %v/%v", i+1, len(tc.pages)) {
+					t.Errorf("[%v] Page number expected on page %v. Got: %v", tc.name, i+1, lines[len(lines)-1])
+				}
+				lines = lines[:len(lines)-1]
+			}
+			if len(lines)-1 != n {
+				t.Errorf("[%v] Wrong number of lines on page %v. Expected: %v, got: %v", tc.name, i+1, n, len(lines)-1)
+			}
+			text += strings.Join(lines[1:], "")
+		}
+
+		if strings.Replace(text, "\n", "", -1) != strings.Replace(tc.text, "\n", "", -1) {
+			t.Errorf("[%v] Text expected to be kept in full", tc.name)
+		}
+	}
+}
+
+func TestPagerTurn(t *testing.T) {
+	p := message.NewPager(pagerText("Header", 45, 10))
+	first, prev, next, last := message.PageControls[0], message.PageControls[1], message.PageControls[2], message.PageControls[3]
+
+	testCases := []struct {
+		name    string
+		control string
+		turned  bool
+		page    int
+	}{
+		{name: "previous on first", control: prev, turned: false, page: 0},
+		{name: "first on first", control: first, turned: false, page: 0},
+		{name: "next", control: next, turned: true, page: 1},
+		{name: "last", control: last, turned: true, page: 2},
+		{name: "next on last", control: next, turned: false, page: 2},
+		{name: "last on last", control: last, turned: false, page: 2},
+		{name: "previous", control: prev, turned: true, page: 1},
+		{name: "first", control: first, turned: true, page: 0},
+		{name: "no variation selector", control: strings.TrimSuffix(next, "\ufe0f"), turned: true, page: 1},
+		{name: "unknown", control: "\U0001f44d", turned: false, page: 1},
+	}
+
+	for _, tc := range testCases {
+		if turned := p.Turn(tc.control); turned != tc.turned || p.Page() != tc.page {
+			t.Errorf("[%v] Wrong turn. Expected: %v on page %v, got: %v on page %v", tc.name, tc.turned, tc.page, turned, p.Page())
+		}
+	}
+
+	p = message.NewPager(pagerText("Header", 5, 10))
+	for _, c := range message.PageControls {
+		if p.Turn(c) {
+			t.Errorf("[single page] Page expected to stay with %v", c)
+		}
+	}
+	if strings.Contains(p.String(), "Page") {
+		t.Errorf("[single page] No page number expected. Got: %v", p.String())
+	}
+}
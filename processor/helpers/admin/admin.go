@@ -16,20 +16,29 @@ func NewAdminProcessor(prov database.DataProvider) helpers.MessageProcessor {
 	apg := NewAdminGuildProcessor(prov)
 	apr := NewAdminRoleProcessor(prov)
 	aps := NewAdminStatsProcessor(prov)
+	apa := NewAdminAuditProcessor(prov)
+	apt := NewAdminTrashProcessor(prov)
+	apv := NewAdminApproveProcessor(prov)
 
 	ap.Prov = prov
 
 	ap.Funcs = map[string]func(message.Message) (string, error){
-		"h":     ap.help,
-		"help":  ap.help,
-		"u":     apu.ProcessMessage,
-		"user":  apu.ProcessMessage,
-		"g":     apg.ProcessMessage,
-		"guild": apg.ProcessMessage,
-		"r":     apr.ProcessMessage,
-		"role":  apr.ProcessMessage,
-		"s":     aps.ProcessMessage,
-		"stats": aps.ProcessMessage,
+		"h":       ap.help,
+		"help":    ap.help,
+		"u":       apu.ProcessMessage,
+		"user":    apu.ProcessMessage,
+		"g":       apg.ProcessMessage,
+		"guild":   apg.ProcessMessage,
+		"r":       apr.ProcessMessage,
+		"role":    apr.ProcessMessage,
+		"s":       aps.ProcessMessage,
+		"stats":   aps.ProcessMessage,
+		"au":      apa.ProcessMessage,
+		"audit":   apa.ProcessMessage,
+		"t":       apt.ProcessMessage,
+		"trash":   apt.ProcessMessage,
+		"ap":      apv.ProcessMessage,
+		"approve": apv.ProcessMessage,
 	}
 	return ap
 }
@@ -51,7 +60,10 @@ func (ap *AdminProcessor) help(m message.Message) (string, error) {
 	}
 	if perm&database.CharsPermissions > 0 {
 		rv += "\t-- \"!g admin user\" (\"!g a u\") - users management\n"
+		rv += "\t-- \"!g admin approve\" (\"!g a ap\") - review stat values submitted by members\n"
 	}
+	rv += "\t-- \"!g admin audit\" (\"!g a au\") - changes history\n"
+	rv += "\t-- \"!g admin trash\" (\"!g a t\") - restore removed items\n"
 	if perm&database.EditGuildStructurePerm > 0 {
 		rv += "\t-- \"!g admin stat\" (\"!g a s\") - stats management\n"
 		rv += "\t-- \"!g admin role\" (\"!g a r\") - roles management\n"
@@ -0,0 +1,214 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/message"
+	"github.com/mebaranov/disguildie/processor/helpers"
+)
+
+type AdminApproveProcessor struct {
+	helpers.BaseMessageProcessor
+}
+
+func NewAdminApproveProcessor(prov database.DataProvider) helpers.MessageProcessor {
+	ap := &AdminApproveProcessor{}
+	ap.Prov = prov
+	ap.Funcs = map[string]func(message.Message) (string, error){
+		"h":      ap.help,
+		"help":   ap.help,
+		"l":      ap.list,
+		"list":   ap.list,
+		"a":      ap.accept,
+		"accept": ap.accept,
+		"r":      ap.reject,
+		"reject": ap.reject,
+	}
+	return ap
+}
+
+func (ap *AdminApproveProcessor) list(m message.Message) (string, error) {
+	perm, err := m.AuthorPermissions()
+	if err != nil {
+		return "getting author permissions", err
+	}
+
+	if perm&database.CharsPermissions == 0 {
+		return "", errors.New("You don't have permissions to approve stats")
+	}
+
+	subs, err := ap.Prov.GetSubmissions(m.GuildId())
+	if err != nil {
+		return "getting submissions", err
+	}
+
+	rv := ""
+	for _, s := range subs {
+		if ok, err := ap.canApprove(m, s); err != nil {
+			return "checking modification permissions", err
+		} else if !ok {
+			continue
+		}
+
+		rv += fmt.Sprintf("\t%v: %v = %v for character %v of <@!%v>", s.Id, s.Stat, database.FormatStatValue(s.Value), s.Character, s.UserId)
+		rv += fmt.Sprintf(", submitted by <@!%v> on %v\n", s.SubmittedBy, s.Time.UTC().Format("2006-01-02"))
+	}
+
+	if rv == "" {
+		return "There are no submissions waiting for you", nil
+	}
+	return "Submitted stats:\n" + rv, nil
+}
+
+func (ap *AdminApproveProcessor) accept(m message.Message) (string, error) {
+	sub, step, err := ap.submission(m)
+	if err != nil {
+		return step, err
+	}
+
+	c, err := ap.Prov.GetCharacter(m.GuildId(), sub.UserId, sub.Character)
+	if err != nil {
+		return "getting character", err
+	}
+
+	gs, err := helpers.NewGuildStats(ap.Prov, m.GuildId())
+	if err != nil {
+		return "getting guild stats", err
+	}
+
+	stats, err := gs.UserStats(c.UserId)
+	if err != nil {
+		return "getting user stats", err
+	}
+
+	// the stat may have changed since it was submitted
+	s, ok := stats[sub.Stat]
+	if !ok || s.Type == database.Computed {
+		return "", errors.New(fmt.Sprintf("Stat %v can't be set for character %v anymore. Reject the submission instead", sub.Stat, c.Name))
+	}
+	val, err := s.ConvertValue(sub.Value)
+	if err != nil {
+		return "converting value", err
+	}
+	if err = s.Validate(val); err != nil {
+		return "", err
+	}
+
+	err = ap.Prov.RunInTx(func(tx database.DataProvider) error {
+		step = "removing submission"
+		if _, err := tx.RemoveSubmission(sub.GuildId, sub.Id); err != nil {
+			return err
+		}
+
+		step = "setting character stat"
+		if _, err := tx.SetCharacterStat(c.GuildId, c.UserId, c.Name, s.ID, val); err != nil {
+			return err
+		}
+
+		step = "saving stat history"
+		h := &database.StatHistory{GuildId: c.GuildId, UserId: c.UserId, Character: c.Name, Stat: s.ID, Value: val, Time: time.Now()}
+		if _, err := tx.AddStatHistory(h); err != nil {
+			return err
+		}
+
+		step = "setting stat version"
+		_, err := gs.UpdateCharacter(tx, c)
+		return err
+	})
+	if err != nil {
+		return step, err
+	}
+
+	return fmt.Sprintf("<@!%v>, %v = %v for character %v was approved by <@!%v>",
+		sub.SubmittedBy, s.ID, database.FormatStatValue(val), c.Name, m.AuthorId()), nil
+}
+
+func (ap *AdminApproveProcessor) reject(m message.Message) (string, error) {
+	sub, step, err := ap.submission(m)
+	if err != nil {
+		return step, err
+	}
+
+	reason := strings.TrimSpace(m.LeftOverSegments())
+	if _, err = ap.Prov.RemoveSubmission(sub.GuildId, sub.Id); err != nil {
+		return "removing submission", err
+	}
+
+	rv := fmt.Sprintf("<@!%v>, %v = %v for character %v was rejected by <@!%v>",
+		sub.SubmittedBy, sub.Stat, database.FormatStatValue(sub.Value), sub.Character, m.AuthorId())
+	if reason != "" {
+		rv += ": " + reason
+	}
+	return rv, nil
+}
+
+// submission finds the submission given in the message and checks that the author can approve it
+func (ap *AdminApproveProcessor) submission(m message.Message) (*database.Submission, string, error) {
+	id, err := strconv.Atoi(m.CurSegment())
+	if err != nil {
+		return nil, "", errors.New("Invalid command format")
+	}
+
+	subs, err := ap.Prov.GetSubmissions(m.GuildId())
+	if err != nil {
+		return nil, "getting submissions", err
+	}
+
+	var sub *database.Submission
+	for _, s := range subs {
+		if s.Id == id {
+			sub = s
+		}
+	}
+	if sub == nil {
+		return nil, "", errors.New(fmt.Sprintf("Submission %v does not exist", id))
+	}
+
+	if ok, err := ap.canApprove(m, sub); err != nil {
+		return nil, "checking modification permissions", err
+	} else if !ok {
+		return nil, "", errors.New("You don't have permissions to approve this submission")
+	}
+
+	return sub, "", nil
+}
+
+// canApprove lets officers of the character owner handle submissions. Submissions of users
+// that left the guild can only be handled by guild-wide officers
+func (ap *AdminApproveProcessor) canApprove(m message.Message, s *database.Submission) (bool, error) {
+	u, err := ap.Prov.GetUserD(s.UserId)
+	if err != nil {
+		if dbErr := database.ErrToDbErr(err); dbErr == nil || dbErr.Code != database.UserNotFound {
+			return false, err
+		}
+		u = &database.User{Id: s.UserId, Guilds: map[string]*database.GuildPermission{}}
+	}
+
+	return helpers.IsOfficer(ap.Prov, m, u)
+}
+
+func (ap *AdminApproveProcessor) help(m message.Message) (string, error) {
+	rv := "Here's a list of approval commands you're allowed to use:\n"
+
+	perm, err := m.AuthorPermissions()
+	if err != nil {
+		return "getting permissions", err
+	}
+
+	if perm&database.CharsPermissions == 0 {
+		rv += "Sorry, none. Ask leaders to let you do more"
+		return rv, nil
+	}
+
+	rv += "Values of stats that need approval are submitted by members and wait for officers of their sub-guilds\n"
+	rv += "\t -- \"!g admin approve list\" (\"!g a ap l\") - List submissions you can approve\n"
+	rv += "\t -- \"!g admin approve accept <id>\" (\"!g a ap a <id>\") - Save submitted value to the character\n"
+	rv += "\t -- \"!g admin approve reject <id> <reason>\" (\"!g a ap r <id> <reason>\") - Drop submitted value. Reason is optional\n"
+
+	return rv, nil
+}
@@ -0,0 +1,152 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/message"
+	"github.com/mebaranov/disguildie/processor/helpers"
+	"github.com/mebaranov/disguildie/utility"
+)
+
+const (
+	auditPageSize   = 10
+	auditDateFormat = "2006-01-02"
+)
+
+type AdminAuditProcessor struct {
+	helpers.BaseMessageProcessor
+}
+
+func NewAdminAuditProcessor(prov database.DataProvider) helpers.MessageProcessor {
+	ap := &AdminAuditProcessor{}
+	ap.Prov = prov
+	return ap
+}
+
+func (ap *AdminAuditProcessor) ProcessMessage(m message.Message) (string, error) {
+	if cmd := strings.ToLower(m.PeekSegment()); cmd == "h" || cmd == "help" {
+		return ap.help(m)
+	}
+
+	perm, err := m.AuthorPermissions()
+	if err != nil {
+		return "getting author permissions", err
+	}
+
+	if perm == 0 {
+		return "", errors.New("You don't have permissions to read the audit log")
+	}
+
+	f := &database.AuditFilter{Limit: auditPageSize}
+	page := 1
+	for m.PeekSegment() != "" {
+		key := m.CurSegment()
+		if utility.IsUserMention(key) {
+			if f.UserId, err = utility.ParseUserMention(key); err != nil {
+				return "parsing mention", err
+			}
+			continue
+		}
+
+		val := m.CurSegment()
+		if val == "" {
+			return "", errors.New("Invalid command format")
+		}
+
+		switch strings.ToLower(key) {
+		case "char", "c":
+			f.Character = val
+		case "stat", "s":
+			f.Stat = val
+		case "from", "f":
+			if f.From, err = time.Parse(auditDateFormat, val); err != nil {
+				return "parsing start date", err
+			}
+		case "to", "t":
+			if f.To, err = time.Parse(auditDateFormat, val); err != nil {
+				return "parsing end date", err
+			}
+			// the end date is inclusive
+			f.To = f.To.AddDate(0, 0, 1)
+		case "page", "p":
+			if page, err = strconv.Atoi(val); err != nil || page < 1 {
+				return "", errors.New(fmt.Sprintf("Invalid page number: %v", val))
+			}
+		default:
+			return "", errors.New(fmt.Sprintf("Unknown audit filter \"%v\"", key))
+		}
+	}
+	f.Offset = (page - 1) * auditPageSize
+
+	entries, err := ap.Prov.GetAuditEntries(m.GuildId(), f)
+	if err != nil {
+		return "getting audit entries", err
+	}
+
+	if len(entries) == 0 {
+		if page > 1 {
+			return fmt.Sprintf("There are no audit entries on page %v", page), nil
+		}
+		return "There are no audit entries matching the filter", nil
+	}
+
+	rv := fmt.Sprintf("Audit log, page %v:\n", page)
+	for _, e := range entries {
+		rv += "\t" + formatAuditEntry(e) + "\n"
+	}
+	if len(entries) == auditPageSize {
+		rv += fmt.Sprintf("Use \"page %v\" to see older entries\n", page+1)
+	}
+
+	return rv, nil
+}
+
+func formatAuditEntry(e *database.AuditEntry) string {
+	rv := fmt.Sprintf("[%v] <@!%v> %v", e.Time.UTC().Format("2006-01-02 15:04"), e.AuthorId, e.Action)
+	if e.UserId != "" {
+		rv += fmt.Sprintf(" user <@!%v>", e.UserId)
+	}
+	if e.Character != "" {
+		rv += " character " + e.Character
+	}
+	if e.Stat != "" {
+		rv += " stat " + e.Stat
+	}
+	if e.Target != "" {
+		rv += " " + e.Target
+	}
+	if e.Before != "" || e.After != "" {
+		rv += fmt.Sprintf(": \"%v\" -> \"%v\"", e.Before, e.After)
+	}
+
+	return rv + fmt.Sprintf(" (%v)", e.Command)
+}
+
+func (ap *AdminAuditProcessor) help(m message.Message) (string, error) {
+	rv := "Here's a list of audit log commands you're allowed to use:\n"
+
+	perm, err := m.AuthorPermissions()
+	if err != nil {
+		return "getting permissions", err
+	}
+
+	if perm == 0 {
+		rv += "Sorry, none. Ask leaders to let you do more"
+		return rv, nil
+	}
+
+	rv += "Filters can be combined in any order. Dates are in YYYY-MM-DD format. Newest changes are shown first\n"
+	rv += "\t -- \"!g admin audit\" (\"!g a au\") - Show latest changes in the guild\n"
+	rv += "\t -- \"!g admin audit <mention user>\" (\"!g a au <mention>\") - Show changes made by or to the user\n"
+	rv += "\t -- \"!g admin audit char <name>\" (\"!g a au c <name>\") - Show changes of the character\n"
+	rv += "\t -- \"!g admin audit stat <name>\" (\"!g a au s <name>\") - Show changes of the stat\n"
+	rv += "\t -- \"!g admin audit from <date> to <date>\" (\"!g a au f <date> t <date>\") - Show changes made in the time range\n"
+	rv += "\t -- \"!g admin audit page <n>\" (\"!g a au p <n>\") - Show older changes\n"
+
+	return rv, nil
+}
@@ -4,6 +4,8 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/google/uuid"
+
 	"github.com/mebaranov/disguildie/database"
 	"github.com/mebaranov/disguildie/message"
 	"github.com/mebaranov/disguildie/processor/helpers"
@@ -162,34 +164,74 @@ func (ap *AdminGuildProcessor) remove(m message.Message) (string, error) {
 		return "", errors.New("You don't have permissions to modify the sub-guild")
 	}
 
-	subs, err := ap.Prov.GetSubGuilds(g.GuildId)
-	if err != nil {
-		return "getting sub-guilds", err
-	}
+	step := ""
+	err = ap.Prov.RunInTx(func(tx database.DataProvider) error {
+		step = "getting sub-guilds"
+		subs, err := tx.GetSubGuilds(g.GuildId)
+		if err != nil {
+			return err
+		}
 
-	users, err := ap.Prov.GetUsersInGuild(m.GuildId())
-	if err != nil {
-		return "getting users in guild", err
-	}
+		step = "getting users in guild"
+		users, err := tx.GetUsersInGuild(m.GuildId())
+		if err != nil {
+			return err
+		}
 
-	for _, s := range users {
-		if perm, ok := s.Guilds[m.GuildId()]; ok {
-			if _, ok = subs[perm.GuildId]; ok {
-				_, err = ap.Prov.SetUserSubGuild(s.Id, &database.GuildPermission{TopGuild: m.GuildId(), GuildId: g.ParentId})
-				if err != nil {
-					return "moving users out from sub-guild", err
+		step = "moving users out from sub-guild"
+		members := make(map[string]uuid.UUID)
+		for _, s := range users {
+			if perm, ok := s.Guilds[m.GuildId()]; ok {
+				if _, ok = subs[perm.GuildId]; ok {
+					members[s.Id] = perm.GuildId
+					_, err = tx.SetUserSubGuild(s.Id, &database.GuildPermission{TopGuild: m.GuildId(), GuildId: g.ParentId})
+					if err != nil {
+						return err
+					}
 				}
 			}
 		}
-	}
 
-	if _, err = ap.Prov.RemoveGuild(g.GuildId); err != nil {
-		return "removing sub-guild", err
+		step = "removing sub-guild"
+		if _, err = tx.RemoveGuild(g.GuildId); err != nil {
+			return err
+		}
+
+		step = "moving sub-guild to the trash"
+		return helpers.MoveToTrash(tx, &database.TrashItem{
+			GuildId:   m.GuildId(),
+			Type:      database.TrashGuild,
+			Name:      g.Name,
+			RemovedBy: m.AuthorId(),
+			Guilds:    parentsFirst(g.GuildId, subs),
+			Members:   members,
+		})
+	})
+	if err != nil {
+		return step, err
 	}
 
 	return fmt.Sprintf("Sub-guild '%v' removed", name), nil
 }
 
+// parentsFirst orders a sub-guild tree, so every guild comes after its parent
+func parentsFirst(root uuid.UUID, subs map[uuid.UUID]*database.Guild) []*database.Guild {
+	rv := make([]*database.Guild, 0, len(subs))
+	if g, ok := subs[root]; ok {
+		rv = append(rv, g)
+	}
+
+	for i := 0; i < len(rv); i++ {
+		for _, g := range subs {
+			if g.ParentId == rv[i].GuildId && g.GuildId != root {
+				rv = append(rv, g)
+			}
+		}
+	}
+
+	return rv
+}
+
 func (ap *AdminGuildProcessor) help(m message.Message) (string, error) {
 	rv := "Here's a list of guild management commands you're allowed to use:\n"
 
@@ -64,30 +64,40 @@ func (ap *AdminRoleProcessor) add(m message.Message) (string, error) {
 		return fmt.Sprintf("Permission %v added for the role %v", permStr, roleStr), nil
 	}
 
-	p = p | role.Permissions
-	if p != role.Permissions {
-		if _, err = ap.Prov.SetRolePermissions(m.GuildId(), rid, p); err != nil {
-			return "setting role permissions", err
-		}
-	}
-
 	usrs, err := m.GuildMembersWithRole(rid)
 	if err != nil {
 		return "getting users", err
 	}
 
-	for uid, _ := range usrs {
-		u, err := ap.Prov.GetUserD(uid)
-		if err != nil {
-			return "getting a user for update", err
+	step := ""
+	err = ap.Prov.RunInTx(func(tx database.DataProvider) error {
+		p = p | role.Permissions
+		if p != role.Permissions {
+			step = "setting role permissions"
+			if _, err := tx.SetRolePermissions(m.GuildId(), rid, p); err != nil {
+				return err
+			}
 		}
-		if uper, ok := u.Guilds[m.GuildId()]; ok {
-			uper.Permissions |= p
-			_, err = ap.Prov.SetUserPermissions(uid, uper)
+
+		for uid, _ := range usrs {
+			step = "getting a user for update"
+			u, err := tx.GetUserD(uid)
 			if err != nil {
-				return "updating user", err
+				return err
+			}
+			if uper, ok := u.Guilds[m.GuildId()]; ok {
+				step = "updating user"
+				uper.Permissions |= p
+				if _, err = tx.SetUserPermissions(uid, uper); err != nil {
+					return err
+				}
 			}
 		}
+
+		return nil
+	})
+	if err != nil {
+		return step, err
 	}
 
 	return fmt.Sprintf("Permission %v added for the role %v", permStr, roleStr), nil
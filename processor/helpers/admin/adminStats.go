@@ -3,8 +3,17 @@ package admin
 import (
 	"errors"
 	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
 
 	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/formula"
 	"github.com/mebaranov/disguildie/message"
 	"github.com/mebaranov/disguildie/processor/helpers"
 )
@@ -24,6 +33,25 @@ func NewAdminStatsProcessor(prov database.DataProvider) helpers.MessageProcessor
 		"r":      ap.remove,
 		"remove": ap.remove,
 		"reset":  ap.reset,
+		"in":     ap.in,
+		"rename": ap.rename,
+		"retype": ap.retype,
+
+		"min":      ap.min,
+		"max":      ap.max,
+		"pattern":  ap.pattern,
+		"regex":    ap.pattern,
+		"length":   ap.length,
+		"len":      ap.length,
+		"required": ap.required,
+		"req":      ap.required,
+
+		"category": ap.category,
+		"cat":      ap.category,
+		"order":    ap.order,
+		"lock":     ap.lock,
+		"unlock":   ap.unlock,
+		"review":   ap.review,
 	}
 	return ap
 }
@@ -38,7 +66,50 @@ func (ap *AdminStatsProcessor) add(m message.Message) (string, error) {
 		return "", errors.New("You don't have permissions to do guild-wide structure modifications.")
 	}
 
-	n, t, d := m.CurSegment(), m.CurSegment(), m.CurSegment()
+	gs, err := helpers.NewGuildStats(ap.Prov, m.GuildId())
+	if err != nil {
+		return "getting guild stats", err
+	}
+
+	return ap.addTo(m, gs, gs.Top)
+}
+
+// in runs stat commands for a sub-guild, so sub-guild officers can manage stats of their members
+func (ap *AdminStatsProcessor) in(m message.Message) (string, error) {
+	sub, cmd := m.CurSegment(), strings.ToLower(m.CurSegment())
+	if sub == "" || cmd == "" {
+		return "", errors.New("Invalid command format")
+	}
+
+	g, err := ap.Prov.GetGuildN(m.GuildId(), sub)
+	if err != nil {
+		return "getting sub-guild", err
+	}
+
+	ok, err := m.CheckGuildModificationPermissions(g.GuildId)
+	if err != nil {
+		return "checking modification permissions", err
+	}
+	if !ok {
+		return "", errors.New("You don't have permissions to modify the sub-guild")
+	}
+
+	gs, err := helpers.NewGuildStats(ap.Prov, m.GuildId())
+	if err != nil {
+		return "getting guild stats", err
+	}
+
+	switch cmd {
+	case "a", "add":
+		return ap.addTo(m, gs, g)
+	case "r", "remove":
+		return ap.removeFrom(m, gs, g)
+	}
+	return "", errors.New("Invalid command format")
+}
+
+func (ap *AdminStatsProcessor) addTo(m message.Message, gs *helpers.GuildStats, g *database.Guild) (string, error) {
+	n, t := m.CurSegment(), m.CurSegment()
 	if n == "" || t == "" {
 		return "", errors.New("Invalid command format")
 	}
@@ -48,12 +119,30 @@ func (ap *AdminStatsProcessor) add(m message.Message) (string, error) {
 		return "parsing type", err
 	}
 
-	g, err := ap.Prov.GetGuildD(m.GuildId())
-	if err != nil {
-		return "getting guild", err
+	var values []string
+	if tval == database.Enum {
+		values, err = enumValues(m.CurSegment())
+		if err != nil {
+			return "parsing enum values", err
+		}
 	}
 
-	if _, ok := g.Stats[n]; ok {
+	// formulas may contain spaces, so they take the rest of the message
+	d, f := "", ""
+	if tval == database.Computed {
+		f = strings.TrimSpace(m.LeftOverSegments())
+		if f == "" {
+			return "", errors.New("Formula stats need an expression, like \"attack + defense * 2\"")
+		}
+	} else {
+		d = m.CurSegment()
+	}
+
+	// names are unique in the whole hierarchy, so tops and formulas are never ambiguous
+	if st, owner := gs.Stat(n); st != nil {
+		if owner.GuildId != g.GuildId {
+			return "", errors.New(fmt.Sprintf("Stat with name %v already exists in %v", n, owner.Name))
+		}
 		return "", errors.New(fmt.Sprintf("Stat with name %v already exists in the system", n))
 	}
 
@@ -61,14 +150,49 @@ func (ap *AdminStatsProcessor) add(m message.Message) (string, error) {
 		ID:          n,
 		Type:        tval,
 		Description: d,
+		Values:      values,
+		Formula:     f,
+	}
+	if tval == database.Computed {
+		if err = helpers.CheckFormula(gs.Stats(g.GuildId), &stat); err != nil {
+			return "", err
+		}
 	}
 	if _, err := ap.Prov.AddGuildStat(g.GuildId, &stat); err != nil {
 		return "adding stat", err
 	}
 
+	if g.GuildId != gs.Top.GuildId {
+		return fmt.Sprintf("Stat %v with type %v was added to sub-guild %v.", n, t, g.Name), nil
+	}
 	return fmt.Sprintf("Stat %v with type %v was added.", n, t), nil
 }
 
+func enumValues(v string) ([]string, error) {
+	if v == "" {
+		return nil, errors.New("Enum stats need a comma-separated list of allowed values")
+	}
+
+	rv := make([]string, 0, 5)
+	seen := make(map[string]database.Void)
+	for _, s := range strings.Split(v, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		if _, ok := seen[strings.ToLower(s)]; ok {
+			return nil, errors.New(fmt.Sprintf("Value %v is listed twice", s))
+		}
+		seen[strings.ToLower(s)] = database.Member
+		rv = append(rv, s)
+	}
+
+	if len(rv) == 0 {
+		return nil, errors.New("Enum stats need a comma-separated list of allowed values")
+	}
+	return rv, nil
+}
+
 func (ap *AdminStatsProcessor) main(m message.Message) (string, error) {
 	perm, err := m.AuthorPermissions()
 	if err != nil {
@@ -110,20 +234,43 @@ func (ap *AdminStatsProcessor) remove(m message.Message) (string, error) {
 		return "", errors.New("You don't have permissions to do guild-wide structure modifications.")
 	}
 
+	gs, err := helpers.NewGuildStats(ap.Prov, m.GuildId())
+	if err != nil {
+		return "getting guild stats", err
+	}
+
+	return ap.removeFrom(m, gs, gs.Top)
+}
+
+func (ap *AdminStatsProcessor) removeFrom(m message.Message, gs *helpers.GuildStats, g *database.Guild) (string, error) {
 	n := m.CurSegment()
 	if n == "" {
 		return "", errors.New("Invalid command format")
 	}
 
-	g, err := ap.Prov.GetGuildD(m.GuildId())
-	if err != nil {
-		return "getting guild", err
-	}
-
 	if _, ok := g.Stats[n]; !ok {
 		return "", errors.New(fmt.Sprintf("Stat %v does not exist in the guild", n))
 	}
 
+	// formulas of sub-guilds may use stats of their parents
+	for _, sg := range gs.Guilds {
+		for _, s := range sg.Stats {
+			if s.ID == n || s.Type != database.Computed {
+				continue
+			}
+
+			f, err := formula.Parse(s.Formula)
+			if err != nil {
+				continue
+			}
+			for _, r := range f.Refs() {
+				if r == n {
+					return "", errors.New(fmt.Sprintf("Stat %v is used in formula of %v. Remove %v first", n, s.ID, s.ID))
+				}
+			}
+		}
+	}
+
 	if _, err := ap.Prov.RemoveGuildStat(g.GuildId, n); err != nil {
 		return "removing stat", err
 	}
@@ -131,6 +278,475 @@ func (ap *AdminStatsProcessor) remove(m message.Message) (string, error) {
 	return fmt.Sprintf("Stat %v was removed.", n), nil
 }
 
+func (ap *AdminStatsProcessor) rename(m message.Message) (string, error) {
+	perm, err := m.AuthorPermissions()
+	if err != nil {
+		return "getting author permissions", err
+	}
+
+	if perm&database.EditGuildStructurePerm == 0 {
+		return "", errors.New("You don't have permissions to do guild-wide structure modifications.")
+	}
+
+	old, n := m.CurSegment(), m.CurSegment()
+	if old == "" || n == "" {
+		return "", errors.New("Invalid command format")
+	}
+
+	gs, err := helpers.NewGuildStats(ap.Prov, m.GuildId())
+	if err != nil {
+		return "getting guild stats", err
+	}
+
+	st, g := gs.Stat(old)
+	if st == nil {
+		return "", errors.New(fmt.Sprintf("Stat %v does not exist in the guild", old))
+	}
+	if st, _ := gs.Stat(n); st != nil {
+		return "", errors.New(fmt.Sprintf("Stat with name %v already exists in the system", n))
+	}
+
+	// formulas using the stat are updated to the new name in all guilds of the hierarchy
+	formulas := make(map[uuid.UUID][]*database.Stat)
+	renamed := make(map[string]*database.Stat)
+	for id, sg := range gs.Guilds {
+		for _, v := range sg.Stats {
+			if v.Type != database.Computed {
+				continue
+			}
+
+			f, err := formula.Rename(v.Formula, old, n)
+			if err != nil {
+				return "", err
+			}
+			if f != v.Formula {
+				st := *v
+				st.Formula = f
+				formulas[id] = append(formulas[id], &st)
+				renamed[st.ID] = &st
+			}
+		}
+	}
+	for id, list := range formulas {
+		stats := make(map[string]*database.Stat)
+		for k, v := range gs.Stats(id) {
+			if r, ok := renamed[k]; ok {
+				v = r
+			}
+			if k == old {
+				st := *v
+				st.ID = n
+				k, v = n, &st
+			}
+			stats[k] = v
+		}
+
+		for _, st := range list {
+			if err = helpers.CheckFormula(stats, st); err != nil {
+				return "", errors.New(fmt.Sprintf("Stat %v is used in formula of %v and can't be renamed to %v: %v", old, st.ID, n, err))
+			}
+		}
+	}
+
+	step := ""
+	err = ap.Prov.RunInTx(func(tx database.DataProvider) error {
+		step = "renaming stat"
+		if _, err := tx.RenameGuildStat(g.GuildId, old, n); err != nil {
+			return err
+		}
+
+		step = "updating formulas"
+		for id, list := range formulas {
+			for _, st := range list {
+				if _, err := tx.AddGuildStat(id, st); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return step, err
+	}
+
+	return fmt.Sprintf("Stat %v was renamed to %v.", old, n), nil
+}
+
+func (ap *AdminStatsProcessor) retype(m message.Message) (string, error) {
+	perm, err := m.AuthorPermissions()
+	if err != nil {
+		return "getting author permissions", err
+	}
+
+	if perm&database.EditGuildStructurePerm == 0 {
+		return "", errors.New("You don't have permissions to do guild-wide structure modifications.")
+	}
+
+	n, t := m.CurSegment(), m.CurSegment()
+	if n == "" || t == "" {
+		return "", errors.New("Invalid command format")
+	}
+
+	tval, err := database.StringToType(t)
+	if err != nil {
+		return "parsing type", err
+	}
+
+	gs, err := helpers.NewGuildStats(ap.Prov, m.GuildId())
+	if err != nil {
+		return "getting guild stats", err
+	}
+
+	old, g := gs.Stat(n)
+	if old == nil {
+		return "", errors.New(fmt.Sprintf("Stat %v does not exist in the guild", n))
+	}
+	if old.Type == database.Computed || tval == database.Computed {
+		return "", errors.New("Formula stats can't be converted. Remove and add the stat instead")
+	}
+	if old.Type == tval && tval != database.Enum {
+		return "", errors.New(fmt.Sprintf("Stat %v already has type %v", n, t))
+	}
+
+	stat := &database.Stat{ID: n, Type: tval, Description: old.Description, Write: old.Write, Review: old.Review, Category: old.Category, Order: old.Order}
+	switch tval {
+	case database.Number, database.Float:
+		stat.Min, stat.Max = old.Min, old.Max
+	case database.Str:
+		stat.Pattern, stat.MaxLength, stat.Required = old.Pattern, old.MaxLength, old.Required
+	case database.Enum:
+		if stat.Values, err = enumValues(m.CurSegment()); err != nil {
+			return "parsing enum values", err
+		}
+	}
+
+	for id, sg := range gs.Guilds {
+		stats := gs.Stats(id)
+		if _, ok := stats[n]; !ok {
+			continue
+		}
+		stats[n] = stat
+
+		for _, st := range sg.Stats {
+			if st.Type != database.Computed {
+				continue
+			}
+			if err = helpers.CheckFormula(stats, st); err != nil {
+				return "", errors.New(fmt.Sprintf("Stat %v is used in formula of %v and can't become %v: %v", n, st.ID, t, err))
+			}
+		}
+	}
+
+	converted, failed := 0, make([]string, 0, 10)
+	step := ""
+	err = ap.Prov.RunInTx(func(tx database.DataProvider) error {
+		step = "getting characters"
+		chars, err := tx.GetGuildCharacters(gs.Top.DiscordId)
+		if err != nil {
+			return err
+		}
+
+		step = "converting values"
+		now := time.Now()
+		for _, c := range chars {
+			v, ok := c.Body[n]
+			if !ok {
+				continue
+			}
+
+			nv, err := stat.ConvertValue(v)
+			if err != nil {
+				failed = append(failed, fmt.Sprintf("\t%v of <@!%v>: %v", c.Name, c.UserId, database.FormatStatValue(v)))
+				if nv, err = stat.DefaultValue(); err != nil {
+					return err
+				}
+			} else {
+				converted++
+			}
+
+			if _, err = tx.SetCharacterStat(c.GuildId, c.UserId, c.Name, n, nv); err != nil {
+				return err
+			}
+
+			h := &database.StatHistory{GuildId: c.GuildId, UserId: c.UserId, Character: c.Name, Stat: n, Value: nv, Time: now}
+			if _, err = tx.AddStatHistory(h); err != nil {
+				return err
+			}
+		}
+
+		step = "changing stat type"
+		_, err = tx.ChangeGuildStatType(g.GuildId, stat)
+		return err
+	})
+	if err != nil {
+		return step, err
+	}
+
+	rv := fmt.Sprintf("Stat %v type was changed to %v. %v values were converted.", n, database.TypeToString(tval), converted)
+	if len(failed) > 0 {
+		sort.Strings(failed)
+		rv += fmt.Sprintf("\n%v values couldn't be converted and were reset:\n", len(failed)) + strings.Join(failed, "\n")
+	}
+	return rv, nil
+}
+
+func (ap *AdminStatsProcessor) min(m message.Message) (string, error) {
+	return ap.constrain(m, func(s *database.Stat, v string) error {
+		min, err := numericLimit(s, v)
+		s.Min = min
+		return err
+	})
+}
+
+func (ap *AdminStatsProcessor) max(m message.Message) (string, error) {
+	return ap.constrain(m, func(s *database.Stat, v string) error {
+		max, err := numericLimit(s, v)
+		s.Max = max
+		return err
+	})
+}
+
+func (ap *AdminStatsProcessor) pattern(m message.Message) (string, error) {
+	return ap.constrain(m, func(s *database.Stat, v string) error {
+		if s.Type != database.Str {
+			return errors.New(fmt.Sprintf("Stat %v is not a string", s.ID))
+		}
+		if v == "none" {
+			s.Pattern = ""
+			return nil
+		}
+		if _, err := regexp.Compile(v); err != nil {
+			return errors.New(fmt.Sprintf("Invalid pattern %v: %v", v, err))
+		}
+		s.Pattern = v
+		return nil
+	})
+}
+
+func (ap *AdminStatsProcessor) length(m message.Message) (string, error) {
+	return ap.constrain(m, func(s *database.Stat, v string) error {
+		if s.Type != database.Str {
+			return errors.New(fmt.Sprintf("Stat %v is not a string", s.ID))
+		}
+		if v == "none" {
+			s.MaxLength = 0
+			return nil
+		}
+		l, err := strconv.Atoi(v)
+		if err != nil || l < 0 {
+			return errors.New(fmt.Sprintf("Expected positive number. Got %v", v))
+		}
+		s.MaxLength = l
+		return nil
+	})
+}
+
+func (ap *AdminStatsProcessor) required(m message.Message) (string, error) {
+	return ap.constrain(m, func(s *database.Stat, v string) error {
+		if s.Type != database.Str {
+			return errors.New(fmt.Sprintf("Stat %v is not a string", s.ID))
+		}
+		r, err := (&database.Stat{ID: s.ID, Type: database.Bool}).ParseValue(v)
+		if err != nil {
+			return err
+		}
+		s.Required = r.(bool)
+		return nil
+	})
+}
+
+func numericLimit(s *database.Stat, v string) (*float64, error) {
+	if s.Type != database.Number && s.Type != database.Float {
+		return nil, errors.New(fmt.Sprintf("Stat %v is not a number", s.ID))
+	}
+	if v == "none" {
+		return nil, nil
+	}
+
+	rv, err := strconv.ParseFloat(v, 64)
+	if err != nil || math.IsNaN(rv) || math.IsInf(rv, 0) {
+		return nil, errors.New(fmt.Sprintf("Expected numeric value. Got %v", v))
+	}
+	return &rv, nil
+}
+
+func (ap *AdminStatsProcessor) category(m message.Message) (string, error) {
+	return ap.arrange(m, func(s *database.Stat, v string) error {
+		if v == "none" {
+			v = ""
+		}
+		s.Category = v
+		return nil
+	})
+}
+
+func (ap *AdminStatsProcessor) order(m message.Message) (string, error) {
+	return ap.arrange(m, func(s *database.Stat, v string) error {
+		o, err := strconv.Atoi(v)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Expected whole number. Got %v", v))
+		}
+		s.Order = o
+		return nil
+	})
+}
+
+// arrange changes how the stat is shown to members
+func (ap *AdminStatsProcessor) arrange(m message.Message, f func(s *database.Stat, v string) error) (string, error) {
+	n, v := m.CurSegment(), m.CurSegment()
+	if n == "" || v == "" {
+		return "", errors.New("Invalid command format")
+	}
+
+	stat, step, err := ap.changeStat(m, n, v, f)
+	if err != nil {
+		return step, err
+	}
+
+	if stat.Category == "" {
+		return fmt.Sprintf("Stat %v is shown without category at position %v", stat.ID, stat.Order), nil
+	}
+	return fmt.Sprintf("Stat %v is shown in category %v at position %v", stat.ID, stat.Category, stat.Order), nil
+}
+
+func (ap *AdminStatsProcessor) lock(m message.Message) (string, error) {
+	n, v := m.CurSegment(), m.CurSegment()
+	if n == "" {
+		return "", errors.New("Invalid command format")
+	}
+
+	return ap.setWritePolicy(m, n, v)
+}
+
+func (ap *AdminStatsProcessor) unlock(m message.Message) (string, error) {
+	n := m.CurSegment()
+	if n == "" {
+		return "", errors.New("Invalid command format")
+	}
+
+	return ap.setWritePolicy(m, n, "self")
+}
+
+func (ap *AdminStatsProcessor) setWritePolicy(m message.Message, n string, v string) (string, error) {
+	policies := map[string]int{
+		"":         database.WriteOfficers,
+		"officers": database.WriteOfficers,
+		"o":        database.WriteOfficers,
+		"guild":    database.WriteGuild,
+		"g":        database.WriteGuild,
+		"self":     database.WriteSelf,
+	}
+
+	stat, step, err := ap.changeStat(m, n, v, func(s *database.Stat, v string) error {
+		p, ok := policies[strings.ToLower(v)]
+		if !ok {
+			return errors.New(fmt.Sprintf("Unknown lock level %v. Use \"officers\" or \"guild\"", v))
+		}
+		if s.Type == database.Computed {
+			return errors.New(fmt.Sprintf("Stat %v is calculated by formula and can't be set anyway", s.ID))
+		}
+		s.Write = p
+		return nil
+	})
+	if err != nil {
+		return step, err
+	}
+
+	switch stat.Write {
+	case database.WriteOfficers:
+		return fmt.Sprintf("Stat %v can only be set by officers of the character owner", stat.ID), nil
+	case database.WriteGuild:
+		return fmt.Sprintf("Stat %v can only be set by guild-wide officers", stat.ID), nil
+	}
+	return fmt.Sprintf("Stat %v can be set by character owners", stat.ID), nil
+}
+
+func (ap *AdminStatsProcessor) review(m message.Message) (string, error) {
+	n, v := m.CurSegment(), m.CurSegment()
+	if n == "" || v == "" {
+		return "", errors.New("Invalid command format")
+	}
+
+	stat, step, err := ap.changeStat(m, n, v, func(s *database.Stat, v string) error {
+		if s.Type == database.Computed {
+			return errors.New(fmt.Sprintf("Stat %v is calculated by formula and can't be set anyway", s.ID))
+		}
+		r, err := (&database.Stat{ID: s.ID, Type: database.Bool}).ParseValue(v)
+		if err != nil {
+			return err
+		}
+		s.Review = r.(bool)
+		return nil
+	})
+	if err != nil {
+		return step, err
+	}
+
+	if stat.Review {
+		return fmt.Sprintf("Values of stat %v set by members will wait for officer approval", stat.ID), nil
+	}
+	return fmt.Sprintf("Values of stat %v are saved right away", stat.ID), nil
+}
+
+func (ap *AdminStatsProcessor) constrain(m message.Message, f func(s *database.Stat, v string) error) (string, error) {
+	n, v := m.CurSegment(), m.CurSegment()
+	if n == "" || v == "" {
+		return "", errors.New("Invalid command format")
+	}
+
+	stat, step, err := ap.changeStat(m, n, v, func(s *database.Stat, v string) error {
+		if err := f(s, v); err != nil {
+			return err
+		}
+		if s.Min != nil && s.Max != nil && *s.Min > *s.Max {
+			return errors.New("Minimum can't be greater than maximum")
+		}
+		return nil
+	})
+	if err != nil {
+		return step, err
+	}
+
+	c := stat.Constraints()
+	if c == "" {
+		c = "none"
+	}
+	return fmt.Sprintf("Stat %v constraints: %v", stat.ID, c), nil
+}
+
+// changeStat applies f with value v to the definition of stat n
+func (ap *AdminStatsProcessor) changeStat(m message.Message, n string, v string, f func(s *database.Stat, v string) error) (*database.Stat, string, error) {
+	perm, err := m.AuthorPermissions()
+	if err != nil {
+		return nil, "getting author permissions", err
+	}
+
+	if perm&database.EditGuildStructurePerm == 0 {
+		return nil, "", errors.New("You don't have permissions to do guild-wide structure modifications.")
+	}
+
+	gs, err := helpers.NewGuildStats(ap.Prov, m.GuildId())
+	if err != nil {
+		return nil, "getting guild stats", err
+	}
+
+	old, g := gs.Stat(n)
+	if old == nil {
+		return nil, "", errors.New(fmt.Sprintf("Stat %v does not exist in the guild", n))
+	}
+
+	stat := *old
+	if err = f(&stat, v); err != nil {
+		return nil, "", err
+	}
+
+	if _, err := ap.Prov.AddGuildStat(g.GuildId, &stat); err != nil {
+		return nil, "updating stat", err
+	}
+
+	return &stat, "", nil
+}
+
 func (ap *AdminStatsProcessor) reset(m message.Message) (string, error) {
 	perm, err := m.AuthorPermissions()
 	if err != nil {
@@ -141,12 +757,24 @@ func (ap *AdminStatsProcessor) reset(m message.Message) (string, error) {
 		return "", errors.New("You don't have permissions to do guild-wide structure modifications.")
 	}
 
-	g, err := ap.Prov.GetGuildD(m.GuildId())
+	gs, err := helpers.NewGuildStats(ap.Prov, m.GuildId())
 	if err != nil {
-		return "getting guild", err
+		return "getting guild stats", err
 	}
 
-	if _, err := ap.Prov.RemoveAllGuildStats(g.GuildId); err != nil {
+	// sub-guild stats go as well, their formulas may use top-level stats
+	err = ap.Prov.RunInTx(func(tx database.DataProvider) error {
+		for _, g := range gs.Guilds {
+			if g != gs.Top && len(g.Stats) == 0 {
+				continue
+			}
+			if _, err := tx.RemoveAllGuildStats(g.GuildId); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
 		return "resetting stats", err
 	}
 
@@ -166,12 +794,35 @@ func (ap *AdminStatsProcessor) help(m message.Message) (string, error) {
 		return rv, nil
 	}
 
-	rv += "Stats are identified by name. Stat type can be either \"int\" for numbers or \"str\" for everything else"
+	rv += "Stats are identified by name. Stat type can be \"int\" for whole numbers, \"float\" for decimals, \"bool\" for yes/no flags, "
+	rv += "\"enum\" for a fixed list of values, \"date\" (YYYY-MM-DD), \"duration\" (e.g. 1h30m) or \"str\" for everything else\n"
 	rv += "\t -- \"!g admin stats add <statName> <statType> <description>\" (\"!g a s a <statName> <statType> <description>\") - Add a stat with description\n"
 	rv += "\t -- \"!g admin stats add <statName> <statType>\" (\"!g a s a <statName> <statType>\") - Add a stat without description\n"
+	rv += "\t -- \"!g admin stats add <statName> enum <value1,value2,...> <description>\" (\"!g a s a <statName> enum <values> <description>\") - Add an enum stat with allowed values\n"
+	rv += "\t -- \"!g admin stats add <statName> formula <expression>\" (\"!g a s a <statName> formula <expression>\") - Add a stat calculated from other stats. "
+	rv += "Expressions support + - * / %, comparisons, && || !, min(), max(), abs(), round(), floor(), ceil() and if(condition, then, else). Durations are counted in hours\n"
 	rv += "\t -- \"!g admin stats main <statName>\" (\"!g a s m <statName>\") - Set stat as main\n"
 	rv += "\t -- \"!g admin stats remove <statName>\" (\"!g a s r <statName>\") - Remove a stat (notice that it will not be removed from existing characters data)\n"
-	rv += "\t -- \"!g admin stats reset\" (\"!g a s reset\") - Remove all stats that were set\n"
+	rv += "\t -- \"!g admin stats rename <statName> <newName>\" (\"!g a s rename <statName> <newName>\") - Rename a stat keeping values and history of all characters\n"
+	rv += "\t -- \"!g admin stats retype <statName> <statType>\" (\"!g a s retype <statName> <statType>\") - Change stat type converting values of all characters. "
+	rv += "Numbers and decimals convert into each other, yes/no becomes 1/0, anything can become text and text is parsed. Values that can't be converted are reset\n"
+	rv += "\t -- \"!g admin stats reset\" (\"!g a s reset\") - Remove all stats that were set, including sub-guild stats\n"
+	rv += "\nSub-guilds can have own stats. Members of a sub-guild see its stats along with stats of all guilds above it. Stat names are unique across all sub-guilds:\n"
+	rv += "\t -- \"!g admin stats in <sub-guild> add <statName> <statType> <description>\" (\"!g a s in <sub-guild> a <statName> <statType> <description>\") - Add a stat to the sub-guild. Enum and formula stats work the same way as above\n"
+	rv += "\t -- \"!g admin stats in <sub-guild> remove <statName>\" (\"!g a s in <sub-guild> r <statName>\") - Remove a stat of the sub-guild\n"
+	rv += "\nConstraints are checked when members set stats. Pass \"none\" as a value to drop a constraint:\n"
+	rv += "\t -- \"!g admin stats min <statName> <value>\" (\"!g a s min <statName> <value>\") - Set lowest allowed value of a numeric stat\n"
+	rv += "\t -- \"!g admin stats max <statName> <value>\" (\"!g a s max <statName> <value>\") - Set highest allowed value of a numeric stat\n"
+	rv += "\t -- \"!g admin stats pattern <statName> <regex>\" (\"!g a s regex <statName> <regex>\") - Require string stat to match a regular expression\n"
+	rv += "\t -- \"!g admin stats length <statName> <count>\" (\"!g a s len <statName> <count>\") - Limit length of a string stat\n"
+	rv += "\t -- \"!g admin stats required <statName> <yes|no>\" (\"!g a s req <statName> <yes|no>\") - Forbid empty values of a string stat\n"
+	rv += "\t -- \"!g admin stats lock <statName>\" (\"!g a s lock <statName>\") - Only officers with character permissions over the owner can set the stat\n"
+	rv += "\t -- \"!g admin stats lock <statName> guild\" (\"!g a s lock <statName> g\") - Only officers with guild-wide character permissions can set the stat\n"
+	rv += "\t -- \"!g admin stats unlock <statName>\" (\"!g a s unlock <statName>\") - Let character owners set the stat again\n"
+	rv += "\t -- \"!g admin stats review <statName> <yes|no>\" (\"!g a s review <statName> <yes|no>\") - Values set by members wait until officers accept them with \"!g admin approve\"\n"
+	rv += "\nStats are shown grouped by category. Inside a category stats with lower order go first, then stats are sorted by name:\n"
+	rv += "\t -- \"!g admin stats category <statName> <category>\" (\"!g a s cat <statName> <category>\") - Put stat into a category. Pass \"none\" to remove it from category\n"
+	rv += "\t -- \"!g admin stats order <statName> <number>\" (\"!g a s order <statName> <number>\") - Set position of stat inside its category\n"
 
 	return rv, nil
 }
@@ -0,0 +1,257 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/message"
+	"github.com/mebaranov/disguildie/processor/helpers"
+)
+
+type AdminTrashProcessor struct {
+	helpers.BaseMessageProcessor
+}
+
+func NewAdminTrashProcessor(prov database.DataProvider) helpers.MessageProcessor {
+	ap := &AdminTrashProcessor{}
+	ap.Prov = prov
+	ap.Funcs = map[string]func(message.Message) (string, error){
+		"h":       ap.help,
+		"help":    ap.help,
+		"l":       ap.list,
+		"list":    ap.list,
+		"r":       ap.restore,
+		"restore": ap.restore,
+	}
+	return ap
+}
+
+var trashTypeToString = map[int]string{
+	database.TrashCharacter: "character",
+	database.TrashGuild:     "sub-guild",
+	database.TrashUser:      "user",
+}
+
+func (ap *AdminTrashProcessor) list(m message.Message) (string, error) {
+	perm, err := m.AuthorPermissions()
+	if err != nil {
+		return "getting author permissions", err
+	}
+
+	if perm == 0 {
+		return "", errors.New("You don't have permissions to see the trash")
+	}
+
+	items, err := ap.items(m)
+	if err != nil {
+		return "getting trash", err
+	}
+
+	if len(items) == 0 {
+		return "The trash is empty", nil
+	}
+
+	rv := "Removed items:\n"
+	for _, t := range items {
+		rv += fmt.Sprintf("\t%v: %v", t.Id, trashItemName(t))
+		if t.Type == database.TrashCharacter {
+			rv += fmt.Sprintf(" of <@!%v>", t.Characters[0].UserId)
+		}
+
+		left := int(t.Time.Add(helpers.TrashRetention).Sub(time.Now()).Hours() / 24)
+		rv += fmt.Sprintf(", removed by <@!%v> on %v, %v days left\n", t.RemovedBy, t.Time.UTC().Format("2006-01-02"), left)
+	}
+
+	return rv, nil
+}
+
+func (ap *AdminTrashProcessor) restore(m message.Message) (string, error) {
+	id, err := strconv.Atoi(m.CurSegment())
+	if err != nil {
+		return "", errors.New("Invalid command format")
+	}
+
+	items, err := ap.items(m)
+	if err != nil {
+		return "getting trash", err
+	}
+
+	var item *database.TrashItem
+	for _, t := range items {
+		if t.Id == id {
+			item = t
+		}
+	}
+	if item == nil {
+		return "", errors.New(fmt.Sprintf("Item %v is not in the trash", id))
+	}
+
+	if ok, err := ap.canRestore(item, m); err != nil {
+		return "checking modification permissions", err
+	} else if !ok {
+		return "", errors.New("You don't have permissions to restore this item")
+	}
+
+	step := ""
+	err = ap.Prov.RunInTx(func(tx database.DataProvider) error {
+		step = "taking item from the trash"
+		if _, err := tx.RemoveTrashItem(m.GuildId(), id); err != nil {
+			return err
+		}
+
+		switch item.Type {
+		case database.TrashCharacter:
+			step = "restoring character"
+			return restoreCharacters(tx, item)
+		case database.TrashGuild:
+			step = "restoring sub-guild"
+			return restoreGuilds(tx, item)
+		case database.TrashUser:
+			step = "restoring user"
+			return restoreUser(tx, item)
+		}
+
+		return errors.New("Unknown item type")
+	})
+	if err != nil {
+		return step, err
+	}
+
+	return fmt.Sprintf("Restored %v", trashItemName(item)), nil
+}
+
+func trashItemName(t *database.TrashItem) string {
+	if t.Type == database.TrashUser {
+		return fmt.Sprintf("%v <@!%v>", trashTypeToString[t.Type], t.Name)
+	}
+
+	return fmt.Sprintf("%v %v", trashTypeToString[t.Type], t.Name)
+}
+
+// items returns the trash of the guild without expired items
+func (ap *AdminTrashProcessor) items(m message.Message) ([]*database.TrashItem, error) {
+	items, err := ap.Prov.GetTrashItems(m.GuildId())
+	if err != nil {
+		return nil, err
+	}
+
+	rv := make([]*database.TrashItem, 0, len(items))
+	expired := time.Now().Add(-helpers.TrashRetention)
+	for _, t := range items {
+		if !t.Time.Before(expired) {
+			rv = append(rv, t)
+		}
+	}
+
+	return rv, nil
+}
+
+func (ap *AdminTrashProcessor) canRestore(t *database.TrashItem, m message.Message) (bool, error) {
+	switch t.Type {
+	case database.TrashCharacter:
+		return m.CheckUserModificationPermissions(t.Characters[0].UserId)
+	case database.TrashGuild:
+		return m.CheckGuildModificationPermissions(t.Guilds[0].ParentId)
+	}
+
+	perm, err := m.AuthorPermissions()
+	if err != nil {
+		return false, err
+	}
+	return perm&database.EditGuildCharsPerm != 0, nil
+}
+
+func restoreCharacters(prov database.DataProvider, t *database.TrashItem) error {
+	for _, c := range t.Characters {
+		if c.Main {
+			if _, err := prov.GetMainCharacter(c.GuildId, c.UserId); err == nil {
+				c.Main = false
+			}
+		}
+
+		if _, err := prov.AddCharacter(c); err != nil {
+			return err
+		}
+	}
+
+	for _, h := range t.History {
+		if _, err := prov.AddStatHistory(h); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func restoreGuilds(prov database.DataProvider, t *database.TrashItem) error {
+	root := t.Guilds[0]
+	if _, err := prov.GetGuild(root.ParentId); err != nil {
+		return errors.New(fmt.Sprintf("Parent of sub-guild %v does not exist anymore", root.Name))
+	}
+
+	for _, g := range t.Guilds {
+		tmp := *g
+		tmp.ChildNames = nil
+		if _, err := prov.AddGuild(&tmp); err != nil {
+			return err
+		}
+	}
+
+	// users are put back only if nobody has assigned them somewhere else
+	for uid, gid := range t.Members {
+		u, err := prov.GetUserD(uid)
+		if err != nil {
+			continue
+		}
+
+		if gp, ok := u.Guilds[t.GuildId]; ok && gp.GuildId == root.ParentId {
+			if _, err = prov.SetUserSubGuild(uid, &database.GuildPermission{TopGuild: t.GuildId, GuildId: gid}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func restoreUser(prov database.DataProvider, t *database.TrashItem) error {
+	gp, ok := t.User.Guilds[t.GuildId]
+	if !ok {
+		return errors.New("Removed user has no guild registration")
+	}
+
+	tmp := *gp
+	if _, err := prov.GetGuild(tmp.GuildId); err != nil {
+		g, err := prov.GetGuildD(t.GuildId)
+		if err != nil {
+			return err
+		}
+		tmp.GuildId = g.GuildId
+	}
+
+	_, err := prov.AddUser(t.User.Id, &tmp)
+	return err
+}
+
+func (ap *AdminTrashProcessor) help(m message.Message) (string, error) {
+	rv := "Here's a list of trash commands you're allowed to use:\n"
+
+	perm, err := m.AuthorPermissions()
+	if err != nil {
+		return "getting permissions", err
+	}
+
+	if perm == 0 {
+		rv += "Sorry, none. Ask leaders to let you do more"
+		return rv, nil
+	}
+
+	rv += fmt.Sprintf("Removed characters, sub-guilds and users are kept in the trash for %v days\n", int(helpers.TrashRetention.Hours()/24))
+	rv += "\t -- \"!g admin trash list\" (\"!g a t l\") - List removed items\n"
+	rv += "\t -- \"!g admin trash restore <id>\" (\"!g a t r <id>\") - Restore removed item with its stats or sub-guilds\n"
+
+	return rv, nil
+}
@@ -65,7 +65,9 @@ func (ap *AdminUserProcessor) remove(m message.Message) (string, error) {
 		return "", errors.New("You don't have permissions to delete this user")
 	}
 
-	err = ap.removeUser(uid, m.GuildId())
+	err = ap.Prov.RunInTx(func(tx database.DataProvider) error {
+		return removeUser(tx, uid, m.GuildId(), m.AuthorId())
+	})
 	if err != nil {
 		return "removing user", err
 	}
@@ -88,19 +90,28 @@ func (ap *AdminUserProcessor) cleanup(m message.Message) (string, error) {
 		return "getting guild memebers", err
 	}
 
-	registered, err := ap.Prov.GetUsersInGuild(m.GuildId())
-	if err != nil {
-		return "getting users in guild", err
-	}
+	step, count := "", 0
+	err = ap.Prov.RunInTx(func(tx database.DataProvider) error {
+		step = "getting users in guild"
+		registered, err := tx.GetUsersInGuild(m.GuildId())
+		if err != nil {
+			return err
+		}
 
-	count := 0
-	for _, u := range registered {
-		if _, ok := guildies[u.Id]; !ok {
-			if err = ap.removeUser(u.Id, m.GuildId()); err != nil {
-				return "deleting user", err
+		step = "deleting user"
+		for _, u := range registered {
+			if _, ok := guildies[u.Id]; !ok {
+				if err = removeUser(tx, u.Id, m.GuildId(), m.AuthorId()); err != nil {
+					return err
+				}
+				count += 1
 			}
-			count += 1
 		}
+
+		return nil
+	})
+	if err != nil {
+		return step, err
 	}
 
 	return fmt.Sprintf("Cleaned up %v users", count), nil
@@ -374,18 +385,30 @@ func (ap *AdminUserProcessor) syncUser(dbu *database.User, guild *database.Guild
 	return nil
 }
 
-func (ap *AdminUserProcessor) removeUser(id string, guildId string) error {
-	dbu, err := ap.Prov.GetUserD(id)
+// removeUser moves user registration in the guild to the trash. Characters are kept
+func removeUser(prov database.DataProvider, id string, guildId string, author string) error {
+	dbu, err := prov.GetUserD(id)
 	if err != nil {
 		return err
 	}
 
-	if _, ok := dbu.Guilds[guildId]; !ok {
+	gp, ok := dbu.Guilds[guildId]
+	if !ok {
 		return nil
 	}
+	tmp := *gp
+
+	if _, err = prov.RemoveUserD(id, guildId); err != nil {
+		return err
+	}
 
-	_, err = ap.Prov.RemoveUserD(id, guildId)
-	return err
+	return helpers.MoveToTrash(prov, &database.TrashItem{
+		GuildId:   guildId,
+		Type:      database.TrashUser,
+		Name:      id,
+		RemovedBy: author,
+		User:      &database.User{Id: id, Guilds: map[string]*database.GuildPermission{guildId: &tmp}},
+	})
 }
 
 func (ap *AdminUserProcessor) userPermissions(id string, m message.Message) (int, error) {
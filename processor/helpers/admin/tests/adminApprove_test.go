@@ -0,0 +1,177 @@
+package admin_tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/database/memory"
+	"github.com/mebaranov/disguildie/processor/helpers/admin"
+	"github.com/mebaranov/disguildie/processor/helpers/tests"
+	"github.com/mebaranov/disguildie/processor/helpers/user"
+)
+
+func TestApprove(t *testing.T) {
+	msg := &tests.TestMessage{}
+	prov := memory.NewMemoryDb()
+	gld, _ := prov.AddGuild(&database.Guild{DiscordId: uuid.New().String(), Name: "test"})
+	alpha, _ := prov.AddGuild(&database.Guild{Name: "alpha", ParentId: gld.GuildId})
+	beta, _ := prov.AddGuild(&database.Guild{Name: "beta", ParentId: gld.GuildId})
+	max := 1000.0
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "power", Type: database.Number, Max: &max, Review: true})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "lvl", Type: database.Number})
+	prov.AddUser("u1", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: alpha.GuildId})
+	prov.AddUser("u2", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: beta.GuildId})
+	prov.AddUser("off", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: alpha.GuildId, Permissions: database.EditSubCharsPerm})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u1", Name: "ch1", Main: true, Body: map[string]interface{}{"power": 10}})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u2", Name: "ch2", Main: true, Body: map[string]interface{}{"power": 20}})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "off", Name: "och", Main: true, Body: map[string]interface{}{"power": 30}})
+
+	author := "u1"
+	msg.GuildIdMock = func() string { return gld.DiscordId }
+	msg.AuthorIdMock = func() string { return author }
+	msg.AuthorMock = func() (*database.User, error) { return prov.GetUserD(author) }
+	msg.AuthorPermissionsMock = func() (int, error) {
+		u, _ := prov.GetUserD(author)
+		return u.Guilds[gld.DiscordId].Permissions, nil
+	}
+	msg.CheckUserModificationPermissionsMock = func(uid string) (bool, error) { return uid == author, nil }
+
+	stats := user.NewStatsProcessor(prov)
+	submissions := []struct {
+		author   string
+		msg      string
+		expected string
+	}{
+		{author: "u1", msg: "power 100", expected: "Stat power = 100 for character ch1 was submitted for approval (#1). Officers will review it"},
+		{author: "u1", msg: "power=+50 lvl=3", expected: "Stats of character ch1 were updated:\n\tpower = 60 submitted for approval (#2)\n\tlvl set to 3"},
+		{author: "u2", msg: "power 200", expected: "Stat power = 200 for character ch2 was submitted for approval (#3). Officers will review it"},
+		{author: "off", msg: "<@!u1> power 70", expected: "Stat power set to 70 for character ch1"},
+	}
+	for _, s := range submissions {
+		author = s.author
+		msg.CurMsg = s.msg
+		if s.author == "off" {
+			msg.CheckUserModificationPermissionsMock = func(string) (bool, error) { return true, nil }
+		}
+		rv, err := stats.ProcessMessage(msg)
+		if err != nil {
+			t.Fatalf("[submit %v] Unexpected processing error: %v", s.msg, err)
+		}
+		if rv != s.expected {
+			t.Errorf("[submit %v] Wrong processing result. Expected: %v, got: %v", s.msg, s.expected, rv)
+		}
+	}
+
+	c, _ := prov.GetCharacter(gld.DiscordId, "u1", "ch1")
+	if c.Body["power"] != 70 || c.Body["lvl"] != 3 {
+		t.Errorf("[submit] Only stats without review expected to be set. Got: %v", c.Body)
+	}
+	if subs, _ := prov.GetSubmissions(gld.DiscordId); len(subs) != 2 {
+		t.Errorf("[submit] Older submission of the same stat expected to be replaced. Got: %v", subs)
+	}
+
+	target := admin.NewAdminApproveProcessor(prov)
+	author = "u1"
+	msg.CurMsg = "list"
+	if rv, err := target.ProcessMessage(msg); err == nil {
+		t.Errorf("[member list] Error expected. Got: %v", rv)
+	}
+
+	author = "off"
+	testData := []struct {
+		name     string
+		msg      string
+		expected string
+		err      bool
+	}{
+		{name: "list", msg: "list", expected: "Submitted stats:\n\t2: power = 60 for character ch1 of <@!u1>"},
+		{name: "replaced", msg: "accept 1", err: true},
+		{name: "bad id", msg: "accept one", err: true},
+		{name: "accept", msg: "accept 2", expected: "<@!u1>, power = 60 for character ch1 was approved by <@!off>"},
+		{name: "accepted is gone", msg: "reject 2", err: true},
+	}
+
+	for _, td := range testData {
+		msg.CurMsg = td.msg
+		rv, err := target.ProcessMessage(msg)
+		if td.err {
+			if err == nil {
+				t.Errorf("[%v] Error expected. Got: %v", td.name, rv)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%v] Unexpected processing error: %v", td.name, err)
+		}
+		if !strings.HasPrefix(rv, td.expected) {
+			t.Errorf("[%v] Wrong processing result. Expected: %v, got: %v", td.name, td.expected, rv)
+		}
+	}
+
+	c, _ = prov.GetCharacter(gld.DiscordId, "u1", "ch1")
+	if c.Body["power"] != 60 {
+		t.Errorf("[accept] Submitted value expected to be set. Got: %v", c.Body)
+	}
+	if h, _ := prov.GetStatHistory(gld.DiscordId, "u1", "ch1", "power"); len(h) != 2 || h[1].Value != 60 {
+		t.Errorf("[accept] Accepted value expected in history. Got: %v", h)
+	}
+
+	author = "u2"
+	msg.CurMsg = "accept 3"
+	if rv, err := target.ProcessMessage(msg); err == nil {
+		t.Errorf("[own submission] Error expected. Got: %v", rv)
+	}
+
+	author = "off"
+	msg.CurMsg = "reject 3 screenshot please"
+	rv, err := target.ProcessMessage(msg)
+	if err != nil {
+		t.Errorf("[reject] Unexpected processing error: %v", err)
+	}
+	if rv != "<@!u2>, power = 200 for character ch2 was rejected by <@!off>: screenshot please" {
+		t.Errorf("[reject] Wrong processing result. Got: %v", rv)
+	}
+	c, _ = prov.GetCharacter(gld.DiscordId, "u2", "ch2")
+	if c.Body["power"] != 20 {
+		t.Errorf("[reject] Value expected to stay. Got: %v", c.Body)
+	}
+	if subs, _ := prov.GetSubmissions(gld.DiscordId); len(subs) != 0 {
+		t.Errorf("[reject] No submissions expected. Got: %v", subs)
+	}
+
+	// officers don't review their own values. Permission constants share bits with the guild-wide one, so they are masked out
+	prov.SetUserPermissions("off", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: alpha.GuildId, Permissions: database.EditOneUpCharsPerm &^ database.EditGuildCharsPerm})
+	msg.CurMsg = "<@!u2> power 250"
+	if rv, err = stats.ProcessMessage(msg); err != nil || rv != "Stat power set to 250 for character ch2" {
+		t.Errorf("[officer set] Wrong processing result. Got: %v, %v", rv, err)
+	}
+	msg.CurMsg = "power 300"
+	rv, err = stats.ProcessMessage(msg)
+	if err != nil {
+		t.Errorf("[officer submit] Unexpected processing error: %v", err)
+	}
+	if rv != "Stat power = 300 for character och was submitted for approval (#4). Officers will review it" {
+		t.Errorf("[officer submit] Wrong processing result. Got: %v", rv)
+	}
+	msg.CurMsg = "accept 4"
+	if rv, err := target.ProcessMessage(msg); err == nil {
+		t.Errorf("[officer own submission] Error expected. Got: %v", rv)
+	}
+
+	prov.SetUserPermissions("off", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: alpha.GuildId, Permissions: database.EditGuildCharsPerm})
+	msg.CurMsg = "accept 4"
+	if _, err := target.ProcessMessage(msg); err != nil {
+		t.Errorf("[guild officer own submission] Unexpected processing error: %v", err)
+	}
+	msg.CurMsg = "power 400"
+	rv, err = stats.ProcessMessage(msg)
+	if err != nil {
+		t.Errorf("[guild officer set] Unexpected processing error: %v", err)
+	}
+	if rv != "Stat power set to 400 for character och" {
+		t.Errorf("[guild officer set] Wrong processing result. Got: %v", rv)
+	}
+}
@@ -0,0 +1,73 @@
+package admin_tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/database/memory"
+	"github.com/mebaranov/disguildie/processor/helpers/admin"
+	"github.com/mebaranov/disguildie/processor/helpers/tests"
+)
+
+func TestAudit(t *testing.T) {
+	msg := &tests.TestMessage{}
+	prov := memory.NewMemoryDb()
+	now := time.Now()
+	for i := 0; i < 12; i++ {
+		prov.AddAuditEntry(&database.AuditEntry{GuildId: "g", AuthorId: "o1", Time: now, Action: "stat set", UserId: "u1", Character: "ch", Stat: "lvl", After: "1"})
+	}
+	prov.AddAuditEntry(&database.AuditEntry{GuildId: "g", AuthorId: "o1", Time: now, Action: "user assigned", UserId: "u2", Before: "main", After: "sub", Command: "!g a u a"})
+
+	msg.GuildIdMock = func() string { return "g" }
+	msg.AuthorPermissionsMock = func() (int, error) { return 0, nil }
+	target := admin.NewAdminAuditProcessor(prov)
+
+	msg.CurMsg = ""
+	if _, err := target.ProcessMessage(msg); err == nil {
+		t.Errorf("[no permissions] Error expected")
+	}
+
+	msg.AuthorPermissionsMock = func() (int, error) { return database.EditSubCharsPerm, nil }
+	msg.CurMsg = "<@!u2>"
+	rv, err := target.ProcessMessage(msg)
+	if err != nil {
+		t.Errorf("[user] Unexpected processing error: %v", err)
+	}
+	if !strings.Contains(rv, "user assigned user <@!u2>: \"main\" -> \"sub\" (!g a u a)") || strings.Contains(rv, "stat set") {
+		t.Errorf("[user] Wrong processing result. Got: %v", rv)
+	}
+
+	msg.CurMsg = "stat lvl"
+	rv, err = target.ProcessMessage(msg)
+	if err != nil {
+		t.Errorf("[page 1] Unexpected processing error: %v", err)
+	}
+	if strings.Count(rv, "stat set") != 10 || !strings.Contains(rv, "page 2") {
+		t.Errorf("[page 1] Wrong processing result. Got: %v", rv)
+	}
+
+	msg.CurMsg = "s lvl p 2"
+	rv, err = target.ProcessMessage(msg)
+	if err != nil {
+		t.Errorf("[page 2] Unexpected processing error: %v", err)
+	}
+	if strings.Count(rv, "stat set") != 2 {
+		t.Errorf("[page 2] Wrong processing result. Got: %v", rv)
+	}
+
+	msg.CurMsg = "to " + now.AddDate(0, 0, -1).Format("2006-01-02")
+	rv, err = target.ProcessMessage(msg)
+	if err != nil {
+		t.Errorf("[range] Unexpected processing error: %v", err)
+	}
+	if rv != "There are no audit entries matching the filter" {
+		t.Errorf("[range] Wrong processing result. Got: %v", rv)
+	}
+
+	msg.CurMsg = "from yesterday"
+	if rv, err = target.ProcessMessage(msg); rv != "parsing start date" || err == nil {
+		t.Errorf("[bad date] Wrong processing result. Got: %v, %v", rv, err)
+	}
+}
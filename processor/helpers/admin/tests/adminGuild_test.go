@@ -284,6 +284,7 @@ func TestRemove(t *testing.T) {
 	msg.GuildIdMock = func() string { return uuid.New().String() }
 	msg.CheckGuildModificationPermissionsMock = func(uuid.UUID) (bool, error) { return false, errors.New("Test error") }
 	msg.GuildIdMock = func() string { return mainGld.DiscordId }
+	msg.AuthorIdMock = func() string { return "u3" }
 
 	testActions := []guildTest{
 		{
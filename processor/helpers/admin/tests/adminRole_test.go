@@ -0,0 +1,70 @@
+package admin_tests
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/database/memory"
+	"github.com/mebaranov/disguildie/processor/helpers/admin"
+	"github.com/mebaranov/disguildie/processor/helpers/tests"
+)
+
+func TestRoleAdd(t *testing.T) {
+	msg := &tests.TestMessage{}
+	prov := memory.NewMemoryDb()
+	gid := uuid.New().String()
+	sub := uuid.New()
+	prov.AddRole(&database.Role{GuildId: gid, Id: "r1", Permissions: database.EditSubCharsPerm})
+	prov.AddUser("u1", &database.GuildPermission{TopGuild: gid, GuildId: sub, Permissions: database.EditSubCharsPerm})
+
+	msg.GuildIdMock = func() string { return gid }
+	msg.AuthorPermissionsMock = func() (int, error) { return database.FullPermissions, nil }
+	msg.GetRoleIdMock = func(string) (string, error) { return "r1", nil }
+	msg.GuildMembersWithRoleMock = func(string) (map[string]string, error) {
+		return map[string]string{"u1": "one", "u2": "two"}, nil
+	}
+	target := admin.NewAdminRoleProcessor(prov)
+
+	// u2 is not registered, so the whole update is rolled back
+	msg.CurMsg = "add officer sg"
+	rv, err := target.ProcessMessage(msg)
+	if rv != "getting a user for update" {
+		t.Errorf("[rollback] Wrong processing result. Got: %v, Wish: %v", rv, "getting a user for update")
+	}
+	if err == nil || err.Error() != "User was not found" {
+		t.Errorf("[rollback] Wrong processing error. Got: %v, Wish: %v", err, "User was not found")
+	}
+
+	r, _ := prov.GetRole(gid, "r1")
+	if r.Permissions != database.EditSubCharsPerm {
+		t.Errorf("[rollback] Role permissions expected to be rolled back. Got: %v", r.Permissions)
+	}
+	u, _ := prov.GetUserD("u1")
+	if u.Guilds[gid].Permissions != database.EditSubCharsPerm {
+		t.Errorf("[rollback] User permissions expected to be rolled back. Got: %v", u.Guilds[gid].Permissions)
+	}
+
+	msg.GuildMembersWithRoleMock = func(string) (map[string]string, error) {
+		return map[string]string{"u1": "one"}, nil
+	}
+	msg.CurMsg = "add officer sg"
+	rv, err = target.ProcessMessage(msg)
+	if err != nil {
+		t.Errorf("[success] Unexpected processing error: %v", err)
+	}
+	if rv != "Permission sg added for the role officer" {
+		t.Errorf("[success] Wrong processing result. Got: %v", rv)
+	}
+
+	expected := database.EditSubCharsPerm | database.EditSubStructurePerm
+	r, _ = prov.GetRole(gid, "r1")
+	if r.Permissions != expected {
+		t.Errorf("[success] Wrong role permissions. Got: %v, Wish: %v", r.Permissions, expected)
+	}
+	u, _ = prov.GetUserD("u1")
+	if u.Guilds[gid].Permissions != expected {
+		t.Errorf("[success] Wrong user permissions. Got: %v, Wish: %v", u.Guilds[gid].Permissions, expected)
+	}
+}
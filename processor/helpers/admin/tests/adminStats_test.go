@@ -0,0 +1,390 @@
+package admin_tests
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/database/memory"
+	"github.com/mebaranov/disguildie/processor/helpers"
+	"github.com/mebaranov/disguildie/processor/helpers/admin"
+	"github.com/mebaranov/disguildie/processor/helpers/tests"
+)
+
+func TestStatConstraints(t *testing.T) {
+	msg := &tests.TestMessage{}
+	prov := memory.NewMemoryDb()
+	gld, _ := prov.AddGuild(&database.Guild{DiscordId: uuid.New().String(), Name: "test"})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "power", Type: database.Number})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "nick", Type: database.Str})
+
+	msg.GuildIdMock = func() string { return gld.DiscordId }
+	msg.AuthorPermissionsMock = func() (int, error) { return database.FullPermissions, nil }
+
+	target := admin.NewAdminStatsProcessor(prov)
+	testData := []struct {
+		name     string
+		msg      string
+		expected string
+		err      bool
+	}{
+		{name: "min", msg: "min power 1", expected: "Stat power constraints: min 1"},
+		{name: "max", msg: "max power 100000", expected: "Stat power constraints: min 1, max 100000"},
+		{name: "max below min", msg: "max power 0", err: true},
+		{name: "min of string", msg: "min nick 1", err: true},
+		{name: "infinite max", msg: "max power inf", err: true},
+		{name: "NaN min", msg: "min power NaN", err: true},
+		{name: "pattern", msg: "pattern nick ^[a-z]+$", expected: "Stat nick constraints: pattern ^[a-z]+$"},
+		{name: "bad pattern", msg: "pattern nick [a-", err: true},
+		{name: "length", msg: "len nick 12", expected: "Stat nick constraints: max length 12, pattern ^[a-z]+$"},
+		{name: "required", msg: "req nick yes", expected: "Stat nick constraints: max length 12, pattern ^[a-z]+$, required"},
+		{name: "pattern of number", msg: "regex power .*", err: true},
+		{name: "missing stat", msg: "min level 1", err: true},
+		{name: "drop min", msg: "min power none", expected: "Stat power constraints: max 100000"},
+		{name: "lock", msg: "lock power", expected: "Stat power can only be set by officers of the character owner"},
+		{name: "lock guild-wide", msg: "lock nick guild", expected: "Stat nick can only be set by guild-wide officers"},
+		{name: "bad lock level", msg: "lock nick everyone", err: true},
+		{name: "lock missing stat", msg: "lock level", err: true},
+		{name: "constraints show lock", msg: "min power 2", expected: "Stat power constraints: min 2, max 100000, set by officers"},
+		{name: "unlock", msg: "unlock power", expected: "Stat power can be set by character owners"},
+		{name: "review", msg: "review power yes", expected: "Values of stat power set by members will wait for officer approval"},
+		{name: "bad review flag", msg: "review power maybe", err: true},
+		{name: "constraints show review", msg: "max power 100000", expected: "Stat power constraints: min 2, max 100000, needs approval"},
+	}
+
+	for _, td := range testData {
+		msg.CurMsg = td.msg
+		rv, err := target.ProcessMessage(msg)
+		if td.err {
+			if err == nil {
+				t.Errorf("[%v] Error expected. Got: %v", td.name, rv)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%v] Unexpected processing error: %v", td.name, err)
+		}
+		if rv != td.expected {
+			t.Errorf("[%v] Wrong processing result. Expected: %v, got: %v", td.name, td.expected, rv)
+		}
+	}
+
+	g, _ := prov.GetGuildD(gld.DiscordId)
+	nick := g.Stats["nick"]
+	if err := nick.Validate("someverylongname"); err == nil {
+		t.Errorf("[validate] Too long value expected to fail")
+	}
+	if err := nick.Validate("Nick"); err == nil {
+		t.Errorf("[validate] Value not matching pattern expected to fail")
+	}
+	if err := nick.Validate("nick"); err != nil {
+		t.Errorf("[validate] Unexpected error: %v", err)
+	}
+	if err := g.Stats["power"].Validate(999999999); err == nil {
+		t.Errorf("[validate] Too big value expected to fail")
+	}
+	if nick.Write != database.WriteGuild || g.Stats["power"].Write != database.WriteSelf {
+		t.Errorf("[lock] Wrong write policies. Got: %v, %v", nick.Write, g.Stats["power"].Write)
+	}
+	if !g.Stats["power"].Review || nick.Review {
+		t.Errorf("[review] Only power expected to need approval")
+	}
+}
+
+func TestStatDisplay(t *testing.T) {
+	msg := &tests.TestMessage{}
+	prov := memory.NewMemoryDb()
+	gld, _ := prov.AddGuild(&database.Guild{DiscordId: uuid.New().String(), Name: "test"})
+	min := 1.0
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "power", Type: database.Number, Min: &min})
+
+	msg.GuildIdMock = func() string { return gld.DiscordId }
+	msg.AuthorPermissionsMock = func() (int, error) { return database.FullPermissions, nil }
+
+	target := admin.NewAdminStatsProcessor(prov)
+	testData := []struct {
+		name     string
+		msg      string
+		expected string
+		err      bool
+	}{
+		{name: "category", msg: "cat power combat", expected: "Stat power is shown in category combat at position 0"},
+		{name: "order", msg: "order power -2", expected: "Stat power is shown in category combat at position -2"},
+		{name: "bad order", msg: "order power first", err: true},
+		{name: "missing stat", msg: "category level combat", err: true},
+		{name: "retype keeps category", msg: "retype power float", expected: "Stat power type was changed to float. 0 values were converted."},
+		{name: "drop category", msg: "category power none", expected: "Stat power is shown without category at position -2"},
+	}
+
+	for _, td := range testData {
+		msg.CurMsg = td.msg
+		rv, err := target.ProcessMessage(msg)
+		if td.err {
+			if err == nil {
+				t.Errorf("[%v] Error expected. Got: %v", td.name, rv)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%v] Unexpected processing error: %v", td.name, err)
+		}
+		if rv != td.expected {
+			t.Errorf("[%v] Wrong processing result. Expected: %v, got: %v", td.name, td.expected, rv)
+		}
+	}
+
+	g, _ := prov.GetGuildD(gld.DiscordId)
+	if st := g.Stats["power"]; st.Order != -2 || st.Min == nil || *st.Min != 1 {
+		t.Errorf("[stat] Display settings expected to keep constraints. Got: %v", st)
+	}
+}
+
+func TestStatFormulas(t *testing.T) {
+	msg := &tests.TestMessage{}
+	prov := memory.NewMemoryDb()
+	gld, _ := prov.AddGuild(&database.Guild{DiscordId: uuid.New().String(), Name: "test"})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "attack", Type: database.Number})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "defense", Type: database.Float})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "wins", Type: database.Number})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "games", Type: database.Number})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "class", Type: database.Str})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "сила", Type: database.Number})
+
+	msg.GuildIdMock = func() string { return gld.DiscordId }
+	msg.AuthorPermissionsMock = func() (int, error) { return database.FullPermissions, nil }
+
+	target := admin.NewAdminStatsProcessor(prov)
+	testData := []struct {
+		name string
+		msg  string
+		err  bool
+	}{
+		{name: "total", msg: "add total formula attack + defense * 2"},
+		{name: "ratio", msg: "add ratio formula if(games > 0, wins / games, 0)"},
+		{name: "nested", msg: "add score formula max(total, 10) * ratio"},
+		{name: "self reference", msg: "add loop formula loop + 1", err: true},
+		{name: "unknown reference", msg: "add bad formula attack + speed", err: true},
+		{name: "string reference", msg: "add bad formula class * 2", err: true},
+		{name: "syntax", msg: "add bad formula (attack + ", err: true},
+		{name: "unknown function", msg: "add bad formula sqrt(attack)", err: true},
+		{name: "empty", msg: "add bad formula", err: true},
+		{name: "remove used", msg: "remove total", err: true},
+		{name: "remove unused", msg: "remove score"},
+		{name: "non-ASCII names", msg: "add мощь formula сила * 2 + attack"},
+		{name: "rename non-ASCII", msg: "rename сила крепость"},
+		{name: "non-ASCII symbol", msg: "add bad formula attack × 2", err: true},
+	}
+
+	for _, td := range testData {
+		msg.CurMsg = td.msg
+		rv, err := target.ProcessMessage(msg)
+		if td.err && err == nil {
+			t.Errorf("[%v] Error expected. Got: %v", td.name, rv)
+		}
+		if !td.err && err != nil {
+			t.Errorf("[%v] Unexpected processing error: %v", td.name, err)
+		}
+	}
+
+	g, _ := prov.GetGuildD(gld.DiscordId)
+	if _, ok := g.Stats["bad"]; ok {
+		t.Fatalf("[check] Invalid formula stat was added")
+	}
+
+	if f := g.Stats["мощь"].Formula; f != "крепость * 2 + attack" {
+		t.Errorf("[rename] Formula expected to follow rename. Got: %v", f)
+	}
+
+	c := &database.Character{Name: "c1", Body: map[string]interface{}{"attack": 10, "defense": 2.5, "wins": 3, "games": 0, "крепость": 4}}
+	testValues := []struct {
+		stat     string
+		expected float64
+	}{
+		{stat: "total", expected: 15},
+		{stat: "ratio", expected: 0},
+		{stat: "мощь", expected: 18},
+	}
+	for _, tv := range testValues {
+		v, err := helpers.StatValue(g.Stats, c, tv.stat)
+		if err != nil {
+			t.Errorf("[%v] Unexpected calculation error: %v", tv.stat, err)
+		}
+		if v != tv.expected {
+			t.Errorf("[%v] Wrong value. Expected: %v, got: %v", tv.stat, tv.expected, v)
+		}
+	}
+
+	c.Body["games"] = 4
+	if v, _ := helpers.StatValue(g.Stats, c, "ratio"); v != 0.75 {
+		t.Errorf("[ratio] Wrong value. Expected: %v, got: %v", 0.75, v)
+	}
+	delete(c.Body, "attack")
+	if v, err := helpers.StatValue(g.Stats, c, "total"); err == nil {
+		t.Errorf("[missing] Error expected. Got: %v", v)
+	}
+}
+
+func TestStatRenameRetype(t *testing.T) {
+	msg := &tests.TestMessage{}
+	prov := memory.NewMemoryDb()
+	gld, _ := prov.AddGuild(&database.Guild{DiscordId: uuid.New().String(), Name: "test"})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "lvl", Type: database.Number})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "power", Type: database.Str})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "total", Type: database.Computed, Formula: "lvl * 2 + max(lvl, 1)"})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u1", Name: "c1", Body: map[string]interface{}{"lvl": 3, "power": "12"}})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u2", Name: "c2", Body: map[string]interface{}{"lvl": 4, "power": "strong"}})
+
+	msg.GuildIdMock = func() string { return gld.DiscordId }
+	msg.AuthorPermissionsMock = func() (int, error) { return database.FullPermissions, nil }
+
+	target := admin.NewAdminStatsProcessor(prov)
+	testData := []struct {
+		name     string
+		msg      string
+		expected string
+		err      bool
+	}{
+		{name: "rename missing", msg: "rename level power", err: true},
+		{name: "rename taken", msg: "rename lvl power", err: true},
+		{name: "rename to invalid formula name", msg: "rename lvl my-level", err: true},
+		{name: "rename", msg: "rename lvl level", expected: "Stat lvl was renamed to level."},
+		{name: "retype used in formula", msg: "retype level str", err: true},
+		{name: "retype formula", msg: "retype total int", err: true},
+		{name: "retype same", msg: "retype level int", err: true},
+		{name: "retype", msg: "retype power int", expected: "Stat power type was changed to int. 1 values were converted.\n1 values couldn't be converted and were reset:\n\tc2 of <@!u2>: strong"},
+		{name: "retype to float", msg: "retype level float", expected: "Stat level type was changed to float. 2 values were converted."},
+	}
+
+	for _, td := range testData {
+		msg.CurMsg = td.msg
+		rv, err := target.ProcessMessage(msg)
+		if td.err {
+			if err == nil {
+				t.Errorf("[%v] Error expected. Got: %v", td.name, rv)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%v] Unexpected processing error: %v", td.name, err)
+		}
+		if rv != td.expected {
+			t.Errorf("[%v] Wrong processing result. Expected: %v, got: %v", td.name, td.expected, rv)
+		}
+	}
+
+	g, _ := prov.GetGuildD(gld.DiscordId)
+	if g.DefaultStat != "level" {
+		t.Errorf("[default] Default stat expected to follow rename. Got: %v", g.DefaultStat)
+	}
+	if f := g.Stats["total"].Formula; f != "level * 2 + max(level, 1)" {
+		t.Errorf("[formula] Formula expected to follow rename. Got: %v", f)
+	}
+
+	c, _ := prov.GetCharacter(gld.DiscordId, "u1", "c1")
+	if c.Body["level"] != 3.0 || c.Body["power"] != 12 {
+		t.Errorf("[convert] Wrong converted values. Got: %v", c.Body)
+	}
+	c, _ = prov.GetCharacter(gld.DiscordId, "u2", "c2")
+	if c.Body["power"] != 0 {
+		t.Errorf("[convert] Value expected to be reset. Got: %v", c.Body)
+	}
+	if h, _ := prov.GetStatHistory(gld.DiscordId, "u2", "c2", "power"); len(h) != 1 || h[0].Value != 0 {
+		t.Errorf("[convert] Reset value expected in history. Got: %v", h)
+	}
+	if h, _ := prov.GetStatHistory(gld.DiscordId, "u2", "c2", "level"); len(h) != 1 || h[0].Value != 4.0 {
+		t.Errorf("[convert] Converted value expected in history. Got: %v", h)
+	}
+	if v, _ := helpers.StatValue(g.Stats, c, "total"); v != 12.0 {
+		t.Errorf("[formula] Wrong formula value. Got: %v", v)
+	}
+}
+
+func TestSubGuildStats(t *testing.T) {
+	msg := &tests.TestMessage{}
+	prov := memory.NewMemoryDb()
+	gld, _ := prov.AddGuild(&database.Guild{DiscordId: uuid.New().String(), Name: "test"})
+	alpha, _ := prov.AddGuild(&database.Guild{Name: "alpha", ParentId: gld.GuildId})
+	beta, _ := prov.AddGuild(&database.Guild{Name: "beta", ParentId: alpha.GuildId})
+	prov.AddGuild(&database.Guild{Name: "gamma", ParentId: gld.GuildId})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "lvl", Type: database.Number})
+	prov.AddUser("u1", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: beta.GuildId})
+	prov.AddUser("u2", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: gld.GuildId})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u1", Name: "c1", Body: map[string]interface{}{"lvl": 3, "raids": 2}})
+
+	msg.GuildIdMock = func() string { return gld.DiscordId }
+	msg.AuthorPermissionsMock = func() (int, error) { return database.FullPermissions, nil }
+	allowed := true
+	msg.CheckGuildModificationPermissionsMock = func(uuid.UUID) (bool, error) { return allowed, nil }
+
+	target := admin.NewAdminStatsProcessor(prov)
+	testData := []struct {
+		name     string
+		msg      string
+		expected string
+		err      bool
+		denied   bool
+	}{
+		{name: "unknown sub-guild", msg: "in delta add raids int", err: true},
+		{name: "no permissions", msg: "in alpha add raids int", err: true, denied: true},
+		{name: "unknown command", msg: "in alpha rename raids wins", err: true},
+		{name: "add", msg: "in alpha add raids int Raids done", expected: "Stat raids with type int was added to sub-guild alpha."},
+		{name: "add formula over parent", msg: "in beta add score formula raids + lvl", expected: "Stat score with type formula was added to sub-guild beta."},
+		{name: "add formula over sibling", msg: "in gamma add bad formula raids * 2", err: true},
+		{name: "name taken by parent", msg: "in alpha add lvl int", err: true},
+		{name: "name taken by sub-guild", msg: "add raids int", err: true},
+		{name: "name taken in sibling", msg: "in gamma add score int", err: true},
+		{name: "remove used in formula", msg: "in alpha remove raids", err: true},
+		{name: "remove parent stat used in formula", msg: "remove lvl", err: true},
+		{name: "remove from wrong sub-guild", msg: "in gamma remove score", err: true},
+		{name: "rename sub-guild stat", msg: "rename raids wins", expected: "Stat raids was renamed to wins."},
+		{name: "rename to name taken by sub-guild", msg: "rename lvl score", err: true},
+		{name: "rename used in sub-guild formula", msg: "rename lvl level", expected: "Stat lvl was renamed to level."},
+		{name: "constrain sub-guild stat", msg: "min wins 0", expected: "Stat wins constraints: min 0"},
+		{name: "retype sub-guild stat", msg: "retype wins float", expected: "Stat wins type was changed to float. 1 values were converted."},
+		{name: "remove", msg: "in beta remove score", expected: "Stat score was removed."},
+	}
+
+	for _, td := range testData {
+		allowed = !td.denied
+		msg.CurMsg = td.msg
+		rv, err := target.ProcessMessage(msg)
+		if td.err {
+			if err == nil {
+				t.Errorf("[%v] Error expected. Got: %v", td.name, rv)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("[%v] Unexpected processing error: %v", td.name, err)
+		}
+		if rv != td.expected {
+			t.Errorf("[%v] Wrong processing result. Expected: %v, got: %v", td.name, td.expected, rv)
+		}
+	}
+
+	gs, err := helpers.NewGuildStats(prov, gld.DiscordId)
+	if err != nil {
+		t.Fatalf("[stats] Unexpected error: %v", err)
+	}
+	if st, g := gs.Stat("wins"); st == nil || g.GuildId != alpha.GuildId || st.Min == nil || st.Type != database.Float {
+		t.Errorf("[stats] Wrong sub-guild stat: %v in %v", st, g)
+	}
+	if st, _ := gs.Stat("raids"); st != nil {
+		t.Errorf("[stats] Renamed stat expected to be gone: %v", st)
+	}
+	if us, _ := gs.UserStats("u1"); len(us) != 2 || us["level"] == nil || us["wins"] == nil {
+		t.Errorf("[stats] Wrong stats of sub-guild member: %v", us)
+	}
+	if us, _ := gs.UserStats("u2"); len(us) != 1 || us["level"] == nil {
+		t.Errorf("[stats] Wrong stats of top-level member: %v", us)
+	}
+	if gs.Top.StatVersion != 7 {
+		t.Errorf("[stats] Sub-guild stat changes expected to update top-level version. Got: %v", gs.Top.StatVersion)
+	}
+
+	c, _ := prov.GetCharacter(gld.DiscordId, "u1", "c1")
+	if _, ok := c.Body["raids"]; ok || c.Body["wins"] != 2.0 || c.Body["level"] != 3 {
+		t.Errorf("[values] Values expected to follow rename and retype of sub-guild stat. Got: %v", c.Body)
+	}
+}
@@ -0,0 +1,126 @@
+package admin_tests
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/database/memory"
+	"github.com/mebaranov/disguildie/processor/helpers"
+	"github.com/mebaranov/disguildie/processor/helpers/admin"
+	"github.com/mebaranov/disguildie/processor/helpers/tests"
+)
+
+func TestTrashGuild(t *testing.T) {
+	msg := &tests.TestMessage{}
+	prov := memory.NewMemoryDb()
+	mainGld, _ := prov.AddGuild(&database.Guild{DiscordId: uuid.New().String(), Name: "test"})
+	removeMe, _ := prov.AddGuild(&database.Guild{Name: "removeMe", ParentId: mainGld.GuildId})
+	sub, _ := prov.AddGuild(&database.Guild{Name: "sub", ParentId: removeMe.GuildId})
+	prov.AddUser("u1", &database.GuildPermission{GuildId: sub.GuildId, TopGuild: mainGld.DiscordId})
+
+	msg.GuildIdMock = func() string { return mainGld.DiscordId }
+	msg.AuthorIdMock = func() string { return "officer" }
+	msg.AuthorPermissionsMock = func() (int, error) { return database.FullPermissions, nil }
+	msg.CheckGuildModificationPermissionsMock = func(uuid.UUID) (bool, error) { return true, nil }
+
+	msg.CurMsg = "remove removeMe"
+	if _, err := admin.NewAdminGuildProcessor(prov).ProcessMessage(msg); err != nil {
+		t.Fatalf("[remove] Unexpected processing error: %v", err)
+	}
+
+	target := admin.NewAdminTrashProcessor(prov)
+	msg.CurMsg = "list"
+	rv, err := target.ProcessMessage(msg)
+	if err != nil {
+		t.Errorf("[list] Unexpected processing error: %v", err)
+	}
+	if !strings.Contains(rv, "sub-guild removeMe, removed by <@!officer>") {
+		t.Errorf("[list] Wrong processing result. Got: %v", rv)
+	}
+
+	items, _ := prov.GetTrashItems(mainGld.DiscordId)
+	if len(items) != 1 {
+		t.Fatalf("[remove] One trash item expected. Got: %v", items)
+	}
+
+	msg.CurMsg = "restore 100"
+	if _, err = target.ProcessMessage(msg); err == nil {
+		t.Errorf("[restore missing] Error expected")
+	}
+
+	msg.CurMsg = fmt.Sprintf("restore %v", items[0].Id)
+	rv, err = target.ProcessMessage(msg)
+	if err != nil {
+		t.Fatalf("[restore] Unexpected processing error: %v", err)
+	}
+	if rv != "Restored sub-guild removeMe" {
+		t.Errorf("[restore] Wrong processing result. Got: %v", rv)
+	}
+
+	g, err := prov.GetGuildN(mainGld.DiscordId, "sub")
+	if err != nil || g.GuildId != sub.GuildId || g.ParentId != removeMe.GuildId {
+		t.Errorf("[restore] Sub-guild tree expected to be restored. Got: %v, %v", g, err)
+	}
+	u, _ := prov.GetUserD("u1")
+	if u.Guilds[mainGld.DiscordId].GuildId != sub.GuildId {
+		t.Errorf("[restore] User expected to be moved back. Got: %v", u.Guilds[mainGld.DiscordId])
+	}
+	if items, _ = prov.GetTrashItems(mainGld.DiscordId); len(items) != 0 {
+		t.Errorf("[restore] Empty trash expected. Got: %v", items)
+	}
+}
+
+func TestTrashCharacter(t *testing.T) {
+	msg := &tests.TestMessage{}
+	prov := memory.NewMemoryDb()
+	prov.AddCharacter(&database.Character{GuildId: "g", UserId: "u1", Name: "other", Main: true})
+	helpers.MoveToTrash(prov, &database.TrashItem{
+		GuildId:    "g",
+		Type:       database.TrashCharacter,
+		Name:       "ch",
+		Characters: []*database.Character{{GuildId: "g", UserId: "u1", Name: "ch", Main: true, Body: map[string]interface{}{"lvl": 10}}},
+		History: []*database.StatHistory{
+			{GuildId: "g", UserId: "u1", Character: "ch", Stat: "lvl", Value: 5, Time: time.Unix(100, 0)},
+			{GuildId: "g", UserId: "u1", Character: "ch", Stat: "lvl", Value: 10, Time: time.Unix(200, 0)},
+		},
+	})
+	prov.AddTrashItem(&database.TrashItem{GuildId: "g", Type: database.TrashUser, Name: "expired", Time: time.Now().Add(-helpers.TrashRetention - time.Hour)})
+
+	msg.GuildIdMock = func() string { return "g" }
+	msg.AuthorPermissionsMock = func() (int, error) { return database.EditSubCharsPerm, nil }
+	msg.CheckUserModificationPermissionsMock = func(string) (bool, error) { return false, nil }
+	target := admin.NewAdminTrashProcessor(prov)
+
+	msg.CurMsg = "list"
+	rv, err := target.ProcessMessage(msg)
+	if err != nil {
+		t.Errorf("[list] Unexpected processing error: %v", err)
+	}
+	if !strings.Contains(rv, "1: character ch of <@!u1>") || strings.Contains(rv, "expired") {
+		t.Errorf("[list] Wrong processing result. Got: %v", rv)
+	}
+
+	msg.CurMsg = "restore 1"
+	if rv, err = target.ProcessMessage(msg); err == nil || err.Error() != "You don't have permissions to restore this item" {
+		t.Errorf("[no permissions] Wrong processing result. Got: %v, %v", rv, err)
+	}
+
+	msg.CheckUserModificationPermissionsMock = func(string) (bool, error) { return true, nil }
+	msg.CurMsg = "restore 1"
+	if rv, err = target.ProcessMessage(msg); err != nil {
+		t.Fatalf("[restore] Unexpected processing error: %v", err)
+	}
+
+	c, err := prov.GetCharacter("g", "u1", "ch")
+	if err != nil || c.Body["lvl"] != 10 || c.Main {
+		t.Errorf("[restore] Character expected to be restored with stats and without main flag. Got: %v, %v", c, err)
+	}
+	if h, _ := prov.GetStatHistory("g", "u1", "ch", "lvl"); len(h) != 2 || h[0].Value != 5 || h[1].Value != 10 {
+		t.Errorf("[restore] Character history expected to be restored. Got: %v", h)
+	}
+}
@@ -0,0 +1,120 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/formula"
+)
+
+// CheckFormula makes sure formula of computed stat s only references numeric stats and has no cycles
+func CheckFormula(stats map[string]*database.Stat, s *database.Stat) error {
+	all := make(map[string]*database.Stat, len(stats)+1)
+	for k, v := range stats {
+		all[k] = v
+	}
+	all[s.ID] = s
+
+	return checkFormula(all, s, make(map[string]bool))
+}
+
+func checkFormula(stats map[string]*database.Stat, s *database.Stat, path map[string]bool) error {
+	f, err := formula.Parse(s.Formula)
+	if err != nil {
+		return err
+	}
+
+	path[s.ID] = true
+	defer delete(path, s.ID)
+	for _, r := range f.Refs() {
+		ref, ok := stats[r]
+		if !ok {
+			return errors.New(fmt.Sprintf("Formula of %v references unknown stat %v", s.ID, r))
+		}
+
+		switch ref.Type {
+		case database.Number, database.Float, database.Bool, database.Duration:
+		case database.Computed:
+			if path[r] {
+				return errors.New(fmt.Sprintf("Formula of %v references %v, which depends on %v", s.ID, r, s.ID))
+			}
+			if err = checkFormula(stats, ref, path); err != nil {
+				return err
+			}
+		default:
+			return errors.New(fmt.Sprintf("Formula of %v references %v stat %v. Only numbers, yes/no and durations can be used", s.ID, database.TypeToString(ref.Type), r))
+		}
+	}
+
+	return nil
+}
+
+// StatValue returns value of character stat, calculating it for computed stats
+func StatValue(stats map[string]*database.Stat, c *database.Character, name string) (interface{}, error) {
+	return statValue(stats, c, name, 0)
+}
+
+// NumericValue returns value of numeric character stat as a number. Durations are counted in hours
+func NumericValue(stats map[string]*database.Stat, c *database.Character, name string) (float64, error) {
+	v, err := statValue(stats, c, name, 0)
+	if err != nil {
+		return 0, err
+	}
+
+	return ToNumber(name, v)
+}
+
+func statValue(stats map[string]*database.Stat, c *database.Character, name string, depth int) (interface{}, error) {
+	s, ok := stats[name]
+	if !ok || s.Type != database.Computed {
+		v, ok := c.Body[name]
+		if !ok {
+			return nil, errors.New(fmt.Sprintf("Stat %v is not set", name))
+		}
+		return v, nil
+	}
+
+	// definitions are checked for cycles, but stats could have changed since
+	if depth > len(stats) {
+		return nil, errors.New(fmt.Sprintf("Formula of %v is recursive", name))
+	}
+
+	f, err := formula.Parse(s.Formula)
+	if err != nil {
+		return nil, err
+	}
+
+	rv, err := f.Eval(func(ref string) (float64, error) {
+		v, err := statValue(stats, c, ref, depth+1)
+		if err != nil {
+			return 0, err
+		}
+
+		return ToNumber(ref, v)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rv, nil
+}
+
+// ToNumber converts stat value to a number. Durations are counted in hours, booleans are 0 or 1
+func ToNumber(name string, v interface{}) (float64, error) {
+	switch val := v.(type) {
+	case int:
+		return float64(val), nil
+	case float64:
+		return val, nil
+	case bool:
+		if val {
+			return 1, nil
+		}
+		return 0, nil
+	case time.Duration:
+		return val.Hours(), nil
+	}
+	return 0, errors.New(fmt.Sprintf("Stat %v is not a number", name))
+}
@@ -0,0 +1,169 @@
+package helpers
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+// GuildStats resolves stats of a guild hierarchy. Sub-guilds inherit stats of all their parents,
+// so characters see stats of their owner's sub-guild and of every guild above it
+type GuildStats struct {
+	Top    *database.Guild
+	Guilds map[uuid.UUID]*database.Guild
+
+	prov    database.DataProvider
+	users   map[string]map[string]*database.Stat
+	members map[string]uuid.UUID
+}
+
+// constructor function
+func NewGuildStats(prov database.DataProvider, d string) (*GuildStats, error) {
+	top, err := prov.GetGuildD(d)
+	if err != nil {
+		return nil, err
+	}
+
+	guilds, err := prov.GetSubGuilds(top.GuildId)
+	if err != nil {
+		return nil, err
+	}
+	guilds[top.GuildId] = top
+
+	return &GuildStats{
+		Top:     top,
+		Guilds:  guilds,
+		prov:    prov,
+		users:   make(map[string]map[string]*database.Stat),
+		members: make(map[string]uuid.UUID),
+	}, nil
+}
+
+// Stats returns stats of guild g along with stats of its parents
+func (gs *GuildStats) Stats(g uuid.UUID) map[string]*database.Stat {
+	gld, ok := gs.Guilds[g]
+	if !ok {
+		gld = gs.Top
+	}
+
+	rv := make(map[string]*database.Stat)
+	for {
+		for k, v := range gld.Stats {
+			if _, ok := rv[k]; !ok {
+				rv[k] = v
+			}
+		}
+
+		if gld.DiscordId != "" {
+			return rv
+		}
+		if gld, ok = gs.Guilds[gld.ParentId]; !ok {
+			gld = gs.Top
+		}
+	}
+}
+
+// UserStats returns stats visible to characters of user u
+func (gs *GuildStats) UserStats(u string) (map[string]*database.Stat, error) {
+	if rv, ok := gs.users[u]; ok {
+		return rv, nil
+	}
+
+	g, err := gs.UserGuild(u)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := gs.Stats(g)
+	gs.users[u] = rv
+	return rv, nil
+}
+
+// UserGuild returns sub-guild user u belongs to. Users that left the guild belong to the top guild
+func (gs *GuildStats) UserGuild(u string) (uuid.UUID, error) {
+	if rv, ok := gs.members[u]; ok {
+		return rv, nil
+	}
+
+	rv := gs.Top.GuildId
+	usr, err := gs.prov.GetUserD(u)
+	if err != nil {
+		if dbErr := database.ErrToDbErr(err); dbErr == nil || dbErr.Code != database.UserNotFound {
+			return uuid.Nil, err
+		}
+	} else if p, ok := usr.Guilds[gs.Top.DiscordId]; ok {
+		rv = p.GuildId
+	}
+
+	gs.members[u] = rv
+	return rv, nil
+}
+
+// Contains checks if sub is guild g or one of its sub-guilds
+func (gs *GuildStats) Contains(g uuid.UUID, sub uuid.UUID) bool {
+	for sub != g {
+		gld, ok := gs.Guilds[sub]
+		if !ok || gld.DiscordId != "" {
+			return false
+		}
+		sub = gld.ParentId
+	}
+
+	return true
+}
+
+// Subtree returns guild g along with all its sub-guilds
+func (gs *GuildStats) Subtree(g uuid.UUID) []uuid.UUID {
+	rv := make([]uuid.UUID, 0, len(gs.Guilds))
+	for id := range gs.Guilds {
+		if gs.Contains(g, id) {
+			rv = append(rv, id)
+		}
+	}
+
+	return rv
+}
+
+// Children returns direct sub-guilds of guild g sorted by name
+func (gs *GuildStats) Children(g uuid.UUID) []*database.Guild {
+	rv := make([]*database.Guild, 0, len(gs.Guilds))
+	for _, gld := range gs.Guilds {
+		if gld.DiscordId == "" && gld.ParentId == g {
+			rv = append(rv, gld)
+		}
+	}
+
+	sort.Slice(rv, func(i, j int) bool { return rv[i].Name < rv[j].Name })
+	return rv
+}
+
+// Stat looks stat up in all guilds of the hierarchy and returns it along with the guild it is defined in
+func (gs *GuildStats) Stat(n string) (*database.Stat, *database.Guild) {
+	if s, ok := gs.Top.Stats[n]; ok {
+		return s, gs.Top
+	}
+
+	for _, g := range gs.Guilds {
+		if s, ok := g.Stats[n]; ok {
+			return s, g
+		}
+	}
+
+	return nil, nil
+}
+
+// UpdateCharacter brings stats of the character in line with stats visible to its owner
+func (gs *GuildStats) UpdateCharacter(prov database.DataProvider, c *database.Character) (*database.Character, error) {
+	if c.StatVersion >= gs.Top.StatVersion {
+		return c, nil
+	}
+
+	stats, err := gs.UserStats(c.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	return prov.SetCharacterStatVersion(c.GuildId, c.UserId, c.Name, stats, gs.Top.StatVersion)
+}
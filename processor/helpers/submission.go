@@ -0,0 +1,58 @@
+package helpers
+
+import (
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/message"
+	"github.com/mebaranov/disguildie/utility"
+)
+
+// IsOfficer checks if author of m has character permissions over sub-guild of user u.
+// Unlike message permission checks, users are not officers of themselves unless they have guild-wide permissions
+func IsOfficer(prov database.DataProvider, m message.Message, u *database.User) (bool, error) {
+	perm, err := m.AuthorPermissions()
+	if err != nil {
+		return false, err
+	}
+	if perm&database.EditGuildCharsPerm != 0 {
+		return true, nil
+	}
+	if m.AuthorId() == u.Id {
+		return false, nil
+	}
+
+	auth, err := m.Author()
+	if err != nil {
+		return false, err
+	}
+	from, ok := auth.Guilds[m.GuildId()]
+	if !ok {
+		return false, nil
+	}
+	to, ok := u.Guilds[m.GuildId()]
+	if !ok {
+		return false, nil
+	}
+
+	return utility.ValidateUserAccess(prov, &database.GuildPermission{GuildId: from.GuildId, Permissions: perm}, to.GuildId)
+}
+
+// SubmitStat stores s for approval. Earlier submissions of the same stat of the character are replaced
+func SubmitStat(prov database.DataProvider, s *database.Submission) (*database.Submission, error) {
+	subs, err := prov.GetSubmissions(s.GuildId)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, old := range subs {
+		if old.UserId == s.UserId && old.Character == s.Character && old.Stat == s.Stat {
+			if _, err = prov.RemoveSubmission(old.GuildId, old.Id); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	s.Time = time.Now()
+	return prov.AddSubmission(s)
+}
@@ -28,6 +28,7 @@ type TestMessage struct {
 	MoreSegmentsMock     func() bool
 
 	SendMessageMock func(string, ...interface{})
+	SendPagedMock   func(string)
 
 	CheckGuildModificationPermissionsMock func(gid uuid.UUID) (bool, error)
 	CheckUserModificationPermissionsMock  func(uid string) (bool, error)
@@ -114,6 +115,10 @@ func (tm *TestMessage) SendMessage(s string, strs ...interface{}) {
 	tm.SendMessageMock(s, strs)
 }
 
+func (tm *TestMessage) SendPaged(s string) {
+	tm.SendPagedMock(s)
+}
+
 func (tm *TestMessage) UserRoles(id string) ([]string, error) {
 	return tm.UserRolesMock(id)
 }
@@ -0,0 +1,45 @@
+package helpers
+
+import (
+	"sort"
+	"time"
+
+	"github.com/mebaranov/disguildie/database"
+)
+
+// TrashRetention is how long removed entities can be restored
+const TrashRetention = 30 * 24 * time.Hour
+
+// MoveToTrash stores removed entities of t and purges items that are out of retention
+func MoveToTrash(prov database.DataProvider, t *database.TrashItem) error {
+	t.Time = time.Now()
+	if _, err := prov.PurgeTrash(t.GuildId, t.Time.Add(-TrashRetention)); err != nil {
+		return err
+	}
+
+	_, err := prov.AddTrashItem(t)
+	return err
+}
+
+// CharacterHistory collects values of all guild stats the character had, so they can be kept in the trash
+func CharacterHistory(prov database.DataProvider, gs *GuildStats, c *database.Character) ([]*database.StatHistory, error) {
+	seen := make(map[string]database.Void)
+	rv := make([]*database.StatHistory, 0, 10)
+	for _, g := range gs.Guilds {
+		for n := range g.Stats {
+			if _, ok := seen[n]; ok {
+				continue
+			}
+			seen[n] = database.Member
+
+			h, err := prov.GetStatHistory(c.GuildId, c.UserId, c.Name, n)
+			if err != nil {
+				return nil, err
+			}
+			rv = append(rv, h...)
+		}
+	}
+
+	sort.SliceStable(rv, func(i, j int) bool { return rv[i].Time.Before(rv[j].Time) })
+	return rv, nil
+}
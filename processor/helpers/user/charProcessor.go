@@ -41,12 +41,12 @@ func (ap *CharProcessor) create(m message.Message) (string, error) {
 		return "getting target user", err
 	}
 
-	gld, err := ap.Prov.GetGuildD(m.GuildId())
+	gs, err := helpers.NewGuildStats(ap.Prov, m.GuildId())
 	if err != nil {
-		return "getting guild", err
+		return "getting guild stats", err
 	}
 
-	ok, err := ap.CheckUserModificationPermissions(m, u.Id)
+	ok, err := m.CheckUserModificationPermissions(u.Id)
 	if err != nil {
 		return "checking modification permissions", err
 	}
@@ -65,15 +65,19 @@ func (ap *CharProcessor) create(m message.Message) (string, error) {
 		}
 	}
 
+	us, err := gs.UserStats(u.Id)
+	if err != nil {
+		return "getting user stats", err
+	}
+
 	stats := make(map[string]interface{})
-	for _, s := range gld.Stats {
-		switch s.Type {
-		case database.Number:
-			stats[s.ID] = 0
-		case database.Str:
-			stats[s.ID] = ""
-		default:
-			return "", errors.New("Undefined stat type for " + s.ID)
+	for _, s := range us {
+		v, err := s.DefaultValue()
+		if err != nil {
+			return "getting default stat value", err
+		}
+		if v != nil {
+			stats[s.ID] = v
 		}
 	}
 
@@ -82,7 +86,7 @@ func (ap *CharProcessor) create(m message.Message) (string, error) {
 		GuildId:     m.GuildId(),
 		Name:        c,
 		Body:        stats,
-		StatVersion: gld.StatVersion,
+		StatVersion: gs.Top.StatVersion,
 	}
 	_, err = ap.Prov.AddCharacter(&ch)
 	if err != nil {
@@ -105,7 +109,7 @@ func (ap *CharProcessor) main(m message.Message) (string, error) {
 		return "getting target user", err
 	}
 
-	ok, err := ap.CheckUserModificationPermissions(m, u.Id)
+	ok, err := m.CheckUserModificationPermissions(u.Id)
 	if err != nil {
 		return "checking modification permissions", err
 	}
@@ -147,7 +151,7 @@ func (ap *CharProcessor) rename(m message.Message) (string, error) {
 		return "getting target user", err
 	}
 
-	ok, err := ap.CheckUserModificationPermissions(m, u.Id)
+	ok, err := m.CheckUserModificationPermissions(u.Id)
 	if err != nil {
 		return "checking modification permissions", err
 	}
@@ -197,7 +201,7 @@ func (ap *CharProcessor) give(m message.Message) (string, error) {
 		return "getting source user", err
 	}
 
-	ok, err := ap.CheckUserModificationPermissions(m, o.Id)
+	ok, err := m.CheckUserModificationPermissions(o.Id)
 	if err != nil {
 		return "checking modification permissions", err
 	}
@@ -210,7 +214,7 @@ func (ap *CharProcessor) give(m message.Message) (string, error) {
 		return "getting target user", err
 	}
 
-	ok, err = ap.CheckUserModificationPermissions(m, n.Id)
+	ok, err = m.CheckUserModificationPermissions(n.Id)
 	if err != nil {
 		return "checking modification permissions", err
 	}
@@ -259,7 +263,7 @@ func (ap *CharProcessor) remove(m message.Message) (string, error) {
 		return "getting target user", err
 	}
 
-	ok, err := ap.CheckUserModificationPermissions(m, u.Id)
+	ok, err := m.CheckUserModificationPermissions(u.Id)
 	if err != nil {
 		return "checking modification permissions", err
 	}
@@ -272,9 +276,36 @@ func (ap *CharProcessor) remove(m message.Message) (string, error) {
 		return "getting character", err
 	}
 
-	_, err = ap.Prov.RemoveCharacter(m.GuildId(), c.UserId, c.Name)
+	gs, err := helpers.NewGuildStats(ap.Prov, m.GuildId())
+	if err != nil {
+		return "getting guild stats", err
+	}
+
+	step := ""
+	err = ap.Prov.RunInTx(func(tx database.DataProvider) error {
+		step = "getting character history"
+		h, err := helpers.CharacterHistory(tx, gs, c)
+		if err != nil {
+			return err
+		}
+
+		step = "removing character"
+		if _, err := tx.RemoveCharacter(m.GuildId(), c.UserId, c.Name); err != nil {
+			return err
+		}
+
+		step = "moving character to the trash"
+		return helpers.MoveToTrash(tx, &database.TrashItem{
+			GuildId:    m.GuildId(),
+			Type:       database.TrashCharacter,
+			Name:       c.Name,
+			RemovedBy:  m.AuthorId(),
+			Characters: []*database.Character{c},
+			History:    h,
+		})
+	})
 	if err != nil {
-		return "removing character", err
+		return step, err
 	}
 
 	return fmt.Sprintf("Character %v was removed", c.Name), nil
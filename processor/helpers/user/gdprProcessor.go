@@ -22,6 +22,7 @@ func NewGdprProcessor(prov database.DataProvider) helpers.MessageProcessor {
 		"l":      ap.list,
 		"list":   ap.list,
 		"remove": ap.remove,
+		"forget": ap.forget,
 	}
 	return ap
 }
@@ -78,38 +79,19 @@ func (ap *GdprProcessor) remove(m message.Message) (string, error) {
 		return fmt.Sprintf("Please, use the following command: \"!g g remove me %v\" to approve deletion." + m.GuildId()), nil
 	}
 
-	gld, err := ap.Prov.GetGuildD(id)
-	if err != nil {
-		return "getting guild", err
-	}
-
-	money, err := ap.Prov.GetMoney(gld.DiscordId)
-	if err != nil {
-		return "getting payments", err
-	}
-
-	chars, err := ap.Prov.GetCharacters(gld.DiscordId, a.Id)
-	if err != nil {
-		return "getting characters", err
-	}
-
-	if money.UserId == a.Id {
-		_, err := ap.Prov.ChangeMoneyOwner(gld.DiscordId, "")
+	step := ""
+	err = ap.Prov.RunInTx(func(tx database.DataProvider) error {
+		step = "getting guild"
+		gld, err := tx.GetGuildD(id)
 		if err != nil {
-			return "changing payment owner", nil
+			return err
 		}
-	}
 
-	for _, c := range chars {
-		_, err = ap.Prov.RemoveCharacter(c.GuildId, c.UserId, c.Name)
-		if err != nil {
-			return "removing character", err
-		}
-	}
-
-	_, err = ap.Prov.RemoveUserD(a.Id, gld.DiscordId)
+		step, err = removeFromGuild(tx, a.Id, gld.DiscordId)
+		return err
+	})
 	if err != nil {
-		return "removing user", err
+		return step, err
 	}
 
 	return "You were removed from guild with ID " + id, nil
@@ -129,44 +111,61 @@ func (ap *GdprProcessor) forget(m message.Message) (string, error) {
 	if id == "" {
 		return fmt.Sprintf("Please, use the following command: \"!g g forget me %v\" to approve deletion." + a.Id), nil
 	}
+	if id != a.Id {
+		return "", errors.New("Wrong ID. Use \"!g g forget me\" to get your ID")
+	}
 
-	for _, g := range a.Guilds {
-		gld, err := ap.Prov.GetGuildD(g.TopGuild)
-		if err != nil {
-			return "getting guild", err
-		}
+	step := ""
+	err = ap.Prov.RunInTx(func(tx database.DataProvider) error {
+		for _, g := range a.Guilds {
+			step = "getting guild"
+			gld, err := tx.GetGuildD(g.TopGuild)
+			if err != nil {
+				return err
+			}
 
-		money, err := ap.Prov.GetMoney(gld.DiscordId)
-		if err != nil {
-			return "getting payments", err
+			if step, err = removeFromGuild(tx, a.Id, gld.DiscordId); err != nil {
+				return err
+			}
 		}
 
-		chars, err := ap.Prov.GetCharacters(gld.DiscordId, a.Id)
-		if err != nil {
-			return "getting characters", err
-		}
+		return nil
+	})
+	if err != nil {
+		return step, err
+	}
 
-		if money.UserId == a.Id {
-			_, err := ap.Prov.ChangeMoneyOwner(gld.DiscordId, "")
-			if err != nil {
-				return "changing payment owner", nil
-			}
-		}
+	return "You were totally removed from the system. You're always welcome to come back.", nil
+}
 
-		for _, c := range chars {
-			_, err = ap.Prov.RemoveCharacter(c.GuildId, c.UserId, c.Name)
-			if err != nil {
-				return "removing character", err
-			}
+func removeFromGuild(prov database.DataProvider, uid string, g string) (string, error) {
+	money, err := prov.GetMoney(g)
+	if err != nil {
+		return "getting payments", err
+	}
+
+	chars, err := prov.GetCharacters(g, uid)
+	if err != nil {
+		return "getting characters", err
+	}
+
+	if money.UserId == uid {
+		if _, err = prov.ChangeMoneyOwner(g, ""); err != nil {
+			return "changing payment owner", err
 		}
+	}
 
-		_, err = ap.Prov.RemoveUserD(a.Id, gld.DiscordId)
-		if err != nil {
-			return "removing user", err
+	for _, c := range chars {
+		if _, err = prov.RemoveCharacter(c.GuildId, c.UserId, c.Name); err != nil {
+			return "removing character", err
 		}
 	}
 
-	return "You were totally removed from the system. You're always welcome to come back.", nil
+	if _, err = prov.RemoveUserD(uid, g); err != nil {
+		return "removing user", err
+	}
+
+	return "", nil
 }
 
 func (ap *GdprProcessor) help(m message.Message) (string, error) {
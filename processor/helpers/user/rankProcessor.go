@@ -0,0 +1,219 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/message"
+	"github.com/mebaranov/disguildie/processor/helpers"
+	"github.com/mebaranov/disguildie/utility"
+)
+
+type RankProcessor struct {
+	helpers.BaseMessageProcessor
+}
+
+func NewRankProcessor(prov database.DataProvider) helpers.MessageProcessor {
+	ap := &RankProcessor{}
+	ap.Prov = prov
+	return ap
+}
+
+func (ap *RankProcessor) ProcessMessage(m message.Message) (string, error) {
+	segs := make([]string, 0, 3)
+	for s := m.CurSegment(); s != ""; s = m.CurSegment() {
+		segs = append(segs, s)
+	}
+	if len(segs) == 1 && (segs[0] == "h" || segs[0] == "help") {
+		return ap.help(m)
+	}
+
+	ment := ""
+	if len(segs) > 0 && utility.IsUserMention(segs[0]) {
+		ment, segs = segs[0], segs[1:]
+	}
+	if len(segs) > 2 {
+		return "", errors.New("Invalid command format")
+	}
+
+	u, err := ap.UserOrAuthorByMention(ment, m)
+	if err != nil {
+		return "getting target user", err
+	}
+
+	gs, err := helpers.NewGuildStats(ap.Prov, m.GuildId())
+	if err != nil {
+		return "getting guild stats", err
+	}
+
+	// a single argument is a character if the user has one with such name, a stat otherwise
+	char, stat := "", gs.Top.DefaultStat
+	switch len(segs) {
+	case 2:
+		char, stat = segs[0], segs[1]
+	case 1:
+		if _, err := ap.Prov.GetCharacter(m.GuildId(), u.Id, segs[0]); err == nil {
+			char = segs[0]
+		} else if dbErr := database.ErrToDbErr(err); dbErr == nil || dbErr.Code != database.CharacterNotFound {
+			return "getting character", err
+		} else {
+			stat = segs[0]
+		}
+	}
+
+	s, owner := gs.Stat(stat)
+	if s == nil {
+		if stat == "" {
+			return "", errors.New("This guild doesn't have any stats yet")
+		}
+		return "", errors.New("Stat with name " + stat + " is not defined in guild")
+	}
+
+	chars, err := ap.Prov.GetCharactersOutdated(m.GuildId(), gs.Top.StatVersion)
+	if err != nil {
+		return "getting outdated characters", err
+	}
+	for _, c := range chars {
+		if _, err = gs.UpdateCharacter(ap.Prov, c); err != nil {
+			return "setting character stat version", err
+		}
+	}
+
+	var r *database.CharacterRank
+	if s.Type == database.Computed {
+		r, err = ap.rankComputed(gs, u.Id, char, s)
+	} else {
+		q := &database.CharacterQuery{Order: []*database.SortKey{{Stat: s.ID, Type: s.Type}}}
+		// characters keep values of sub-guild stats their owners don't see anymore
+		if owner != gs.Top {
+			q.OwnerGuilds = gs.Subtree(owner.GuildId)
+		}
+		r, err = ap.Prov.RankCharacter(m.GuildId(), u.Id, char, q)
+	}
+	if err != nil {
+		return "getting character rank", err
+	}
+
+	rv := fmt.Sprintf("Character %v of <@!%v> is #%v of %v by %v (percentile %v).",
+		r.Character.Name, r.Character.UserId, r.Rank, r.Total, s.ID, (r.Total-r.Rank+1)*100/r.Total)
+	if owner != gs.Top {
+		rv += " Sub-guild " + owner.Name + "."
+	}
+	if r.Above != nil {
+		if g, ok := ap.gap(gs, s, r.Above, r.Character); ok && g == 0 {
+			rv += fmt.Sprintf(" Tied with #%v.", r.Rank-1)
+		} else if ok {
+			rv += fmt.Sprintf(" %v more to reach #%v.", aggregateValue(s, g), r.Rank-1)
+		}
+	}
+	rv += "\n"
+
+	if r.Above != nil {
+		rv += fmt.Sprintf("\t#%v : %v : %v\n", r.Rank-1, r.Above.Name, rankValue(s, r.Above))
+	}
+	rv += fmt.Sprintf("\t#%v : %v : %v\n", r.Rank, r.Character.Name, rankValue(s, r.Character))
+	if r.Below != nil {
+		rv += fmt.Sprintf("\t#%v : %v : %v\n", r.Rank+1, r.Below.Name, rankValue(s, r.Below))
+	}
+
+	return rv, nil
+}
+
+// rankComputed ranks character by a computed stat. Values are not stored, so all characters are evaluated.
+// Characters of the returned rank carry calculated values
+func (ap *RankProcessor) rankComputed(gs *helpers.GuildStats, u string, char string, s *database.Stat) (*database.CharacterRank, error) {
+	c, err := ap.Prov.GetCharacter(gs.Top.DiscordId, u, char)
+	if err != nil {
+		return nil, err
+	}
+
+	chars, err := ap.Prov.GetGuildCharacters(gs.Top.DiscordId)
+	if err != nil {
+		return nil, err
+	}
+	if chars, err = visibleCharacters(gs, chars, []string{s.ID}); err != nil {
+		return nil, err
+	}
+
+	for i, ch := range chars {
+		if chars[i], err = evaluate(gs, ch, []string{s.ID}); err != nil {
+			return nil, err
+		}
+	}
+	q := &database.CharacterQuery{Order: []*database.SortKey{{Stat: s.ID, Type: evaluatedType(s)}}}
+	sort.Slice(chars, func(i int, j int) bool { return q.Less(chars[i], chars[j]) })
+
+	for i, ch := range chars {
+		if ch.UserId != c.UserId || ch.Name != c.Name {
+			continue
+		}
+
+		rv := &database.CharacterRank{Character: ch, Rank: i + 1, Total: len(chars)}
+		if i > 0 {
+			rv.Above = chars[i-1]
+		}
+		if i < len(chars)-1 {
+			rv.Below = chars[i+1]
+		}
+		return rv, nil
+	}
+
+	return nil, &database.Error{Code: database.CharacterNotFound, Message: fmt.Sprintf("Character %v is not ranked", c.Name)}
+}
+
+// gap is the difference of numeric stat values of characters. Durations are counted in hours
+func (ap *RankProcessor) gap(gs *helpers.GuildStats, s *database.Stat, a *database.Character, b *database.Character) (float64, bool) {
+	switch evaluatedType(s) {
+	case database.Number, database.Float, database.Duration:
+	default:
+		return 0, false
+	}
+
+	vals := make([]float64, 2)
+	for i, c := range []*database.Character{a, b} {
+		stats, err := gs.UserStats(c.UserId)
+		if err != nil {
+			return 0, false
+		}
+		if _, ok := rankStatValue(s, c); !ok {
+			return 0, false
+		}
+		if vals[i], err = helpers.NumericValue(stats, c, s.ID); err != nil {
+			return 0, false
+		}
+	}
+
+	return vals[0] - vals[1], true
+}
+
+func rankStatValue(s *database.Stat, c *database.Character) (interface{}, bool) {
+	v, ok := c.Body[s.ID]
+	if ok {
+		ok, _ = database.IsStatType(evaluatedType(s), v)
+	}
+	return v, ok
+}
+
+func rankValue(s *database.Stat, c *database.Character) string {
+	v, ok := rankStatValue(s, c)
+	if !ok {
+		return "n/a"
+	}
+	if f, isFloat := v.(float64); isFloat && s.Type == database.Computed {
+		return aggregateValue(s, f)
+	}
+	return database.FormatStatValue(v)
+}
+
+func (ap *RankProcessor) help(m message.Message) (string, error) {
+	rv := "Here's a list of rank commands you're allowed to use:\n"
+	rv += "\t -- \"!g rank\" (\"!g r\") - Get position of your main character in guild top by default stat\n"
+	rv += "\t -- \"!g rank <stat>\" (\"!g r <stat>\") - Get position of your main character in guild top by stat\n"
+	rv += "\t -- \"!g rank <character> <stat>\" (\"!g r <character> <stat>\") - Get position of your character by stat. Stat is optional\n"
+	rv += "\t -- \"!g rank <mention user> <character> <stat>\" (\"!g r <mention> <character> <stat>\") - Get position of users character. Character and stat are optional\n"
+	rv += "Rank is counted from the highest value. Percentile is a share of characters ranked the same or lower\n"
+
+	return rv, nil
+}
@@ -3,8 +3,10 @@ package user
 import (
 	"errors"
 	"fmt"
+	"math"
 	"sort"
-	"strconv"
+	"strings"
+	"time"
 
 	"github.com/mebaranov/disguildie/database"
 	"github.com/mebaranov/disguildie/message"
@@ -28,8 +30,50 @@ func NewStatsProcessor(prov database.DataProvider) helpers.MessageProcessor {
 	return ap
 }
 
+type statUpdate struct {
+	stat  string
+	value string
+}
+
 func (ap *StatsProcessor) ProcessMessage(m message.Message) (string, error) {
-	v1, v2, v3, v4 := m.CurSegment(), m.CurSegment(), m.CurSegment(), m.CurSegment()
+	segs := make([]string, 0, 4)
+	for s := m.CurSegment(); s != ""; s = m.CurSegment() {
+		segs = append(segs, s)
+	}
+	if ment, char, ups, ok := statUpdates(segs); ok {
+		gs, err := helpers.NewGuildStats(ap.Prov, m.GuildId())
+		if err != nil {
+			return "getting guild stats", err
+		}
+		if definedStats(gs, ups) {
+			return ap.setStats(m, ment, char, ups)
+		}
+	}
+	for len(segs) < 4 {
+		segs = append(segs, "")
+	}
+
+	v1, v2, v3, v4 := segs[0], segs[1], segs[2], segs[3]
+	kw, err := ap.isKeyword(m, v1)
+	if err != nil {
+		return "checking command keyword", err
+	}
+	if kw && (v1 == "summary" || v1 == "su") {
+		if v4 != "" {
+			return "", errors.New("Invalid command format")
+		}
+		return ap.summary(m, v2, v3)
+	}
+	if kw && (v1 == "history" || v1 == "hi") {
+		if utility.IsUserMention(v2) {
+			return ap.history(m, v2, v3, v4)
+		}
+		if v4 != "" {
+			return "", errors.New("Invalid command format")
+		}
+		return ap.history(m, "", v2, v3)
+	}
+
 	if v1 != "" && v2 == "" && v3 == "" && v4 == "" {
 		f, ok := ap.Funcs[v1]
 		if ok {
@@ -59,6 +103,72 @@ func (ap *StatsProcessor) ProcessMessage(m message.Message) (string, error) {
 	return ap.setStat(m, "", v1, v2, v3)
 }
 
+// isKeyword tells if the word is a subcommand. Stats of the guild and characters of the author with the same name
+// take precedence over subcommands, so they can still be got and set
+func (ap *StatsProcessor) isKeyword(m message.Message, word string) (bool, error) {
+	switch word {
+	case "summary", "su", "history", "hi":
+	default:
+		return false, nil
+	}
+
+	gs, err := helpers.NewGuildStats(ap.Prov, m.GuildId())
+	if err != nil {
+		return false, err
+	}
+	if s, _ := gs.Stat(word); s != nil {
+		return false, nil
+	}
+
+	_, err = ap.Prov.GetCharacter(m.GuildId(), m.AuthorId(), word)
+	if err == nil {
+		return false, nil
+	}
+	if dbErr := database.ErrToDbErr(err); dbErr == nil || dbErr.Code != database.CharacterNotFound {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// statUpdates parses "[mention] [char name] stat=value stat=value ..." form of the command
+func statUpdates(segs []string) (string, string, []*statUpdate, bool) {
+	ment, char, i := "", "", 0
+	if i < len(segs) && utility.IsUserMention(segs[i]) {
+		ment = segs[i]
+		i++
+	}
+	if i < len(segs) && !strings.Contains(segs[i], "=") {
+		char = segs[i]
+		i++
+	}
+	if i == len(segs) {
+		return "", "", nil, false
+	}
+
+	ups := make([]*statUpdate, 0, len(segs)-i)
+	for _, s := range segs[i:] {
+		p := strings.Index(s, "=")
+		if p <= 0 {
+			return "", "", nil, false
+		}
+		ups = append(ups, &statUpdate{stat: s[:p], value: s[p+1:]})
+	}
+
+	return ment, char, ups, true
+}
+
+// definedStats tells if all updates are of stats of the guild. Otherwise "=" belongs to a value
+// (e.g. a note or a link) set in the usual form of the command
+func definedStats(gs *helpers.GuildStats, ups []*statUpdate) bool {
+	for _, up := range ups {
+		if st, _ := gs.Stat(up.stat); st == nil {
+			return false
+		}
+	}
+	return true
+}
+
 func (ap *StatsProcessor) getStat(m message.Message, ment string, char string) (string, error) {
 	u, err := ap.UserOrAuthorByMention(ment, m)
 	if err != nil {
@@ -70,38 +180,93 @@ func (ap *StatsProcessor) getStat(m message.Message, ment string, char string) (
 		return "getting character", err
 	}
 
-	gld, err := ap.Prov.GetGuildD(m.GuildId())
+	gs, err := helpers.NewGuildStats(ap.Prov, m.GuildId())
 	if err != nil {
-		return "getting guild", err
+		return "getting guild stats", err
 	}
 
-	if c.StatVersion < gld.StatVersion {
-		c, err = ap.Prov.SetCharacterStatVersion(c.GuildId, c.UserId, c.Name, gld.Stats, gld.StatVersion)
-		if err != nil {
-			return "setting stat version", err
-		}
+	c, err = gs.UpdateCharacter(ap.Prov, c)
+	if err != nil {
+		return "setting stat version", err
 	}
 
-	rvs := make([]string, 0, len(c.Body))
+	stats, err := gs.UserStats(c.UserId)
+	if err != nil {
+		return "getting user stats", err
+	}
+
+	lines := make(map[string]string, len(stats))
 	for n, v := range c.Body {
-		rvs = append(rvs, fmt.Sprintf("\t%v:%v\n", n, v))
+		// values of sub-guild stats stay with characters until stats change after owner moved to other sub-guild
+		st, ok := stats[n]
+		if !ok {
+			continue
+		}
+
+		line := fmt.Sprintf("%v:%v", n, database.FormatStatValue(v))
+		if st.Required && v == "" {
+			line += " (required, not set)"
+		}
+		lines[n] = line
+	}
+	for _, st := range stats {
+		if st.Type != database.Computed {
+			continue
+		}
+
+		v, err := helpers.StatValue(stats, c, st.ID)
+		if err != nil {
+			lines[st.ID] = fmt.Sprintf("%v:n/a (%v)", st.ID, err)
+		} else {
+			lines[st.ID] = fmt.Sprintf("%v:%v", st.ID, database.FormatStatValue(v))
+		}
 	}
 
-	sort.Strings(rvs)
 	rv := fmt.Sprintf("Stats are:\n\tmain:%v\n\tname:%v\n", c.Main, c.Name)
-	for _, s := range rvs {
-		rv += s + "\n"
+	return rv + groupStats(stats, lines), nil
+}
+
+// groupStats renders lines of stats grouped by category. Stats without category go first,
+// inside a category stats are sorted by display order and then by name
+func groupStats(stats map[string]*database.Stat, lines map[string]string) string {
+	list := make([]*database.Stat, 0, len(lines))
+	for n := range lines {
+		list = append(list, stats[n])
 	}
-	return rv, nil
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		if a.Order != b.Order {
+			return a.Order < b.Order
+		}
+		return a.ID < b.ID
+	})
+
+	rv, cat, indent := "", "", "\t"
+	for _, st := range list {
+		if st.Category != cat {
+			cat, indent = st.Category, "\t\t"
+			rv += "\t" + cat + ":\n"
+		}
+		rv += indent + lines[st.ID] + "\n"
+	}
+	return rv
 }
 
 func (ap *StatsProcessor) setStat(m message.Message, ment string, char string, stat string, value string) (string, error) {
+	return ap.setStats(m, ment, char, []*statUpdate{{stat: stat, value: value}})
+}
+
+// setStats applies all updates to the character at once. Either all of them are saved or none
+func (ap *StatsProcessor) setStats(m message.Message, ment string, char string, ups []*statUpdate) (string, error) {
 	u, err := ap.UserOrAuthorByMention(ment, m)
 	if err != nil {
 		return "getting target user", err
 	}
 
-	ok, err := ap.CheckUserModificationPermissions(m, u.Id)
+	ok, err := m.CheckUserModificationPermissions(u.Id)
 	if err != nil {
 		return "checking modification permissions", err
 	}
@@ -114,64 +279,472 @@ func (ap *StatsProcessor) setStat(m message.Message, ment string, char string, s
 		return "getting character", err
 	}
 
-	gld, err := ap.Prov.GetGuildD(m.GuildId())
+	gs, err := helpers.NewGuildStats(ap.Prov, m.GuildId())
 	if err != nil {
-		return "getting guild", err
+		return "getting guild stats", err
 	}
 
-	s, ok := gld.Stats[stat]
-	if !ok {
-		return "", errors.New(fmt.Sprintf("Stat %v is not defined in your guild", stat))
+	stats, err := gs.UserStats(c.UserId)
+	if err != nil {
+		return "getting user stats", err
+	}
+
+	// values of reviewed stats set by members wait for officers
+	officer, err := helpers.IsOfficer(ap.Prov, m, u)
+	if err != nil {
+		return "checking officer permissions", err
 	}
 
-	var val interface{}
-	switch s.Type {
-	case database.Number:
-		val, err = strconv.Atoi(value)
+	defs := make([]*database.Stat, len(ups))
+	for i, up := range ups {
+		s, ok := stats[up.stat]
+		if !ok {
+			return "", errors.New(fmt.Sprintf("Stat %v is not defined in your guild", up.stat))
+		}
+		for _, prev := range ups[:i] {
+			if prev.stat == up.stat {
+				return "", errors.New(fmt.Sprintf("Stat %v is set twice", up.stat))
+			}
+		}
+
+		ok, err := ap.canWrite(m, s, u)
 		if err != nil {
-			return "", errors.New(fmt.Sprintf("Expected numeric value. Got %v", value))
+			return "checking stat permissions", err
 		}
-	case database.Str:
-		val = value
+		if !ok {
+			return "", errors.New(fmt.Sprintf("Stat %v is locked and can only be set by officers", s.ID))
+		}
+		defs[i] = s
 	}
 
-	_, err = ap.Prov.SetCharacterStat(c.GuildId, c.UserId, c.Name, stat, val)
+	res := make([]string, len(ups))
+	subs := make([]*database.Submission, len(ups))
+	step := ""
+	err = ap.Prov.RunInTx(func(tx database.DataProvider) error {
+		// relative values are counted from the character read in the transaction, so concurrent updates are not lost
+		step = "getting character"
+		c, err := tx.GetCharacter(c.GuildId, c.UserId, c.Name)
+		if err != nil {
+			return err
+		}
+
+		vals := make([]interface{}, len(ups))
+		for i, up := range ups {
+			s := defs[i]
+			step = "parsing value"
+			val, rel, err := newValue(s, c.Body[s.ID], up.value)
+			if err != nil {
+				return err
+			}
+			step = ""
+			if err = s.Validate(val); err != nil {
+				return err
+			}
+
+			vals[i] = val
+			res[i] = fmt.Sprintf("%v set to %v", s.ID, database.FormatStatValue(val))
+			if rel {
+				res[i] += fmt.Sprintf(" (%v)", up.value)
+			}
+			if s.Review && !officer {
+				subs[i] = &database.Submission{GuildId: c.GuildId, UserId: c.UserId, Character: c.Name, Stat: s.ID, Value: val, SubmittedBy: m.AuthorId()}
+			}
+		}
+
+		now := time.Now()
+		for i, up := range ups {
+			if subs[i] != nil {
+				step = "submitting stat for approval"
+				sub, err := helpers.SubmitStat(tx, subs[i])
+				if err != nil {
+					return err
+				}
+				subs[i] = sub
+				continue
+			}
+
+			step = "setting character stat"
+			if _, err := tx.SetCharacterStat(c.GuildId, c.UserId, c.Name, up.stat, vals[i]); err != nil {
+				return err
+			}
+
+			step = "saving stat history"
+			h := &database.StatHistory{GuildId: c.GuildId, UserId: c.UserId, Character: c.Name, Stat: up.stat, Value: vals[i], Time: now}
+			if _, err := tx.AddStatHistory(h); err != nil {
+				return err
+			}
+		}
+
+		step = "setting stat version"
+		_, err = gs.UpdateCharacter(tx, c)
+		return err
+	})
 	if err != nil {
-		return "setting character stat", err
+		return step, err
 	}
 
-	if c.StatVersion < gld.StatVersion {
-		c, err = ap.Prov.SetCharacterStatVersion(c.GuildId, c.UserId, c.Name, gld.Stats, gld.StatVersion)
-		if err != nil {
-			return "setting stat version", err
+	for i, sub := range subs {
+		if sub != nil {
+			res[i] = fmt.Sprintf("%v = %v submitted for approval (#%v)", sub.Stat, database.FormatStatValue(sub.Value), sub.Id)
+		}
+	}
+	if len(res) == 1 {
+		if subs[0] != nil {
+			return fmt.Sprintf("Stat %v = %v for character %v was submitted for approval (#%v). Officers will review it",
+				subs[0].Stat, database.FormatStatValue(subs[0].Value), c.Name, subs[0].Id), nil
+		}
+		return fmt.Sprintf("Stat %v for character %v", res[0], c.Name), nil
+	}
+	return fmt.Sprintf("Stats of character %v were updated:\n\t%v", c.Name, strings.Join(res, "\n\t")), nil
+}
+
+// canWrite checks write policy of stat s for characters of user u
+func (ap *StatsProcessor) canWrite(m message.Message, s *database.Stat, u *database.User) (bool, error) {
+	switch s.Write {
+	case database.WriteSelf:
+		return true, nil
+	case database.WriteGuild:
+		perm, err := m.AuthorPermissions()
+		return perm&database.EditGuildCharsPerm != 0, err
+	}
+
+	return helpers.IsOfficer(ap.Prov, m, u)
+}
+
+// newValue parses value of the stat. Values of numeric stats starting with + or - are added to the current value,
+// "=" prefix sets negative values as is
+func newValue(s *database.Stat, cur interface{}, v string) (interface{}, bool, error) {
+	if s.Type != database.Number && s.Type != database.Float && s.Type != database.Duration {
+		val, err := s.ParseValue(v)
+		return val, false, err
+	}
+	if strings.HasPrefix(v, "=") {
+		val, err := s.ParseValue(v[1:])
+		return val, false, err
+	}
+
+	val, err := s.ParseValue(v)
+	if err != nil || !(strings.HasPrefix(v, "+") || strings.HasPrefix(v, "-")) {
+		return val, false, err
+	}
+	if ok, _ := s.IsValid(cur); !ok {
+		if cur, err = s.DefaultValue(); err != nil {
+			return nil, false, err
+		}
+	}
+
+	switch d := val.(type) {
+	case int:
+		return cur.(int) + d, true, nil
+	case float64:
+		return cur.(float64) + d, true, nil
+	case time.Duration:
+		return cur.(time.Duration) + d, true, nil
+	}
+	return val, false, nil
+}
+
+func (ap *StatsProcessor) history(m message.Message, ment string, char string, stat string) (string, error) {
+	if char == "" || stat == "" {
+		return "", errors.New("Invalid command format")
+	}
+
+	u, err := ap.UserOrAuthorByMention(ment, m)
+	if err != nil {
+		return "getting target user", err
+	}
+
+	hs, err := ap.Prov.GetStatHistory(m.GuildId(), u.Id, char, stat)
+	if err != nil {
+		return "getting stat history", err
+	}
+
+	if len(hs) == 0 {
+		return fmt.Sprintf("Stat %v was never set for character %v", stat, char), nil
+	}
+
+	rv := fmt.Sprintf("History of %v for character %v:\n", stat, hs[0].Character)
+	var prev interface{}
+	for _, h := range hs {
+		rv += fmt.Sprintf("\t%v: %v", h.Time.UTC().Format("2006-01-02 15:04"), database.FormatStatValue(h.Value))
+		if d, ok := statDelta(prev, h.Value); ok {
+			rv += fmt.Sprintf(" (%v)", d)
+		}
+		rv += "\n"
+		prev = h.Value
+	}
+
+	return rv, nil
+}
+
+// statDelta formats change between values. It is defined for numeric values only
+func statDelta(prev interface{}, cur interface{}) (string, bool) {
+	s := &database.Stat{Type: database.Number}
+	for _, v := range []interface{}{prev, cur} {
+		switch v.(type) {
+		case int, float64:
+		case time.Duration:
+			s.Type = database.Duration
+		default:
+			return "", false
+		}
+	}
+
+	p, _ := helpers.ToNumber("", prev)
+	c, _ := helpers.ToNumber("", cur)
+	return signedValue(s, c-p), true
+}
+
+const histogramBuckets = 10
+const histogramWidth = 20
+const frequencyLimit = 15
+
+// summary describes distribution of stat values: summary statistics and a histogram for numeric stats,
+// value frequencies for others
+func (ap *StatsProcessor) summary(m message.Message, stat string, guild string) (string, error) {
+	if stat == "" {
+		return "", errors.New("Invalid command format")
+	}
+
+	gs, err := helpers.NewGuildStats(ap.Prov, m.GuildId())
+	if err != nil {
+		return "getting guild stats", err
+	}
+
+	s, _ := gs.Stat(stat)
+	if s == nil {
+		return "", errors.New("Stat with name " + stat + " is not defined in guild")
+	}
+
+	q := &database.CharacterQuery{}
+	var scope *database.Guild
+	if guild != "" {
+		if scope, err = ap.Prov.GetGuildN(m.GuildId(), guild); err != nil {
+			return "getting sub-guild", err
+		}
+		q.OwnerGuilds = gs.Subtree(scope.GuildId)
+	}
+
+	chars, err := ap.Prov.GetCharactersOutdated(m.GuildId(), gs.Top.StatVersion)
+	if err != nil {
+		return "getting outdated characters", err
+	}
+	for _, c := range chars {
+		if _, err = gs.UpdateCharacter(ap.Prov, c); err != nil {
+			return "setting character stat version", err
 		}
 	}
 
-	return fmt.Sprintf("Stat %v set to %v for character %v", stat, value, c.Name), nil
+	if chars, err = ap.Prov.QueryCharacters(m.GuildId(), q); err != nil {
+		return "getting characters", err
+	}
+	if chars, err = visibleCharacters(gs, chars, []string{s.ID}); err != nil {
+		return "getting user stats", err
+	}
+
+	rv := fmt.Sprintf("Summary of %v over %v characters", s.ID, len(chars))
+	if scope != nil {
+		rv += " of " + scope.Name
+	}
+	rv += ":\n"
+
+	switch evaluatedType(s) {
+	case database.Number, database.Float, database.Duration:
+		vals := make([]float64, 0, len(chars))
+		for _, c := range chars {
+			stats, err := gs.UserStats(c.UserId)
+			if err != nil {
+				return "getting user stats", err
+			}
+			if v, err := helpers.NumericValue(stats, c, s.ID); err == nil {
+				vals = append(vals, v)
+			}
+		}
+		return rv + numericSummary(s, vals, len(chars)), nil
+	}
+
+	vals := make([]string, 0, len(chars))
+	for _, c := range chars {
+		if v, ok := rankStatValue(s, c); ok {
+			vals = append(vals, database.FormatStatValue(v))
+		}
+	}
+	return rv + frequencySummary(s, vals, len(chars)), nil
+}
+
+func numericSummary(s *database.Stat, vals []float64, total int) string {
+	// NaN and infinite values saved before they were rejected can't be counted or placed in buckets
+	finite := make([]float64, 0, len(vals))
+	for _, v := range vals {
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			finite = append(finite, v)
+		}
+	}
+
+	rv := fmt.Sprintf("\tcount : %v", len(finite))
+	if len(vals) < total {
+		rv += fmt.Sprintf(" (not set for %v)", total-len(vals))
+	}
+	if len(finite) < len(vals) {
+		rv += fmt.Sprintf(" (not a number for %v)", len(vals)-len(finite))
+	}
+	rv += "\n"
+	vals = finite
+	if len(vals) == 0 {
+		return rv
+	}
+
+	sort.Float64s(vals)
+	sum := 0.0
+	for _, v := range vals {
+		sum += v
+	}
+	rv += fmt.Sprintf("\tmin : %v\n", aggregateValue(s, vals[0]))
+	rv += fmt.Sprintf("\tlower quartile : %v\n", aggregateValue(s, quantile(vals, 0.25)))
+	rv += fmt.Sprintf("\tmedian : %v\n", aggregateValue(s, quantile(vals, 0.5)))
+	rv += fmt.Sprintf("\tupper quartile : %v\n", aggregateValue(s, quantile(vals, 0.75)))
+	rv += fmt.Sprintf("\tmax : %v\n", aggregateValue(s, vals[len(vals)-1]))
+	rv += fmt.Sprintf("\tmean : %v\n", aggregateValue(s, sum/float64(len(vals))))
+
+	min, max := vals[0], vals[len(vals)-1]
+	n := histogramBuckets
+	if len(vals) < n {
+		n = len(vals)
+	}
+	if min == max {
+		n = 1
+	}
+	width := (max - min) / float64(n)
+
+	counts, most := make([]int, n), 0
+	for _, v := range vals {
+		// the range of huge values overflows, so the index is kept within buckets
+		i := n - 1
+		if p := (v - min) / width; width > 0 && p < float64(n) {
+			i = int(math.Max(0, p))
+		}
+		counts[i]++
+		if counts[i] > most {
+			most = counts[i]
+		}
+	}
+
+	rv += "Histogram:\n"
+	for i, c := range counts {
+		bar := ""
+		if c > 0 {
+			bar = strings.Repeat("#", int(math.Max(1, float64(c*histogramWidth/most)))) + " "
+		}
+		rv += fmt.Sprintf("\t%v - %v : %v%v\n", aggregateValue(s, min+width*float64(i)), aggregateValue(s, min+width*float64(i+1)), bar, c)
+	}
+
+	return rv
+}
+
+// quantile interpolates between closest values of sorted list
+func quantile(sorted []float64, p float64) float64 {
+	pos := p * float64(len(sorted)-1)
+	i := int(pos)
+	if i+1 >= len(sorted) {
+		return sorted[i]
+	}
+	return sorted[i] + (pos-float64(i))*(sorted[i+1]-sorted[i])
+}
+
+func frequencySummary(s *database.Stat, vals []string, total int) string {
+	counts := make(map[string]int)
+	if s.Type == database.Enum {
+		for _, v := range s.Values {
+			counts[v] = 0
+		}
+	}
+	for _, v := range vals {
+		if v == "" {
+			v = "(empty)"
+		}
+		counts[v]++
+	}
+
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i int, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+
+	rv := fmt.Sprintf("\tcount : %v", len(vals))
+	if len(vals) < total {
+		rv += fmt.Sprintf(" (not set for %v)", total-len(vals))
+	}
+	rv += "\n"
+	if len(vals) == 0 {
+		return rv
+	}
+
+	percent := func(c int) string {
+		return database.FormatStatValue(math.Round(float64(c)*1000/float64(len(vals))) / 10)
+	}
+	rv += "Values:\n"
+	others := 0
+	for i, k := range keys {
+		if i >= frequencyLimit {
+			others += counts[k]
+			continue
+		}
+		rv += fmt.Sprintf("\t%v : %v (%v%%)\n", k, counts[k], percent(counts[k]))
+	}
+	if len(keys) > frequencyLimit {
+		rv += fmt.Sprintf("\t%v other values : %v (%v%%)\n", len(keys)-frequencyLimit, others, percent(others))
+	}
+
+	return rv
 }
 
 func (ap *StatsProcessor) list(m message.Message) (string, error) {
-	gld, err := ap.Prov.GetGuildD(m.GuildId())
+	gs, err := helpers.NewGuildStats(ap.Prov, m.GuildId())
 	if err != nil {
-		return "getting guild", err
+		return "getting guild stats", err
 	}
 
-	rvs := make([]string, 0, len(gld.Stats))
-	for _, v := range gld.Stats {
+	u, err := m.Author()
+	if err != nil {
+		return "getting author", err
+	}
+
+	stats, err := gs.UserStats(u.Id)
+	if err != nil {
+		return "getting user stats", err
+	}
+
+	gld := gs.Top
+	lines := make(map[string]string, len(stats))
+	for _, v := range stats {
 		t := database.TypeToString(v.Type)
+		if v.Type == database.Enum {
+			t += ": " + strings.Join(v.Values, "/")
+		} else if v.Type == database.Computed {
+			t += ": " + v.Formula
+		}
 		id := v.ID
 		if v.ID == gld.DefaultStat {
 			id = "(*) " + id
 		}
-		rvs = append(rvs, fmt.Sprintf("\t%v[%v]:%v\n", id, t, v.Description))
+		line := fmt.Sprintf("%v[%v]:%v", id, t, v.Description)
+		if c := v.Constraints(); c != "" {
+			line += " (" + c + ")"
+		}
+		if _, owner := gs.Stat(v.ID); owner != nil && owner != gld {
+			line += " (sub-guild " + owner.Name + ")"
+		}
+		lines[v.ID] = line
 	}
 
-	sort.Strings(rvs)
-	rv := "Guild stats are:\n"
-	for _, s := range rvs {
-		rv += s + "\n"
-	}
-	rv += "* - default stat for sorting"
+	rv := "Guild stats are:\n" + groupStats(stats, lines)
+	rv += "* - default stat for sorting. Sub-guild stats are only available to members of the sub-guild and its sub-guilds"
 	return rv, nil
 }
 
@@ -190,12 +763,23 @@ func (ap *StatsProcessor) help(m message.Message) (string, error) {
 	rv += "\t -- \"!g stat <mention user>\" (\"!g s <mention>\") - Get stats for users main character\n"
 	rv += "\t -- \"!g stat <mention user> <char name>\" (\"!g s <mention> <name>\") - Get stats for users character\n"
 
+	rv += "\t -- \"!g stat history <char name> <stat name>\" (\"!g s hi <name> <stat name>\") - Show how stat of your character changed\n"
+	rv += "\t -- \"!g stat history <mention user> <char name> <stat name>\" (\"!g s hi <mention> <name> <stat name>\") - Show how stat of users character changed\n"
+
+	rv += "\t -- \"!g stat summary <stat name>\" (\"!g s su <stat name>\") - Show how values of stat are spread over guild characters\n"
+	rv += "\t -- \"!g stat summary <stat name> <sub-guild>\" (\"!g s su <stat name> <sub-guild>\") - Show how values of stat are spread over characters of the sub-guild\n"
+
 	rv += "\t -- \"!g stat <stat name> <stat value>\" (\"!g s <stat name> <stat value>\") - Set stat for your main character\n"
 	rv += "\t -- \"!g stat <char name> <stat name> <stat value>\" (\"!g s <char name> <stat name> <stat value>\") - Set stat for your character\n"
+	rv += "\t -- \"!g stat <stat name>=<value> <stat name>=<value> ...\" (\"!g s <stat>=<value> ...\") - Set several stats for your main character at once. Character name or mention can go before the list\n"
 	if perm&database.CharsPermissions != 0 {
 		rv += "\t -- \"!g stat <mention user> <stat name> <stat value>\" (\"!g s <mention user> <stat name> <stat value>\") - Set stat for other users main character\n"
 		rv += "\t -- \"!g stat <mention user> <char name> <stat name> <stat value>\" (\"!g s <mention user> <char name> <stat name> <stat value>\") - Set stat for other users character\n"
 	}
+	rv += "\nHistory and summary commands are not recognized if a stat or your character is named the same, full and short forms can be used instead. "
+	rv += "Stats locked by officers can't be set for your own characters unless you have character permissions over them. "
+	rv += "Values of stats that need approval are submitted to officers and are saved once they accept them\n"
+	rv += "Values of numeric stats starting with + or - are added to the current value, like \"!g s power +1500\". Use = to set a negative value, like \"!g s gold =-200\"\n"
 
 	return rv, nil
 }
@@ -0,0 +1,82 @@
+package user_tests
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/database/memory"
+	"github.com/mebaranov/disguildie/processor/helpers/tests"
+	"github.com/mebaranov/disguildie/processor/helpers/user"
+)
+
+func TestRank(t *testing.T) {
+	msg := &tests.TestMessage{}
+	prov := memory.NewMemoryDb()
+	gld, _ := prov.AddGuild(&database.Guild{DiscordId: uuid.New().String(), Name: "test"})
+	alpha, _ := prov.AddGuild(&database.Guild{Name: "alpha", ParentId: gld.GuildId})
+	beta, _ := prov.AddGuild(&database.Guild{Name: "beta", ParentId: gld.GuildId})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "lvl", Type: database.Number})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "dps", Type: database.Float})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "power", Type: database.Computed, Formula: "lvl * 10 - dps"})
+	prov.AddGuildStat(alpha.GuildId, &database.Stat{ID: "rating", Type: database.Number})
+	prov.SetDefaultGuildStat(gld.GuildId, "lvl")
+	prov.AddUser("u1", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: alpha.GuildId})
+	prov.AddUser("u2", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: alpha.GuildId})
+	prov.AddUser("u3", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: beta.GuildId})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u1", Name: "ch1", Main: true,
+		Body: map[string]interface{}{"lvl": 10, "dps": 50.0, "rating": 1500}})
+	// character named like a stat
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u1", Name: "dps",
+		Body: map[string]interface{}{"lvl": 30, "dps": 5.0}})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u2", Name: "ch2", Main: true,
+		Body: map[string]interface{}{"lvl": 20, "dps": 100.0, "rating": 1200}})
+	// rating is left from the time u3 was in alpha
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u3", Name: "ch3", Main: true,
+		Body: map[string]interface{}{"lvl": 10, "dps": 120.0, "rating": 2000}})
+
+	msg.GuildIdMock = func() string { return gld.DiscordId }
+	msg.AuthorIdMock = func() string { return "u1" }
+	msg.AuthorMock = func() (*database.User, error) { return prov.GetUserD("u1") }
+	target := user.NewRankProcessor(prov)
+
+	testCases := []struct {
+		name     string
+		msg      string
+		expected string
+		err      bool
+	}{
+		{name: "default", msg: "", expected: "Character ch1 of <@!u1> is #3 of 4 by lvl (percentile 50). 10 more to reach #2.\n\t#2 : ch2 : 20\n\t#3 : ch1 : 10\n\t#4 : ch3 : 10\n"},
+		{name: "stat", msg: "lvl", expected: "Character ch1 of <@!u1> is #3 of 4 by lvl (percentile 50). 10 more to reach #2.\n\t#2 : ch2 : 20\n\t#3 : ch1 : 10\n\t#4 : ch3 : 10\n"},
+		{name: "character named like stat", msg: "dps",
+			expected: "Character dps of <@!u1> is #1 of 4 by lvl (percentile 100).\n\t#1 : dps : 30\n\t#2 : ch2 : 20\n"},
+		{name: "stat of other user", msg: "<@!u2> dps",
+			expected: "Character ch2 of <@!u2> is #2 of 4 by dps (percentile 75). 20 more to reach #1.\n\t#1 : ch3 : 120\n\t#2 : ch2 : 100\n\t#3 : ch1 : 50\n"},
+		{name: "character and stat", msg: "dps dps",
+			expected: "Character dps of <@!u1> is #4 of 4 by dps (percentile 25). 45 more to reach #3.\n\t#3 : ch1 : 50\n\t#4 : dps : 5\n"},
+		{name: "computed", msg: "ch1 power",
+			expected: "Character ch1 of <@!u1> is #3 of 4 by power (percentile 50). 50 more to reach #2.\n\t#2 : ch2 : 100\n\t#3 : ch1 : 50\n\t#4 : ch3 : -20\n"},
+		{name: "sub-guild stat", msg: "rating",
+			expected: "Character ch1 of <@!u1> is #1 of 3 by rating (percentile 100). Sub-guild alpha.\n\t#1 : ch1 : 1500\n\t#2 : ch2 : 1200\n"},
+		{name: "sub-guild stat of other sub-guild", msg: "<@!u3> rating", err: true},
+		{name: "unknown stat", msg: "speed", err: true},
+		{name: "unknown character", msg: "ch9 lvl", err: true},
+	}
+	for _, tc := range testCases {
+		msg.CurMsg = tc.msg
+		rv, err := target.ProcessMessage(msg)
+		if tc.err {
+			if err == nil {
+				t.Errorf("[%v] Error expected. Got: %v", tc.name, rv)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[%v] Unexpected processing error: %v", tc.name, err)
+		}
+		if rv != tc.expected {
+			t.Errorf("[%v] Wrong processing result. Expected: %v, got: %v", tc.name, tc.expected, rv)
+		}
+	}
+}
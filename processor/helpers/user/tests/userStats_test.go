@@ -0,0 +1,196 @@
+package user_tests
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/database/memory"
+	"github.com/mebaranov/disguildie/processor/helpers/tests"
+	"github.com/mebaranov/disguildie/processor/helpers/user"
+)
+
+func TestStatsKeywords(t *testing.T) {
+	msg := &tests.TestMessage{}
+	prov := memory.NewMemoryDb()
+	gld, _ := prov.AddGuild(&database.Guild{DiscordId: uuid.New().String(), Name: "test"})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "lvl", Type: database.Number})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "su", Type: database.Number})
+	prov.AddUser("u1", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: gld.GuildId})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u1", Name: "ch", Main: true, Body: map[string]interface{}{"lvl": 1}})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u1", Name: "history", Body: map[string]interface{}{"lvl": 7}})
+	prov.AddStatHistory(&database.StatHistory{GuildId: gld.DiscordId, UserId: "u1", Character: "ch", Stat: "lvl", Value: 1})
+
+	msg.GuildIdMock = func() string { return gld.DiscordId }
+	msg.AuthorIdMock = func() string { return "u1" }
+	msg.AuthorMock = func() (*database.User, error) { return prov.GetUserD("u1") }
+	msg.AuthorPermissionsMock = func() (int, error) { return 0, nil }
+	msg.CheckUserModificationPermissionsMock = func(uid string) (bool, error) { return uid == "u1", nil }
+	target := user.NewStatsProcessor(prov)
+
+	testCases := []struct {
+		msg      string
+		expected string
+	}{
+		{msg: "history", expected: "name:history"},
+		{msg: "hi ch lvl", expected: "History of lvl for character ch"},
+		{msg: "su 5", expected: "Stat su set to 5"},
+		{msg: "summary lvl", expected: "Summary of lvl over 2 characters"},
+	}
+	for _, tc := range testCases {
+		msg.CurMsg = tc.msg
+		rv, err := target.ProcessMessage(msg)
+		if err != nil {
+			t.Fatalf("[%v] Unexpected processing error: %v", tc.msg, err)
+		}
+		if !strings.Contains(rv, tc.expected) {
+			t.Errorf("[%v] Wrong processing result. Expected to contain: %v, got: %v", tc.msg, tc.expected, rv)
+		}
+	}
+}
+
+func TestStatsNonFinite(t *testing.T) {
+	msg := &tests.TestMessage{}
+	prov := memory.NewMemoryDb()
+	gld, _ := prov.AddGuild(&database.Guild{DiscordId: uuid.New().String(), Name: "test"})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "dps", Type: database.Float})
+	prov.AddUser("u1", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: gld.GuildId})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u1", Name: "ch", Main: true, Body: map[string]interface{}{"dps": 1.5}})
+
+	msg.GuildIdMock = func() string { return gld.DiscordId }
+	msg.AuthorIdMock = func() string { return "u1" }
+	msg.AuthorMock = func() (*database.User, error) { return prov.GetUserD("u1") }
+	msg.AuthorPermissionsMock = func() (int, error) { return 0, nil }
+	msg.CheckUserModificationPermissionsMock = func(uid string) (bool, error) { return uid == "u1", nil }
+	target := user.NewStatsProcessor(prov)
+
+	for _, v := range []string{"inf", "-Inf", "NaN", "+inf"} {
+		msg.CurMsg = "dps " + v
+		if rv, err := target.ProcessMessage(msg); err == nil {
+			t.Errorf("[%v] Error expected. Got: %v", v, rv)
+		}
+	}
+
+	c, _ := prov.GetCharacter(gld.DiscordId, "u1", "ch")
+	if c.Body["dps"] != 1.5 {
+		t.Errorf("[non-finite] Value expected to stay. Got: %v", c.Body)
+	}
+}
+
+func statsTestMessage(prov database.DataProvider, gid string) *tests.TestMessage {
+	msg := &tests.TestMessage{}
+	msg.GuildIdMock = func() string { return gid }
+	msg.AuthorIdMock = func() string { return "u1" }
+	msg.AuthorMock = func() (*database.User, error) { return prov.GetUserD("u1") }
+	msg.AuthorPermissionsMock = func() (int, error) { return 0, nil }
+	msg.CheckUserModificationPermissionsMock = func(uid string) (bool, error) { return uid == "u1", nil }
+	return msg
+}
+
+func TestStatsSet(t *testing.T) {
+	prov := memory.NewMemoryDb()
+	gld, _ := prov.AddGuild(&database.Guild{DiscordId: uuid.New().String(), Name: "test"})
+	max := 100.0
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "lvl", Type: database.Number, Max: &max})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "dps", Type: database.Float})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "played", Type: database.Duration})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "class", Type: database.Str})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "note", Type: database.Str})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "link", Type: database.Str})
+	prov.AddUser("u1", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: gld.GuildId})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u1", Name: "ch", Main: true,
+		Body: map[string]interface{}{"lvl": 10, "dps": 1.5, "played": time.Hour, "class": "warrior"}})
+
+	msg := statsTestMessage(prov, gld.DiscordId)
+	target := user.NewStatsProcessor(prov)
+
+	testCases := []struct {
+		msg      string
+		expected string
+		err      bool
+		body     map[string]interface{}
+	}{
+		{msg: "lvl +5", expected: "Stat lvl set to 15 (+5) for character ch", body: map[string]interface{}{"lvl": 15}},
+		{msg: "lvl -3", expected: "Stat lvl set to 12 (-3) for character ch", body: map[string]interface{}{"lvl": 12}},
+		{msg: "lvl =-3", expected: "Stat lvl set to -3 for character ch", body: map[string]interface{}{"lvl": -3}},
+		{msg: "ch lvl 7", expected: "Stat lvl set to 7 for character ch", body: map[string]interface{}{"lvl": 7}},
+		{msg: "dps +0.25", expected: "Stat dps set to 1.75 (+0.25) for character ch", body: map[string]interface{}{"dps": 1.75}},
+		{msg: "played +30m", expected: "Stat played set to 1h30m0s (+30m) for character ch", body: map[string]interface{}{"played": 90 * time.Minute}},
+		{msg: "lvl +100", err: true, body: map[string]interface{}{"lvl": 7}},
+		{msg: "lvl=+1 dps=2 class=mage", expected: "Stats of character ch were updated:\n\tlvl set to 8 (+1)\n\tdps set to 2\n\tclass set to mage",
+			body: map[string]interface{}{"lvl": 8, "dps": 2.0, "class": "mage"}},
+		{msg: "ch lvl=-1 class=rogue", expected: "Stats of character ch were updated:\n\tlvl set to 7 (-1)\n\tclass set to rogue",
+			body: map[string]interface{}{"lvl": 7, "class": "rogue"}},
+		{msg: "lvl=+1 dps=fast class=priest", err: true, body: map[string]interface{}{"lvl": 7, "dps": 2.0, "class": "rogue"}},
+		{msg: "class=priest lvl=+200", err: true, body: map[string]interface{}{"lvl": 7, "class": "rogue"}},
+		{msg: "lvl=+1 lvl=+2", err: true, body: map[string]interface{}{"lvl": 7}},
+		{msg: "lvl=+1 speed=3", err: true, body: map[string]interface{}{"lvl": 7}},
+		{msg: "note a=b", expected: "Stat note set to a=b for character ch", body: map[string]interface{}{"note": "a=b"}},
+		{msg: "link https://x?a=b", expected: "Stat link set to https://x?a=b for character ch", body: map[string]interface{}{"link": "https://x?a=b"}},
+		{msg: "ch note x=y", expected: "Stat note set to x=y for character ch", body: map[string]interface{}{"note": "x=y"}},
+		{msg: "note=a=b class=mage", expected: "Stats of character ch were updated:\n\tnote set to a=b\n\tclass set to mage",
+			body: map[string]interface{}{"note": "a=b", "class": "mage"}},
+	}
+	for _, tc := range testCases {
+		msg.CurMsg = tc.msg
+		rv, err := target.ProcessMessage(msg)
+		if tc.err {
+			if err == nil {
+				t.Errorf("[%v] Error expected. Got: %v", tc.msg, rv)
+			}
+		} else if err != nil {
+			t.Fatalf("[%v] Unexpected processing error: %v", tc.msg, err)
+		} else if rv != tc.expected {
+			t.Errorf("[%v] Wrong processing result. Expected: %v, got: %v", tc.msg, tc.expected, rv)
+		}
+
+		c, _ := prov.GetCharacter(gld.DiscordId, "u1", "ch")
+		for k, v := range tc.body {
+			if c.Body[k] != v {
+				t.Errorf("[%v] Wrong value of %v. Expected: %v, got: %v", tc.msg, k, v, c.Body[k])
+			}
+		}
+	}
+
+	if h, _ := prov.GetStatHistory(gld.DiscordId, "u1", "ch", "lvl"); len(h) != 6 {
+		t.Errorf("[history] Only saved values expected in history. Got: %v", h)
+	}
+}
+
+func TestStatsConcurrentIncrements(t *testing.T) {
+	prov := memory.NewMemoryDb()
+	gld, _ := prov.AddGuild(&database.Guild{DiscordId: uuid.New().String(), Name: "test"})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "lvl", Type: database.Number})
+	prov.AddUser("u1", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: gld.GuildId})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u1", Name: "ch", Main: true, Body: map[string]interface{}{"lvl": 0}})
+	target := user.NewStatsProcessor(prov)
+
+	const n = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			msg := statsTestMessage(prov, gld.DiscordId)
+			msg.CurMsg = "lvl +1"
+			if _, err := target.ProcessMessage(msg); err != nil {
+				errs <- err
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("[concurrent] Unexpected processing error: %v", err)
+	}
+
+	c, _ := prov.GetCharacter(gld.DiscordId, "u1", "ch")
+	if c.Body["lvl"] != n {
+		t.Errorf("[concurrent] No increments expected to be lost. Got: %v", c.Body["lvl"])
+	}
+}
@@ -0,0 +1,78 @@
+package user_tests
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/database/memory"
+	"github.com/mebaranov/disguildie/processor/helpers/tests"
+	"github.com/mebaranov/disguildie/processor/helpers/user"
+)
+
+func TestStatsSummary(t *testing.T) {
+	prov := memory.NewMemoryDb()
+	gld, _ := prov.AddGuild(&database.Guild{DiscordId: uuid.New().String(), Name: "test"})
+	alpha, _ := prov.AddGuild(&database.Guild{Name: "alpha", ParentId: gld.GuildId})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "lvl", Type: database.Number})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "dps", Type: database.Float})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "class", Type: database.Str})
+	prov.AddUser("u1", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: alpha.GuildId})
+	prov.AddUser("u2", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: gld.GuildId})
+	// infinite values could be saved before they were rejected
+	bodies := []map[string]interface{}{
+		{"lvl": 10, "dps": 1.0, "class": "mage"},
+		{"lvl": 20, "dps": math.Inf(1), "class": "mage"},
+		{"lvl": 30, "dps": math.NaN(), "class": "rogue"},
+		{"lvl": 40, "dps": 4.0},
+		{"dps": 2.5},
+	}
+	for i, b := range bodies {
+		u := "u1"
+		if i > 2 {
+			u = "u2"
+		}
+		prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: u, Name: fmt.Sprintf("ch%v", i), Body: b})
+	}
+
+	msg := &tests.TestMessage{}
+	msg.GuildIdMock = func() string { return gld.DiscordId }
+	msg.AuthorIdMock = func() string { return "u1" }
+	msg.AuthorMock = func() (*database.User, error) { return prov.GetUserD("u1") }
+	target := user.NewStatsProcessor(prov)
+
+	testCases := []struct {
+		msg      string
+		expected string
+		err      bool
+	}{
+		{msg: "summary lvl", expected: "Summary of lvl over 5 characters:\n\tcount : 5\n\tmin : 0\n\tlower quartile : 10\n\tmedian : 20\n\tupper quartile : 30\n\tmax : 40\n\tmean : 20\n" +
+			"Histogram:\n\t0 - 8 : #################### 1\n\t8 - 16 : #################### 1\n\t16 - 24 : #################### 1\n\t24 - 32 : #################### 1\n\t32 - 40 : #################### 1\n"},
+		{msg: "summary lvl alpha", expected: "Summary of lvl over 3 characters of alpha:\n\tcount : 3\n\tmin : 10\n\tlower quartile : 15\n\tmedian : 20\n\tupper quartile : 25\n\tmax : 30\n\tmean : 20\n" +
+			"Histogram:\n\t10 - 16.67 : #################### 1\n\t16.67 - 23.33 : #################### 1\n\t23.33 - 30 : #################### 1\n"},
+		{msg: "su dps", expected: "Summary of dps over 5 characters:\n\tcount : 3 (not a number for 2)\n\tmin : 1\n\tlower quartile : 1.75\n\tmedian : 2.5\n\tupper quartile : 3.25\n\tmax : 4\n\tmean : 2.5\n" +
+			"Histogram:\n\t1 - 2 : #################### 1\n\t2 - 3 : #################### 1\n\t3 - 4 : #################### 1\n"},
+		{msg: "su class", expected: "Summary of class over 5 characters:\n\tcount : 5\nValues:\n\t(empty) : 2 (40%)\n\tmage : 2 (40%)\n\trogue : 1 (20%)\n"},
+		{msg: "su speed", err: true},
+		{msg: "su lvl gamma", err: true},
+	}
+	for _, tc := range testCases {
+		msg.CurMsg = tc.msg
+		rv, err := target.ProcessMessage(msg)
+		if tc.err {
+			if err == nil {
+				t.Errorf("[%v] Error expected. Got: %v", tc.msg, rv)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[%v] Unexpected processing error: %v", tc.msg, err)
+		}
+		if rv != tc.expected {
+			t.Errorf("[%v] Wrong processing result. Expected: %v, got: %v", tc.msg, tc.expected, rv)
+		}
+	}
+}
@@ -0,0 +1,208 @@
+package user_tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/database/memory"
+	"github.com/mebaranov/disguildie/processor/helpers/tests"
+	"github.com/mebaranov/disguildie/processor/helpers/user"
+)
+
+func TestTopGain(t *testing.T) {
+	msg := &tests.TestMessage{}
+	prov := memory.NewMemoryDb()
+	gld, _ := prov.AddGuild(&database.Guild{DiscordId: uuid.New().String(), Name: "test"})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "lvl", Type: database.Number})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "dps", Type: database.Float})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "played", Type: database.Duration})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "class", Type: database.Str})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u1", Name: "c1", Main: true})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u2", Name: "c2", Main: true})
+
+	now := time.Now()
+	history := []struct {
+		user  string
+		char  string
+		stat  string
+		value interface{}
+		ago   time.Duration
+	}{
+		{user: "u1", char: "c1", stat: "lvl", value: 10, ago: 30 * 24 * time.Hour},
+		{user: "u1", char: "c1", stat: "lvl", value: 12, ago: time.Hour},
+		{user: "u2", char: "c2", stat: "lvl", value: 5, ago: 2 * time.Hour},
+		{user: "u2", char: "c2", stat: "lvl", value: 8, ago: time.Hour},
+		{user: "u1", char: "c1", stat: "dps", value: 100.5, ago: 30 * 24 * time.Hour},
+		{user: "u1", char: "c1", stat: "dps", value: 99.25, ago: time.Hour},
+		{user: "u2", char: "c2", stat: "dps", value: 50.0, ago: 2 * time.Hour},
+		{user: "u2", char: "c2", stat: "dps", value: 60.75, ago: time.Hour},
+		{user: "u1", char: "c1", stat: "played", value: 10 * time.Hour, ago: 2 * time.Hour},
+		{user: "u1", char: "c1", stat: "played", value: 12*time.Hour + 30*time.Minute, ago: time.Hour},
+		{user: "u2", char: "c2", stat: "played", value: 5 * time.Hour, ago: 30 * 24 * time.Hour},
+		{user: "u2", char: "c2", stat: "played", value: 6 * time.Hour, ago: time.Hour},
+	}
+	for _, h := range history {
+		prov.AddStatHistory(&database.StatHistory{GuildId: gld.DiscordId, UserId: h.user, Character: h.char, Stat: h.stat, Value: h.value, Time: now.Add(-h.ago)})
+	}
+
+	msg.GuildIdMock = func() string { return gld.DiscordId }
+	msg.AuthorIdMock = func() string { return "u1" }
+	target := user.NewTopProcessor(prov)
+
+	testCases := []struct {
+		msg      string
+		expected string
+		err      bool
+	}{
+		{msg: "gain lvl 7d", expected: "Top 2 characters by lvl gain in the last 7d.\n\t0 : c2 : +3\n\t1 : c1 : +2\n"},
+		{msg: "gain dps 7d", expected: "Top 2 characters by dps gain in the last 7d.\n\t0 : c2 : +10.75\n\t1 : c1 : -1.25\n"},
+		{msg: "gain played 7d 1", expected: "Top 1 characters by played gain in the last 7d.\n\t0 : c1 : +2h30m0s\n"},
+		{msg: "gain class 7d", err: true},
+	}
+	for _, tc := range testCases {
+		msg.CurMsg = tc.msg
+		rv, err := target.ProcessMessage(msg)
+		if tc.err {
+			if err == nil {
+				t.Errorf("[%v] Error expected. Got: %v", tc.msg, rv)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[%v] Unexpected processing error: %v", tc.msg, err)
+		}
+		if rv != tc.expected {
+			t.Errorf("[%v] Wrong processing result. Expected: %v, got: %v", tc.msg, tc.expected, rv)
+		}
+	}
+}
+
+func TestTopSubGuilds(t *testing.T) {
+	msg := &tests.TestMessage{}
+	prov := memory.NewMemoryDb()
+	gld, _ := prov.AddGuild(&database.Guild{DiscordId: uuid.New().String(), Name: "test"})
+	alpha, _ := prov.AddGuild(&database.Guild{Name: "alpha", ParentId: gld.GuildId})
+	squad, _ := prov.AddGuild(&database.Guild{Name: "squad", ParentId: alpha.GuildId})
+	beta, _ := prov.AddGuild(&database.Guild{Name: "beta", ParentId: gld.GuildId})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "lvl", Type: database.Number})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "class", Type: database.Str})
+	prov.AddUser("u1", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: alpha.GuildId})
+	prov.AddUser("u2", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: squad.GuildId})
+	prov.AddUser("u3", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: beta.GuildId})
+	prov.AddUser("u4", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: gld.GuildId})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u1", Name: "c1", Main: true, Body: map[string]interface{}{"lvl": 10}})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u1", Name: "alt", Body: map[string]interface{}{"lvl": 50}})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u2", Name: "c2", Main: true, Body: map[string]interface{}{"lvl": 20}})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u3", Name: "c3", Main: true, Body: map[string]interface{}{"lvl": 30}})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u4", Name: "c4", Main: true, Body: map[string]interface{}{"lvl": 40}})
+
+	msg.GuildIdMock = func() string { return gld.DiscordId }
+	msg.AuthorIdMock = func() string { return "u1" }
+	target := user.NewTopProcessor(prov)
+
+	testCases := []struct {
+		msg      string
+		expected string
+		err      bool
+	}{
+		{msg: "in alpha lvl", expected: "Top 3 characters of alpha by lvl. (Highest first)\n\t0 : alt : 50\n\t1 : c2 : 20\n\t2 : c1 : 10\n"},
+		{msg: "in alpha lvl mains-only 1", expected: "Top 1 characters of alpha by lvl. Main characters only. (Highest first)\n\t0 : c2 : 20\n"},
+		{msg: "in beta lvl", expected: "Top 1 characters of beta by lvl. (Highest first)\n\t0 : c3 : 30\n"},
+		{msg: "in gamma lvl", err: true},
+		{msg: "in", err: true},
+		{msg: "guilds lvl", expected: "Sub-guilds of test by avg of lvl.\n\t0 : beta : 30 (1 characters)\n\t1 : alpha : 15 (2 characters)\n"},
+		{msg: "gs lvl max", expected: "Sub-guilds of test by max of lvl.\n\t0 : beta : 30 (1 characters)\n\t1 : alpha : 20 (2 characters)\n"},
+		{msg: "in alpha guilds lvl sum", expected: "Sub-guilds of alpha by sum of lvl.\n\t0 : squad : 20 (1 characters)\n"},
+		{msg: "in beta guilds lvl", err: true},
+		{msg: "guilds lvl mode", err: true},
+		{msg: "guilds class", err: true},
+		{msg: "guilds speed", err: true},
+	}
+	for _, tc := range testCases {
+		msg.CurMsg = tc.msg
+		rv, err := target.ProcessMessage(msg)
+		if tc.err {
+			if err == nil {
+				t.Errorf("[%v] Error expected. Got: %v", tc.msg, rv)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[%v] Unexpected processing error: %v", tc.msg, err)
+		}
+		if rv != tc.expected {
+			t.Errorf("[%v] Wrong processing result. Expected: %v, got: %v", tc.msg, tc.expected, rv)
+		}
+	}
+}
+
+func TestTopQuery(t *testing.T) {
+	msg := &tests.TestMessage{}
+	prov := memory.NewMemoryDb()
+	gld, _ := prov.AddGuild(&database.Guild{DiscordId: uuid.New().String(), Name: "test"})
+	alpha, _ := prov.AddGuild(&database.Guild{Name: "alpha", ParentId: gld.GuildId})
+	beta, _ := prov.AddGuild(&database.Guild{Name: "beta", ParentId: gld.GuildId})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "lvl", Type: database.Number})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "dps", Type: database.Float})
+	prov.AddGuildStat(gld.GuildId, &database.Stat{ID: "class", Type: database.Str})
+	prov.SetDefaultGuildStat(gld.GuildId, "lvl")
+	prov.AddUser("u1", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: alpha.GuildId})
+	prov.AddUser("u2", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: beta.GuildId})
+	prov.AddUser("u3", &database.GuildPermission{TopGuild: gld.DiscordId, GuildId: beta.GuildId})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u1", Name: "c1", Main: true, Body: map[string]interface{}{"lvl": 60, "dps": 10.0, "class": "mage"}})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u1", Name: "alt", Body: map[string]interface{}{"lvl": 40, "dps": 30.0, "class": "rogue"}})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u2", Name: "c2", Main: true, Body: map[string]interface{}{"lvl": 60, "dps": 20.0, "class": "rogue"}})
+	prov.AddCharacter(&database.Character{GuildId: gld.DiscordId, UserId: "u3", Name: "c3", Main: true, Body: map[string]interface{}{"lvl": 30, "dps": 50.0, "class": "mage"}})
+
+	msg.GuildIdMock = func() string { return gld.DiscordId }
+	msg.AuthorIdMock = func() string { return "u1" }
+	target := user.NewTopProcessor(prov)
+
+	testCases := []struct {
+		msg      string
+		expected string
+		err      bool
+	}{
+		{msg: "", expected: "Top 4 characters by lvl. (Highest first)\n\t0 : c1 : 60\n\t1 : c2 : 60\n\t2 : alt : 40\n\t3 : c3 : 30\n"},
+		{msg: "asc dps", expected: "Top 4 characters by dps. (Lowest first)\n\t0 : c1 : 10\n\t1 : c2 : 20\n\t2 : alt : 30\n\t3 : c3 : 50\n"},
+		{msg: "lvl desc, dps asc", expected: "Top 4 characters by lvl desc, dps asc.\n\t0 : c1 : 60 : 10\n\t1 : c2 : 60 : 20\n\t2 : alt : 40 : 30\n\t3 : c3 : 30 : 50\n"},
+		{msg: "lvl,dps", expected: "Top 4 characters by lvl desc, dps desc.\n\t0 : c2 : 60 : 20\n\t1 : c1 : 60 : 10\n\t2 : alt : 40 : 30\n\t3 : c3 : 30 : 50\n"},
+		{msg: "lvl a, class d 2", expected: "Top 2 characters by lvl asc, class desc.\n\t0 : c3 : 30 : mage\n\t1 : alt : 40 : rogue\n"},
+		{msg: "dps where class=mage", expected: "Top 2 characters by dps. Where class = mage. (Highest first)\n\t0 : c3 : 50\n\t1 : c1 : 10\n"},
+		{msg: "dps where lvl >= 40 and class = rogue", expected: "Top 2 characters by dps. Where lvl >= 40 and class = rogue. (Highest first)\n\t0 : alt : 30\n\t1 : c2 : 20\n"},
+		{msg: "dps where lvl > 50", expected: "Top 2 characters by dps. Where lvl > 50. (Highest first)\n\t0 : c2 : 20\n\t1 : c1 : 10\n"},
+		{msg: "dps where lvl > 30 2", expected: "Top 2 characters by dps. Where lvl > 30. (Highest first)\n\t0 : alt : 30\n\t1 : c2 : 20\n"},
+		{msg: "lvl mains-only", expected: "Top 3 characters by lvl. Main characters only. (Highest first)\n\t0 : c1 : 60\n\t1 : c2 : 60\n\t2 : c3 : 30\n"},
+		{msg: "lvl where main=no", expected: "Top 1 characters by lvl. Where main = no. (Highest first)\n\t0 : alt : 40\n"},
+		{msg: "lvl where guild!=alpha", expected: "Top 2 characters by lvl. Where guild != alpha. (Highest first)\n\t0 : c2 : 60\n\t1 : c3 : 30\n"},
+		{msg: "lvl where guild=alpha and dps<20", expected: "Top 1 characters by lvl. Where guild = alpha and dps < 20. (Highest first)\n\t0 : c1 : 60\n"},
+		{msg: "speed", err: true},
+		{msg: "lvl,", err: true},
+		{msg: ", lvl", err: true},
+		{msg: "lvl dps", err: true},
+		{msg: "lvl where class", err: true},
+		{msg: "lvl where speed>1", err: true},
+		{msg: "lvl where lvl>high", err: true},
+		{msg: "lvl where guild>alpha", err: true},
+		{msg: "lvl where guild=gamma", err: true},
+	}
+	for _, tc := range testCases {
+		msg.CurMsg = tc.msg
+		rv, err := target.ProcessMessage(msg)
+		if tc.err {
+			if err == nil {
+				t.Errorf("[%v] Error expected. Got: %v", tc.msg, rv)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[%v] Unexpected processing error: %v", tc.msg, err)
+		}
+		if rv != tc.expected {
+			t.Errorf("[%v] Wrong processing result. Expected: %v, got: %v", tc.msg, tc.expected, rv)
+		}
+	}
+}
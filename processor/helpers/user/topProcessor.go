@@ -3,7 +3,13 @@ package user
 import (
 	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
 
 	"github.com/mebaranov/disguildie/database"
 	"github.com/mebaranov/disguildie/message"
@@ -21,87 +27,731 @@ func NewTopProcessor(prov database.DataProvider) helpers.MessageProcessor {
 }
 
 func (ap *TopProcessor) ProcessMessage(m message.Message) (string, error) {
-	t, s, l := m.CurSegment(), m.CurSegment(), m.CurSegment()
+	p := m.PeekSegment()
+	if p == "gain" || p == "g" {
+		m.CurSegment()
+		return ap.gain(m)
+	}
+
+	var scope *database.Guild
+	if p == "in" {
+		m.CurSegment()
+		n := m.CurSegment()
+		if n == "" {
+			return "", errors.New("Invalid command format")
+		}
+
+		var err error
+		if scope, err = ap.Prov.GetGuildN(m.GuildId(), n); err != nil {
+			return "getting sub-guild", err
+		}
+		p = m.PeekSegment()
+	}
+
+	if p == "guilds" || p == "gs" {
+		m.CurSegment()
+		return ap.guilds(m, scope)
+	}
+	return ap.top(m, scope)
+}
+
+// top ranks characters of the guild. If scope is set, only characters of its members are ranked
+func (ap *TopProcessor) top(m message.Message, scope *database.Guild) (string, error) {
+	segs := make([]string, 0, 10)
+	for s := m.CurSegment(); s != ""; s = m.CurSegment() {
+		segs = append(segs, s)
+	}
+	if len(segs) == 1 && (segs[0] == "h" || segs[0] == "help") && !m.MoreSegments() {
+		return ap.help(m)
+	}
+
+	gs, err := helpers.NewGuildStats(ap.Prov, m.GuildId())
+	if err != nil {
+		return "getting guild stats", err
+	}
+
+	tq, err := parseTopQuery(gs, segs)
+	if err != nil {
+		return "", err
+	}
+	if scope != nil {
+		tq.restrict(gs.Subtree(scope.GuildId))
+	}
+
+	chars, err := ap.Prov.GetCharactersOutdated(m.GuildId(), gs.Top.StatVersion)
+	if err != nil {
+		return "getting outdated characters", nil
+	}
+
+	for _, c := range chars {
+		if _, err = gs.UpdateCharacter(ap.Prov, c); err != nil {
+			return "setting character stat version", err
+		}
+	}
+
+	chars, vals, err := ap.query(gs, tq)
+	if err != nil {
+		return "getting sorted characters", err
+	}
+
+	limit := tq.limit
+	if limit <= 0 {
+		limit = len(chars)
+	}
+	rv := fmt.Sprintf("Top %v characters by %v.", limit, tq.keysDescription())
+	if scope != nil {
+		rv = fmt.Sprintf("Top %v characters of %v by %v.", limit, scope.Name, tq.keysDescription())
+	}
+	if _, owner := gs.Stat(tq.keys[0].stat.ID); owner != gs.Top {
+		rv += " Sub-guild " + owner.Name + "."
+	}
+	if len(tq.text) > 0 {
+		rv += " Where " + strings.Join(tq.text, " and ") + "."
+	}
+	if tq.main != nil && *tq.main {
+		rv += " Main characters only."
+	}
+	if len(tq.keys) == 1 && tq.keys[0].asc {
+		rv += " (Lowest first)"
+	} else if len(tq.keys) == 1 {
+		rv += " (Highest first)"
+	}
+	rv += "\n"
+
+	for i, c := range chars {
+		rv += fmt.Sprintf("\t%v : %v : %v\n", i, c.Name, strings.Join(vals[i], " : "))
+	}
+
+	return rv, nil
+}
+
+type topKey struct {
+	stat *database.Stat
+	asc  bool
+}
+
+type topFilter struct {
+	stat  *database.Stat
+	op    int
+	value interface{}
+}
+
+// topQuery is a parsed "!g top" command. Nil guilds means owners from any sub-guild
+type topQuery struct {
+	keys    []*topKey
+	filters []*topFilter
+	main    *bool
+	guilds  map[uuid.UUID]bool
+	limit   int
+	text    []string
+}
+
+var topOps = []struct {
+	s  string
+	op int
+}{
+	{">=", database.OpGreaterEq},
+	{"<=", database.OpLessEq},
+	{"!=", database.OpNotEq},
+	{"=", database.OpEq},
+	{">", database.OpGreater},
+	{"<", database.OpLess},
+}
+
+// parseTopQuery parses "[asc] <stat> [asc|desc], <stat> [asc|desc] ... [where <condition> and <condition> ...] [mains-only] [count]"
+func parseTopQuery(gs *helpers.GuildStats, segs []string) (*topQuery, error) {
+	toks := strings.Fields(strings.ReplaceAll(strings.Join(segs, " "), ",", " , "))
+	tq := &topQuery{limit: -1}
+
 	asc := false
-	if t == "asc" || t == "a" {
+	if len(toks) > 0 && (toks[0] == "asc" || toks[0] == "a") {
 		asc = true
-	} else if l != "" {
-		return "", errors.New("Invalid command format")
-	} else {
-		l = s
-		s = t
+		toks = toks[1:]
+	}
+	if n := len(toks); n > 0 {
+		if l, err := strconv.Atoi(toks[n-1]); err == nil && l > 0 && (n == 1 || !strings.ContainsAny(toks[n-2][len(toks[n-2])-1:], "=<>,") && toks[n-2] != "and" && toks[n-2] != "where") {
+			tq.limit = l
+			toks = toks[:n-1]
+		}
 	}
 
-	if l == "" && (s == "h" || s == "help") && !m.MoreSegments() {
-		return ap.help(m)
+	conds, where := make([][]string, 0, 4), false
+	for _, t := range toks {
+		switch {
+		case t == "mains-only":
+			main := true
+			tq.main = &main
+		case where && t == "and":
+			conds = append(conds, []string{})
+		case where:
+			conds[len(conds)-1] = append(conds[len(conds)-1], t)
+		case t == "where":
+			where = true
+			conds = append(conds, []string{})
+		case t == ",":
+			if len(tq.keys) == 0 || tq.keys[len(tq.keys)-1] == nil {
+				return nil, errors.New("Invalid command format")
+			}
+			tq.keys = append(tq.keys, nil)
+		case len(tq.keys) > 0 && tq.keys[len(tq.keys)-1] != nil && (t == "asc" || t == "a" || t == "desc" || t == "d"):
+			tq.keys[len(tq.keys)-1].asc = t == "asc" || t == "a"
+		case len(tq.keys) == 0 || tq.keys[len(tq.keys)-1] == nil:
+			s, _ := gs.Stat(t)
+			if s == nil {
+				return nil, errors.New("Stat with name " + t + " is not defined in guild")
+			}
+			if len(tq.keys) > 0 {
+				tq.keys = tq.keys[:len(tq.keys)-1]
+			}
+			tq.keys = append(tq.keys, &topKey{stat: s, asc: asc})
+		default:
+			return nil, errors.New("Invalid command format")
+		}
+	}
+
+	if len(tq.keys) > 0 && tq.keys[len(tq.keys)-1] == nil {
+		return nil, errors.New("Invalid command format")
+	}
+	if len(tq.keys) == 0 {
+		s, _ := gs.Stat(gs.Top.DefaultStat)
+		if s == nil {
+			return nil, errors.New("This guild doesn't have any stats yet")
+		}
+		tq.keys = append(tq.keys, &topKey{stat: s, asc: asc})
 	}
 
+	for _, c := range conds {
+		if err := tq.addCondition(gs, strings.Join(c, " ")); err != nil {
+			return nil, err
+		}
+	}
+
+	return tq, nil
+}
+
+// addCondition parses "<stat> <op> <value>". Besides stats, "main" and "guild" (owner's sub-guild) can be checked
+func (tq *topQuery) addCondition(gs *helpers.GuildStats, c string) error {
+	pos, op, ops := -1, 0, ""
+	for i := 0; i < len(c) && pos < 0; i++ {
+		for _, o := range topOps {
+			if strings.HasPrefix(c[i:], o.s) {
+				pos, op, ops = i, o.op, o.s
+				break
+			}
+		}
+	}
+	if pos <= 0 {
+		return errors.New("Invalid condition " + c + ". Use something like \"class=mage\" or \"lvl>=50\"")
+	}
+
+	n := strings.TrimSpace(c[:pos])
+	v := strings.TrimSpace(c[pos+len(ops):])
+	tq.text = append(tq.text, fmt.Sprintf("%v %v %v", n, ops, v))
+
+	s, _ := gs.Stat(n)
+	if s == nil && (strings.ToLower(n) == "main" || strings.ToLower(n) == "guild") {
+		if op != database.OpEq && op != database.OpNotEq {
+			return errors.New("Only = and != can be used with " + n)
+		}
+		if strings.ToLower(n) == "main" {
+			b, err := (&database.Stat{ID: n, Type: database.Bool}).ParseValue(v)
+			if err != nil {
+				return err
+			}
+			main := b.(bool) == (op == database.OpEq)
+			tq.main = &main
+			return nil
+		}
+
+		var g *database.Guild
+		for _, sg := range gs.Guilds {
+			if sg.Name == v {
+				g = sg
+			}
+		}
+		if g == nil {
+			return errors.New("Sub-guild " + v + " was not found")
+		}
+
+		sub := gs.Subtree(g.GuildId)
+		if op == database.OpNotEq {
+			other := make([]uuid.UUID, 0, len(gs.Guilds))
+			for id := range gs.Guilds {
+				if !gs.Contains(g.GuildId, id) {
+					other = append(other, id)
+				}
+			}
+			sub = other
+		}
+		tq.restrict(sub)
+		return nil
+	}
+	if s == nil {
+		return errors.New("Stat with name " + n + " is not defined in guild")
+	}
+
+	var val interface{}
 	var err error
-	limit := -1
-	if l == "" {
-		limit, err = strconv.Atoi(s)
-		if err != nil {
-			limit = -1
+	if s.Type == database.Computed {
+		val, err = strconv.ParseFloat(v, 64)
+	} else {
+		val, err = s.ParseValue(v)
+	}
+	if err != nil {
+		return errors.New(fmt.Sprintf("Invalid value %v of stat %v", v, s.ID))
+	}
+
+	tq.filters = append(tq.filters, &topFilter{stat: s, op: op, value: val})
+	return nil
+}
+
+// restrict leaves owners from given sub-guilds only
+func (tq *topQuery) restrict(guilds []uuid.UUID) {
+	rv := make(map[uuid.UUID]bool)
+	for _, g := range guilds {
+		if tq.guilds == nil || tq.guilds[g] {
+			rv[g] = true
+		}
+	}
+	tq.guilds = rv
+}
+
+func (tq *topQuery) keysDescription() string {
+	if len(tq.keys) == 1 {
+		return tq.keys[0].stat.ID
+	}
+
+	rv := make([]string, len(tq.keys))
+	for i, k := range tq.keys {
+		rv[i] = k.stat.ID + " desc"
+		if k.asc {
+			rv[i] = k.stat.ID + " asc"
+		}
+	}
+	return strings.Join(rv, ", ")
+}
+
+// query runs tq and returns characters along with values of sort keys. Stored stats are filtered and sorted by the
+// database. If a computed stat is involved, the database only filters stored stats and the rest is done here
+func (ap *TopProcessor) query(gs *helpers.GuildStats, tq *topQuery) ([]*database.Character, [][]string, error) {
+	stats, computed, visible := make([]string, 0, 4), false, true
+	q := &database.CharacterQuery{Main: tq.main}
+	// evaluated is a query over values of computed stats, which are numbers
+	evaluated := &database.CharacterQuery{}
+	for _, k := range tq.keys {
+		stats = append(stats, k.stat.ID)
+		q.Order = append(q.Order, &database.SortKey{Stat: k.stat.ID, Type: k.stat.Type, Asc: k.asc})
+		evaluated.Order = append(evaluated.Order, &database.SortKey{Stat: k.stat.ID, Type: evaluatedType(k.stat), Asc: k.asc})
+	}
+	for _, f := range tq.filters {
+		stats = append(stats, f.stat.ID)
+		sf := &database.StatFilter{Stat: f.stat.ID, Type: evaluatedType(f.stat), Op: f.op, Value: f.value}
+		if f.stat.Type == database.Computed {
+			evaluated.Filters = append(evaluated.Filters, sf)
 		} else {
-			s = ""
+			q.Filters = append(q.Filters, sf)
 		}
-	} else {
-		limit, err = strconv.Atoi(l)
-		if err != nil {
-			return "", errors.New("Invalid command format")
+	}
+	for _, s := range stats {
+		st, owner := gs.Stat(s)
+		computed = computed || st.Type == database.Computed
+		visible = visible && owner == gs.Top
+	}
+	if tq.guilds != nil {
+		q.OwnerGuilds = make([]uuid.UUID, 0, len(tq.guilds))
+		for g := range tq.guilds {
+			q.OwnerGuilds = append(q.OwnerGuilds, g)
+		}
+	}
+	if computed {
+		q.Order = nil
+	} else if visible {
+		q.Limit = tq.limit
+	}
+
+	chars, err := ap.Prov.QueryCharacters(gs.Top.DiscordId, q)
+	if err != nil {
+		return nil, nil, err
+	}
+	// sub-guild stats are filtered after the query, as characters keep values of stats their owners don't see anymore
+	if !visible || computed {
+		if chars, err = visibleCharacters(gs, chars, stats); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	values := make(map[*database.Character]*database.Character, len(chars))
+	for _, c := range chars {
+		values[c] = c
+	}
+	if computed {
+		rv := make([]*database.Character, 0, len(chars))
+		for _, c := range chars {
+			if values[c], err = evaluate(gs, c, stats); err != nil {
+				return nil, nil, err
+			}
+
+			ok := true
+			for _, f := range evaluated.Filters {
+				ok = ok && f.Matches(values[c])
+			}
+			if ok {
+				rv = append(rv, c)
+			}
+		}
+
+		chars = rv
+		sort.Slice(chars, func(i int, j int) bool { return evaluated.Less(values[chars[i]], values[chars[j]]) })
+	}
+
+	if tq.limit > 0 && tq.limit < len(chars) {
+		chars = chars[:tq.limit]
+	}
+	vals := make([][]string, len(chars))
+	for i, c := range chars {
+		for _, k := range tq.keys {
+			v, ok := values[c].Body[k.stat.ID]
+			if ok {
+				ok, _ = database.IsStatType(evaluatedType(k.stat), v)
+			}
+			if !ok {
+				vals[i] = append(vals[i], "n/a")
+				continue
+			}
+			vals[i] = append(vals[i], database.FormatStatValue(v))
 		}
 	}
 
-	gld, err := ap.Prov.GetGuildD(m.GuildId())
+	return chars, vals, nil
+}
+
+// evaluatedType is the type of stat values after computed stats are calculated
+func evaluatedType(s *database.Stat) int {
+	if s.Type == database.Computed {
+		return database.Float
+	}
+	return s.Type
+}
+
+// evaluate returns a copy of the character with calculated values of computed stats.
+// Stats it can't be calculated for are left out
+func evaluate(gs *helpers.GuildStats, c *database.Character, names []string) (*database.Character, error) {
+	stats, err := gs.UserStats(c.UserId)
 	if err != nil {
-		return "getting guild", err
+		return nil, err
 	}
-	if s == "" {
-		s = gld.DefaultStat
+
+	rv := *c
+	rv.Body = make(map[string]interface{}, len(c.Body))
+	for k, v := range c.Body {
+		rv.Body[k] = v
 	}
-	if s == "" {
-		return "", errors.New("This guild doesn't have any stats yet")
+	for _, n := range names {
+		if s, ok := stats[n]; !ok || s.Type != database.Computed {
+			continue
+		}
+		if v, err := helpers.StatValue(stats, c, n); err == nil {
+			rv.Body[n] = v
+		}
 	}
 
-	stat, ok := gld.Stats[s]
+	return &rv, nil
+}
+
+// visibleCharacters filters out characters whose owners don't see some of the stats
+func visibleCharacters(gs *helpers.GuildStats, chars []*database.Character, names []string) ([]*database.Character, error) {
+	rv := make([]*database.Character, 0, len(chars))
+	for _, c := range chars {
+		stats, err := gs.UserStats(c.UserId)
+		if err != nil {
+			return nil, err
+		}
+
+		ok := true
+		for _, n := range names {
+			_, found := stats[n]
+			ok = ok && found
+		}
+		if ok {
+			rv = append(rv, c)
+		}
+	}
+
+	return rv, nil
+}
+
+var aggregates = map[string]func(vals []float64) float64{
+	"sum": func(vals []float64) float64 {
+		rv := 0.0
+		for _, v := range vals {
+			rv += v
+		}
+		return rv
+	},
+	"avg": func(vals []float64) float64 {
+		rv := 0.0
+		for _, v := range vals {
+			rv += v
+		}
+		return rv / float64(len(vals))
+	},
+	"median": func(vals []float64) float64 {
+		sort.Float64s(vals)
+		if n := len(vals); n%2 == 0 {
+			return (vals[n/2-1] + vals[n/2]) / 2
+		}
+		return vals[len(vals)/2]
+	},
+	"max": func(vals []float64) float64 {
+		rv := vals[0]
+		for _, v := range vals[1:] {
+			rv = math.Max(rv, v)
+		}
+		return rv
+	},
+}
+
+type guildAggregate struct {
+	g     *database.Guild
+	v     float64
+	count int
+}
+
+// guilds ranks sub-guilds of scope, or of the guild, by aggregated stat of main characters of their members
+func (ap *TopProcessor) guilds(m message.Message, scope *database.Guild) (string, error) {
+	s, a := m.CurSegment(), strings.ToLower(m.CurSegment())
+	if s == "" || m.PeekSegment() != "" {
+		return "", errors.New("Invalid command format")
+	}
+	if a == "" {
+		a = "avg"
+	}
+	agg, ok := aggregates[a]
 	if !ok {
+		return "", errors.New("Unknown aggregation " + a + ". Use sum, avg, median or max")
+	}
+
+	gs, err := helpers.NewGuildStats(ap.Prov, m.GuildId())
+	if err != nil {
+		return "getting guild stats", err
+	}
+
+	stat, _ := gs.Stat(s)
+	if stat == nil {
 		return "", errors.New("Stat with name " + s + " is not defined in guild")
 	}
+	switch stat.Type {
+	case database.Number, database.Float, database.Duration, database.Computed:
+	default:
+		return "", errors.New("Stat " + s + " is not a number")
+	}
+
+	parent := gs.Top
+	if scope != nil {
+		parent = scope
+	}
+	children := gs.Children(parent.GuildId)
+	if len(children) == 0 {
+		return "", errors.New(parent.Name + " doesn't have sub-guilds")
+	}
 
-	chars, err := ap.Prov.GetCharactersOutdated(m.GuildId(), gld.StatVersion)
+	chars, err := ap.Prov.GetGuildCharacters(gs.Top.DiscordId)
 	if err != nil {
-		return "getting outdated characters", nil
+		return "getting characters", err
 	}
 
+	vals := make(map[*database.Guild][]float64)
 	for _, c := range chars {
-		_, err = ap.Prov.SetCharacterStatVersion(c.GuildId, c.UserId, c.Name, gld.Stats, gld.StatVersion)
+		if !c.Main {
+			continue
+		}
+
+		ug, err := gs.UserGuild(c.UserId)
 		if err != nil {
-			return "setting character stat version", err
+			return "getting user sub-guild", err
+		}
+		stats, err := gs.UserStats(c.UserId)
+		if err != nil {
+			return "getting user stats", err
+		}
+		if _, ok := stats[stat.ID]; !ok {
+			continue
+		}
+
+		for _, g := range children {
+			if !gs.Contains(g.GuildId, ug) {
+				continue
+			}
+			// characters without the value don't drag aggregates down
+			if v, err := helpers.NumericValue(stats, c, stat.ID); err == nil {
+				vals[g] = append(vals[g], v)
+			}
+			break
 		}
 	}
 
-	chars, err = ap.Prov.GetCharactersSorted(m.GuildId(), stat.ID, stat.Type, asc, limit)
+	list := make([]*guildAggregate, 0, len(vals))
+	for g, vs := range vals {
+		list = append(list, &guildAggregate{g: g, v: agg(vs), count: len(vs)})
+	}
+	if len(list) == 0 {
+		return fmt.Sprintf("Members of sub-guilds of %v don't have %v set", parent.Name, stat.ID), nil
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].v != list[j].v {
+			return list[i].v > list[j].v
+		}
+		return list[i].g.Name < list[j].g.Name
+	})
+
+	rv := fmt.Sprintf("Sub-guilds of %v by %v of %v.\n", parent.Name, a, stat.ID)
+	for i, ga := range list {
+		rv += fmt.Sprintf("\t%v : %v : %v (%v characters)\n", i, ga.g.Name, aggregateValue(stat, ga.v), ga.count)
+	}
+
+	return rv, nil
+}
+
+// aggregateValue rounds aggregated value for chat. Durations are aggregated in hours
+func aggregateValue(s *database.Stat, v float64) string {
+	if s.Type == database.Duration {
+		return database.FormatStatValue(time.Duration(v * float64(time.Hour)).Round(time.Second))
+	}
+
+	return database.FormatStatValue(math.Round(v*100) / 100)
+}
+
+// signedValue formats a change of numeric stat value. Durations are counted in hours
+func signedValue(s *database.Stat, d float64) string {
+	if d < 0 {
+		return aggregateValue(s, d)
+	}
+	return "+" + aggregateValue(s, d)
+}
+
+type statGain struct {
+	name  string
+	first float64
+	last  float64
+	from  bool
+}
+
+func (ap *TopProcessor) gain(m message.Message) (string, error) {
+	s, p, l := m.CurSegment(), m.CurSegment(), m.CurSegment()
+	if s == "" || p == "" || m.PeekSegment() != "" {
+		return "", errors.New("Invalid command format")
+	}
+
+	period, err := parsePeriod(p)
 	if err != nil {
-		return "getting sorted characters", err
+		return "", err
 	}
 
-	if limit <= 0 {
-		limit = len(chars)
+	limit := -1
+	if l != "" {
+		limit, err = strconv.Atoi(l)
+		if err != nil {
+			return "", errors.New("Invalid command format")
+		}
 	}
-	rv := fmt.Sprintf("Top %v characters by %v.", limit, stat.ID)
-	if asc {
-		rv += " (Lowest first)\n"
-	} else {
-		rv += " (Highest first)\n"
+
+	gs, err := helpers.NewGuildStats(ap.Prov, m.GuildId())
+	if err != nil {
+		return "getting guild stats", err
 	}
 
-	for i, c := range chars {
-		rv += fmt.Sprintf("\t%v : %v : %v", i, c.Name, c.Body[stat.ID])
+	stat, owner := gs.Stat(s)
+	if stat == nil {
+		return "", errors.New("Stat with name " + s + " is not defined in guild")
+	}
+	switch stat.Type {
+	case database.Number, database.Float, database.Duration:
+	default:
+		return "", errors.New("Stat " + s + " is not numeric")
+	}
+
+	since := time.Now().Add(-period)
+	hs, err := ap.Prov.GetGuildStatHistory(m.GuildId(), stat.ID, since)
+	if err != nil {
+		return "getting stat history", err
+	}
+
+	gains := make(map[string]*statGain)
+	for _, h := range hs {
+		v, err := helpers.ToNumber(stat.ID, h.Value)
+		if err != nil {
+			continue
+		}
+
+		key := h.UserId + "/" + h.Character
+		g, ok := gains[key]
+		if !ok && owner != gs.Top {
+			stats, err := gs.UserStats(h.UserId)
+			if err != nil {
+				return "getting user stats", err
+			}
+			if _, ok := stats[stat.ID]; !ok {
+				continue
+			}
+		}
+		if !ok {
+			g = &statGain{name: h.Character, first: v}
+			gains[key] = g
+		}
+		g.last = v
+		if !h.Time.Before(since) {
+			g.from = true
+		}
+	}
+
+	list := make([]*statGain, 0, len(gains))
+	for _, g := range gains {
+		if g.from {
+			list = append(list, g)
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		di, dj := list[i].last-list[i].first, list[j].last-list[j].first
+		if di != dj {
+			return di > dj
+		}
+		return list[i].name < list[j].name
+	})
+
+	if limit > 0 && limit < len(list) {
+		list = list[:limit]
+	}
+	if len(list) == 0 {
+		return fmt.Sprintf("No %v changes in the last %v", stat.ID, p), nil
+	}
+
+	rv := fmt.Sprintf("Top %v characters by %v gain in the last %v.\n", len(list), stat.ID, p)
+	for i, g := range list {
+		rv += fmt.Sprintf("\t%v : %v : %v\n", i, g.name, signedValue(stat, g.last-g.first))
 	}
 
 	return rv, nil
 }
 
+func parsePeriod(p string) (time.Duration, error) {
+	units := map[byte]time.Duration{
+		'h': time.Hour,
+		'd': 24 * time.Hour,
+		'w': 7 * 24 * time.Hour,
+	}
+
+	if len(p) < 2 {
+		return 0, errors.New("Invalid period " + p + ". Use something like 7d, 2w or 12h")
+	}
+	u, ok := units[p[len(p)-1]]
+	n, err := strconv.Atoi(p[:len(p)-1])
+	if !ok || err != nil || n <= 0 {
+		return 0, errors.New("Invalid period " + p + ". Use something like 7d, 2w or 12h")
+	}
+
+	return time.Duration(n) * u, nil
+}
+
 func (ap *TopProcessor) help(m message.Message) (string, error) {
 	rv := "Here's a list of guild tops commands you're allowed to use:\n"
 	rv += "\t -- \"!g top\" (\"!g t\") - Get guild top characters by default stat (descending)\n"
@@ -110,6 +760,19 @@ func (ap *TopProcessor) help(m message.Message) (string, error) {
 	rv += "\t -- \"!g top <stat> <count>\" (\"!g t <stat> <count>\") - Get guild top <count> characters by stat name (descending)\n"
 	rv += "\nTo get top in ascending order - pass the same commands with \"!g top asc\" (\"!g t a\") prefix. For example:\n"
 	rv += "\t -- \"!g top asc <stat> <count>\" (\"!g t a <stat> <count>\") - Get guild top <count> characters by stat name in ascendong order\n"
+	rv += "\nTo sort by several stats - separate them with commas. Each stat can be followed by asc or desc (desc by default):\n"
+	rv += "\t -- \"!g top <stat> desc, <stat> asc <count>\" (\"!g t <stat> d, <stat> a <count>\") - Sort by the first stat, then by the next one for equal values\n"
+	rv += "\nTo filter characters - add \"where\" with conditions joined by \"and\". Conditions use =, !=, <, <=, > or >= and may check stats, \"main\" or \"guild\":\n"
+	rv += "\t -- \"!g top <stat> where class=mage and lvl>=50 <count>\" (\"!g t <stat> where class=mage and lvl>=50 <count>\") - Get top of characters matching all conditions\n"
+	rv += "\t -- \"!g top <stat> mains-only\" (\"!g t <stat> mains-only\") - Count main characters only\n"
+	rv += "\nTo rank members of a sub-guild only - pass the same commands with \"!g top in <sub-guild>\" (\"!g t in <sub-guild>\") prefix. For example:\n"
+	rv += "\t -- \"!g top in <sub-guild> <stat> <count>\" (\"!g t in <sub-guild> <stat> <count>\") - Get top <count> characters of the sub-guild and its sub-guilds by stat name\n"
+	rv += "\nTo compare sub-guilds - use \"!g top guilds\" (\"!g t gs\"). Main characters of members are counted, aggregation is sum, avg, median or max:\n"
+	rv += "\t -- \"!g top guilds <stat> <aggregation>\" (\"!g t gs <stat> <aggregation>\") - Rank sub-guilds by aggregated stat of their members (avg by default)\n"
+	rv += "\t -- \"!g top in <sub-guild> guilds <stat> <aggregation>\" (\"!g t in <sub-guild> gs <stat> <aggregation>\") - Rank sub-guilds of the sub-guild\n"
+	rv += "\nTo get top by stat progression - use \"!g top gain\" (\"!g t g\"). Period is a number followed by h, d or w (e.g. 7d, 30d):\n"
+	rv += "\t -- \"!g top gain <stat> <period>\" (\"!g t g <stat> <period>\") - Get guild top characters by how much a number, float or duration stat increased over the period\n"
+	rv += "\t -- \"!g top gain <stat> <period> <count>\" (\"!g t g <stat> <period> <count>\") - Get guild top <count> characters by stat increase over the period\n"
 
 	return rv, nil
 }
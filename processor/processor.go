@@ -9,6 +9,7 @@ import (
 	"github.com/bwmarrin/discordgo"
 
 	"github.com/mebaranov/disguildie/database"
+	"github.com/mebaranov/disguildie/database/audit"
 	"github.com/mebaranov/disguildie/message"
 	"github.com/mebaranov/disguildie/processor/helpers"
 	"github.com/mebaranov/disguildie/processor/helpers/admin"
@@ -37,15 +38,6 @@ func New(
 	ownerDiscord string,
 	paymentLink string) (*Processor, error) {
 
-	admin := admin.NewAdminProcessor(prov)
-	char := user.NewCharProcessor(prov)
-	list := user.NewListProcessor(prov)
-	owner := user.NewOwnerProcessor(prov)
-	stats := user.NewStatsProcessor(prov)
-	top := user.NewTopProcessor(prov)
-	hierarchy := user.NewHierarchyProcessor(prov)
-	gdpr := user.NewGdprProcessor(prov)
-
 	proc := &Processor{
 		rc:           make(chan bool),
 		superUser:    superUser,
@@ -56,26 +48,7 @@ func New(
 	}
 
 	proc.Prov = prov
-	proc.Funcs = map[string]func(message.Message) (string, error){
-		"help":      proc.help,
-		"h":         proc.help,
-		"admin":     admin.ProcessMessage,
-		"a":         admin.ProcessMessage,
-		"list":      list.ProcessMessage,
-		"l":         list.ProcessMessage,
-		"stat":      stats.ProcessMessage,
-		"s":         stats.ProcessMessage,
-		"char":      char.ProcessMessage,
-		"c":         char.ProcessMessage,
-		"top":       top.ProcessMessage,
-		"t":         top.ProcessMessage,
-		"owner":     owner.ProcessMessage,
-		"o":         owner.ProcessMessage,
-		"hierarchy": hierarchy.ProcessMessage,
-		"hi":        hierarchy.ProcessMessage,
-		"gdpr":      gdpr.ProcessMessage,
-		"g":         gdpr.ProcessMessage,
-	}
+	proc.Funcs = proc.funcs(prov)
 
 	s, err := discordgo.New("Bot " + token)
 	if err != nil {
@@ -110,6 +83,56 @@ func New(
 	return proc, nil
 }
 
+// funcs builds command handlers working with given provider
+func (proc *Processor) funcs(prov database.DataProvider) map[string]func(message.Message) (string, error) {
+	admin := admin.NewAdminProcessor(prov)
+	char := user.NewCharProcessor(prov)
+	list := user.NewListProcessor(prov)
+	owner := user.NewOwnerProcessor(prov)
+	stats := user.NewStatsProcessor(prov)
+	top := user.NewTopProcessor(prov)
+	rank := user.NewRankProcessor(prov)
+	hierarchy := user.NewHierarchyProcessor(prov)
+	gdpr := user.NewGdprProcessor(prov)
+
+	return map[string]func(message.Message) (string, error){
+		"help":      proc.help,
+		"h":         proc.help,
+		"admin":     admin.ProcessMessage,
+		"a":         admin.ProcessMessage,
+		"list":      paged(list.ProcessMessage),
+		"l":         paged(list.ProcessMessage),
+		"stat":      stats.ProcessMessage,
+		"s":         stats.ProcessMessage,
+		"stats":     stats.ProcessMessage,
+		"char":      char.ProcessMessage,
+		"c":         char.ProcessMessage,
+		"top":       paged(top.ProcessMessage),
+		"t":         paged(top.ProcessMessage),
+		"rank":      rank.ProcessMessage,
+		"r":         rank.ProcessMessage,
+		"owner":     owner.ProcessMessage,
+		"o":         owner.ProcessMessage,
+		"hierarchy": hierarchy.ProcessMessage,
+		"hi":        hierarchy.ProcessMessage,
+		"gdpr":      gdpr.ProcessMessage,
+		"g":         gdpr.ProcessMessage,
+	}
+}
+
+// paged sends output of long listings page by page instead of returning it
+func paged(f func(message.Message) (string, error)) func(message.Message) (string, error) {
+	return func(m message.Message) (string, error) {
+		rv, err := f(m)
+		if err != nil {
+			return rv, err
+		}
+
+		m.SendPaged(rv)
+		return "", nil
+	}
+}
+
 func (proc *Processor) Close() {
 	proc.s.Close()
 }
@@ -130,6 +153,7 @@ func (proc *Processor) help(m message.Message) (string, error) {
 	rv += "\t-- \"!g owner\" (\"!g o\") - get owner(s) of character\n"
 	rv += "\t-- \"!g stat\" (\"!g s\") - stats management\n"
 	rv += "\t-- \"!g top\" (\"!g t\") - guild tops\n"
+	rv += "\t-- \"!g rank\" (\"!g r\") - position of a character in guild top\n"
 	rv += "\t-- \"!g hierarchy\" (\"!g hi\") - sub-guilds structure\n"
 	rv += "\t-- \"!g gdpr\" - GDPR-related\n"
 
@@ -204,12 +228,17 @@ func (proc *Processor) messageCreate(s *discordgo.Session, m *discordgo.MessageC
 		}
 	}
 
-	rv, err := proc.ProcessMessage(msg)
+	// changes are recorded in the audit log on behalf of the author
+	prov := audit.NewAuditDb(proc.Prov, msg.AuthorId(), msg.FullMessage())
+	p := &helpers.BaseMessageProcessor{Prov: prov, Funcs: proc.funcs(prov)}
+	rv, err := p.ProcessMessage(msg)
 	if err != nil {
 		msg.SendMessage("Error %v: %v", rv, err)
 		return
 	}
-	msg.SendMessage(rv)
+	if rv != "" {
+		msg.SendMessage(rv)
+	}
 }
 
 func (proc *Processor) tryRegisterGuild(g *discordgo.Guild) error {